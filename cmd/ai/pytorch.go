@@ -8,24 +8,32 @@ import (
 )
 
 // Install and configure PyTorch.
-func pytorch(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		cudaVersion := utils.GetStringFlag(cmd, "cuda-version")
+func pytorch(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		cudaVersion, err := utils.GetStringFlag(cmd, "cuda-version")
+		if err != nil {
+			return err
+		}
 		version := "cpu"
 		if cudaVersion != "" {
 			version = "cu" + strings.ReplaceAll(cudaVersion, ".", "")
 		}
+		pipInstall, err := utils.BuildPipInstall(cmd)
+		if err != nil {
+			return err
+		}
 		command := utils.Format(`{pip_install} torch torchvision torchaudio \
 				--extra-index-url https://download.pytorch.org/whl/{version}`, map[string]string{
-			"pip_install": utils.BuildPipInstall(cmd),
+			"pip_install": pipInstall,
 			"version":     version,
 		})
-		utils.RunCmd(command)
-	}
-	if utils.GetBoolFlag(cmd, "config") {
-	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+		return utils.RunCmd(command)
 	}
+	return nil
 }
 
 var pyTorchCmd = &cobra.Command{
@@ -33,7 +41,7 @@ var pyTorchCmd = &cobra.Command{
 	Aliases: []string{"torch"},
 	Short:   "Install and configure PyTorch.",
 	//Args:  cobra.ExactArgs(1),
-	Run: pytorch,
+	RunE: pytorch,
 }
 
 func ConfigPyTorchCmd(rootCmd *cobra.Command) {
@@ -0,0 +1,44 @@
+package ai
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/internal/testutil"
+)
+
+var testRoot = sync.OnceValue(func() *cobra.Command {
+	root := &cobra.Command{Use: "icon"}
+	ConfigPyTorchCmd(root)
+	return root
+})
+
+func TestPyTorch(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"cpu", []string{"pytorch", "--install"}, []string{
+			"PIP_BREAK_SYSTEM_PACKAGES=1 python3 -m pip install torch torchvision torchaudio " +
+				"--extra-index-url https://download.pytorch.org/whl/cpu",
+		}},
+		{"cuda", []string{"pytorch", "--install", "--cuda-version", "12.4", "--user"}, []string{
+			"PIP_BREAK_SYSTEM_PACKAGES=1 python3 -m pip install --user torch torchvision torchaudio " +
+				"--extra-index-url https://download.pytorch.org/whl/cu124",
+		}},
+		{"python not found", []string{"pytorch", "--install", "--python", "python3.99"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			env.Exec.AddCommands("python3")
+			err := env.Run(testRoot(), tt.args...)
+			if (err != nil) != (tt.want == nil) {
+				t.Fatalf("error = %v", err)
+			}
+			env.AssertCommands(tt.want...)
+		})
+	}
+}
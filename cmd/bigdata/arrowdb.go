@@ -7,42 +7,93 @@ import (
 	"legendu.net/icon/utils"
 )
 
-func linkArrowDBProfileFromHost(backup, doCopy bool) {
+func linkArrowDBProfileFromHost(backup, doCopy bool) error {
+	currentUser, err := utils.GetCurrentUser()
+	if err != nil {
+		return err
+	}
+	home, err := utils.UserHomeDir()
+	if err != nil {
+		return err
+	}
 	//nolint:gocritic // filepathJoin: "/" is intentional to start an absolute path
-	srcProfile := filepath.Join("/", "home_host", utils.GetCurrentUser().Name, ".arrowdb_profile")
-	dstProfile := filepath.Join(utils.UserHomeDir(), ".arrowdb_profile")
+	srcProfile := filepath.Join("/", "home_host", currentUser.Name, ".arrowdb_profile")
+	dstProfile := filepath.Join(home, ".arrowdb_profile")
 	if utils.ExistsFile(srcProfile) {
 		// inside a Docker container, link profile from host
-		utils.BackupOrRemove(dstProfile, backup)
-		utils.CopyOrSymlink(srcProfile, dstProfile, doCopy)
+		if err := utils.BackupOrRemove(dstProfile, backup); err != nil {
+			return err
+		}
+		return utils.CopyOrSymlink(srcProfile, dstProfile, doCopy)
 	}
+	return nil
+}
+
+// Run "pip install" or "pip uninstall" on arrowdb.
+//
+// @param cmd    The cobra command whose flags are used to build the pip command.
+// @param action Either "install" or "uninstall".
+func pipArrowDB(cmd *cobra.Command, action string) error {
+	forceSudo, err := utils.GetBoolFlag(cmd, "sudo")
+	if err != nil {
+		return err
+	}
+	prefix, err := utils.GetCommandPrefix(forceSudo, map[string]uint32{})
+	if err != nil {
+		return err
+	}
+	var pip string
+	if action == "install" {
+		pip, err = utils.BuildPipInstall(cmd)
+	} else {
+		pip, err = utils.BuildPipUninstall(cmd)
+	}
+	if err != nil {
+		return err
+	}
+	command := utils.Format("{prefix} {pip} arrowdb", map[string]string{
+		"prefix": prefix,
+		"pip":    pip,
+	})
+	return utils.RunCmd(command)
 }
 
 // Install and configure the Python library arrowdb.
-func arrowDB(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		command := utils.Format("{prefix} {pip_install} arrowdb", map[string]string{
-			"prefix": utils.GetCommandPrefix(
-				utils.GetBoolFlag(cmd, "sudo"),
-				map[string]uint32{},
-			),
-			"pip_install": utils.BuildPipInstall(cmd),
-		})
-		utils.RunCmd(command)
+func arrowDB(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := pipArrowDB(cmd, "install"); err != nil {
+			return err
+		}
+	}
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		backup, err := utils.ShouldBackup(cmd)
+		if err != nil {
+			return err
+		}
+		doCopy, err := utils.GetBoolFlag(cmd, "copy")
+		if err != nil {
+			return err
+		}
+		if err := linkArrowDBProfileFromHost(backup, doCopy); err != nil {
+			return err
+		}
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		linkArrowDBProfileFromHost(utils.ShouldBackup(cmd), utils.GetBoolFlag(cmd, "copy"))
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		command := utils.Format("{prefix} {pip_uninstall} arrowdb", map[string]string{
-			"prefix": utils.GetCommandPrefix(
-				utils.GetBoolFlag(cmd, "sudo"),
-				map[string]uint32{},
-			),
-			"pip_uninstall": utils.BuildPipUninstall(cmd),
-		})
-		utils.RunCmd(command)
+	if uninstall {
+		return pipArrowDB(cmd, "uninstall")
 	}
+	return nil
 }
 
 var arrowDBCmd = &cobra.Command{
@@ -50,7 +101,7 @@ var arrowDBCmd = &cobra.Command{
 	Aliases: []string{},
 	Short:   "Install and configure arrowdb.",
 	//Args:  cobra.ExactArgs(1),
-	Run: arrowDB,
+	RunE: arrowDB,
 }
 
 func ConfigArrowDBCmd(rootCmd *cobra.Command) {
@@ -59,6 +110,7 @@ func ConfigArrowDBCmd(rootCmd *cobra.Command) {
 	arrowDBCmd.Flags().BoolP("config", "c", false, "Configure Spark.")
 	arrowDBCmd.Flags().Bool("no-backup", false, "Do not backup existing configuration files.")
 	arrowDBCmd.Flags().Bool("copy", false, "Make copies (instead of symbolic links) of configuration files.")
+	arrowDBCmd.Flags().Bool("sudo", false, "Force using sudo.")
 	utils.AddPythonFlags(arrowDBCmd)
 	rootCmd.AddCommand(arrowDBCmd)
 }
@@ -0,0 +1,71 @@
+package bigdata
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/internal/testutil"
+)
+
+var testRoot = sync.OnceValue(func() *cobra.Command {
+	root := &cobra.Command{Use: "icon"}
+	ConfigArrowDBCmd(root)
+	ConfigSparkCmd(root)
+	return root
+})
+
+func TestArrowDB(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"install", []string{"arrowdb", "--install"}, []string{
+			"PIP_BREAK_SYSTEM_PACKAGES=1 python3 -m pip install arrowdb",
+		}},
+		{"install with sudo", []string{"arrowdb", "--install", "--sudo"}, []string{
+			"sudo true",
+			"sudo PIP_BREAK_SYSTEM_PACKAGES=1 python3 -m pip install arrowdb",
+		}},
+		{"uninstall", []string{"arrowdb", "--uninstall"}, []string{
+			"python3 -m pip uninstall arrowdb",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			env.Exec.AddCommands("python3")
+			if err := env.Run(testRoot(), tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands(tt.want...)
+		})
+	}
+}
+
+func TestArrowDBConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		host    bool
+		wantDst string
+	}{
+		{"in a container", true, "/home_host/Tester/.arrowdb_profile"},
+		{"on the host", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			if tt.host {
+				env.WriteFile("/home_host/Tester/.arrowdb_profile", "export ARROWDB=1\n")
+			}
+			if err := env.Run(testRoot(), "arrowdb", "--config"); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands()
+			if got := env.Readlink("~/.arrowdb_profile"); got != tt.wantDst {
+				t.Errorf("~/.arrowdb_profile links to %q, want %q", got, tt.wantDst)
+			}
+		})
+	}
+}
@@ -2,11 +2,10 @@ package bigdata
 
 import (
 	"bufio"
-	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
-	"net/http"
 	"os"
 	"path/filepath"
 	"strings"
@@ -32,28 +31,20 @@ func getSparkDownloadURL(sparkVersion, hadoopVersion string) (string, error) {
 	url := "https://www.apache.org/dyn/closer.lua/spark/spark-%s/spark-%s-bin-hadoop%s%s.tgz"
 	suffix := ""
 	const firstSparkConnectVersion = 4
-	if utils.Atoi(extractMajorVersion(sparkVersion)) >= firstSparkConnectVersion {
-		suffix = "-connect"
-	}
-	url = fmt.Sprintf(url, sparkVersion, sparkVersion, hadoopVersion, suffix)
-	req, err := http.NewRequestWithContext(context.Background(),
-		http.MethodGet, url, http.NoBody)
-	if err != nil {
-		return "", fmt.Errorf("failed to create a HTTP GET request to the URL '%s' with context: %w", url, err)
-	}
-	resp, err := http.DefaultClient.Do(req)
+	major, err := utils.Atoi(extractMajorVersion(sparkVersion))
 	if err != nil {
-		return "", fmt.Errorf("the HTTP GET request to the URL '%s' failed: %w", url, err)
+		return "", err
 	}
-	defer resp.Body.Close()
-	if utils.IsErrorHTTPResponse(resp) {
-		return "", fmt.Errorf("the HTTP GET request got an error response with the status code %d: %w", resp.StatusCode, err)
+	if major >= firstSparkConnectVersion {
+		suffix = "-connect"
 	}
-	body, err := io.ReadAll(resp.Body)
+	url = fmt.Sprintf(url, sparkVersion, sparkVersion, hadoopVersion, suffix)
+	const numRetry = 3
+	const initialWaitingSeconds = 2
+	html, err := utils.HTTPGetAsString(url, numRetry, initialWaitingSeconds)
 	if err != nil {
-		return "", fmt.Errorf("failed to read the body of the response of the HTTP GET request: %w", err)
+		return "", err
 	}
-	html := string(body)
 	begin := strings.Index(html, "<strong>")
 	if begin < 0 {
 		return "", fmt.Errorf("the HTML does NOT contain the tag <strong>\n%s", html)
@@ -75,7 +66,7 @@ type sparkHadoopVersion struct {
 	Hadoop string `yaml:"hadoop"`
 }
 
-func chooseSparkVersion(versions []sparkHadoopVersion) sparkHadoopVersion {
+func chooseSparkVersion(versions []sparkHadoopVersion) (sparkHadoopVersion, error) {
 	for idx, version := range versions {
 		fmt.Printf("%d: Spark version - %s, Hadoop version - %s\n", idx, version.Spark, version.Hadoop)
 	}
@@ -83,110 +74,170 @@ func chooseSparkVersion(versions []sparkHadoopVersion) sparkHadoopVersion {
 	reader := bufio.NewReader(os.Stdin)
 	input, err := reader.ReadString('\n')
 	if err != nil {
-		log.Fatalf("Error reading input: %v", err)
+		return sparkHadoopVersion{}, fmt.Errorf("error reading input: %w", err)
+	}
+	idx, err := utils.Atoi(strings.TrimSpace(input))
+	if err != nil {
+		return sparkHadoopVersion{}, err
 	}
-	idx := utils.Atoi(strings.TrimSpace(input))
 	if idx < 0 || idx >= len(versions) {
-		log.Fatalf("Invalid index %d: please enter a value between 0 and %d.", idx, len(versions)-1)
+		return sparkHadoopVersion{}, fmt.Errorf("invalid index %d: please enter a value between 0 and %d", idx, len(versions)-1)
 	}
-	return versions[idx]
+	return versions[idx], nil
 }
 
-func readSparkHadoopVersion(interactive bool) sparkHadoopVersion {
+func readSparkHadoopVersion(interactive bool) (sparkHadoopVersion, error) {
 	file := "~/.config/icon-data/spark/version.yaml"
 	if !utils.ExistsFile(file) {
-		log.Fatalf("Spar/Hadoop versions are not specified or configured (%s).", file)
+		return sparkHadoopVersion{}, &utils.MissingConfigError{Path: file}
 	}
-	var versions []sparkHadoopVersion
-	err := yaml.Unmarshal(utils.ReadFile(file), &versions)
+	bytes, err := utils.ReadFile(file)
 	if err != nil {
-		log.Fatalf("Error unmarshaling data: %v", err)
+		return sparkHadoopVersion{}, err
+	}
+	var versions []sparkHadoopVersion
+	if err := yaml.Unmarshal(bytes, &versions); err != nil {
+		return sparkHadoopVersion{}, fmt.Errorf("error unmarshaling data: %w", err)
 	}
 	switch len(versions) {
 	case 0:
-		log.Fatalf("No Spark versions configured in %s", file)
+		return sparkHadoopVersion{}, &utils.MissingConfigError{Path: file, Key: "Spark/Hadoop versions"}
 	case 1:
-		return versions[0]
+		return versions[0], nil
 	}
 	if interactive {
 		return chooseSparkVersion(versions)
 	}
-	return versions[0]
+	return versions[0], nil
+}
+
+// Resolve the Spark/Hadoop versions from the command-line flags or the configuration file.
+func resolveSparkHadoopVersion(cmd *cobra.Command) (string, string, error) {
+	sparkVersion, err := utils.GetStringFlag(cmd, "spark-version")
+	if err != nil {
+		return "", "", err
+	}
+	hadoopVersion, err := utils.GetStringFlag(cmd, "hadoop-version")
+	if err != nil {
+		return "", "", err
+	}
+	if (sparkVersion == "") != (hadoopVersion == "") {
+		return "", "", errors.New("either both of spark/hadoop versions or neither of them should be specified")
+	}
+	if sparkVersion != "" {
+		return sparkVersion, hadoopVersion, nil
+	}
+	interactive, err := utils.GetBoolFlag(cmd, "interactive")
+	if err != nil {
+		return "", "", err
+	}
+	version, err := readSparkHadoopVersion(interactive)
+	if err != nil {
+		return "", "", err
+	}
+	return version.Spark, version.Hadoop, nil
+}
+
+func installSpark(url, prefix, dir, sparkHome string) error {
+	sparkTgz, err := utils.DownloadFile(url, "spark.tgz", true)
+	if err != nil {
+		return err
+	}
+	log.Printf("Installing Spark into the directory %s ...\n", sparkHome)
+	if err := utils.NewCommand("mkdir", "-p", dir).Sudo(prefix).Run(); err != nil {
+		return err
+	}
+	if err := utils.NewCommand("tar", "-zxf", sparkTgz, "-C", dir).Sudo(prefix).Run(); err != nil {
+		return err
+	}
+	return utils.RemoveAll(sparkTgz)
+}
+
+func configSpark(prefix, sparkHome string) error {
+	if err := icon.FetchConfigData(false, ""); err != nil {
+		return err
+	}
+	metastoreDB := filepath.Join(sparkHome, "metastoreDb")
+	warehouse := filepath.Join(sparkHome, "warehouse")
+	if err := utils.MkdirAll(metastoreDB, "777"); err != nil {
+		return err
+	}
+	if err := utils.MkdirAll(warehouse, "777"); err != nil {
+		return err
+	}
+	// spark-defaults.conf
+	text, err := utils.ReadFileAsString("~/.config/icon-data/spark/spark-defaults.conf")
+	if err != nil {
+		return err
+	}
+	conf := strings.ReplaceAll(text, "$SPARK_HOME", sparkHome) + "\n"
+	command := utils.NewCommand("tee", filepath.Join(sparkHome, "conf", "spark-defaults.conf")).Sudo(prefix)
+	if err := command.Stdin(strings.NewReader(conf)).Stdout(io.Discard).Run(); err != nil {
+		return err
+	}
+	log.Printf(
+		"Spark is configured to use %s as the metastore database and %s as the Hive warehouse.",
+		metastoreDB, warehouse,
+	)
+	// create databases and tables
+	/*
+	   if schemaDir:
+	       createDbs(sparkHome, schemaDir)
+	   if not isWin():
+	       runCmd(f"{args.prefix} chmod -R 777 {metastoreDb}")
+	*/
+	return nil
 }
 
 // Install and configure Spark.
-func spark(cmd *cobra.Command, _ []string) {
+func spark(cmd *cobra.Command, _ []string) error {
 	// installation location
-	dir := utils.GetStringFlag(cmd, "directory")
-	// Spark/Hadoop version
-	sparkVersion := utils.GetStringFlag(cmd, "spark-version")
-	hadoopVersion := utils.GetStringFlag(cmd, "hadoop-version")
-	if (sparkVersion == "") != (hadoopVersion == "") {
-		log.Fatal("Either both of spark/hadoop versions or neither of them should be specified!")
+	dir, err := utils.GetStringFlag(cmd, "directory")
+	if err != nil {
+		return err
 	}
-	if sparkVersion == "" {
-		version := readSparkHadoopVersion(utils.GetBoolFlag(cmd, "interactive"))
-		sparkVersion = version.Spark
-		hadoopVersion = version.Hadoop
+	// Spark/Hadoop version
+	sparkVersion, hadoopVersion, err := resolveSparkHadoopVersion(cmd)
+	if err != nil {
+		return err
 	}
 	url, err := getSparkDownloadURL(sparkVersion, hadoopVersion)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	sparkHdp := extractHdp(url)
 	sparkHome := filepath.Join(dir, sparkHdp)
 	// install Spark
-	prefix := utils.GetCommandPrefix(false, map[string]uint32{
+	prefix, err := utils.GetCommandPrefix(false, map[string]uint32{
 		dir: unix.W_OK | unix.R_OK,
 	})
-	if utils.GetBoolFlag(cmd, "install") {
-		sparkTgz, err := utils.DownloadFile(url, "spark.tgz", true)
-		if err != nil {
-			log.Fatal(err)
+	if err != nil {
+		return err
+	}
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := installSpark(url, prefix, dir, sparkHome); err != nil {
+			return err
 		}
-		log.Printf("Installing Spark into the directory %s ...\n", sparkHome)
-		cmd := utils.Format("{prefix} mkdir -p {dir} && {prefix} tar -zxf {sparkTgz} -C {dir} && rm {sparkTgz}", map[string]string{
-			"prefix":   prefix,
-			"dir":      dir,
-			"sparkTgz": sparkTgz,
-		})
-		utils.RunCmd(cmd)
-	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
-
-		metastoreDB := filepath.Join(sparkHome, "metastoreDb")
-		warehouse := filepath.Join(sparkHome, "warehouse")
-		utils.MkdirAll(metastoreDB, "777")
-		utils.MkdirAll(warehouse, "777")
-		// spark-defaults.conf
-		text := utils.ReadFileAsString("~/.config/icon-data/spark/spark-defaults.conf")
-		cmd := utils.Format("echo '{conf}' | {prefix} tee {sparkDefaults} > /dev/null",
-			map[string]string{
-				"prefix":        prefix,
-				"conf":          strings.ReplaceAll(text, "$SPARK_HOME", sparkHome),
-				"sparkDefaults": filepath.Join(sparkHome, "conf", "spark-defaults.conf"),
-			},
-		)
-		utils.RunCmd(cmd)
-		log.Printf(
-			"Spark is configured to use %s as the metastore database and %s as the Hive warehouse.",
-			metastoreDB, warehouse,
-		)
-		// create databases and tables
-		/*
-		   if schemaDir:
-		       createDbs(sparkHome, schemaDir)
-		   if not isWin():
-		       runCmd(f"{args.prefix} chmod -R 777 {metastoreDb}")
-		*/
-	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		/*
+	}
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		if err := configSpark(prefix, sparkHome); err != nil {
+			return err
+		}
+	}
+	/*
+		if uninstall:
 			cmd = f"{args.prefix} rm -rf {sparkHome}"
 			runCmd(cmd)
-		*/
-	}
+	*/
+	return nil
 }
 
 var sparkCmd = &cobra.Command{
@@ -194,7 +245,7 @@ var sparkCmd = &cobra.Command{
 	Aliases: []string{},
 	Short:   "Install and configure Spark.",
 	//Args:  cobra.ExactArgs(1),
-	Run: spark,
+	RunE: spark,
 }
 
 func ConfigSparkCmd(rootCmd *cobra.Command) {
@@ -9,27 +9,33 @@ import (
 )
 
 // Install and configure Rust.
-func bytehound(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		if utils.IsLinux() {
-			network.DownloadGitHubRelease("koute/bytehound", "", map[string][]string{
-				"common": {"bytehound", "tgz"},
-				"amd64":  {"x86_64"},
-				"linux":  {"linux", "gnu"},
-			}, []string{}, "/tmp/bytehound.tar.gz")
-			command := utils.Format(`mkdir -p ~/.local/bin && tar -zxvf /tmp/bytehound.tar.gz -C ~/.local/bin \
-				&& mkdir -p ~/.local/lib && mv ~/.local/bin/libbytehound.so ~/.local/lib`, map[string]string{})
-			utils.RunCmd(command)
-			log.Println("libbytehound.so has been installed to ~/.local/lib.")
-			log.Println("bytehound and bytehound-gather has been installed to ~/.local/bin.")
-		} else {
-			log.Fatalln("Bytehound is only supported on linux!")
-		}
-	}
-	if utils.GetBoolFlag(cmd, "config") {
+func bytehound(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+	if install {
+		if !utils.IsLinux() {
+			return utils.NewUnsupportedDistroError("Bytehound")
+		}
+		err := network.DownloadGitHubRelease("koute/bytehound", "", map[string][]string{
+			"common": {"bytehound", "tgz"},
+			"amd64":  {"x86_64"},
+			"linux":  {"linux", "gnu"},
+		}, []string{}, "/tmp/bytehound.tar.gz")
+		if err != nil {
+			return err
+		}
+		if _, err := utils.InstallBinaries("/tmp/bytehound.tar.gz", []string{"bytehound", "bytehound-gather"}, "~/.local/bin"); err != nil {
+			return err
+		}
+		if _, err := utils.InstallBinaries("/tmp/bytehound.tar.gz", []string{"libbytehound.so"}, "~/.local/lib"); err != nil {
+			return err
+		}
+		log.Println("libbytehound.so has been installed to ~/.local/lib.")
+		log.Println("bytehound and bytehound-gather has been installed to ~/.local/bin.")
 	}
+	return nil
 }
 
 var bytehoundCmd = &cobra.Command{
@@ -37,7 +43,7 @@ var bytehoundCmd = &cobra.Command{
 	Aliases: []string{"bh", "byteh", "bhound"},
 	Short:   "Install and configure Bytehound.",
 	//Args:  cobra.ExactArgs(1),
-	Run: bytehound,
+	RunE: bytehound,
 }
 
 func ConfigBytehoundCmd(rootCmd *cobra.Command) {
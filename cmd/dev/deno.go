@@ -6,15 +6,16 @@ import (
 )
 
 // Install and configure Deno.
-func deno(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		cmd := `curl -fsSL https://deno.land/install.sh | sh -s - -y --no-modify-path`
-		utils.RunCmd(cmd)
-	}
-	if utils.GetBoolFlag(cmd, "config") {
+func deno(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+	if install {
+		cmd := `curl -fsSL https://deno.land/install.sh | sh -s - -y --no-modify-path`
+		return utils.RunCmd(cmd)
 	}
+	return nil
 }
 
 var denoCmd = &cobra.Command{
@@ -22,7 +23,7 @@ var denoCmd = &cobra.Command{
 	Aliases: []string{},
 	Short:   "Install and configure Deno.",
 	//Args:  cobra.ExactArgs(1),
-	Run: deno,
+	RunE: deno,
 }
 
 func ConfigDenoCmd(rootCmd *cobra.Command) {
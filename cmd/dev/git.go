@@ -2,8 +2,8 @@ package dev
 
 import (
 	"log"
-	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"github.com/spf13/cobra"
@@ -12,69 +12,74 @@ import (
 	"legendu.net/icon/utils"
 )
 
-func installGitUI(cmd *cobra.Command) {
-	if utils.GetBoolFlag(cmd, "gitui") {
-		tmpdir := utils.CreateTempDir("")
-		defer os.RemoveAll(tmpdir)
-		file := filepath.Join(tmpdir, "gitui.tar.gz")
-		network.DownloadGitHubRelease("extrawurst/gitui", "", map[string][]string{
-			"common": {"tar.gz"},
-			"linux":  {"linux"},
-			"darwin": {"mac"},
-			"amd64":  {"musl"},
-			"arm64":  {"aarch64"},
-		}, []string{}, file)
-		command := utils.Format(`{prefix} tar -zxvf {file} -C /usr/local/bin/`, map[string]string{
-			"prefix": utils.GetCommandPrefix(
-				true,
-				map[string]uint32{},
-			),
-			"file": file,
-		})
-		utils.RunCmd(command)
+func installGitUI(cmd *cobra.Command) error {
+	gitui, err := utils.GetBoolFlag(cmd, "gitui")
+	if err != nil || !gitui {
+		return err
 	}
+	ver, err := utils.GetStringFlag(cmd, "gitui-version")
+	if err != nil {
+		return err
+	}
+	return network.DownloadRelease("extrawurst/gitui", ver, map[string][]string{
+		"common": {"tar.gz"},
+		"linux":  {"linux"},
+		"darwin": {"mac"},
+		"amd64":  {"musl"},
+		"arm64":  {"aarch64"},
+	}, []string{}, "", network.ReleaseOptions{
+		InstallBin: []string{"gitui"},
+		BinDir:     "/usr/local/bin",
+	})
 }
 
-func configGitUI(cmd *cobra.Command) {
-	if utils.GetBoolFlag(cmd, "gitui") {
-		src := "~/.config/icon-data/git/gitui/key_bindings.ron"
-		//nolint:gocritic // linux and macOS only
-		dst := filepath.Join("~/.config/gitui", filepath.Base(src))
-		utils.BackupOrRemove(dst, utils.ShouldBackup(cmd))
-		utils.CopyOrSymlink(src, dst, utils.GetBoolFlag(cmd, "copy"))
+func configGitUI(cmd *cobra.Command) error {
+	gitui, err := utils.GetBoolFlag(cmd, "gitui")
+	if err != nil || !gitui {
+		return err
+	}
+	backup, err := utils.ShouldBackup(cmd)
+	if err != nil {
+		return err
 	}
+	doCopy, err := utils.GetBoolFlag(cmd, "copy")
+	if err != nil {
+		return err
+	}
+	src := "~/.config/icon-data/git/gitui/key_bindings.ron"
+	//nolint:gocritic // linux and macOS only
+	dst := filepath.Join("~/.config/gitui", filepath.Base(src))
+	if err := utils.BackupOrRemove(dst, backup); err != nil {
+		return err
+	}
+	return utils.CopyOrSymlink(src, dst, doCopy)
 }
 
-func installGitDelta() {
-	tmpdir := utils.CreateTempDir("")
-	defer os.RemoveAll(tmpdir)
-	file := filepath.Join(tmpdir, "git-delta.tar.gz")
-	network.DownloadGitHubRelease("dandavison/delta", "", map[string][]string{
+func installGitDelta(ver string) error {
+	return network.DownloadRelease("dandavison/delta", ver, map[string][]string{
 		"common": {},
 		"amd64":  {"x86_64"},
 		"arm64":  {"aarch64"},
 		"linux":  {"linux", "gnu"},
 		"darwin": {"apple", "darwin"},
-	}, []string{}, file)
-	command := utils.Format(`{prefix} tar -zxvf {file} \
-			-C /usr/local/bin/ --wildcards --no-anchored delta --strip-components=1 \
-		&& rm {file}`, map[string]string{
-		"prefix": utils.GetCommandPrefix(
-			true,
-			map[string]uint32{},
-		),
-		"file": file,
+	}, []string{}, "", network.ReleaseOptions{
+		InstallBin: []string{"delta"},
+		BinDir:     "/usr/local/bin",
 	})
-	utils.RunCmd(command)
 }
 
 // configGitUser writes the shared user identity into ~/.config/git/user, which
 // the gitconfig template includes via an [include] directive. This keeps the
 // user name/email single-sourced from ~/.config/icon-data/user.yaml instead of
 // being hard-coded in the (symlinked) gitconfig.
-func configGitUser() {
-	cfg := utils.ReadUserConfig()
-	utils.MkdirAll("~/.config/git", "700")
+func configGitUser() error {
+	cfg, err := utils.ReadUserConfig()
+	if err != nil {
+		return err
+	}
+	if err := utils.MkdirAll("~/.config/git", "700"); err != nil {
+		return err
+	}
 	content := utils.Format(`[user]
     name = {userName}
     email = {userEmail}
@@ -83,122 +88,185 @@ func configGitUser() {
 		"userEmail": cfg.UserEmail,
 	})
 	//nolint:mnd // readable
-	utils.WriteTextFile("~/.config/git/user", content, 0o600)
+	return utils.WriteTextFile("~/.config/git/user", content, 0o600)
 }
 
-func configGitProxy(cmd *cobra.Command) {
-	git := utils.GetStringFlag(cmd, "git")
-	proxy := utils.GetStringFlag(cmd, "proxy")
-	if proxy != "" {
-		command := utils.Format(`{git} config --global http.proxy {proxy} \
-				&& {git} config --global https.proxy {proxy}`, map[string]string{
-			"proxy": proxy,
-			"git":   git,
-		})
-		utils.RunCmd(command)
+func configGitProxy(cmd *cobra.Command) error {
+	git, err := utils.GetStringFlag(cmd, "git")
+	if err != nil {
+		return err
+	}
+	proxy, err := utils.GetStringFlag(cmd, "proxy")
+	if err != nil || proxy == "" {
+		return err
 	}
+	if err := utils.NewCommand(git, "config", "--global", "http.proxy", proxy).Run(); err != nil {
+		return err
+	}
+	return utils.NewCommand(git, "config", "--global", "https.proxy", proxy).Run()
 }
 
-// Install and configure Git.
-func git(cmd *cobra.Command, args []string) {
-	git := utils.GetStringFlag(cmd, "git")
-	if utils.GetBoolFlag(cmd, "install") {
-		if utils.IsLinux() {
-			if utils.IsUniversalBlue() {
-				utils.BrewInstallSafe([]string{"git-delta", "gitui"})
-				if utils.LookPath("git") == "" || utils.LookPath("git-lfs") == "" {
-					log.Print("Please switch to developer mode using `ujust devmode` for git/git-lfs.")
-				}
-			} else if utils.IsDebianUbuntuSeries() {
-				command := utils.Format(`{prefix} apt-get {yesStr} update \
-						&& {prefix} apt-get {yesStr} install git git-lfs`, map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-				installGitDelta()
-				installGitUI(cmd)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format("{prefix} dnf {yesStr} install git", map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-				installGitDelta()
-				installGitUI(cmd)
-			}
-		} else {
-			utils.BrewInstallSafe([]string{"git", "git-lfs", "git-delta", "gitui"})
+// gitPackages are packages of Git for package managers.
+// git-delta and gitui are installed from GitHub releases if they are not packaged.
+var gitPackages = utils.Packages{
+	"apt":    {"git", "git-lfs"},
+	"dnf":    {"git", "git-lfs"},
+	"brew":   {"git", "git-lfs", "git-delta", "gitui"},
+	"pacman": {"git", "git-lfs", "git-delta", "gitui"},
+	"zypper": {"git", "git-lfs"},
+	"apk":    {"git", "git-lfs", "delta", "gitui"},
+}
+
+func installGit(cmd *cobra.Command, git string) error {
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	if utils.IsUniversalBlue() {
+		if err := utils.BrewInstallSafe([]string{"git-delta", "gitui"}); err != nil {
+			return err
+		}
+		if utils.LookPath("git") == "" || utils.LookPath("git-lfs") == "" {
+			log.Print("Please switch to developer mode using `ujust devmode` for git/git-lfs.")
+		}
+	} else {
+		pm, pkgs, err := utils.ResolvePackages("Git", gitPackages)
+		if err != nil {
+			return err
 		}
-		command := utils.Format("{git} lfs install", map[string]string{
-			"git": git,
-		})
-		utils.RunCmd(command)
-	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
-		network.SSHClient(cmd, args)
-		src := "~/.config/icon-data/git/gitconfig"
-		dst := "~/.gitconfig"
-		utils.BackupOrRemove(dst, utils.ShouldBackup(cmd))
-		utils.CopyOrSymlink(src, dst, utils.GetBoolFlag(cmd, "copy"))
-		configGitUser()
-		configGitProxy(cmd)
-		configGitUI(cmd)
-	}
-	configureGitIgnore(cmd)
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		command := utils.Format("{git} lfs uninstall", map[string]string{
-			"git": git,
-		})
-		utils.RunCmd(command)
-		if utils.IsLinux() {
-			if utils.IsDebianUbuntuSeries() {
-				command := utils.Format("{prefix} apt-get {yesStr} purge git git-lfs", map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format("{prefix} dnf {yesStr} remove git", map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
+		if err := pm.Install(yes, pkgs...); err != nil {
+			return err
+		}
+		if !slices.Contains(pkgs, "gitui") {
+			ver, err := utils.GetStringFlag(cmd, "delta-version")
+			if err != nil {
+				return err
+			}
+			// delta and gitui are downloaded and installed concurrently
+			err = utils.RunParallel(
+				func() error { return installGitDelta(ver) },
+				func() error { return installGitUI(cmd) },
+			)
+			if err != nil {
+				return err
 			}
-		} else {
-			utils.RunCmd("brew uninstall git git-lfs")
 		}
 	}
+	return utils.NewCommand(git, "lfs", "install").Run()
+}
+
+func configGit(cmd *cobra.Command, args []string) error {
+	if err := icon.FetchConfigData(false, ""); err != nil {
+		return err
+	}
+	if err := network.SSHClient(cmd, args); err != nil {
+		return err
+	}
+	backup, err := utils.ShouldBackup(cmd)
+	if err != nil {
+		return err
+	}
+	doCopy, err := utils.GetBoolFlag(cmd, "copy")
+	if err != nil {
+		return err
+	}
+	src := "~/.config/icon-data/git/gitconfig"
+	dst := "~/.gitconfig"
+	if err := utils.BackupOrRemove(dst, backup); err != nil {
+		return err
+	}
+	if err := utils.CopyOrSymlink(src, dst, doCopy); err != nil {
+		return err
+	}
+	if err := configGitUser(); err != nil {
+		return err
+	}
+	if err := configGitProxy(cmd); err != nil {
+		return err
+	}
+	return configGitUI(cmd)
+}
+
+func uninstallGit(cmd *cobra.Command, git string) error {
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	if err := utils.NewCommand(git, "lfs", "uninstall").Run(); err != nil {
+		return err
+	}
+	return utils.RemovePackages("Git", gitPackages, yes)
+}
+
+// Install and configure Git.
+func git(cmd *cobra.Command, args []string) error {
+	git, err := utils.GetStringFlag(cmd, "git")
+	if err != nil {
+		return err
+	}
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := installGit(cmd, git); err != nil {
+			return err
+		}
+	}
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		if err := configGit(cmd, args); err != nil {
+			return err
+		}
+	}
+	if err := configureGitIgnore(cmd); err != nil {
+		return err
+	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return uninstallGit(cmd, git)
+	}
+	return nil
 }
 
 // Insert patterns to ingore into .gitignore in the current directory.
-func configureGitIgnore(cmd *cobra.Command) {
-	lang := strings.ToLower(utils.GetStringFlag(cmd, "lang"))
+func configureGitIgnore(cmd *cobra.Command) error {
+	lang, err := utils.GetStringFlag(cmd, "lang")
+	if err != nil {
+		return err
+	}
+	lang = strings.ToLower(lang)
 	if lang == "" {
-		return
+		return nil
 	}
 	srcFile := "~/.config/icon-data/git/gitignore_" + lang
-	dstDir := utils.GetStringFlag(cmd, "dest-dir")
+	dstDir, err := utils.GetStringFlag(cmd, "dest-dir")
+	if err != nil {
+		return err
+	}
 	dstFile := filepath.Join(dstDir, ".gitignore")
-	if utils.GetBoolFlag(cmd, "append") {
-		utils.AppendToTextFile(dstFile, utils.ReadFileAsString(srcFile), true)
-		log.Printf("%s is appended into %s.", srcFile, dstFile)
-	} else {
-		utils.CopyFile(srcFile, dstFile)
+	doAppend, err := utils.GetBoolFlag(cmd, "append")
+	if err != nil {
+		return err
+	}
+	if !doAppend {
+		return utils.CopyFile(srcFile, dstFile)
+	}
+	text, err := utils.ReadFileAsString(srcFile)
+	if err != nil {
+		return err
+	}
+	if err := utils.AppendToTextFile(dstFile, text, true); err != nil {
+		return err
 	}
+	log.Printf("%s is appended into %s.", srcFile, dstFile)
+	return nil
 }
 
 var gitCmd = &cobra.Command{
@@ -206,7 +274,7 @@ var gitCmd = &cobra.Command{
 	Aliases: []string{},
 	Short:   "Install and configure Git.",
 	//Args:  cobra.ExactArgs(1),
-	Run: git,
+	RunE: git,
 }
 
 func ConfigGitCmd(rootCmd *cobra.Command) {
@@ -218,6 +286,10 @@ func ConfigGitCmd(rootCmd *cobra.Command) {
 	gitCmd.Flags().String("git", "git", "Path to the Git command.")
 	gitCmd.Flags().BoolP("yes", "y", false, "Automatically yes to prompt questions.")
 	gitCmd.Flags().Bool("gitui", false, "Install and configure gitui too.")
+	gitCmd.Flags().String("gitui-version", "",
+		"A version constraint of the gitui release to install if gitui is not packaged (the latest by default).")
+	gitCmd.Flags().String("delta-version", "",
+		"A version constraint of the delta release to install if delta is not packaged (the latest by default).")
 	gitCmd.Flags().String("proxy", "", "Configure Git to use the specified proxy.")
 	gitCmd.Flags().StringP("dest-dir", "d", ".", "The destination directory (current directory, by default) to copy gitignore files to.")
 	gitCmd.Flags().BoolP("append", "a", false, "Append to the .gitignore instead of oveerwriting it.")
@@ -0,0 +1,97 @@
+package dev
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/internal/testutil"
+)
+
+// testRoot is a root command with the commands of the package, which are configured only once.
+var testRoot = sync.OnceValue(func() *cobra.Command {
+	root := &cobra.Command{Use: "icon"}
+	ConfigGitCmd(root)
+	ConfigJjCmd(root)
+	ConfigDenoCmd(root)
+	ConfigRustCmd(root)
+	return root
+})
+
+func TestGitInstall(t *testing.T) {
+	tests := []struct {
+		distro testutil.Distro
+		want   []string
+	}{
+		{testutil.Ubuntu, []string{
+			"sudo true",
+			"sudo apt-get update",
+			"sudo apt-get install git git-lfs",
+			"sudo install -m 755 */delta /usr/local/bin/delta",
+			"git lfs install",
+		}},
+		{testutil.Fedora, []string{
+			"sudo true",
+			"sudo dnf install git git-lfs",
+			"sudo install -m 755 */delta /usr/local/bin/delta",
+			"git lfs install",
+		}},
+		{testutil.UniversalBlue, []string{
+			"brew install --force git-delta || brew link --overwrite --force git-delta",
+			"brew install --force gitui || brew link --overwrite --force gitui",
+			"git lfs install",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.distro.Name, func(t *testing.T) {
+			env := testutil.New(t, tt.distro)
+			env.HTTP.GitHubRelease("dandavison/delta", "0.18.2", map[string][]byte{
+				"delta-0.18.2-x86_64-unknown-linux-gnu.tar.gz": testutil.TarGz(map[string]string{
+					"delta-0.18.2-x86_64-unknown-linux-gnu/delta": "#!/bin/sh\n",
+				}),
+			})
+			if err := env.Run(testRoot(), "git", "--install"); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands(tt.want...)
+		})
+	}
+}
+
+func TestGitConfig(t *testing.T) {
+	env := testutil.New(t, testutil.Ubuntu)
+	env.FakeConfigData(map[string]string{
+		"git/gitconfig":     "[include]\n    path = ~/.config/git/user\n",
+		"ssh/client/config": "Host *\n",
+		"user.yaml":         "userName: Tester\nuserEmail: tester@example.com\n",
+	})
+	env.WriteFile("~/.gitconfig", "[user]\n    name = old\n")
+	if err := env.Run(testRoot(), "git", "--config", "--proxy", "http://proxy:3128"); err != nil {
+		t.Fatal(err)
+	}
+	env.AssertCommands(
+		"git config --global http.proxy http://proxy:3128",
+		"git config --global https.proxy http://proxy:3128",
+	)
+	if got, want := env.Readlink("~/.gitconfig"), testutil.Home+"/.config/icon-data/git/gitconfig"; got != want {
+		t.Errorf("~/.gitconfig links to %q, want %q", got, want)
+	}
+	if got, want := env.ReadFile("~/.config/git/user"), "[user]\n    name = Tester\n    email = tester@example.com\n"; got != want {
+		t.Errorf("~/.config/git/user = %q, want %q", got, want)
+	}
+	if got, want := env.ReadFile("~/.ssh/config"), "Host *\n"; got != want {
+		t.Errorf("~/.ssh/config = %q, want %q", got, want)
+	}
+}
+
+func TestRustUninstall(t *testing.T) {
+	env := testutil.New(t, testutil.Ubuntu)
+	err := env.Run(testRoot(), "rust", "--uninstall", "--rustup-home", "/opt/my rust", "--cargo-home", "/opt/$(id)")
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.AssertCommands(
+		"sudo true",
+		"sudo '/opt/$(id)/bin/rustup' self uninstall",
+	)
+}
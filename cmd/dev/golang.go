@@ -1,13 +1,9 @@
 package dev
 
 import (
-	"context"
 	"fmt"
-	"log"
-	"net/http"
 	"path/filepath"
 	"regexp"
-	"runtime"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/sys/unix"
@@ -16,36 +12,37 @@ import (
 
 func getGolangVersion() (string, error) {
 	url := "https://github.com/golang/go/tags"
-	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, http.NoBody)
+	const numRetry = 3
+	const initialWaitingSeconds = 2
+	html, err := utils.HTTPGetAsString(url, numRetry, initialWaitingSeconds)
 	if err != nil {
-		return "", fmt.Errorf("failed to create a HTTP GET request to the URL '%s' with context: %w", url, err)
-	}
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return "", fmt.Errorf("the HTTP GET request to the URL '%s' failed: %w", url, err)
-	}
-	defer resp.Body.Close()
-	html := utils.ReadAllAsText(resp.Body)
-	if utils.IsErrorHTTPResponse(resp) {
-		return "", fmt.Errorf("failed to read the body of the response of the HTTP GET request: %w", err)
+		return "", err
 	}
 	re := regexp.MustCompile(`tag/go(\d+\.\d+\.\d+)`)
-	return re.FindStringSubmatch(html)[1], nil
+	match := re.FindStringSubmatch(html)
+	if match == nil {
+		return "", fmt.Errorf("no Golang version is found in %s", url)
+	}
+	return match[1], nil
 }
 
-func installGoLang(prefix string) {
+func installGoLang(prefix string) error {
 	ver, err := getGolangVersion()
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	arch, err := utils.HostKernelArch()
+	if err != nil {
+		return err
 	}
 	url := utils.Format("https://go.dev/dl/go{ver}.{os}-{arch}.tar.gz", map[string]string{
 		"ver":  ver,
-		"os":   runtime.GOOS,
-		"arch": utils.HostKernelArch(),
+		"os":   utils.CurrentPlatform().OS,
+		"arch": arch,
 	})
 	goTgz, err := utils.DownloadFile(url, "go_*.tar.gz", true)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 	cmd := utils.Format(`{prefix} rm -rf /usr/local/go \
 				&& {prefix} tar -C /usr/local/ -xzf {goTgz}`,
@@ -54,52 +51,77 @@ func installGoLang(prefix string) {
 			"goTgz":  goTgz,
 		},
 	)
-	utils.RunCmd(cmd)
+	return utils.RunCmd(cmd)
 }
 
-func installGoLangCiLint(prefix string) {
+func installGoLangCiLint(prefix string) error {
 	script := "https://raw.githubusercontent.com/golangci/golangci-lint/HEAD/install.sh"
 	cmd := utils.Format(`curl -sSfL {script} | {prefix} sh -s -- -b /usr/local/go/bin`, map[string]string{
 		"script": script,
 		"prefix": prefix,
 	})
-	utils.RunCmd(cmd)
+	return utils.RunCmd(cmd)
 }
 
-func installGoPls(prefix string) {
+func installGoPls(prefix string) error {
 	cmd := utils.Format("{prefix} go install golang.org/x/tools/gopls@latest", map[string]string{
 		"prefix": prefix,
 	})
-	utils.RunCmd(cmd)
+	return utils.RunCmd(cmd)
+}
+
+func linkGoLang() error {
+	usrLocalBin := "/usr/local/bin"
+	goBin := "/usr/local/go/bin"
+	entries, err := utils.ReadDir(goBin)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		file := filepath.Join(goBin, entry.Name())
+		if err := utils.RemoveAll(filepath.Join(usrLocalBin, filepath.Base(file))); err != nil {
+			return err
+		}
+		if err := utils.SymlinkIntoDir(file, usrLocalBin); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 // Install and configure Golang.
-func golang(cmd *cobra.Command, _ []string) {
-	prefix := utils.GetCommandPrefix(false, map[string]uint32{
+func golang(cmd *cobra.Command, _ []string) error {
+	prefix, err := utils.GetCommandPrefix(false, map[string]uint32{
 		"/usr/local/go":  unix.W_OK | unix.R_OK,
 		"/usr/local":     unix.W_OK | unix.R_OK,
 		"/usr/local/bin": unix.W_OK | unix.R_OK,
 	})
-	if utils.GetBoolFlag(cmd, "install") {
-		installGoLang(prefix)
-		installGoLangCiLint(prefix)
-		installGoPls(prefix)
+	if err != nil {
+		return err
+	}
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		if utils.IsLinux() {
-			usrLocalBin := "/usr/local/bin"
-			goBin := "/usr/local/go/bin"
-			entries := utils.ReadDir(goBin)
-			for _, entry := range entries {
-				file := filepath.Join(goBin, entry.Name())
-				utils.RemoveAll(filepath.Join(usrLocalBin, filepath.Base(file)))
-				utils.SymlinkIntoDir(file, usrLocalBin)
-			}
-		} else {
+	if install {
+		if err := installGoLang(prefix); err != nil {
+			return err
+		}
+		if err := installGoLangCiLint(prefix); err != nil {
+			return err
+		}
+		if err := installGoPls(prefix); err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config && utils.IsLinux() {
+		return linkGoLang()
 	}
+	return nil
 }
 
 var golangCmd = &cobra.Command{
@@ -107,7 +129,7 @@ var golangCmd = &cobra.Command{
 	Aliases: []string{"go"},
 	Short:   "Install and configure Golang.",
 	//Args:  cobra.ExactArgs(1),
-	Run: golang,
+	RunE: golang,
 }
 
 func ConfigGolangCmd(rootCmd *cobra.Command) {
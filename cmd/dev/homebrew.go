@@ -5,51 +5,88 @@ import (
 	"legendu.net/icon/utils"
 )
 
-// Install and configure Homebrew.
-func homebrew(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		url := "https://raw.githubusercontent.com/Homebrew/install/HEAD/install.sh"
-		command := utils.Format(`NONINTERACTIVE=1 /bin/bash -c "$(curl -fsSL {url})"`, map[string]string{
-			"url": url,
+// homebrewDependencies are packages required by Homebrew on Linux for package managers.
+var homebrewDependencies = utils.Packages{
+	"apt":    {"build-essential", "procps", "curl", "file", "git"},
+	"dnf":    {"procps-ng", "curl", "file"},
+	"pacman": {"base-devel", "procps-ng", "curl", "file", "git"},
+	"zypper": {"gcc", "make", "procps", "curl", "file", "git"},
+}
+
+func installHomebrewDependencies(yes bool) error {
+	pm, pkgs, err := utils.ResolvePackages("Homebrew", homebrewDependencies)
+	if err != nil {
+		return err
+	}
+	if pm.Name() == "dnf" {
+		prefix, err := utils.GetCommandPrefix(true, map[string]uint32{})
+		if err != nil {
+			return err
+		}
+		command := utils.Format("{prefix} dnf {yesStr} group install development-tools", map[string]string{
+			"prefix": prefix,
+			"yesStr": utils.IfElseString(yes, "-y", ""),
 		})
-		utils.RunCmd(command)
-		if utils.IsLinux() {
-			if utils.IsDebianUbuntuSeries() {
-				command := utils.Format(`{prefix} apt-get {yesStr} update \
-						&& {prefix} apt-get {yesStr} install build-essential procps curl file git`, map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format(`{prefix} dnf {yesStr} group install development-tools \
-						&& {prefix} dnf {yesStr} install procps-ng curl file`, map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			}
+		if err := utils.RunCmd(command); err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		if utils.IsLinux() {
-			cmd := utils.Format(`{prefix} grep -q -E 'Defaults\s+secure_path\s*=.+/home/linuxbrew/.linuxbrew/bin.*' {file} \
-			|| {prefix} sed -i '/^Defaults\s\+secure_path\s*=/s/"$/:\/home\/linuxbrew\/.linuxbrew\/bin"/g' {file}`, map[string]string{
-				"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-				"file":   "/etc/sudoers",
-			})
-			utils.RunCmd(cmd)
+	return pm.Install(yes, pkgs...)
+}
+
+func installHomebrew(yes bool) error {
+	// Universal Blue distributions ship with dependencies of Homebrew
+	if utils.IsLinux() && !utils.IsUniversalBlue() {
+		if err := installHomebrewDependencies(yes); err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+	url := "https://raw.githubusercontent.com/Homebrew/install/HEAD/install.sh"
+	command := utils.Format(`NONINTERACTIVE=1 /bin/bash -c "$(curl -fsSL {url})"`, map[string]string{
+		"url": url,
+	})
+	return utils.RunCmd(command)
+}
+
+// Install and configure Homebrew.
+func homebrew(cmd *cobra.Command, _ []string) error {
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := installHomebrew(yes); err != nil {
+			return err
+		}
+	}
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config && utils.IsLinux() {
+		prefix, err := utils.GetCommandPrefix(true, map[string]uint32{})
+		if err != nil {
+			return err
+		}
+		cmd := utils.Format(`{prefix} grep -q -E 'Defaults\s+secure_path\s*=.+/home/linuxbrew/.linuxbrew/bin.*' {file} \
+		|| {prefix} sed -i '/^Defaults\s\+secure_path\s*=/s/"$/:\/home\/linuxbrew\/.linuxbrew\/bin"/g' {file}`, map[string]string{
+			"prefix": prefix,
+			"file":   "/etc/sudoers",
+		})
+		return utils.RunCmd(cmd)
 	}
+	return nil
 }
 
 var homebrewCmd = &cobra.Command{
 	Use:     "homebrew",
 	Aliases: []string{"brew"},
 	Short:   "Install and configure Homebrew.",
-	Run:     homebrew,
+	RunE:    homebrew,
 }
 
 func ConfigHomebrewCmd(rootCmd *cobra.Command) {
@@ -1,10 +1,6 @@
 package dev
 
 import (
-	"log"
-	"os"
-	"path/filepath"
-
 	"github.com/spf13/cobra"
 	"legendu.net/icon/cmd/icon"
 	"legendu.net/icon/cmd/network"
@@ -16,49 +12,39 @@ import (
 // otherwise it is installed into ~/.local/bin (no privilege escalation).
 // jj is not reliably packaged in the Debian/Ubuntu and Fedora series, so the
 // official static binary is used.
-func installJj(global bool) {
-	tmpdir := utils.CreateTempDir("")
-	defer os.RemoveAll(tmpdir)
-	file := filepath.Join(tmpdir, "jj.tar.gz")
-	network.DownloadGitHubRelease("jj-vcs/jj", "", map[string][]string{
-		"common": {"tar.gz"},
-		"amd64":  {"x86_64"},
-		"arm64":  {"aarch64"},
-		"linux":  {"linux", "musl"},
-		"darwin": {"apple", "darwin"},
-	}, []string{}, file)
-	prefix := ""
-	binDir := "~/.local/bin"
-	if global {
-		prefix = utils.GetCommandPrefix(
-			true,
-			map[string]uint32{},
-		)
-		binDir = "/usr/local/bin"
-	}
-	command := utils.Format(`{prefix} mkdir -p {binDir} \
-		&& {prefix} tar -zxvf {file} -C {binDir} --strip-components=1 \
-			--exclude=LICENSE --exclude='README.*' --exclude=CHANGELOG.md`, map[string]string{
-		"prefix": prefix,
-		"binDir": binDir,
-		"file":   file,
+func installJj(ver string, global bool) error {
+	return network.DownloadRelease("jj-vcs/jj", ver, nil, nil, "", network.ReleaseOptions{
+		Pattern:    "jj-*-{arch}-*{os}*.tar.gz",
+		InstallBin: []string{"jj"},
+		BinDir:     utils.IfElseString(global, "/usr/local/bin", "~/.local/bin"),
 	})
-	utils.RunCmd(command)
 }
 
-// uninstallJj removes the jj binary installed by installJj. It searches the
-// candidate installation directories (~/.local/bin and /usr/local/bin) and
-// removes the binary wherever it is found. RemoveAll derives privilege
-// escalation from the target path's write permissions, so the system location
-// is handled with sudo only when necessary.
-func uninstallJj() {
+// uninstallJj removes the jj binary installed by installJj. The binary recorded
+// in the state of jj is removed after uninstalling (see icon.TrackToolCmd).
+// If no binary is recorded (e.g., jj was installed by an older version of icon),
+// it searches the candidate installation directories (~/.local/bin and
+// /usr/local/bin) and removes the binary wherever it is found. RemoveAll derives
+// privilege escalation from the target path's write permissions, so the system
+// location is handled with sudo only when necessary.
+func uninstallJj() error {
+	state, err := utils.ReadToolState("jj")
+	if err != nil {
+		return err
+	}
+	if state != nil && len(state.Files) > 0 {
+		return nil
+	}
 	for _, binDir := range []string{"~/.local/bin", "/usr/local/bin"} {
 		path := binDir + "/jj"
 		if !utils.ExistsFile(path) {
 			continue
 		}
-		utils.RemoveAll(path)
+		if err := utils.RemoveAll(path); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // resolveJj returns the command used to invoke jj. It prefers the jj found on
@@ -78,47 +64,77 @@ func resolveJj() string {
 	return "jj"
 }
 
+func installJjForOS(cmd *cobra.Command) error {
+	global, err := utils.GetBoolFlag(cmd, "global")
+	if err != nil {
+		return err
+	}
+	ver, err := utils.GetStringFlag(cmd, "version")
+	if err != nil {
+		return err
+	}
+	if !utils.IsLinux() {
+		if global {
+			utils.Warnf("--global is not respected on macOS; jj is installed into ~/.local/bin.")
+		}
+		return installJj(ver, false)
+	}
+	if utils.IsUniversalBlue() {
+		if global {
+			utils.Warnf("--global is not respected on Universal Blue; jj is installed into ~/.local/bin.")
+		}
+		return installJj(ver, false)
+	}
+	// jj is installed from GitHub releases which work on all Linux distributions
+	return installJj(ver, global)
+}
+
+func configJj() error {
+	if err := icon.FetchConfigData(false, ""); err != nil {
+		return err
+	}
+	cfg, err := utils.ReadUserConfig()
+	if err != nil {
+		return err
+	}
+	jjBin := resolveJj()
+	if err := utils.NewCommand(jjBin, "config", "set", "--user", "user.name", cfg.UserName).Run(); err != nil {
+		return err
+	}
+	if err := utils.NewCommand(jjBin, "config", "set", "--user", "user.email", cfg.UserEmail).Run(); err != nil {
+		return err
+	}
+	return utils.NewCommand(jjBin, "config", "set", "--user", "ui.diff-editor", ":builtin").Run()
+}
+
 // Install and configure jj (Jujutsu).
-func jj(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		if utils.IsLinux() {
-			if utils.IsUniversalBlue() {
-				if utils.GetBoolFlag(cmd, "global") {
-					log.Print("WARNING: --global is not respected on Universal Blue; jj is installed into ~/.local/bin.")
-				}
-				installJj(false)
-			} else if utils.IsDebianUbuntuSeries() {
-				installJj(utils.GetBoolFlag(cmd, "global"))
-			} else if utils.IsFedoraSeries() {
-				installJj(utils.GetBoolFlag(cmd, "global"))
-			}
-		} else {
-			if utils.GetBoolFlag(cmd, "global") {
-				log.Print("WARNING: --global is not respected on macOS; jj is installed into ~/.local/bin.")
-			}
-			installJj(false)
+func jj(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := installJjForOS(cmd); err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
-		cfg := utils.ReadUserConfig()
-		jjBin := resolveJj()
-		utils.RunCmd(utils.Format(
-			`{jjBin} config set --user user.name "{userName}"`,
-			map[string]string{"jjBin": jjBin, "userName": cfg.UserName},
-		))
-		utils.RunCmd(utils.Format(
-			`{jjBin} config set --user user.email "{userEmail}"`,
-			map[string]string{"jjBin": jjBin, "userEmail": cfg.UserEmail},
-		))
-		utils.RunCmd(utils.Format(
-			`{jjBin} config set --user ui.diff-editor :builtin`,
-			map[string]string{"jjBin": jjBin},
-		))
-	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		uninstallJj()
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		if err := configJj(); err != nil {
+			return err
+		}
+	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return uninstallJj()
 	}
+	return nil
 }
 
 var jjCmd = &cobra.Command{
@@ -126,7 +142,7 @@ var jjCmd = &cobra.Command{
 	Aliases: []string{},
 	Short:   "Install and configure jj (Jujutsu).",
 	//Args:  cobra.ExactArgs(1),
-	Run: jj,
+	RunE: jj,
 }
 
 func ConfigJjCmd(rootCmd *cobra.Command) {
@@ -135,6 +151,7 @@ func ConfigJjCmd(rootCmd *cobra.Command) {
 	jjCmd.Flags().BoolP("config", "c", false, "Configure jj.")
 	jjCmd.Flags().Bool("no-backup", false, "Do not backup existing configuration files.")
 	jjCmd.Flags().Bool("copy", false, "Make copies (instead of symbolic links) of configuration files.")
+	jjCmd.Flags().StringP("version", "v", "", "A version constraint (e.g., 0.30.0 or >=0.30) of the release to install (the latest by default).")
 	jjCmd.Flags().Bool("global", false, "Install jj into /usr/local/bin instead of ~/.local/bin.")
 	jjCmd.Flags().BoolP("yes", "y", false, "Automatically yes to prompt questions.")
 	rootCmd.AddCommand(jjCmd)
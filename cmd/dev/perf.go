@@ -5,94 +5,81 @@ import (
 	"legendu.net/icon/utils"
 )
 
+// perfPackages are packages of perf for package managers.
+// The perf package on Ubuntu is specific to the running kernel.
+var perfPackages = utils.Packages{
+	"apt":    {"linux-perf"},
+	"dnf":    {"perf"},
+	"brew":   {"gperftools"},
+	"pacman": {"perf"},
+	"zypper": {"perf"},
+	"apk":    {"perf"},
+}
+
+// resolvePerfPackages returns the package manager and packages of perf.
+func resolvePerfPackages() (utils.PackageManager, []string, error) {
+	pm, pkgs, err := utils.ResolvePackages("perf", perfPackages)
+	if err == nil && pm.Name() == "apt" && utils.IsUbuntuSeries() {
+		pkgs = []string{"linux-tools-common", "linux-tools-generic", "linux-tools-$(uname -r)"}
+	}
+	return pm, pkgs, err
+}
+
+func installPerf(yes bool) error {
+	pm, pkgs, err := resolvePerfPackages()
+	if err != nil {
+		return err
+	}
+	return pm.Install(yes, pkgs...)
+}
+
+func uninstallPerf(yes bool) error {
+	pm, pkgs, err := resolvePerfPackages()
+	if err != nil {
+		return err
+	}
+	return pm.Remove(yes, pkgs...)
+}
+
 // Install and configure perf.
-func perf(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		if utils.IsLinux() {
-			if utils.IsDebianSeries() {
-				command := utils.Format(`{prefix} apt-get {yesStr} update \
-						&& {prefix} apt-get {yesStr} install linux-perf`, map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsUbuntuSeries() {
-				command := utils.Format(`{prefix} apt-get {yesStr} update \
-						&& {prefix} apt-get {yesStr} install \
-							linux-tools-common \
-							linux-tools-generic \
-							linux-tools-$(uname -r)`, map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format("{prefix} dnf {yesStr} install perf", map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			}
-		} else {
-			utils.BrewInstallSafe([]string{"gperftools"})
-		}
+func perf(cmd *cobra.Command, _ []string) error {
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		if utils.IsLinux() {
-			command := utils.Format("{prefix} sysctl -w kernel.perf_event_paranoid=-1", map[string]string{
-				"prefix": utils.GetCommandPrefix(
-					true,
-					map[string]uint32{},
-				),
-			})
-			utils.RunCmd(command)
-		} else {
+	if install {
+		if err := installPerf(yes); err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		if utils.IsLinux() {
-			if utils.IsDebianSeries() {
-				command := utils.Format("{prefix} apt-get {yesStr} purge linux-perf", map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsUbuntuSeries() {
-				command := utils.Format(`{prefix} apt-get {yesStr} purge \
-						linux-tools-common linux-tools-generic linux-tools-$(uname -r)`, map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format("{prefix} dnf {yesStr} remove perf", map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			}
-		} else {
-			utils.RunCmd("brew uninstall gperftools")
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config && utils.IsLinux() {
+		prefix, err := utils.GetCommandPrefix(true, map[string]uint32{})
+		if err != nil {
+			return err
+		}
+		command := utils.Format("{prefix} sysctl -w kernel.perf_event_paranoid=-1", map[string]string{
+			"prefix": prefix,
+		})
+		if err := utils.RunCmd(command); err != nil {
+			return err
 		}
 	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return uninstallPerf(yes)
+	}
+	return nil
 }
 
 var perfCmd = &cobra.Command{
@@ -100,7 +87,7 @@ var perfCmd = &cobra.Command{
 	Aliases: []string{},
 	Short:   "Install and configure perf.",
 	//Args:  cobra.ExactArgs(1),
-	Run: perf,
+	RunE: perf,
 }
 
 func ConfigPerfCmd(rootCmd *cobra.Command) {
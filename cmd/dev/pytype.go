@@ -1,6 +1,7 @@
 package dev
 
 import (
+	"fmt"
 	"log"
 	"path/filepath"
 
@@ -11,46 +12,98 @@ import (
 	"legendu.net/icon/utils"
 )
 
+// readTomlMap parses a TOML file into an ordered map.
+//
+// @param path The path to the TOML file.
+//
+// @return The parsed TOML content as an ordered map.
+func readTomlMap(path string) (orderedmap.OrderedMap[string, any], error) {
+	var hmap orderedmap.OrderedMap[string, any]
+	bytes, err := utils.ReadFile(path)
+	if err != nil {
+		return hmap, err
+	}
+	if err := toml.Unmarshal(bytes, &hmap); err != nil {
+		return hmap, fmt.Errorf("failed to parse the TOML file %s: %w", path, err)
+	}
+	return hmap, nil
+}
+
+func configPytype(cmd *cobra.Command) error {
+	if err := icon.FetchConfigData(false, ""); err != nil {
+		return err
+	}
+	srcMap, err := readTomlMap("~/.config/icon-data/pytype/pyproject.toml")
+	if err != nil {
+		return err
+	}
+	destDir, err := utils.GetStringFlag(cmd, "dest-dir")
+	if err != nil {
+		return err
+	}
+	destFile := filepath.Join(destDir, "pyproject.toml")
+	destMap := srcMap
+	if utils.ExistsFile(destFile) {
+		destMap, err = readTomlMap(destFile)
+		if err != nil {
+			return err
+		}
+		utils.UpdateMap(destMap, srcMap)
+	}
+	bytes, err := toml.Marshal(destMap)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the pytype configuration: %w", err)
+	}
+	//nolint:mnd // readable
+	if err := utils.WriteFile(destFile, bytes, 0o600); err != nil {
+		return err
+	}
+	log.Printf("pytype is configured via %s.", destFile)
+	return nil
+}
+
 // Install and configure pytype.
-func pytype(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		command := utils.Format("{pip_install} pytype", map[string]string{
-			"pip_install": utils.BuildPipInstall(cmd),
-		})
-		utils.RunCmd(command)
+func pytype(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
-		var srcMap orderedmap.OrderedMap[string, any]
-		err := toml.Unmarshal(utils.ReadFile("~/.config/icon-data/pytype/pyproject.toml"), &srcMap)
+	if install {
+		pipInstall, err := utils.BuildPipInstall(cmd)
 		if err != nil {
-			log.Fatalf("Failed to parse TOML: %v", err)
+			return err
+		}
+		command := utils.Format("{pip_install} pytype", map[string]string{
+			"pip_install": pipInstall,
+		})
+		if err := utils.RunCmd(command); err != nil {
+			return err
 		}
-		destFile := filepath.Join(utils.GetStringFlag(cmd, "dest-dir"), "pyproject.toml")
-		var destMap orderedmap.OrderedMap[string, any]
-		if utils.ExistsFile(destFile) {
-			err = toml.Unmarshal(utils.ReadFile(destFile), &destMap)
-			if err != nil {
-				log.Fatalf("Failed to parse TOML: %v", err)
-			}
-			utils.UpdateMap(destMap, srcMap)
-		} else {
-			destMap = srcMap
+	}
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		if err := configPytype(cmd); err != nil {
+			return err
 		}
-		bytes, err := toml.Marshal(destMap)
+	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		pipUninstall, err := utils.BuildPipUninstall(cmd)
 		if err != nil {
-			log.Fatal("ERROR - ", err)
+			return err
 		}
-		//nolint:mnd // readable
-		utils.WriteFile(destFile, bytes, 0o600)
-		log.Printf("pytype is configured via %s.", destFile)
-	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
 		command := utils.Format("{pip_uninstall} pytype", map[string]string{
-			"pip_uninstall": utils.BuildPipUninstall(cmd),
+			"pip_uninstall": pipUninstall,
 		})
-		utils.RunCmd(command)
+		return utils.RunCmd(command)
 	}
+	return nil
 }
 
 var pytypeCmd = &cobra.Command{
@@ -58,7 +111,7 @@ var pytypeCmd = &cobra.Command{
 	Aliases: []string{},
 	Short:   "Install and configure pytype.",
 	//Args:  cobra.ExactArgs(1),
-	Run: pytype,
+	RunE: pytype,
 }
 
 func ConfigPytypeCmd(rootCmd *cobra.Command) {
@@ -1,7 +1,6 @@
 package dev
 
 import (
-	"os"
 	"path/filepath"
 
 	"github.com/spf13/cobra"
@@ -13,121 +12,168 @@ import (
 const Linux = "linux"
 const Darwin = "darwin"
 
-func linkRust(cmd *cobra.Command, cargoHome string) {
-	linkToDir := utils.GetStringFlag(cmd, "link-to-dir")
-	if linkToDir == "" {
-		return
+func linkRust(cmd *cobra.Command, cargoHome string) error {
+	linkToDir, err := utils.GetStringFlag(cmd, "link-to-dir")
+	if err != nil || linkToDir == "" {
+		return err
 	}
 	cargoBin := filepath.Join(cargoHome, "bin")
-	for _, entry := range utils.ReadDir(cargoBin) {
+	entries, err := utils.ReadDir(cargoBin)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
 		src := filepath.Join(cargoBin, entry.Name())
-		utils.RemoveAll(filepath.Join(linkToDir, filepath.Base(src)))
-		utils.SymlinkIntoDir(src, linkToDir)
+		if err := utils.RemoveAll(filepath.Join(linkToDir, filepath.Base(src))); err != nil {
+			return err
+		}
+		if err := utils.SymlinkIntoDir(src, linkToDir); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
-func installRustNix(rustupHome, cargoHome, toolchain string) {
-	command := utils.Format(`
-		curl --proto '=https' --tlsv1.2 -sSf https://sh.rustup.rs | \
-			{prefix} bash -s -- --default-toolchain {toolchain} -y \
-		&& {cargoHome}/bin/rustup component add rust-src rustfmt clippy \
-		&& {cargoHome}/bin/cargo install cargo-cache cargo-edit cargo-criterion`, map[string]string{
-		"rustupHome": rustupHome,
-		"cargoHome":  cargoHome,
-		"toolchain":  toolchain,
-		"prefix": utils.GetCommandPrefix(false, map[string]uint32{
-			rustupHome: unix.W_OK | unix.R_OK,
-			cargoHome:  unix.W_OK | unix.R_OK,
-		}),
+func installRustNix(rustupHome, cargoHome, toolchain string) error {
+	prefix, err := utils.GetCommandPrefix(false, map[string]uint32{
+		rustupHome: unix.W_OK | unix.R_OK,
+		cargoHome:  unix.W_OK | unix.R_OK,
 	})
-	utils.RunCmd(command, "RUSTUP_HOME="+rustupHome, "CARGO_HOME="+cargoHome)
-	utils.RemoveAll(filepath.Join(cargoHome, "registry"))
-	installCargoBinstall()
-	installSccache()
+	if err != nil {
+		return err
+	}
+	env := []string{"RUSTUP_HOME=" + rustupHome, "CARGO_HOME=" + cargoHome}
+	// the prefix is generated by GetCommandPrefix and is not quoted
+	command := utils.Format(utils.FormatShell(
+		"curl --proto '=https' --tlsv1.2 -sSf https://sh.rustup.rs | {prefix} bash -s -- --default-toolchain {toolchain} -y",
+		map[string]string{"toolchain": toolchain},
+	), map[string]string{"prefix": prefix})
+	if err := utils.RunCmd(command, env...); err != nil {
+		return err
+	}
+	rustup := filepath.Join(cargoHome, "bin", "rustup")
+	if err := utils.NewCommand(rustup, "component", "add", "rust-src", "rustfmt", "clippy").Env(env...).Run(); err != nil {
+		return err
+	}
+	cargo := filepath.Join(cargoHome, "bin", "cargo")
+	if err := utils.NewCommand(cargo, "install", "cargo-cache", "cargo-edit", "cargo-criterion").Env(env...).Run(); err != nil {
+		return err
+	}
+	return utils.RemoveAll(filepath.Join(cargoHome, "registry"))
 }
 
-func installSccache() {
-	tmpdir := utils.CreateTempDir("")
-	defer os.RemoveAll(tmpdir)
-	file := filepath.Join(tmpdir, "sccache.tar.gz")
-	network.DownloadGitHubRelease("mozilla/sccache", "", map[string][]string{
-		"common": {"tar.gz"},
-		"amd64":  {"x86_64"},
-		"arm64":  {"aarch64"},
-		Linux:    {"unknown", Linux, "musl"},
-		Darwin:   {"apple", Darwin},
-	}, []string{"pre", "dist", "sha256"}, file)
-	command := utils.Format(`{prefix} tar --wildcards --strip-components=1 \
-			-C /usr/local/bin/ -zxvf {file} */sccache`, map[string]string{
-		"prefix": utils.GetCommandPrefix(false, map[string]uint32{
-			"/usr/local/bin": unix.W_OK | unix.R_OK,
-		}),
-		"file": file,
+func installSccache(ver string) error {
+	return network.DownloadRelease("mozilla/sccache", ver, nil, nil, "", network.ReleaseOptions{
+		Pattern:    "sccache-v*-{arch}-*{os}*.tar.gz",
+		InstallBin: []string{"sccache"},
+		BinDir:     "/usr/local/bin",
 	})
-	utils.RunCmd(command)
 }
 
-func installCargoBinstall() {
-	tmpdir := utils.CreateTempDir("")
-	defer os.RemoveAll(tmpdir)
-	file := filepath.Join(tmpdir, "cargo-binstall.tgz")
-	network.DownloadGitHubRelease("cargo-bins/cargo-binstall", "", map[string][]string{
+func installCargoBinstall(ver string) error {
+	return network.DownloadRelease("cargo-bins/cargo-binstall", ver, map[string][]string{
 		"common": {"tgz"},
 		"amd64":  {"x86_64"},
 		"arm64":  {"aarch64"},
 		Linux:    {"unknown", Linux, "gnu"},
 		Darwin:   {"apple", Darwin},
-	}, []string{"pre", "full"}, file)
-	command := utils.Format("{prefix} tar -C /usr/local/bin/ -zxvf {file}", map[string]string{
-		"prefix": utils.GetCommandPrefix(false, map[string]uint32{
-			"/usr/local/bin": unix.W_OK | unix.R_OK,
-		}),
-		"file": file,
+	}, []string{"pre", "full"}, "", network.ReleaseOptions{
+		InstallBin: []string{"cargo-binstall"},
+		BinDir:     "/usr/local/bin",
+	})
+}
+
+// rustDependencies are packages required to build Rust crates for package managers.
+var rustDependencies = utils.Packages{
+	"apt":    {"gcc", "cmake", "libssl-dev", "pkg-config"},
+	"dnf":    {"gcc", "cmake", "openssl-devel", "pkgconf-pkg-config"},
+	"brew":   {"pkg-config", "openssl"},
+	"pacman": {"gcc", "cmake", "openssl", "pkgconf"},
+	"zypper": {"gcc", "cmake", "libopenssl-devel", "pkg-config"},
+	"apk":    {"gcc", "musl-dev", "cmake", "openssl-dev", "pkgconf"},
+}
+
+func installRust(rustupHome, cargoHome, toolchain string) error {
+	if err := utils.InstallPackages("Rust", rustDependencies, true); err != nil {
+		return err
+	}
+	return installRustNix(rustupHome, cargoHome, toolchain)
+}
+
+// installRustReleases installs cargo-binstall and sccache from their GitHub releases.
+func installRustReleases(cmd *cobra.Command) error {
+	binstallVer, err := utils.GetStringFlag(cmd, "cargo-binstall-version")
+	if err != nil {
+		return err
+	}
+	sccacheVer, err := utils.GetStringFlag(cmd, "sccache-version")
+	if err != nil {
+		return err
+	}
+	// cargo-binstall and sccache are downloaded and installed concurrently
+	return utils.RunParallel(
+		func() error { return installCargoBinstall(binstallVer) },
+		func() error { return installSccache(sccacheVer) },
+	)
+}
+
+func uninstallRust(rustupHome, cargoHome string) error {
+	prefix, err := utils.GetCommandPrefix(false, map[string]uint32{
+		rustupHome: unix.W_OK | unix.R_OK,
+		cargoHome:  unix.W_OK | unix.R_OK,
 	})
-	utils.RunCmd(command)
+	if err != nil {
+		return err
+	}
+	return utils.NewCommand(filepath.Join(cargoHome, "bin", "rustup"), "self", "uninstall").
+		Env("RUSTUP_HOME="+rustupHome, "CARGO_HOME="+cargoHome).Sudo(prefix).Run()
 }
 
 // Install and configure Rust.
-func rust(cmd *cobra.Command, _ []string) {
-	rustupHome := utils.GetStringFlag(cmd, "rustup-home")
-	if rustupHome == "" {
-		rustupHome = filepath.Join(utils.UserHomeDir(), ".rustup")
-	}
-	cargoHome := utils.GetStringFlag(cmd, "cargo-home")
-	if cargoHome == "" {
-		cargoHome = filepath.Join(utils.UserHomeDir(), ".cargo")
-	}
-	toolchain := utils.GetStringFlag(cmd, "toolchain")
-	if utils.GetBoolFlag(cmd, "install") {
-		if utils.IsLinux() {
-			if utils.IsDebianUbuntuSeries() {
-				command := utils.Format(`{prefix} apt-get update \
-						&& {prefix} apt-get install -y gcc cmake libssl-dev pkg-config`, map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-				})
-				utils.RunCmd(command)
-			}
-			installRustNix(rustupHome, cargoHome, toolchain)
-		} else {
-			utils.BrewInstallSafe([]string{"pkg-config", "openssl"})
-			installRustNix(rustupHome, cargoHome, toolchain)
+func rust(cmd *cobra.Command, _ []string) error {
+	rustupHome, err := utils.GetStringFlag(cmd, "rustup-home")
+	if err != nil {
+		return err
+	}
+	rustupHome = utils.NormalizePath(utils.IfElseString(rustupHome == "", "~/.rustup", rustupHome))
+	cargoHome, err := utils.GetStringFlag(cmd, "cargo-home")
+	if err != nil {
+		return err
+	}
+	cargoHome = utils.NormalizePath(utils.IfElseString(cargoHome == "", "~/.cargo", cargoHome))
+	toolchain, err := utils.GetStringFlag(cmd, "toolchain")
+	if err != nil {
+		return err
+	}
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := installRust(rustupHome, cargoHome, toolchain); err != nil {
+			return err
+		}
+		if err := installRustReleases(cmd); err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		linkRust(cmd, cargoHome)
-	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		command := utils.Format(`RUSTUP_HOME={rustupHome} CARGO_HOME={cargoHome} PATH={cargoHome}/bin:$PATH \
-				{prefix} rustup self uninstall`, map[string]string{
-			"rustupHome": rustupHome,
-			"cargoHome":  cargoHome,
-			"prefix": utils.GetCommandPrefix(false, map[string]uint32{
-				rustupHome: unix.W_OK | unix.R_OK,
-				cargoHome:  unix.W_OK | unix.R_OK,
-			}),
-		})
-		utils.RunCmd(command)
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		if err := linkRust(cmd, cargoHome); err != nil {
+			return err
+		}
+	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return uninstallRust(rustupHome, cargoHome)
 	}
+	return nil
 }
 
 var rustCmd = &cobra.Command{
@@ -135,7 +181,7 @@ var rustCmd = &cobra.Command{
 	Aliases: []string{"rustup", "cargo"},
 	Short:   "Install and configure Rust.",
 	//Args:  cobra.ExactArgs(1),
-	Run: rust,
+	RunE: rust,
 }
 
 func ConfigRustCmd(rootCmd *cobra.Command) {
@@ -149,5 +195,8 @@ func ConfigRustCmd(rootCmd *cobra.Command) {
 	rustCmd.Flags().String("cargo-home", "", "Value for the CARGO_HOME environment.")
 	rustCmd.Flags().String("toolchain", "stable", "The Rust toolchain (stable by default) to install.")
 	rustCmd.Flags().BoolP("path", "p", false, "Configure the PATH environment variable.")
+	rustCmd.Flags().String("cargo-binstall-version", "",
+		"A version constraint of the cargo-binstall release to install (the latest by default).")
+	rustCmd.Flags().String("sccache-version", "", "A version constraint of the sccache release to install (the latest by default).")
 	rootCmd.AddCommand(rustCmd)
 }
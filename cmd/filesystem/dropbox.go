@@ -6,38 +6,62 @@ import (
 )
 
 // Install and configure Dropbox.
-func dropbox(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		command := utils.Format("flatpak install {yesStr} flathub com.dropbox.Client", map[string]string{
-			"yesStr": utils.BuildYesFlag(cmd),
-		})
-		utils.RunCmd(command)
-	}
-	if utils.GetBoolFlag(cmd, "config") {
-		if utils.IsAtomicLinux() {
-			utils.WriteTextFile(
-				"~/.local/share/flatpak/overrides",
-				`[Context]
+func dropbox(cmd *cobra.Command, _ []string) error {
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	// Dropbox is installed from Flathub on all Linux distributions
+	flatpak, err := utils.GetPackageManager("flatpak")
+	if err != nil {
+		return err
+	}
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := flatpak.Install(yes, "com.dropbox.Client"); err != nil {
+			return err
+		}
+	}
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config && utils.IsAtomicLinux() {
+		// flatpak reads per-user overrides of an app from ~/.local/share/flatpak/overrides/<app-id>
+		dir := "~/.local/share/flatpak/overrides"
+		if err := utils.MkdirAll(dir, ""); err != nil {
+			return err
+		}
+		err := utils.WriteTextFile(
+			dir+"/com.dropbox.Client",
+			`[Context]
 filesystems=/var/home/dclong
 
 [Environment]
 HOME=/var/home/dclong
 `, 0o644) //nolint:mnd // readable
+		if err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		command := utils.Format("flatpak uninstall {yesStr} com.dropbox.Client", map[string]string{
-			"yesStr": utils.BuildYesFlag(cmd),
-		})
-		utils.RunCmd(command)
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return flatpak.Remove(yes, "com.dropbox.Client")
 	}
+	return nil
 }
 
 var dropboxCmd = &cobra.Command{
 	Use:     "dropbox",
 	Aliases: []string{},
 	Short:   "Install and configure Dropbox.",
-	Run:     dropbox,
+	RunE:    dropbox,
 }
 
 func ConfigDropboxCmd(rootCmd *cobra.Command) {
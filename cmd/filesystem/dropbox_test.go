@@ -0,0 +1,48 @@
+package filesystem
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/internal/testutil"
+)
+
+var testRoot = sync.OnceValue(func() *cobra.Command {
+	root := &cobra.Command{Use: "icon"}
+	ConfigDropboxCmd(root)
+	ConfigRipCmd(root)
+	return root
+})
+
+func TestDropbox(t *testing.T) {
+	tests := []struct {
+		distro        testutil.Distro
+		args          []string
+		want          []string
+		wantOverrides bool
+	}{
+		{testutil.Ubuntu, []string{"dropbox", "--install", "--config", "--yes"}, []string{
+			"flatpak install -y flathub com.dropbox.Client",
+		}, false},
+		{testutil.UniversalBlue, []string{"dropbox", "--install", "--config", "--yes"}, []string{
+			"flatpak install -y flathub com.dropbox.Client",
+		}, true},
+		{testutil.Fedora, []string{"dropbox", "--uninstall", "--yes"}, []string{
+			"flatpak uninstall -y com.dropbox.Client",
+		}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.distro.Name+"/"+tt.args[1], func(t *testing.T) {
+			env := testutil.New(t, tt.distro)
+			env.Exec.AddCommands("flatpak")
+			if err := env.Run(testRoot(), tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands(tt.want...)
+			if got := env.Exists("~/.local/share/flatpak/overrides/com.dropbox.Client"); got != tt.wantOverrides {
+				t.Errorf("flatpak overrides written: %t, want %t", got, tt.wantOverrides)
+			}
+		})
+	}
+}
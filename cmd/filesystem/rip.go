@@ -1,32 +1,40 @@
 package filesystem
 
 import (
-	"runtime"
-
 	"github.com/spf13/cobra"
 	"legendu.net/icon/utils"
 )
 
 // Install and configure rip (rm-improved).
-func rip(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		switch runtime.GOOS {
+func rip(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		switch utils.CurrentPlatform().OS {
 		case "linux":
-			utils.RunCmd("cargo install rip2")
+			err = utils.RunCmd("cargo install rip2")
 		case "darwin":
-			utils.BrewInstallSafe([]string{"rip2"})
+			err = utils.BrewInstallSafe([]string{"rip2"})
+		}
+		if err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "config") {
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		switch runtime.GOOS {
+	if uninstall {
+		switch utils.CurrentPlatform().OS {
 		case "linux":
-			utils.RemoveAll("~/.cargo/bin/rip")
+			return utils.RunCmd("cargo uninstall rip2")
 		case "darwin":
-			utils.RunCmd("brew uninstall rip2")
+			return utils.RunCmd("brew uninstall rip2")
 		}
 	}
+	return nil
 }
 
 var ripCmd = &cobra.Command{
@@ -34,7 +42,7 @@ var ripCmd = &cobra.Command{
 	Aliases: []string{},
 	Short:   "Install and configure rip2 (rm-improved).",
 	//Args:  cobra.ExactArgs(1),
-	Run: rip,
+	RunE: rip,
 }
 
 func ConfigRipCmd(rootCmd *cobra.Command) {
@@ -0,0 +1,354 @@
+package icon
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"gopkg.in/yaml.v3"
+	"legendu.net/icon/utils"
+)
+
+// toolDependencies lists tools which must be set up before a tool.
+// A dependency is only honored if it is also listed in the manifest.
+// Homebrew, if listed, is always set up first as other tools might be installed using it.
+var toolDependencies = map[string][]string{
+	"alacritty": {"rust"},
+	"firenvim":  {"neovim"},
+	"git":       {"ssh_client"},
+	"gopass":    {"git"},
+	"jj":        {"git"},
+	"rip":       {"rust"},
+}
+
+// getToolDependencies returns tools which must be set up before the specified tool.
+func getToolDependencies(name string) []string {
+	if name == "homebrew" {
+		return nil
+	}
+	return append([]string{"homebrew"}, toolDependencies[name]...)
+}
+
+// ToolSpec is a tool in a manifest together with the flags to run its command with.
+type ToolSpec struct {
+	// Name is the name (or an alias) of the command of the tool.
+	Name string
+	// Flags are the flags (without the leading --) and their values in the order they are specified.
+	Flags []FlagValue
+}
+
+// FlagValue is a flag and its value(s).
+type FlagValue struct {
+	Name   string
+	Values []string
+}
+
+// ParseManifest parses a manifest of tools.
+// A manifest is a YAML mapping from names of tools to mappings of flags, e.g.,
+//
+//	git: {install: true, config: true, gitui: true}
+//	spark: {spark-version: 3.5.1}
+//	neovim: {config: true, copy: true}
+//
+// @param bytes The content of the manifest.
+//
+// @return The tools in the order they are listed in the manifest.
+func ParseManifest(bytes []byte) ([]ToolSpec, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(bytes, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse the manifest: %w", err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: the manifest must be a mapping from tools to flags", root.Line)
+	}
+	specs := make([]ToolSpec, 0, len(root.Content)/2)
+	for i := 0; i+1 < len(root.Content); i += 2 {
+		spec, err := parseToolSpec(root.Content[i], root.Content[i+1])
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+func parseToolSpec(key, value *yaml.Node) (ToolSpec, error) {
+	spec := ToolSpec{Name: key.Value}
+	switch value.Kind {
+	case yaml.ScalarNode:
+		// a tool without flags, e.g., "git:" or "git: {}"
+		if value.Tag != "!!null" {
+			return spec, fmt.Errorf("line %d: the flags of %s must be a mapping", value.Line, spec.Name)
+		}
+		return spec, nil
+	case yaml.MappingNode:
+	default:
+		return spec, fmt.Errorf("line %d: the flags of %s must be a mapping", value.Line, spec.Name)
+	}
+	for i := 0; i+1 < len(value.Content); i += 2 {
+		flag := FlagValue{Name: value.Content[i].Value}
+		node := value.Content[i+1]
+		switch node.Kind {
+		case yaml.ScalarNode:
+			flag.Values = []string{node.Value}
+		case yaml.SequenceNode:
+			for _, item := range node.Content {
+				if item.Kind != yaml.ScalarNode {
+					return spec, fmt.Errorf("line %d: the value of --%s of %s must be a scalar or a list of scalars",
+						item.Line, flag.Name, spec.Name)
+				}
+				flag.Values = append(flag.Values, item.Value)
+			}
+		default:
+			return spec, fmt.Errorf("line %d: the value of --%s of %s must be a scalar or a list of scalars",
+				node.Line, flag.Name, spec.Name)
+		}
+		spec.Flags = append(spec.Flags, flag)
+	}
+	return spec, nil
+}
+
+// FindToolCmd finds the sub command of the root command by its name or alias.
+//
+// @param rootCmd The root command.
+// @param name    The name or an alias of the sub command.
+//
+// @return The sub command or nil if not found.
+func FindToolCmd(rootCmd *cobra.Command, name string) *cobra.Command {
+	for _, cmd := range rootCmd.Commands() {
+		if cmd.Name() == name || cmd.HasAlias(name) {
+			return cmd
+		}
+	}
+	return nil
+}
+
+// SortTools sorts tools so that each tool comes after the tools it depends on.
+// The order in the manifest is kept as much as possible.
+//
+// @param specs The tools whose names have been resolved to command names.
+//
+// @return The sorted tools.
+func SortTools(specs []ToolSpec) ([]ToolSpec, error) {
+	index := make(map[string]int, len(specs))
+	for i, spec := range specs {
+		if _, ok := index[spec.Name]; ok {
+			return nil, fmt.Errorf("the tool %s is listed more than once", spec.Name)
+		}
+		index[spec.Name] = i
+	}
+	const (
+		visiting = iota + 1
+		visited
+	)
+	states := make([]int, len(specs))
+	sorted := make([]ToolSpec, 0, len(specs))
+	var visit func(i int, path []string) error
+	visit = func(i int, path []string) error {
+		switch states[i] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("circular dependency among tools: %s", strings.Join(append(path, specs[i].Name), " -> "))
+		}
+		states[i] = visiting
+		for _, dep := range getToolDependencies(specs[i].Name) {
+			if j, ok := index[dep]; ok {
+				if err := visit(j, append(path, specs[i].Name)); err != nil {
+					return err
+				}
+			}
+		}
+		states[i] = visited
+		sorted = append(sorted, specs[i])
+		return nil
+	}
+	for i := range specs {
+		if err := visit(i, nil); err != nil {
+			return nil, err
+		}
+	}
+	return sorted, nil
+}
+
+// resolveTools resolves names (or aliases) of tools to their commands and sorts the tools in dependency order.
+//
+// @param cmd   The command resolving the tools, which is not a tool itself.
+// @param specs The tools.
+//
+// @return The sorted tools with command names and the commands by name.
+func resolveTools(cmd *cobra.Command, specs []ToolSpec) ([]ToolSpec, map[string]*cobra.Command, error) {
+	cmds := make(map[string]*cobra.Command, len(specs))
+	for i, spec := range specs {
+		toolCmd := FindToolCmd(cmd.Root(), spec.Name)
+		if toolCmd == nil || toolCmd == cmd {
+			return nil, nil, fmt.Errorf("unknown tool %s", spec.Name)
+		}
+		specs[i].Name = toolCmd.Name()
+		cmds[toolCmd.Name()] = toolCmd
+	}
+	specs, err := SortTools(specs)
+	if err != nil {
+		return nil, nil, err
+	}
+	return specs, cmds, nil
+}
+
+// FormatManifest formats tools as a manifest which can be parsed by ParseManifest.
+//
+// @param specs The tools.
+//
+// @return The content of the manifest.
+func FormatManifest(specs []ToolSpec) ([]byte, error) {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, spec := range specs {
+		flags := &yaml.Node{Kind: yaml.MappingNode, Style: yaml.FlowStyle}
+		for _, flag := range spec.Flags {
+			value := &yaml.Node{Kind: yaml.SequenceNode, Style: yaml.FlowStyle}
+			for _, v := range flag.Values {
+				value.Content = append(value.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: v})
+			}
+			if len(flag.Values) == 1 {
+				value = value.Content[0]
+			}
+			flags.Content = append(flags.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: flag.Name}, value)
+		}
+		root.Content = append(root.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: spec.Name}, flags)
+	}
+	bytes, err := yaml.Marshal(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to format the manifest: %w", err)
+	}
+	return bytes, nil
+}
+
+// RunTool runs the command of a tool with the specified flags.
+// Flags of the command are reset to their defaults first
+// so that flags set by a previous run of the command in the same process do not leak into this run.
+// The flags are parsed and validated (arguments, required flags and flag groups) as cobra does,
+// and the (pre/post) run hooks of the command are called.
+// Persistent hooks of the root command (logging, dry-run, platform override, etc.)
+// have been run for the command calling RunTool and are not run again.
+//
+// @param cmd  The command of the tool.
+// @param spec The tool and its flags.
+func RunTool(cmd *cobra.Command, spec ToolSpec) error {
+	args := []string{}
+	for _, flag := range spec.Flags {
+		if cmd.Flags().Lookup(flag.Name) == nil {
+			return fmt.Errorf("the command %s has no flag --%s", cmd.Name(), flag.Name)
+		}
+		for _, value := range flag.Values {
+			args = append(args, "--"+flag.Name+"="+value)
+		}
+	}
+	resetFlags(cmd)
+	if err := cmd.ParseFlags(args); err != nil {
+		return fmt.Errorf("invalid flags of %s: %w", cmd.Name(), err)
+	}
+	if err := cmd.ValidateArgs(cmd.Flags().Args()); err != nil {
+		return err
+	}
+	if err := cmd.ValidateRequiredFlags(); err != nil {
+		return err
+	}
+	if err := cmd.ValidateFlagGroups(); err != nil {
+		return err
+	}
+	return runHooks(cmd, cmd.Flags().Args())
+}
+
+// runHooks calls the pre-run hooks, the run function and the post-run hooks of a command in the order cobra does.
+func runHooks(cmd *cobra.Command, args []string) error {
+	steps := []struct {
+		runE func(*cobra.Command, []string) error
+		run  func(*cobra.Command, []string)
+	}{
+		{cmd.PreRunE, cmd.PreRun},
+		{cmd.RunE, cmd.Run},
+		{cmd.PostRunE, cmd.PostRun},
+	}
+	for _, step := range steps {
+		switch {
+		case step.runE != nil:
+			if err := step.runE(cmd, args); err != nil {
+				return err
+			}
+		case step.run != nil:
+			step.run(cmd, args)
+		}
+	}
+	return nil
+}
+
+// resetFlags resets local flags of a command to their defaults,
+// as flags keep values from previous runs of the command in the same process.
+func resetFlags(cmd *cobra.Command) {
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			values := []string{}
+			if def := strings.Trim(flag.DefValue, "[]"); def != "" {
+				values = strings.Split(def, ",")
+			}
+			_ = slice.Replace(values)
+		} else {
+			_ = flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	})
+}
+
+// Install and configure tools listed in a manifest file in dependency order.
+func apply(cmd *cobra.Command, _ []string) error {
+	file, err := utils.GetStringFlag(cmd, "file")
+	if err != nil {
+		return err
+	}
+	bytes, err := utils.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	specs, err := ParseManifest(bytes)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	specs, cmds, err := resolveTools(cmd, specs)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	for _, spec := range specs {
+		log.Printf("Applying %s ...\n", spec.Name)
+		if err := RunTool(cmds[spec.Name], spec); err != nil {
+			return fmt.Errorf("failed to apply %s: %w", spec.Name, err)
+		}
+	}
+	return nil
+}
+
+var applyCmd = &cobra.Command{
+	Use:   "apply",
+	Short: "Install and configure tools listed in a manifest file.",
+	Long: `Install and configure tools listed in a manifest file in dependency order.
+The manifest is a YAML mapping from tools to flags of their commands, e.g.,
+
+  git: {install: true, config: true, gitui: true}
+  spark: {spark-version: 3.5.1}
+  neovim: {config: true, copy: true}`,
+	Args: cobra.NoArgs,
+	RunE: apply,
+}
+
+func ConfigApplyCmd(rootCmd *cobra.Command) {
+	applyCmd.Flags().StringP("file", "f", "", "The manifest file listing tools and their flags.")
+	err := applyCmd.MarkFlagRequired("file")
+	if err != nil {
+		log.Fatal("ERROR - ", err)
+	}
+	rootCmd.AddCommand(applyCmd)
+}
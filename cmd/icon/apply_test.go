@@ -0,0 +1,154 @@
+package icon
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestParseManifest(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []ToolSpec
+		wantErr string
+	}{
+		{"flags", "git: {install: true, config: true}\nspark: {spark-version: 3.5.1}\n", []ToolSpec{
+			{Name: "git", Flags: []FlagValue{{"install", []string{"true"}}, {"config", []string{"true"}}}},
+			{Name: "spark", Flags: []FlagValue{{"spark-version", []string{"3.5.1"}}}},
+		}, ""},
+		{"list", "rust: {toolchain: [stable, nightly]}\n", []ToolSpec{
+			{Name: "rust", Flags: []FlagValue{{"toolchain", []string{"stable", "nightly"}}}},
+		}, ""},
+		{"no flags", "git:\nneovim: {}\n", []ToolSpec{{Name: "git"}, {Name: "neovim"}}, ""},
+		{"empty", "", nil, ""},
+		{"not a mapping", "- git\n", nil, "line 1: the manifest must be a mapping"},
+		{"scalar flags", "git: true\n", nil, "line 1: the flags of git must be a mapping"},
+		{"nested value", "git:\n  install: {a: b}\n", nil, "line 2: the value of --install of git"},
+		{"invalid YAML", "git: {", nil, "failed to parse the manifest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseManifest([]byte(tt.content))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseManifest() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortTools(t *testing.T) {
+	tests := []struct {
+		name    string
+		deps    map[string][]string
+		tools   []string
+		want    []string
+		wantErr string
+	}{
+		{"dependencies", toolDependencies, []string{"gopass", "git", "ssh_client", "neovim"},
+			[]string{"ssh_client", "git", "gopass", "neovim"}, ""},
+		{"homebrew first", toolDependencies, []string{"rust", "homebrew", "alacritty"},
+			[]string{"homebrew", "rust", "alacritty"}, ""},
+		{"unlisted dependency", toolDependencies, []string{"jj", "rip"}, []string{"jj", "rip"}, ""},
+		{"duplicate", toolDependencies, []string{"git", "git"}, nil, "the tool git is listed more than once"},
+		{"cycle", map[string][]string{"a": {"b"}, "b": {"c"}, "c": {"a"}}, []string{"a", "b", "c"},
+			nil, "circular dependency among tools: a -> b -> c -> a"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func(deps map[string][]string) { toolDependencies = deps }(toolDependencies)
+			toolDependencies = tt.deps
+			specs := make([]ToolSpec, 0, len(tt.tools))
+			for _, tool := range tt.tools {
+				specs = append(specs, ToolSpec{Name: tool})
+			}
+			sorted, err := SortTools(specs)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want an error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, 0, len(sorted))
+			for _, spec := range sorted {
+				got = append(got, spec.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("SortTools() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunTool(t *testing.T) {
+	var calls []string
+	cmd := &cobra.Command{
+		Use: "tool",
+		PreRunE: func(_ *cobra.Command, _ []string) error {
+			calls = append(calls, "pre")
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			install, _ := cmd.Flags().GetBool("install")
+			toolchains, _ := cmd.Flags().GetStringSlice("toolchain")
+			calls = append(calls, "install="+strconv.FormatBool(install),
+				"toolchain="+strings.Join(toolchains, ","))
+			return nil
+		},
+	}
+	cmd.Flags().Bool("install", false, "")
+	cmd.Flags().Bool("uninstall", false, "")
+	cmd.Flags().StringSlice("toolchain", []string{"stable"}, "")
+	cmd.MarkFlagsMutuallyExclusive("install", "uninstall")
+	tests := []struct {
+		name    string
+		flags   []FlagValue
+		want    []string
+		wantErr string
+	}{
+		{"flags", []FlagValue{{"install", []string{"true"}}, {"toolchain", []string{"nightly", "beta"}}},
+			[]string{"pre", "install=true", "toolchain=nightly,beta"}, ""},
+		// flags of the previous run are reset
+		{"defaults", nil, []string{"pre", "install=false", "toolchain=stable"}, ""},
+		{"unknown flag", []FlagValue{{"copy", []string{"true"}}}, nil, "the command tool has no flag --copy"},
+		{"invalid value", []FlagValue{{"install", []string{"maybe"}}}, nil, "invalid flags of tool"},
+		{"flag group", []FlagValue{{"install", []string{"true"}}, {"uninstall", []string{"true"}}}, nil,
+			"if any flags in the group [install uninstall] are set none of the others can be"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			err := RunTool(cmd, ToolSpec{Name: "tool", Flags: tt.flags})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want an error containing %q", err, tt.wantErr)
+				}
+				if calls != nil {
+					t.Errorf("the command is run: %q", calls)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(calls, tt.want) {
+				t.Errorf("calls = %q, want %q", calls, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,223 @@
+package icon
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/utils"
+)
+
+// findBackups finds backups (of the original path in args if specified).
+func findBackups(cmd *cobra.Command, args []string) ([]utils.BackupInfo, error) {
+	dirs, err := utils.GetStringSliceFlag(cmd, "dir")
+	if err != nil {
+		return nil, err
+	}
+	backups, err := utils.FindBackups(dirs)
+	if err != nil || len(args) == 0 {
+		return backups, err
+	}
+	original := utils.NormalizePath(args[0])
+	filtered := []utils.BackupInfo{}
+	for _, backup := range backups {
+		if backup.Original == original {
+			filtered = append(filtered, backup)
+		}
+	}
+	return filtered, nil
+}
+
+// List backups made by icon grouped by original path.
+func listBackups(cmd *cobra.Command, args []string) error {
+	backups, err := findBackups(cmd, args)
+	if err != nil {
+		return err
+	}
+	if len(backups) == 0 {
+		fmt.Println("No backup is found.")
+		return nil
+	}
+	//nolint:mnd // readable
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	original := ""
+	for _, backup := range backups {
+		if backup.Original != original {
+			original = backup.Original
+			fmt.Fprintf(writer, "%s\n", original)
+		}
+		fmt.Fprintf(writer, "  %s\t%s\t%s\n", backup.Backup, formatTime(backup.Time), ifEmpty(backup.Tool, "-"))
+	}
+	return writer.Flush()
+}
+
+// Remove old backups made by icon according to a retention policy.
+func pruneBackups(cmd *cobra.Command, args []string) error {
+	keep, err := utils.GetIntFlag(cmd, "keep")
+	if err != nil {
+		return err
+	}
+	days, err := utils.GetIntFlag(cmd, "older-than")
+	if err != nil {
+		return err
+	}
+	if keep < 0 || days < 0 {
+		return errors.New("--keep and --older-than must be non-negative")
+	}
+	backups, err := findBackups(cmd, args)
+	if err != nil {
+		return err
+	}
+	//nolint:mnd // readable
+	pruned := utils.SelectBackupsToPrune(backups, keep, time.Duration(days)*24*time.Hour)
+	if len(pruned) == 0 {
+		fmt.Println("No backup needs to be pruned.")
+		return nil
+	}
+	paths := make([]string, 0, len(pruned))
+	for _, backup := range pruned {
+		if err := utils.RemoveAll(backup.Backup); err != nil {
+			return err
+		}
+		paths = append(paths, backup.Backup)
+	}
+	if err := utils.ForgetBackups(paths); err != nil {
+		return err
+	}
+	fmt.Printf("%d backup(s) %s pruned.\n", len(paths), utils.IfElseString(utils.IsDryRun(), "would be", "have been"))
+	return nil
+}
+
+// diffBackup shows the unified diff of changes that restoring a backup would make.
+func diffBackup(original, backup string) error {
+	if !utils.ExistsPath(original) {
+		fmt.Printf("%s does not exist.\n", original)
+		return nil
+	}
+	return unifiedDiff(original, backup)
+}
+
+// unifiedDiff shows the unified diff between two paths (recursively if either of them is a directory).
+func unifiedDiff(from, to string) error {
+	option := utils.IfElseString(utils.ExistsDir(from) || utils.ExistsDir(to), "-ru", "-u")
+	err := utils.NewCommand("diff", option, from, to).Run()
+	// diff exits with 1 if there are differences
+	var cmdErr *utils.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.ExitCode == 1 {
+		return nil
+	}
+	return err
+}
+
+// confirm asks the user to confirm an action.
+func confirm(question string) (bool, error) {
+	fmt.Print(question + " [y/N] ")
+	reader := bufio.NewReader(os.Stdin)
+	input, err := reader.ReadString('\n')
+	if err != nil {
+		return false, fmt.Errorf("error reading input: %w", err)
+	}
+	answer := strings.ToLower(strings.TrimSpace(input))
+	return answer == "y" || answer == "yes", nil
+}
+
+// Restore a backup made by icon to its original path.
+func restoreBackup(cmd *cobra.Command, args []string) error {
+	path := utils.NormalizePath(args[0])
+	original, _, ok := utils.ParseBackupPath(path)
+	backups, err := findBackups(cmd, nil)
+	if err != nil {
+		return err
+	}
+	for _, backup := range backups {
+		if backup.Backup == path {
+			original, ok = backup.Original, true
+		}
+	}
+	if !ok {
+		return fmt.Errorf("%s is not a backup made by icon", path)
+	}
+	if !utils.ExistsPath(path) {
+		return fmt.Errorf("the backup %s does not exist", path)
+	}
+	noDiff, err := utils.GetBoolFlag(cmd, "no-diff")
+	if err != nil {
+		return err
+	}
+	if !noDiff {
+		if err := diffBackup(original, path); err != nil {
+			return err
+		}
+	}
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	if !yes && !utils.IsDryRun() {
+		ok, err := confirm(fmt.Sprintf("Restore %s to %s?", path, original))
+		if err != nil || !ok {
+			return err
+		}
+	}
+	backup, err := utils.ShouldBackup(cmd)
+	if err != nil {
+		return err
+	}
+	if err := utils.BackupOrRemove(original, backup); err != nil {
+		return err
+	}
+	if err := utils.Rename(path, original); err != nil {
+		return err
+	}
+	return utils.ForgetBackups([]string{path})
+}
+
+var backupsCmd = &cobra.Command{
+	Use:   "backups",
+	Short: "Manage backups made by icon.",
+	Long: `Manage backups made by icon before overwriting paths.
+Backups recorded in states of tools are found,
+and so are paths looking like backups (<path>_<time>) in the directories specified by --dir.`,
+}
+
+var backupsListCmd = &cobra.Command{
+	Use:     "list [original]",
+	Aliases: []string{"ls"},
+	Short:   "List backups grouped by original path.",
+	Args:    cobra.MaximumNArgs(1),
+	RunE:    listBackups,
+}
+
+var backupsPruneCmd = &cobra.Command{
+	Use:   "prune [original]",
+	Short: "Remove old backups according to a retention policy.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE:  pruneBackups,
+}
+
+var backupsRestoreCmd = &cobra.Command{
+	Use:   "restore <backup>",
+	Short: "Restore a backup to its original path.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  restoreBackup,
+}
+
+func ConfigBackupsCmd(rootCmd *cobra.Command) {
+	backupsCmd.PersistentFlags().StringSlice("dir", []string{"~", "~/.config"},
+		"Directories to scan for backups not recorded in states of tools.")
+	//nolint:mnd // readable
+	backupsPruneCmd.Flags().Int("keep", 3, "The number of newest backups to keep for each original path.")
+	backupsPruneCmd.Flags().Int("older-than", 0, "Only remove backups older than this number of days (0 means no limit).")
+	backupsRestoreCmd.Flags().Bool("no-diff", false, "Do not show the diff between the current path and the backup.")
+	backupsRestoreCmd.Flags().Bool("no-backup", false, "Do not backup the current path before restoring.")
+	backupsRestoreCmd.Flags().BoolP("yes", "y", false, "Restore without asking for confirmation.")
+	backupsCmd.AddCommand(backupsListCmd)
+	backupsCmd.AddCommand(backupsPruneCmd)
+	backupsCmd.AddCommand(backupsRestoreCmd)
+	rootCmd.AddCommand(backupsCmd)
+}
@@ -0,0 +1,50 @@
+package icon
+
+import (
+	"testing"
+
+	"legendu.net/icon/internal/testutil"
+)
+
+func TestBackups(t *testing.T) {
+	backups := []string{
+		"~/.bashrc_2026-01-01T00:00:00Z",
+		"~/.bashrc_2026-02-01T00:00:00Z",
+		"~/.bashrc_2026-03-01T00:00:00Z",
+	}
+	tests := []struct {
+		name     string
+		args     []string
+		wantKept []string
+		wantFile string
+	}{
+		{"prune", []string{"backups", "prune", "--keep", "1"}, backups[2:], "current\n"},
+		{"prune original", []string{"backups", "prune", "~/.profile", "--keep", "1"}, backups, "current\n"},
+		{"restore", []string{"backups", "restore", backups[0], "--yes", "--no-diff", "--no-backup"}, backups[1:], backups[0]},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			env.WriteFile("~/.bashrc", "current\n")
+			for _, backup := range backups {
+				env.WriteFile(backup, backup)
+			}
+			if err := env.Run(testRoot(), tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands()
+			for _, backup := range backups {
+				want := false
+				for _, kept := range tt.wantKept {
+					want = want || kept == backup
+				}
+				if got := env.Exists(backup); got != want {
+					t.Errorf("%s exists: %t, want %t", backup, got, want)
+				}
+			}
+			if got := env.ReadFile("~/.bashrc"); got != tt.wantFile {
+				t.Errorf("~/.bashrc = %q, want %q", got, tt.wantFile)
+			}
+		})
+	}
+}
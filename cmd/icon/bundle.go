@@ -0,0 +1,368 @@
+package icon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+	"legendu.net/icon/utils"
+)
+
+// A bundle is a tar archive with the following layout.
+//   - manifest.yaml: the bundleManifest.
+//   - tools.yaml: the tools to install (in the format of manifests of icon apply).
+//   - cache/: a cache directory (see utils.CacheDir) with downloads and responses needed by the tools.
+//   - icon-data/: a copy of ~/.config/icon-data.
+const (
+	bundleManifestFile = "manifest.yaml"
+	bundleToolsFile    = "tools.yaml"
+	bundleCacheDir     = "cache"
+	bundleDataDir      = "icon-data"
+)
+
+// bundleManifest describes a bundle.
+type bundleManifest struct {
+	// Version is the version of the layout of the bundle.
+	Version int `yaml:"version"`
+	// Created is when the bundle was created.
+	Created time.Time `yaml:"created"`
+	// OS is the OS (as GOOS) which the bundle is for.
+	OS string `yaml:"os"`
+	// Arch is the architecture (amd64 or arm64) which the bundle is for.
+	Arch string `yaml:"arch"`
+	// Tools are names of the tools in the bundle in the order they are installed.
+	Tools []string `yaml:"tools"`
+	// Downloads are URLs of files in the bundle.
+	Downloads []string `yaml:"downloads"`
+	// ConfigData is whether the bundle contains icon-data.
+	ConfigData bool `yaml:"configData"`
+}
+
+const bundleVersion = 1
+
+// setEnv sets an environment variable.
+//
+// @return A function restoring the environment variable.
+func setEnv(name, value string) (func(), error) {
+	old, ok := os.LookupEnv(name)
+	if err := os.Setenv(name, value); err != nil {
+		return nil, fmt.Errorf("failed to set the environment variable %s: %w", name, err)
+	}
+	return func() {
+		if ok {
+			_ = os.Setenv(name, old)
+		} else {
+			_ = os.Unsetenv(name)
+		}
+	}, nil
+}
+
+// bundleTools reads the tools to bundle from --tools and --file.
+func bundleTools(cmd *cobra.Command) ([]ToolSpec, error) {
+	tools, err := utils.GetStringSliceFlag(cmd, "tools")
+	if err != nil {
+		return nil, err
+	}
+	file, err := utils.GetStringFlag(cmd, "file")
+	if err != nil {
+		return nil, err
+	}
+	specs := []ToolSpec{}
+	if file != "" {
+		bytes, err := utils.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		if specs, err = ParseManifest(bytes); err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+	}
+	for _, tool := range tools {
+		specs = append(specs, ToolSpec{Name: tool, Flags: []FlagValue{{Name: "install", Values: []string{"true"}}}})
+	}
+	if len(specs) == 0 {
+		return nil, errors.New("no tool is specified (by --tools or --file)")
+	}
+	return specs, nil
+}
+
+// prefetchTools runs tools in dry-run mode for the target platform
+// so that the files they download are fetched into the cache.
+func prefetchTools(cmds map[string]*cobra.Command, specs []ToolSpec, goos, arch string) error {
+	dryRun := utils.IsDryRun()
+	target := utils.CurrentPlatform()
+	if target.OS != goos {
+		// the distribution of machines with another OS is unknown
+		target = utils.Platform{OS: goos, Overridden: true}
+	}
+	target.Arch = arch
+	restorePlatform := utils.SetPlatform(target)
+	utils.SetDryRun(true)
+	utils.SetPrefetch(!dryRun)
+	defer func() {
+		restorePlatform()
+		utils.SetDryRun(dryRun)
+		utils.SetPrefetch(false)
+	}()
+	for _, spec := range specs {
+		log.Printf("Resolving downloads of %s ...\n", spec.Name)
+		if err := RunTool(cmds[spec.Name], spec); err != nil {
+			return fmt.Errorf("failed to resolve downloads of %s: %w", spec.Name, err)
+		}
+	}
+	return nil
+}
+
+// Create a bundle of tools for installing them on machines without network access.
+func createBundle(cmd *cobra.Command, _ []string) error {
+	specs, err := bundleTools(cmd)
+	if err != nil {
+		return err
+	}
+	specs, cmds, err := resolveTools(cmd, specs)
+	if err != nil {
+		return err
+	}
+	goos, err := utils.GetStringFlag(cmd, "os")
+	if err != nil {
+		return err
+	}
+	if goos == "" {
+		goos = utils.CurrentPlatform().OS
+	}
+	arch, err := utils.GetStringFlag(cmd, "arch")
+	if err != nil {
+		return err
+	}
+	if arch == "" {
+		if arch, err = utils.HostKernelArch(); err != nil {
+			return err
+		}
+	}
+	if !slices.Contains([]string{"linux", "darwin"}, goos) || !slices.Contains([]string{"amd64", "arm64"}, arch) {
+		return fmt.Errorf("unsupported platform %s/%s", goos, arch)
+	}
+	output, err := utils.GetStringFlag(cmd, "output")
+	if err != nil {
+		return err
+	}
+	noData, err := utils.GetBoolFlag(cmd, "no-data")
+	if err != nil {
+		return err
+	}
+	gitURL, err := utils.GetStringFlag(cmd, "git-url")
+	if err != nil {
+		return err
+	}
+	staging, err := utils.CreateTempDir("icon_bundle_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(staging)
+	restore, err := setEnv("ICON_CACHE_DIR", filepath.Join(staging, bundleCacheDir))
+	if err != nil {
+		return err
+	}
+	defer restore()
+	if !noData {
+		if err := FetchConfigData(false, gitURL); err != nil {
+			return err
+		}
+		command := utils.NewCommand("cp", "-a", utils.NormalizePath("~/.config/icon-data"), filepath.Join(staging, bundleDataDir))
+		if err := command.Run(); err != nil {
+			return err
+		}
+	}
+	if err := prefetchTools(cmds, specs, goos, arch); err != nil {
+		return err
+	}
+	manifest := bundleManifest{
+		Version:    bundleVersion,
+		Created:    time.Now(),
+		OS:         goos,
+		Arch:       arch,
+		Tools:      make([]string, 0, len(specs)),
+		Downloads:  []string{},
+		ConfigData: !noData,
+	}
+	for _, spec := range specs {
+		manifest.Tools = append(manifest.Tools, spec.Name)
+	}
+	downloads, err := utils.ListDownloadCache()
+	if err != nil {
+		return err
+	}
+	for _, download := range downloads {
+		if !download.IsPartial() {
+			manifest.Downloads = append(manifest.Downloads, download.URL)
+		}
+	}
+	if err := os.RemoveAll(filepath.Join(staging, bundleCacheDir, "downloads", "partial")); err != nil {
+		return fmt.Errorf("failed to clean up partial downloads: %w", err)
+	}
+	if err := writeBundleFiles(staging, manifest, specs); err != nil {
+		return err
+	}
+	if err := utils.CreateTarball(staging, output); err != nil {
+		return err
+	}
+	if !utils.IsDryRun() {
+		fmt.Printf("%d tool(s) and %d download(s) have been bundled into %s.\n",
+			len(manifest.Tools), len(manifest.Downloads), output)
+	}
+	return nil
+}
+
+// writeBundleFiles writes the manifest and the tools of a bundle into its staging directory.
+func writeBundleFiles(staging string, manifest bundleManifest, specs []ToolSpec) error {
+	bytes, err := yaml.Marshal(manifest)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the manifest of the bundle: %w", err)
+	}
+	//nolint:mnd // readable
+	if err := os.WriteFile(filepath.Join(staging, bundleManifestFile), bytes, 0o644); err != nil {
+		return fmt.Errorf("failed to write the manifest of the bundle: %w", err)
+	}
+	if bytes, err = FormatManifest(specs); err != nil {
+		return err
+	}
+	//nolint:mnd // readable
+	if err := os.WriteFile(filepath.Join(staging, bundleToolsFile), bytes, 0o644); err != nil {
+		return fmt.Errorf("failed to write the tools of the bundle: %w", err)
+	}
+	return nil
+}
+
+// readBundle reads the manifest and the tools of an extracted bundle.
+func readBundle(dir string) (*bundleManifest, []ToolSpec, error) {
+	bytes, err := os.ReadFile(filepath.Join(dir, bundleManifestFile))
+	if err != nil {
+		return nil, nil, fmt.Errorf("the bundle has no manifest: %w", err)
+	}
+	var manifest bundleManifest
+	if err := yaml.Unmarshal(bytes, &manifest); err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the manifest of the bundle: %w", err)
+	}
+	if manifest.Version != bundleVersion {
+		return nil, nil, fmt.Errorf("unsupported version %d of the bundle", manifest.Version)
+	}
+	if bytes, err = os.ReadFile(filepath.Join(dir, bundleToolsFile)); err != nil {
+		return nil, nil, fmt.Errorf("the bundle has no tools: %w", err)
+	}
+	specs, err := ParseManifest(bytes)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%s: %w", bundleToolsFile, err)
+	}
+	return &manifest, specs, nil
+}
+
+// installBundledData installs icon-data from a bundle unless ~/.config/icon-data is a Git repository already.
+func installBundledData(dir string) error {
+	src := filepath.Join(dir, bundleDataDir)
+	dst := "~/.config/icon-data"
+	if !utils.ExistsDir(src) || utils.ExistsDir(dst+"/.git") {
+		return nil
+	}
+	if err := utils.Backup(dst, ""); err != nil {
+		return err
+	}
+	if err := utils.MkdirAll(filepath.Dir(utils.NormalizePath(dst)), "700"); err != nil {
+		return err
+	}
+	return utils.Rename(src, utils.NormalizePath(dst))
+}
+
+// Install tools from a bundle without network access.
+func installBundle(cmd *cobra.Command, args []string) error {
+	dir, err := utils.CreateTempDir("icon_bundle_")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := utils.ExtractTarball(args[0], dir); err != nil {
+		return err
+	}
+	manifest, specs, err := readBundle(dir)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	arch, err := utils.HostKernelArch()
+	if err != nil {
+		return err
+	}
+	if goos := utils.CurrentPlatform().OS; manifest.OS != goos || manifest.Arch != arch {
+		return fmt.Errorf("the bundle %s is for %s/%s instead of %s/%s",
+			args[0], manifest.OS, manifest.Arch, goos, arch)
+	}
+	specs, cmds, err := resolveTools(cmd, specs)
+	if err != nil {
+		return fmt.Errorf("%s: %w", args[0], err)
+	}
+	if err := installBundledData(dir); err != nil {
+		return err
+	}
+	restore, err := setEnv("ICON_CACHE_DIR", filepath.Join(dir, bundleCacheDir))
+	if err != nil {
+		return err
+	}
+	defer restore()
+	utils.SetOffline(true)
+	defer utils.SetOffline(false)
+	for _, spec := range specs {
+		log.Printf("Installing %s from the bundle ...\n", spec.Name)
+		if err := RunTool(cmds[spec.Name], spec); err != nil {
+			return fmt.Errorf("failed to install %s from the bundle: %w", spec.Name, err)
+		}
+	}
+	return nil
+}
+
+var bundleCmd = &cobra.Command{
+	Use:   "bundle",
+	Short: "Bundle tools for installing them on machines without network access.",
+	Long: `Bundle tools for installing them on machines without network access (e.g., air-gapped machines).
+A bundle is a tar archive containing the files (e.g., release assets, Spark and Go tarballs) and the responses
+(e.g., of the GitHub API) which installers of the tools download, and a copy of icon-data.
+Installing a bundle runs the installers of the tools offline against the bundled files.
+Packages installed by package managers (e.g., apt) and scripts piped from the network (e.g., curl ... | sh)
+are not bundled, so such steps of installers still need access to (mirrors of) their repositories.`,
+}
+
+var bundleCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a bundle of tools.",
+	Example: `  icon bundle create --tools git,neovim,zellij,spark --os linux --arch amd64 -o bundle.tar
+  icon bundle create --file manifest.yaml -o bundle.tar.gz`,
+	Args: cobra.NoArgs,
+	RunE: createBundle,
+}
+
+var bundleInstallCmd = &cobra.Command{
+	Use:   "install <bundle>",
+	Short: "Install tools from a bundle without network access.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  installBundle,
+}
+
+func ConfigBundleCmd(rootCmd *cobra.Command) {
+	bundleCreateCmd.Flags().StringSliceP("tools", "t", []string{}, "Tools to bundle (with the flag --install).")
+	bundleCreateCmd.Flags().StringP("file", "f", "",
+		"A manifest (in the format of icon apply) listing tools to bundle and their flags.")
+	bundleCreateCmd.Flags().String("os", "",
+		"The OS (linux or darwin) of the machines to install the bundle on (the current one by default).")
+	bundleCreateCmd.Flags().String("arch", "",
+		"The architecture (amd64 or arm64) of the machines to install the bundle on (the current one by default).")
+	bundleCreateCmd.Flags().StringP("output", "o", "icon_bundle.tar",
+		"The bundle to create, compressed by gzip if its name ends with .gz or .tgz.")
+	bundleCreateCmd.Flags().Bool("no-data", false, "Do not bundle icon-data.")
+	bundleCreateCmd.Flags().StringP("git-url", "g", GitURL, "The Git repo URL for icon-data.")
+	bundleCmd.AddCommand(bundleCreateCmd)
+	bundleCmd.AddCommand(bundleInstallCmd)
+	rootCmd.AddCommand(bundleCmd)
+}
@@ -0,0 +1,98 @@
+package icon
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/utils"
+)
+
+// List downloads in the download cache.
+func listCache(_ *cobra.Command, _ []string) error {
+	downloads, err := utils.ListDownloadCache()
+	if err != nil {
+		return err
+	}
+	if len(downloads) == 0 {
+		fmt.Println("The download cache is empty.")
+		return nil
+	}
+	//nolint:mnd // readable
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "SHA256\tSIZE\tDOWNLOADED\tURL")
+	var total int64
+	for _, d := range downloads {
+		total += d.Size
+		checksum := "(partial)"
+		if !d.IsPartial() {
+			//nolint:mnd // readable
+			checksum = d.Sha256[:12]
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", checksum, utils.FormatBytes(d.Size), formatTime(d.Time), d.URL)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("%d download(s) in %s\n", len(downloads), utils.CacheDir())
+	return nil
+}
+
+// Remove downloads from the download cache.
+func cleanCache(cmd *cobra.Command, _ []string) error {
+	days, err := utils.GetIntFlag(cmd, "older-than")
+	if err != nil {
+		return err
+	}
+	if days < 0 {
+		return errors.New("--older-than must be non-negative")
+	}
+	//nolint:mnd // readable
+	count, freed, err := utils.CleanDownloadCache(time.Duration(days) * 24 * time.Hour)
+	if err != nil {
+		return err
+	}
+	// cached responses (e.g., of the GitHub API) are small and revalidated anyway
+	if days == 0 {
+		if err := utils.CleanResponseCache(); err != nil {
+			return err
+		}
+	}
+	fmt.Printf("%d download(s) %s removed, freeing %s.\n",
+		count, utils.IfElseString(utils.IsDryRun(), "would be", "have been"), utils.FormatBytes(freed))
+	return nil
+}
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the download cache.",
+	Long: `Manage the download cache.
+Downloaded files are cached (in $ICON_CACHE_DIR, $XDG_CACHE_HOME/icon or ~/.cache/icon)
+so that they are not downloaded again and interrupted downloads are resumed.
+Responses of APIs (e.g., the GitHub API) are cached too so that they can be revalidated using ETags.`,
+}
+
+var cacheListCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List downloads in the cache.",
+	Args:    cobra.NoArgs,
+	RunE:    listCache,
+}
+
+var cacheCleanCmd = &cobra.Command{
+	Use:   "clean",
+	Short: "Remove downloads from the cache.",
+	Args:  cobra.NoArgs,
+	RunE:  cleanCache,
+}
+
+func ConfigCacheCmd(rootCmd *cobra.Command) {
+	cacheCleanCmd.Flags().Int("older-than", 0, "Only remove downloads older than this number of days (0 means all).")
+	cacheCmd.AddCommand(cacheListCmd)
+	cacheCmd.AddCommand(cacheCleanCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
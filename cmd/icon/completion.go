@@ -1,7 +1,7 @@
 package icon
 
 import (
-	"log"
+	"fmt"
 	"os"
 
 	"github.com/spf13/cobra"
@@ -30,29 +30,22 @@ $ icon completion fish > ~/.config/fish/completions/icon.fish
 	DisableFlagsInUseLine: true,
 	Args:                  cobra.MatchAll(cobra.ExactArgs(1), cobra.OnlyValidArgs),
 	ValidArgs:             []string{"bash", "zsh", "fish"},
-	Run: func(cmd *cobra.Command, args []string) {
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var err error
 		switch args[0] {
 		case "bash":
-			err := cmd.Root().GenBashCompletion(os.Stdout)
-			if err != nil {
-				log.Fatalf("Failed to generate completion script for bash: %v", err)
-			}
+			err = cmd.Root().GenBashCompletion(os.Stdout)
 		case "zsh":
-			err := cmd.Root().GenZshCompletion(os.Stdout)
-			if err != nil {
-				log.Fatalf("Failed to generate completion script for bash: %v", err)
-			}
+			err = cmd.Root().GenZshCompletion(os.Stdout)
 		case "fish":
-			err := cmd.Root().GenFishCompletion(os.Stdout, true)
-			if err != nil {
-				log.Fatalf("Failed to generate completion script for bash: %v", err)
-			}
+			err = cmd.Root().GenFishCompletion(os.Stdout, true)
 		case "powershell":
-			err := cmd.Root().GenPowerShellCompletion(os.Stdout)
-			if err != nil {
-				log.Fatalf("Failed to generate completion script for bash: %v", err)
-			}
+			err = cmd.Root().GenPowerShellCompletion(os.Stdout)
 		}
+		if err != nil {
+			return fmt.Errorf("failed to generate completion script for %s: %w", args[0], err)
+		}
+		return nil
 	},
 }
 
@@ -2,7 +2,9 @@ package icon
 
 import (
 	"fmt"
+	"log"
 	"path/filepath"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"legendu.net/icon/utils"
@@ -10,48 +12,257 @@ import (
 
 const GitURL = "https://github.com/legendu-net/icon-data.git"
 
-func FetchConfigData(force bool, gitURL string) {
+// configDataDir is the clone of icon-data.
+const configDataDir = "~/.config/icon-data"
+
+func FetchConfigData(force bool, gitURL string) error {
 	if gitURL == "" {
 		gitURL = GitURL
 	}
 
-	dir := "~/.config/icon-data"
+	dir := configDataDir
 	if !force && utils.ExistsDir(dir+"/.git") {
-		fmt.Printf("Using existing data in %s.\n", dir)
-		return
+		log.Printf("Using existing data in %s.\n", dir)
+		return nil
 	}
 
-	utils.Backup(dir, "")
-	utils.MkdirAll(dir, "700")
+	if err := utils.Backup(dir, ""); err != nil {
+		return err
+	}
+	if err := utils.MkdirAll(dir, "700"); err != nil {
+		return err
+	}
 
-	command := utils.Format(`git clone {gitUrl} {dir} \
-			&& cd {dir} && git submodule init && git submodule update --remote`, map[string]string{
-		"gitUrl": gitURL,
-		"dir":    dir,
-	})
-	utils.RunCmd(command)
-	fmt.Printf("Data for icon has been pulled into %s.\n", dir)
+	if err := utils.NewCommand("git", "clone", gitURL, utils.NormalizePath(dir)).Run(); err != nil {
+		return err
+	}
+	if err := setUpConfigData(); err != nil {
+		return err
+	}
+	log.Printf("Data for icon has been pulled into %s.\n", dir)
+	return nil
+}
 
+// setUpConfigData updates submodules of the clone of icon-data
+// and makes the SSH client configuration in it accessible only by the current user.
+func setUpConfigData() error {
+	dir := configDataDir
+	if err := utils.NewCommand("git", "submodule", "init").Dir(dir).Run(); err != nil {
+		return err
+	}
+	if err := utils.NewCommand("git", "submodule", "update", "--remote").Dir(dir).Run(); err != nil {
+		return err
+	}
 	sshConfig := filepath.Join(dir, "ssh", "client", "config")
 	if utils.ExistsFile(sshConfig) {
-		utils.Chmod600(sshConfig)
+		return utils.Chmod600(sshConfig)
+	}
+	return nil
+}
+
+// requireConfigData returns an error if ~/.config/icon-data is not a clone of icon-data.
+func requireConfigData() error {
+	if !utils.ExistsDir(configDataDir + "/.git") {
+		return fmt.Errorf("%s is not a Git repo (run icon data to clone icon-data)", configDataDir)
 	}
+	return nil
 }
 
 // Pull data for icon from GitHub into ~/.config/icon-data.
-func data(cmd *cobra.Command, _ []string) {
-	FetchConfigData(utils.GetBoolFlag(cmd, "force"), utils.GetStringFlag(cmd, "git-url"))
+func data(cmd *cobra.Command, _ []string) error {
+	force, err := utils.GetBoolFlag(cmd, "force")
+	if err != nil {
+		return err
+	}
+	gitURL, err := utils.GetStringFlag(cmd, "git-url")
+	if err != nil {
+		return err
+	}
+	return FetchConfigData(force, gitURL)
+}
+
+// Show uncommitted changes in ~/.config/icon-data and local changes of configuration files not pulled into it yet.
+func dataStatus(cmd *cobra.Command, _ []string) error {
+	if err := requireConfigData(); err != nil {
+		return err
+	}
+	if err := utils.NewCommand("git", "status", "--short", "--branch").Dir(configDataDir).Run(); err != nil {
+		return err
+	}
+	drifts, err := findDrifts(cmd, nil)
+	if err != nil {
+		return err
+	}
+	header := false
+	for _, drift := range drifts {
+		if !drift.hasLocalContent() {
+			continue
+		}
+		if !header {
+			fmt.Println("Local changes not pulled into icon-data (see icon data push):")
+			header = true
+		}
+		fmt.Printf("  %s (%s)\n", drift.Path, drift.Status)
+	}
+	return nil
+}
+
+// Update ~/.config/icon-data from its remote (or clone it if it does not exist).
+func dataPull(cmd *cobra.Command, _ []string) error {
+	if !utils.ExistsDir(configDataDir + "/.git") {
+		gitURL, err := utils.GetStringFlag(cmd, "git-url")
+		if err != nil {
+			return err
+		}
+		return FetchConfigData(false, gitURL)
+	}
+	if err := utils.NewCommand("git", "pull", "--rebase").Dir(configDataDir).Run(); err != nil {
+		return err
+	}
+	if err := setUpConfigData(); err != nil {
+		return err
+	}
+	log.Printf("%s has been updated.\n", configDataDir)
+	return nil
+}
+
+// pullInConfigs copies local changes of configuration files placed by icon back into ~/.config/icon-data.
+//
+// @param paths  Paths of the configuration files.
+// @param backup Whether to backup files in place of symbolic links before linking them again.
+func pullInConfigs(paths []string, backup bool) error {
+	states, err := utils.ReadAllToolStates()
+	if err != nil {
+		return err
+	}
+	for _, path := range paths {
+		path, err := filepath.Abs(utils.NormalizePath(path))
+		if err != nil {
+			return err
+		}
+		found := false
+		for _, state := range states {
+			for _, config := range state.ManagedConfigs() {
+				if config.Path != path {
+					continue
+				}
+				found = true
+				drift, err := utils.CompareConfig(config)
+				if err != nil {
+					return err
+				}
+				if drift.Status == utils.ConfigInSync {
+					log.Printf("%s has no local changes.\n", path)
+					continue
+				}
+				if err := adoptConfig(toolDrift{drift, state.Name}, backup); err != nil {
+					return err
+				}
+			}
+		}
+		if !found {
+			return fmt.Errorf("%s is not a configuration file placed by icon (see icon diff)", path)
+		}
+	}
+	return nil
+}
+
+// commitMessage generates a commit message from paths of changed files.
+func commitMessage(files []string) string {
+	//nolint:mnd // readable
+	if len(files) > 3 {
+		return fmt.Sprintf("Update %s and %d other files", strings.Join(files[:2], ", "), len(files)-2)
+	}
+	return "Update " + strings.Join(files, ", ")
+}
+
+// Pull local configuration files into ~/.config/icon-data, commit changes and push them to the remote.
+func dataPush(cmd *cobra.Command, args []string) error {
+	if err := requireConfigData(); err != nil {
+		return err
+	}
+	backup, err := utils.ShouldBackup(cmd)
+	if err != nil {
+		return err
+	}
+	if err := pullInConfigs(args, backup); err != nil {
+		return err
+	}
+	dir := configDataDir
+	if err := utils.NewCommand("git", "add", "--all").Dir(dir).Run(); err != nil {
+		return err
+	}
+	staged, err := utils.NewCommand("git", "diff", "--cached", "--name-only").Dir(dir).Output()
+	if err != nil {
+		return err
+	}
+	if staged == "" {
+		log.Printf("There are no changes to commit in %s.\n", dir)
+	} else {
+		message, err := utils.GetStringFlag(cmd, "message")
+		if err != nil {
+			return err
+		}
+		if message == "" {
+			message = commitMessage(strings.Fields(staged))
+		}
+		if err := utils.NewCommand("git", "commit", "-m", message).Dir(dir).Run(); err != nil {
+			return err
+		}
+	}
+	push := utils.NewCommand("git", "push").Dir(dir)
+	remote, err := utils.GetStringFlag(cmd, "remote")
+	if err != nil {
+		return err
+	}
+	if remote != "" {
+		push.Arg(remote, "HEAD")
+	}
+	if err := push.Run(); err != nil {
+		return fmt.Errorf("failed to push %s (run icon data pull first if the remote has new commits): %w", dir, err)
+	}
+	return nil
 }
 
 var dataCmd = &cobra.Command{
 	Use:     "data",
 	Aliases: []string{"d"},
 	Short:   "Pull data for icon from GitHub into ~/.config/icon-data.",
-	Run:     data,
+	RunE:    data,
+}
+
+var dataStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show changes in ~/.config/icon-data and local changes of configuration files not pulled into it.",
+	Args:  cobra.NoArgs,
+	RunE:  dataStatus,
+}
+
+var dataPullCmd = &cobra.Command{
+	Use:   "pull",
+	Short: "Update ~/.config/icon-data from its remote.",
+	Args:  cobra.NoArgs,
+	RunE:  dataPull,
+}
+
+var dataPushCmd = &cobra.Command{
+	Use:   "push [path...]",
+	Short: "Commit changes in ~/.config/icon-data and push them to its remote.",
+	Long: `Commit changes in ~/.config/icon-data and push them to its remote.
+Local changes of the specified configuration files placed by icon (e.g., ~/.gitconfig copied by --copy)
+are pulled into ~/.config/icon-data first (see icon diff --adopt).
+The commit message lists the changed files unless --message is specified.`,
+	RunE: dataPush,
 }
 
 func ConfigDataCmd(rootCmd *cobra.Command) {
-	dataCmd.Flags().StringP("git-url", "g", GitURL, "The Git repo URL for icon-data.")
+	dataCmd.PersistentFlags().StringP("git-url", "g", GitURL, "The Git repo URL for icon-data.")
 	dataCmd.Flags().Bool("force", false, "Force pulling data if it alreay exists.")
+	dataPushCmd.Flags().StringP("message", "m", "", "The commit message.")
+	dataPushCmd.Flags().String("remote", "", "The remote to push to instead of the upstream of the current branch.")
+	dataPushCmd.Flags().Bool("no-backup", false, "Do not backup files in place of symbolic links before linking them again.")
+	dataCmd.AddCommand(dataStatusCmd)
+	dataCmd.AddCommand(dataPullCmd)
+	dataCmd.AddCommand(dataPushCmd)
 	rootCmd.AddCommand(dataCmd)
 }
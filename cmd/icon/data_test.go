@@ -0,0 +1,115 @@
+package icon
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/internal/testutil"
+	"legendu.net/icon/utils"
+)
+
+var testRoot = sync.OnceValue(func() *cobra.Command {
+	root := &cobra.Command{Use: "icon"}
+	ConfigBackupsCmd(root)
+	ConfigDataCmd(root)
+	ConfigDiffCmd(root)
+	ConfigDoctorCmd(root)
+	return root
+})
+
+func TestData(t *testing.T) {
+	clone := []string{
+		"git clone https://github.com/legendu-net/icon-data.git " + testutil.Home + "/.config/icon-data",
+		"cd " + testutil.Home + "/.config/icon-data && git submodule init",
+		"cd " + testutil.Home + "/.config/icon-data && git submodule update --remote",
+	}
+	tests := []struct {
+		name       string
+		existing   bool
+		args       []string
+		want       []string
+		wantBackup bool
+	}{
+		{"fresh", false, []string{"data"}, clone, false},
+		{"existing", true, []string{"data"}, nil, false},
+		{"force", true, []string{"data", "--force"}, clone, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			if tt.existing {
+				env.FakeConfigData(map[string]string{"user.yaml": "userName: Tester\n"})
+			}
+			if err := env.Run(testRoot(), tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands(tt.want...)
+			if got := env.Exists("~/.config/icon-data/user.yaml"); got != (tt.existing && !tt.wantBackup) {
+				t.Errorf("the existing data is kept: %t", got)
+			}
+		})
+	}
+}
+
+func TestDataSync(t *testing.T) {
+	dir := testutil.Home + "/.config/icon-data"
+	git := "cd " + dir + " && git "
+	tests := []struct {
+		name     string
+		existing bool
+		staged   string
+		args     []string
+		want     []string
+		wantErr  bool
+		pulledIn bool
+	}{
+		{"status", true, "", []string{"data", "status"}, []string{git + "status --short --branch"}, false, false},
+		{
+			"pull", true, "", []string{"data", "pull"},
+			[]string{git + "pull --rebase", git + "submodule init", git + "submodule update --remote"}, false, false,
+		},
+		{
+			"pull fresh", false, "", []string{"data", "pull"},
+			[]string{"git clone https://github.com/legendu-net/icon-data.git " + dir, git + "submodule init", git + "submodule update --remote"},
+			false, false,
+		},
+		{
+			"push", true, "git/gitconfig\n", []string{"data", "push", "~/.gitconfig"},
+			[]string{git + "add --all", git + "diff --cached --name-only", git + "commit -m 'Update git/gitconfig'", git + "push"},
+			false, true,
+		},
+		{
+			"push nothing", true, "", []string{"data", "push", "--remote", "origin"},
+			[]string{git + "add --all", git + "diff --cached --name-only", git + "push origin HEAD"}, false, false,
+		},
+		{"push unmanaged", true, "", []string{"data", "push", "~/.bashrc"}, nil, true, false},
+		{"push without data", false, "", []string{"data", "push"}, nil, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			if tt.existing {
+				env.FakeConfigData(map[string]string{"git/gitconfig": "[core]\n"})
+			}
+			env.WriteFile("~/.gitconfig", "[core]\n\teditor = vim\n")
+			state := &utils.ToolState{Name: "git", Configs: []utils.ConfigRecord{
+				{Path: testutil.Home + "/.gitconfig", Source: dir + "/git/gitconfig", Copy: true},
+			}}
+			if err := utils.WriteToolState(state); err != nil {
+				t.Fatal(err)
+			}
+			env.Exec.Stub(`git diff --cached --name-only$`, 0, tt.staged)
+			err := env.Run(testRoot(), tt.args...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want an error: %t", err, tt.wantErr)
+			}
+			env.AssertCommands(tt.want...)
+			if tt.pulledIn {
+				if got := env.ReadFile("~/.config/icon-data/git/gitconfig"); got != "[core]\n\teditor = vim\n" {
+					t.Errorf("~/.gitconfig is not pulled into icon-data: %q", got)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package icon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/utils"
+)
+
+// toolDrift is a configuration of a tool which differs from its source.
+type toolDrift struct {
+	utils.ConfigDrift
+	tool string
+}
+
+// hasLocalContent checks whether the configuration has content of its own (instead of a symbolic link),
+// which can be diffed against and adopted into its source.
+func (d toolDrift) hasLocalContent() bool {
+	return d.Target == "" && (d.Status == utils.ConfigModified || d.Status == utils.ConfigReplaced)
+}
+
+// findDrifts compares configurations placed by icon (for the tool in args if specified) with their sources.
+//
+// @return Configurations which differ from their sources.
+func findDrifts(cmd *cobra.Command, args []string) ([]toolDrift, error) {
+	var states []*utils.ToolState
+	if len(args) > 0 {
+		name := ResolveToolName(cmd.Root(), args[0])
+		state, err := utils.ReadToolState(name)
+		if err != nil {
+			return nil, err
+		}
+		if state == nil {
+			return nil, fmt.Errorf("%s has not been installed or configured by icon", name)
+		}
+		states = append(states, state)
+	} else {
+		var err error
+		states, err = utils.ReadAllToolStates()
+		if err != nil {
+			return nil, err
+		}
+	}
+	drifts := []toolDrift{}
+	for _, state := range states {
+		for _, config := range state.ManagedConfigs() {
+			drift, err := utils.CompareConfig(config)
+			if err != nil {
+				return nil, fmt.Errorf("failed to compare %s with %s: %w", config.Path, config.Source, err)
+			}
+			if drift.Status != utils.ConfigInSync {
+				drifts = append(drifts, toolDrift{drift, state.Name})
+			}
+		}
+	}
+	return drifts, nil
+}
+
+// syncConfig places a configuration from its source again (backing up the current one).
+func syncConfig(drift toolDrift, backup bool) error {
+	switch drift.Status {
+	case utils.ConfigSourceMissing:
+		return fmt.Errorf("the source %s of %s does not exist (run icon data pull to update icon-data)",
+			drift.Source, drift.Path)
+	case utils.ConfigBrokenSymlink:
+		// a broken symbolic link cannot be backed up as the path it points to does not exist
+		if err := utils.RemoveAll(drift.Path); err != nil {
+			return err
+		}
+	default:
+		if err := utils.BackupOrRemove(drift.Path, backup); err != nil {
+			return err
+		}
+	}
+	if err := utils.CopyOrSymlink(drift.Source, drift.Path, drift.Copy); err != nil {
+		return err
+	}
+	log.Printf("%s has been synced from %s.\n", drift.Path, drift.Source)
+	return nil
+}
+
+// adoptConfig copies local changes of a configuration back into its source.
+// A file or directory in place of a symbolic link is replaced by the symbolic link again after being adopted.
+func adoptConfig(drift toolDrift, backup bool) error {
+	if !drift.hasLocalContent() {
+		log.Printf("%s is skipped as it has no local changes to adopt (%s).\n", drift.Path, drift.Status)
+		return nil
+	}
+	switch {
+	case len(drift.Files) > 0:
+		for _, file := range drift.Files {
+			local, source := filepath.Join(drift.Path, file), filepath.Join(drift.Source, file)
+			var err error
+			if utils.ExistsFile(local) {
+				err = utils.CopyFile(local, source)
+			} else {
+				err = utils.RemoveAll(source)
+			}
+			if err != nil {
+				return err
+			}
+		}
+	case utils.ExistsDir(drift.Path):
+		if err := utils.CopyDirRegular(drift.Path, drift.Source); err != nil {
+			return err
+		}
+	default:
+		if err := utils.CopyFile(drift.Path, drift.Source); err != nil {
+			return err
+		}
+	}
+	log.Printf("Local changes of %s have been adopted into %s.\n", drift.Path, drift.Source)
+	if drift.Copy {
+		return nil
+	}
+	if err := utils.BackupOrRemove(drift.Path, backup); err != nil {
+		return err
+	}
+	return utils.CopyOrSymlink(drift.Source, drift.Path, false)
+}
+
+// Compare configuration files placed by icon with their sources in icon-data.
+func diff(cmd *cobra.Command, args []string) error {
+	apply, err := utils.GetBoolFlag(cmd, "apply")
+	if err != nil {
+		return err
+	}
+	adopt, err := utils.GetBoolFlag(cmd, "adopt")
+	if err != nil {
+		return err
+	}
+	noDiff, err := utils.GetBoolFlag(cmd, "no-diff")
+	if err != nil {
+		return err
+	}
+	backup, err := utils.ShouldBackup(cmd)
+	if err != nil {
+		return err
+	}
+	drifts, err := findDrifts(cmd, args)
+	if err != nil {
+		return err
+	}
+	if len(drifts) == 0 {
+		fmt.Println("All configuration files placed by icon match their sources.")
+		return nil
+	}
+	//nolint:mnd // readable
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "TOOL\tSTATUS\tPATH\tSOURCE")
+	for _, drift := range drifts {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", drift.tool, drift.Status, drift.Path, drift.Source)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+	if !noDiff {
+		for _, drift := range slices.DeleteFunc(slices.Clone(drifts), func(d toolDrift) bool { return !d.hasLocalContent() }) {
+			if err := unifiedDiff(drift.Source, drift.Path); err != nil {
+				return err
+			}
+		}
+	}
+	errs := []error{}
+	for _, drift := range drifts {
+		switch {
+		case apply:
+			errs = append(errs, syncConfig(drift, backup))
+		case adopt:
+			errs = append(errs, adoptConfig(drift, backup))
+		}
+	}
+	if adopt {
+		log.Printf("Please review changes in ~/.config/icon-data (icon data status) and push them (icon data push).\n")
+	}
+	return errors.Join(errs...)
+}
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [tool]",
+	Short: "Compare configuration files placed by icon with their sources in icon-data.",
+	Long: `Compare configuration files placed by icon (as copies or symbolic links) with their sources in icon-data,
+and report those missing, broken (symbolic links to paths which do not exist), replaced (e.g., a file in place of
+a symbolic link) or modified (copies which have been changed) with unified diffs.
+--apply places configuration files from their sources again (backing up the current ones)
+and --adopt copies local changes back into their sources.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: diff,
+}
+
+func ConfigDiffCmd(rootCmd *cobra.Command) {
+	diffCmd.Flags().Bool("apply", false, "Place configuration files from their sources again.")
+	diffCmd.Flags().Bool("adopt", false, "Copy local changes of configuration files back into their sources.")
+	diffCmd.Flags().Bool("no-diff", false, "Do not show unified diffs.")
+	diffCmd.Flags().Bool("no-backup", false, "Do not backup configuration files replaced by --apply or --adopt.")
+	diffCmd.MarkFlagsMutuallyExclusive("apply", "adopt")
+	rootCmd.AddCommand(diffCmd)
+}
@@ -0,0 +1,160 @@
+package icon
+
+import (
+	"testing"
+
+	"legendu.net/icon/internal/testutil"
+	"legendu.net/icon/utils"
+)
+
+func TestDiff(t *testing.T) {
+	data := testutil.Home + "/.config/icon-data"
+	setup := func(t *testing.T, env *testutil.Env) {
+		env.FakeConfigData(map[string]string{
+			"git/gitconfig":          "[core]\n",
+			"nvim/init.lua":          "init\n",
+			"zellij/config.kdl":      "zellij\n",
+			"ghostty/config.ghostty": "ghostty\n",
+			"ipython/startup.ipy":    "ipython\n",
+			"vscode/settings.json":   "{}\n",
+		})
+		// modified copies
+		env.WriteFile("~/.gitconfig", "[core]\n\teditor = vim\n")
+		env.WriteFile("~/.config/nvim/init.lua", "init\n")
+		env.WriteFile("~/.config/nvim/local.lua", "local\n")
+		// a symbolic link recorded before sources of configurations were recorded
+		env.MkdirAll("~/.config/zellij")
+		if err := env.FS.Symlink(data+"/zellij/config.kdl", testutil.Home+"/.config/zellij/config.kdl"); err != nil {
+			t.Fatal(err)
+		}
+		// a file in place of a symbolic link
+		env.WriteFile("~/.config/ghostty/config", "mine\n")
+		// a symbolic link to a path which has been removed from icon-data
+		env.MkdirAll("~/.ipython/profile_default/startup")
+		if err := env.FS.Symlink(data+"/ipython/old.ipy", testutil.Home+"/.ipython/profile_default/startup/startup.ipy"); err != nil {
+			t.Fatal(err)
+		}
+		states := []*utils.ToolState{
+			{Name: "ghostty", Configs: []utils.ConfigRecord{
+				{Path: testutil.Home + "/.config/ghostty/config", Source: data + "/ghostty/config.ghostty"},
+			}},
+			{Name: "git", Configs: []utils.ConfigRecord{
+				{Path: testutil.Home + "/.gitconfig", Source: data + "/git/gitconfig", Copy: true},
+			}},
+			{Name: "ipython", Configs: []utils.ConfigRecord{
+				{Path: testutil.Home + "/.ipython/profile_default/startup/startup.ipy", Source: data + "/ipython/startup.ipy"},
+			}},
+			{Name: "neovim", Configs: []utils.ConfigRecord{
+				{Path: testutil.Home + "/.config/nvim", Source: data + "/nvim", Copy: true},
+			}},
+			{Name: "vscode", Configs: []utils.ConfigRecord{
+				{Path: testutil.Home + "/.config/Code/User/settings.json", Source: data + "/vscode/settings.json", Copy: true},
+			}},
+			{Name: "zellij", Symlinks: []utils.SymlinkRecord{
+				{Path: testutil.Home + "/.config/zellij/config.kdl", Target: data + "/zellij/config.kdl"},
+			}},
+		}
+		for _, state := range states {
+			if err := utils.WriteToolState(state); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	diffs := []string{
+		"diff -u " + data + "/ghostty/config.ghostty " + testutil.Home + "/.config/ghostty/config",
+		"diff -u " + data + "/git/gitconfig " + testutil.Home + "/.gitconfig",
+		"diff -ru " + data + "/nvim " + testutil.Home + "/.config/nvim",
+	}
+
+	t.Run("report", func(t *testing.T) {
+		env := testutil.New(t, testutil.Ubuntu)
+		setup(t, env)
+		drifts, err := findDrifts(diffCmd, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := map[string]string{}
+		for _, drift := range drifts {
+			got[drift.tool] = drift.Status
+		}
+		want := map[string]string{
+			"ghostty": utils.ConfigReplaced,
+			"git":     utils.ConfigModified,
+			"ipython": utils.ConfigBrokenSymlink,
+			"neovim":  utils.ConfigModified,
+			"vscode":  utils.ConfigMissing,
+		}
+		if len(got) != len(want) {
+			t.Errorf("drifts: %v, want %v", got, want)
+		}
+		for tool, status := range want {
+			if got[tool] != status {
+				t.Errorf("%s: %q, want %q", tool, got[tool], status)
+			}
+		}
+		if err := env.Run(testRoot(), "diff"); err != nil {
+			t.Fatal(err)
+		}
+		env.AssertCommands(diffs...)
+		env.Exec.Reset()
+		if err := env.Run(testRoot(), "diff", "git", "--no-diff"); err != nil {
+			t.Fatal(err)
+		}
+		env.AssertCommands()
+	})
+
+	t.Run("apply", func(t *testing.T) {
+		env := testutil.New(t, testutil.Ubuntu)
+		setup(t, env)
+		if err := env.Run(testRoot(), "diff", "--apply", "--no-diff"); err != nil {
+			t.Fatal(err)
+		}
+		if got := env.ReadFile("~/.gitconfig"); got != "[core]\n" {
+			t.Errorf("~/.gitconfig = %q", got)
+		}
+		if env.Exists("~/.config/nvim/local.lua") {
+			t.Error("~/.config/nvim/local.lua is not removed")
+		}
+		for link, target := range map[string]string{
+			"~/.config/ghostty/config":                       data + "/ghostty/config.ghostty",
+			"~/.ipython/profile_default/startup/startup.ipy": data + "/ipython/startup.ipy",
+		} {
+			if got := env.Readlink(link); got != target {
+				t.Errorf("%s -> %q, want %q", link, got, target)
+			}
+		}
+		if got := env.ReadFile("~/.config/Code/User/settings.json"); got != "{}\n" {
+			t.Errorf("settings.json = %q", got)
+		}
+		drifts, err := findDrifts(diffCmd, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(drifts) != 0 {
+			t.Errorf("drifts after --apply: %v", drifts)
+		}
+	})
+
+	t.Run("adopt", func(t *testing.T) {
+		env := testutil.New(t, testutil.Ubuntu)
+		setup(t, env)
+		if err := env.Run(testRoot(), "diff", "--adopt", "--no-diff"); err != nil {
+			t.Fatal(err)
+		}
+		for path, want := range map[string]string{
+			"~/.config/icon-data/git/gitconfig":          "[core]\n\teditor = vim\n",
+			"~/.config/icon-data/nvim/local.lua":         "local\n",
+			"~/.config/icon-data/ghostty/config.ghostty": "mine\n",
+		} {
+			if got := env.ReadFile(path); got != want {
+				t.Errorf("%s = %q, want %q", path, got, want)
+			}
+		}
+		if got := env.Readlink("~/.config/ghostty/config"); got != data+"/ghostty/config.ghostty" {
+			t.Errorf("~/.config/ghostty/config -> %q", got)
+		}
+		if env.Exists("~/.config/Code/User/settings.json") {
+			t.Error("a missing configuration is placed by --adopt")
+		}
+	})
+}
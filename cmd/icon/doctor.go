@@ -0,0 +1,360 @@
+package icon
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/utils"
+)
+
+// Statuses of checks made by icon doctor.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+	checkSkip = "skip"
+)
+
+// checkResult is the result of a check made by icon doctor.
+type checkResult struct {
+	// Name is the name of the check.
+	Name string `json:"name"`
+	// Status is one of ok, warn, fail and skip.
+	Status string `json:"status"`
+	// Detail describes what is found.
+	Detail string `json:"detail"`
+	// Fix is a hint (e.g., a command) fixing the problem, or an empty string if there is no problem.
+	Fix string `json:"fix,omitempty"`
+}
+
+// brewBinDir is where Homebrew installs executables on Linux.
+const brewBinDir = "/home/linuxbrew/.linuxbrew/bin"
+
+// checkSudo checks whether the current user can run commands with sudo, which icon uses to install tools.
+func checkSudo() checkResult {
+	result := checkResult{Name: "sudo"}
+	currentUser, err := utils.GetCurrentUser()
+	if err != nil {
+		result.Status, result.Detail = checkFail, err.Error()
+		return result
+	}
+	switch {
+	case currentUser.Uid == "0":
+		result.Status, result.Detail = checkOK, "icon runs as root and does not need sudo"
+	case !utils.ExistsCommand("sudo"):
+		result.Status, result.Detail = checkFail, "sudo is not installed"
+		result.Fix = "Install sudo as root and add " + currentUser.Username + " to sudoers."
+	case utils.CanSudoWithoutPassword():
+		result.Status, result.Detail = checkOK, "sudo runs without a password prompt"
+	case slices.ContainsFunc(userGroups(), func(group string) bool {
+		return slices.Contains([]string{"sudo", "wheel", "admin"}, group)
+	}):
+		result.Status, result.Detail = checkOK, "sudo prompts for a password"
+	default:
+		result.Status = checkWarn
+		result.Detail = currentUser.Username + " is not in the group sudo, wheel or admin and might not be a sudoer"
+		result.Fix = "Add " + currentUser.Username + " to sudoers (e.g., usermod -aG sudo " + currentUser.Username + " as root)."
+	}
+	return result
+}
+
+// userGroups returns groups of the current user, or nil if they cannot be found.
+func userGroups() []string {
+	groups, err := utils.NewCommand("id", "-nG").Output()
+	if err != nil {
+		return nil
+	}
+	return strings.Fields(groups)
+}
+
+// checkConfigData checks whether ~/.config/icon-data is a clone of icon-data which is up to date with its remote.
+//
+// @param fetch Whether to fetch the remote first.
+func checkConfigData(fetch bool) checkResult {
+	dir := "~/.config/icon-data"
+	result := checkResult{Name: "icon-data"}
+	if !utils.ExistsDir(dir + "/.git") {
+		result.Status, result.Detail, result.Fix = checkFail, dir+" is not a Git repo", "icon data pull"
+		return result
+	}
+	if fetch {
+		if _, err := utils.NewCommand("git", "fetch", "--quiet").Dir(dir).Output(); err != nil {
+			result.Status, result.Detail = checkWarn, "failed to fetch the remote of "+dir
+			result.Fix = "Check the network and the remote (git -C " + dir + " remote -v)."
+			return result
+		}
+	}
+	counts, err := utils.NewCommand("git", "rev-list", "--left-right", "--count", "HEAD...@{upstream}").Dir(dir).Output()
+	fields := strings.Fields(counts)
+	//nolint:mnd // readable
+	if err != nil || len(fields) != 2 {
+		result.Status, result.Detail = checkWarn, "the current branch of "+dir+" does not track a remote branch"
+		result.Fix = "git -C " + dir + " branch --set-upstream-to origin/main"
+		return result
+	}
+	ahead, _ := strconv.Atoi(fields[0])
+	behind, _ := strconv.Atoi(fields[1])
+	switch {
+	case behind > 0:
+		result.Status = checkWarn
+		result.Detail = fmt.Sprintf("%s is %d commit(s) behind (and %d ahead of) its remote", dir, behind, ahead)
+		result.Fix = "icon data pull"
+	case ahead > 0:
+		result.Status, result.Detail = checkOK, fmt.Sprintf("%s is %d commit(s) ahead of its remote", dir, ahead)
+	default:
+		result.Status, result.Detail = checkOK, dir+" is up to date"
+	}
+	return result
+}
+
+// checkUserConfig checks whether ~/.config/icon-data/user.yaml defines the user identity (see utils.ReadUserConfig).
+func checkUserConfig() checkResult {
+	result := checkResult{Name: "user.yaml"}
+	cfg, err := utils.ReadUserConfig()
+	if err != nil {
+		result.Status, result.Detail = checkFail, err.Error()
+		result.Fix = "Define userName and userEmail in ~/.config/icon-data/user.yaml."
+		return result
+	}
+	result.Status, result.Detail = checkOK, fmt.Sprintf("%s <%s>", cfg.UserName, cfg.UserEmail)
+	return result
+}
+
+// checkSSHPermissions checks whether ~/.ssh and its contents are accessible only by the current user,
+// i.e., directories have the mode 700 and files have the mode 600 as set by utils.Chmod600.
+func checkSSHPermissions() checkResult {
+	dir := "~/.ssh"
+	result := checkResult{Name: "ssh"}
+	if !utils.ExistsDir(dir) {
+		result.Status, result.Detail = checkSkip, dir+" does not exist"
+		return result
+	}
+	wrong := []string{}
+	var walk func(path string) error
+	walk = func(path string) error {
+		info, err := utils.Stat(path)
+		if err != nil {
+			return err
+		}
+		if !info.IsDir() {
+			if info.Mode().Perm() != 0o600 {
+				wrong = append(wrong, fmt.Sprintf("%s (%o)", path, info.Mode().Perm()))
+			}
+			return nil
+		}
+		if info.Mode().Perm() != 0o700 {
+			wrong = append(wrong, fmt.Sprintf("%s (%o)", path, info.Mode().Perm()))
+		}
+		entries, err := utils.ReadDir(path)
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			if err := walk(filepath.Join(path, entry.Name())); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(utils.NormalizePath(dir)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		result.Status, result.Detail = checkFail, err.Error()
+		return result
+	}
+	if len(wrong) > 0 {
+		result.Status = checkFail
+		result.Detail = "wrong permissions: " + strings.Join(wrong, ", ")
+		result.Fix = "icon ssh_client -c"
+		return result
+	}
+	result.Status, result.Detail = checkOK, dir+" is accessible only by the current user"
+	return result
+}
+
+// checkPath checks whether a directory where icon installs executables is in the environment variable PATH.
+//
+// @param dir      The directory.
+// @param required Whether the directory is expected in PATH even if it does not exist.
+func checkPath(dir string, required bool) checkResult {
+	result := checkResult{Name: "PATH " + dir}
+	path := utils.NormalizePath(dir)
+	for _, entry := range filepath.SplitList(os.Getenv("PATH")) {
+		if filepath.Clean(utils.NormalizePath(os.ExpandEnv(entry))) == path {
+			result.Status, result.Detail = checkOK, dir+" is in PATH"
+			return result
+		}
+	}
+	if !required && !utils.ExistsDir(dir) {
+		result.Status, result.Detail = checkSkip, dir+" does not exist"
+		return result
+	}
+	result.Status, result.Detail = checkWarn, dir+" is not in PATH"
+	result.Fix = "Add " + dir + " to PATH in your shell profile (e.g., ~/.bashrc)."
+	return result
+}
+
+// secureBrewPath matches a secure_path in sudoers containing the directory of executables of Homebrew.
+var secureBrewPath = regexp.MustCompile(`(?m)^\s*Defaults\s+secure_path\s*=.*` + regexp.QuoteMeta(brewBinDir))
+
+// checkSudoersBrew checks whether the directory of executables of Homebrew (on Linux) is in secure_path of sudoers
+// so that tools installed by Homebrew can be run with sudo.
+func checkSudoersBrew() checkResult {
+	file := "/etc/sudoers"
+	result := checkResult{Name: "sudoers secure_path"}
+	if !utils.IsLinux() || !utils.ExistsDir(brewBinDir) {
+		result.Status, result.Detail = checkSkip, "Homebrew is not installed on Linux"
+		return result
+	}
+	sudoers, err := utils.ReadFileAsString(file)
+	if err != nil && utils.CanSudoWithoutPassword() {
+		sudoers, err = utils.NewCommand("cat", file).Sudo("sudo -n").Output()
+	}
+	if err != nil {
+		result.Status, result.Detail = checkSkip, "cannot read "+file+" without a password"
+		result.Fix = "sudo -v && icon doctor"
+		return result
+	}
+	if !secureBrewPath.MatchString(sudoers) {
+		result.Status, result.Detail = checkWarn, brewBinDir+" is not in secure_path of "+file
+		result.Fix = "icon homebrew -c"
+		return result
+	}
+	result.Status, result.Detail = checkOK, brewBinDir+" is in secure_path of "+file
+	return result
+}
+
+// checkDockerGroup checks whether the current user is in the group docker so that docker runs without sudo.
+func checkDockerGroup() checkResult {
+	result := checkResult{Name: "docker group"}
+	if !utils.IsLinux() || !utils.ExistsCommand("docker") {
+		result.Status, result.Detail = checkSkip, "Docker is not installed on Linux"
+		return result
+	}
+	currentUser, err := utils.GetCurrentUser()
+	if err != nil {
+		result.Status, result.Detail = checkFail, err.Error()
+		return result
+	}
+	switch {
+	case currentUser.Uid == "0":
+		result.Status, result.Detail = checkOK, "icon runs as root"
+	case slices.Contains(userGroups(), "docker"):
+		result.Status, result.Detail = checkOK, currentUser.Username+" is in the group docker"
+	default:
+		result.Status, result.Detail = checkWarn, currentUser.Username+" is not in the group docker"
+		result.Fix = "icon docker -c --user-to-docker " + currentUser.Username + " && newgrp docker"
+	}
+	return result
+}
+
+// checkPerfEventParanoid checks whether perf can profile all events, i.e., kernel.perf_event_paranoid is -1.
+func checkPerfEventParanoid() checkResult {
+	file := "/proc/sys/kernel/perf_event_paranoid"
+	result := checkResult{Name: "perf_event_paranoid"}
+	if !utils.IsLinux() || !utils.ExistsCommand("perf") {
+		result.Status, result.Detail = checkSkip, "perf is not installed on Linux"
+		return result
+	}
+	value, err := utils.ReadFileAsString(file)
+	if err != nil {
+		result.Status, result.Detail = checkSkip, err.Error()
+		return result
+	}
+	value = strings.TrimSpace(value)
+	if value != "-1" {
+		result.Status, result.Detail = checkWarn, "kernel.perf_event_paranoid is "+value+" instead of -1"
+		result.Fix = "icon perf -c"
+		return result
+	}
+	result.Status, result.Detail = checkOK, "kernel.perf_event_paranoid is -1"
+	return result
+}
+
+// runChecks makes all checks of icon doctor.
+//
+// @param fetch Whether to fetch the remote of ~/.config/icon-data.
+//
+// @return Results of the checks in order.
+func runChecks(fetch bool) []checkResult {
+	return []checkResult{
+		checkSudo(),
+		checkConfigData(fetch),
+		checkUserConfig(),
+		checkSSHPermissions(),
+		checkPath("~/.local/bin", true),
+		checkPath("/usr/local/go/bin", false),
+		checkPath("~/.cargo/bin", false),
+		checkSudoersBrew(),
+		checkDockerGroup(),
+		checkPerfEventParanoid(),
+	}
+}
+
+// Check the machine against what icon expects and report problems with hints to fix them.
+func doctor(cmd *cobra.Command, _ []string) error {
+	format, err := utils.GetStringFlag(cmd, "format")
+	if err != nil {
+		return err
+	}
+	if format != "table" && format != "json" {
+		return fmt.Errorf("unsupported format %s (table or json)", format)
+	}
+	noFetch, err := utils.GetBoolFlag(cmd, "no-fetch")
+	if err != nil {
+		return err
+	}
+	results := runChecks(!noFetch)
+	if format == "json" {
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(results); err != nil {
+			return err
+		}
+	} else {
+		//nolint:mnd // readable
+		writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(writer, "CHECK\tSTATUS\tDETAIL\tFIX")
+		for _, result := range results {
+			fmt.Fprintf(writer, "%s\t%s\t%s\t%s\n", result.Name, result.Status, result.Detail, ifEmpty(result.Fix, "-"))
+		}
+		if err := writer.Flush(); err != nil {
+			return err
+		}
+	}
+	failed := 0
+	for _, result := range results {
+		if result.Status == checkFail {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d checks failed", failed, len(results))
+	}
+	return nil
+}
+
+var doctorCmd = &cobra.Command{
+	Use:   "doctor",
+	Short: "Check the machine against what icon expects and report problems with hints to fix them.",
+	Long: `Check the machine against what icon expects and report problems with hints to fix them.
+Checks are sudo, ~/.config/icon-data (fetched unless --no-fetch) and its user.yaml, permissions of ~/.ssh,
+directories of executables in PATH, Homebrew in secure_path of sudoers, membership of the group docker
+and kernel.perf_event_paranoid. It fails if any check fails, while warnings are only reported.`,
+	Args: cobra.NoArgs,
+	RunE: doctor,
+}
+
+func ConfigDoctorCmd(rootCmd *cobra.Command) {
+	doctorCmd.Flags().Bool("no-fetch", false, "Do not fetch the remote of ~/.config/icon-data.")
+	doctorCmd.Flags().StringP("format", "f", "table", "The output format (table or json).")
+	rootCmd.AddCommand(doctorCmd)
+}
@@ -0,0 +1,94 @@
+package icon
+
+import (
+	"os"
+	"testing"
+
+	"legendu.net/icon/internal/testutil"
+)
+
+func TestDoctor(t *testing.T) {
+	healthy := func(t *testing.T, env *testutil.Env) {
+		env.FakeConfigData(map[string]string{"user.yaml": "userName: Tester\nuserEmail: tester@example.com\n"})
+		env.WriteFile("~/.ssh/id_ed25519", "key")
+		for path, mode := range map[string]os.FileMode{"~/.ssh": 0o700, "~/.ssh/id_ed25519": 0o600} {
+			if err := os.Chmod(env.FS.RealPath(testutil.Home+path[1:]), mode); err != nil {
+				t.Fatal(err)
+			}
+		}
+		env.Exec.Stub(`^cd \S+ && git rev-list`, 0, "0\t0\n")
+		t.Setenv("PATH", "$HOME/.local/bin:/usr/bin")
+	}
+	tests := []struct {
+		name    string
+		distro  testutil.Distro
+		setup   func(t *testing.T, env *testutil.Env)
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			"healthy", testutil.Ubuntu, healthy,
+			map[string]string{
+				"sudo": checkOK, "icon-data": checkOK, "user.yaml": checkOK, "ssh": checkOK,
+				"PATH ~/.local/bin": checkOK, "PATH /usr/local/go/bin": checkSkip, "PATH ~/.cargo/bin": checkSkip,
+				"sudoers secure_path": checkSkip, "docker group": checkSkip, "perf_event_paranoid": checkSkip,
+			},
+			false,
+		},
+		{
+			"no data", testutil.Ubuntu, func(t *testing.T, _ *testutil.Env) { t.Setenv("PATH", "/usr/bin") },
+			map[string]string{"icon-data": checkFail, "user.yaml": checkFail, "ssh": checkSkip, "PATH ~/.local/bin": checkWarn},
+			true,
+		},
+		{
+			"problems", testutil.UniversalBlue, func(t *testing.T, env *testutil.Env) {
+				healthy(t, env)
+				env.WriteFile("~/.ssh/config", "Host *\n")
+				env.MkdirAll("/usr/local/go/bin")
+				env.MkdirAll(brewBinDir)
+				env.WriteFile("/etc/sudoers", "Defaults secure_path = \"/usr/sbin:/usr/bin\"\n")
+				env.WriteFile("/proc/sys/kernel/perf_event_paranoid", "2\n")
+				env.Exec.AddCommands("docker", "perf")
+				env.Exec.Stub(`^cd \S+ && git rev-list`, 0, "1\t3\n")
+				env.Exec.Stub(`^sudo -n true$`, 1, "")
+				env.Exec.Stub(`^id -nG$`, 0, "tester wheel\n")
+			},
+			map[string]string{
+				"sudo": checkOK, "icon-data": checkWarn, "ssh": checkFail, "PATH /usr/local/go/bin": checkWarn,
+				"sudoers secure_path": checkWarn, "docker group": checkWarn, "perf_event_paranoid": checkWarn,
+			},
+			true,
+		},
+		{
+			"configured", testutil.UniversalBlue, func(t *testing.T, env *testutil.Env) {
+				healthy(t, env)
+				env.MkdirAll(brewBinDir)
+				env.WriteFile("/etc/sudoers", "Defaults secure_path = \"/usr/sbin:/usr/bin:"+brewBinDir+"\"\n")
+				env.WriteFile("/proc/sys/kernel/perf_event_paranoid", "-1\n")
+				env.Exec.AddCommands("docker", "perf")
+				env.Exec.Stub(`^id -nG$`, 0, "tester wheel docker\n")
+			},
+			map[string]string{"sudoers secure_path": checkOK, "docker group": checkOK, "perf_event_paranoid": checkOK},
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, tt.distro)
+			tt.setup(t, env)
+			got := map[string]checkResult{}
+			for _, result := range runChecks(false) {
+				got[result.Name] = result
+			}
+			for name, want := range tt.want {
+				if got[name].Status != want {
+					t.Errorf("%s: %s (%s), want %s", name, got[name].Status, got[name].Detail, want)
+				}
+			}
+			err := env.Run(testRoot(), "doctor", "--no-fetch", "--format", "json")
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want an error: %t", err, tt.wantErr)
+			}
+		})
+	}
+}
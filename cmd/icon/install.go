@@ -0,0 +1,223 @@
+package icon
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"slices"
+	"sync"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"legendu.net/icon/utils"
+)
+
+// prefixWriter writes complete lines prefixed (e.g., with the name of a tool) into a shared writer,
+// so that output of concurrent installs is multiplexed line by line.
+type prefixWriter struct {
+	prefix string
+	out    io.Writer
+	mu     *sync.Mutex
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		// progress is reported using carriage returns, which are treated as line breaks
+		idx := bytes.IndexAny(w.buf, "\r\n")
+		if idx < 0 {
+			return len(p), nil
+		}
+		if idx > 0 {
+			w.writeLine(w.buf[:idx])
+		}
+		w.buf = w.buf[idx+1:]
+	}
+}
+
+// Flush writes the last line if it does not end with a line break.
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(w.buf)
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.prefix == "" {
+		fmt.Fprintf(w.out, "%s\n", line)
+		return
+	}
+	fmt.Fprintf(w.out, "%s %s\n", w.prefix, line)
+}
+
+// forwardedFlags returns persistent flags (e.g., --require-checksum) specified for a command
+// as arguments for running icon in a child process.
+func forwardedFlags(cmd *cobra.Command) []string {
+	args := []string{}
+	cmd.InheritedFlags().VisitAll(func(flag *pflag.Flag) {
+		if !flag.Changed {
+			return
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			for _, value := range slice.GetSlice() {
+				args = append(args, "--"+flag.Name+"="+value)
+			}
+			return
+		}
+		args = append(args, "--"+flag.Name+"="+flag.Value.String())
+	})
+	return args
+}
+
+// installInProcess installs a tool by running icon in a child process with multiplexed output.
+func installInProcess(exe string, spec ToolSpec, forwarded []string, mu *sync.Mutex) error {
+	args := []string{spec.Name}
+	for _, flag := range spec.Flags {
+		for _, value := range flag.Values {
+			args = append(args, "--"+flag.Name+"="+value)
+		}
+	}
+	args = append(args, forwarded...)
+	// JSON logs are not prefixed so that they can be parsed, and they carry the tool anyway
+	prefix := utils.IfElseString(utils.LogFormat() == utils.LogFormatJSON, "", "["+spec.Name+"]")
+	stdout := &prefixWriter{prefix: prefix, out: os.Stdout, mu: mu}
+	stderr := &prefixWriter{prefix: prefix, out: os.Stderr, mu: mu}
+	defer stdout.Flush()
+	defer stderr.Flush()
+	command := exec.CommandContext(context.Background(), exe, args...)
+	command.Stdin = os.Stdin
+	command.Stdout = stdout
+	command.Stderr = stderr
+	return command.Run()
+}
+
+// installTools installs tools concurrently in dependency order.
+// A tool starts once the tools it depends on (see toolDependencies) have been installed,
+// with at most utils.Jobs() tools being installed at the same time.
+// Tools depending on a failed tool are skipped.
+func installTools(exe string, specs []ToolSpec, forwarded []string) error {
+	names := make([]string, 0, len(specs))
+	for _, spec := range specs {
+		names = append(names, spec.Name)
+	}
+	type result struct {
+		name string
+		err  error
+	}
+	results := make(chan result)
+	done := map[string]error{}
+	pending := slices.Clone(specs)
+	running := 0
+	var mu sync.Mutex
+	for len(done) < len(specs) {
+		for i := 0; i < len(pending) && running < utils.Jobs(); {
+			spec := pending[i]
+			ready, skipped := true, error(nil)
+			for _, dep := range getToolDependencies(spec.Name) {
+				if !slices.Contains(names, dep) {
+					continue
+				}
+				err, finished := done[dep]
+				switch {
+				case !finished:
+					ready = false
+				case err != nil:
+					skipped = fmt.Errorf("skipped as %s failed", dep)
+				}
+			}
+			switch {
+			case skipped != nil:
+				done[spec.Name] = skipped
+			case ready:
+				running++
+				log.Printf("Installing %s ...\n", spec.Name)
+				go func() {
+					results <- result{spec.Name, installInProcess(exe, spec, forwarded, &mu)}
+				}()
+			default:
+				i++
+				continue
+			}
+			pending = slices.Delete(pending, i, i+1)
+		}
+		if running == 0 {
+			continue
+		}
+		r := <-results
+		running--
+		done[r.name] = r.err
+		if r.err == nil {
+			log.Printf("%s has been installed.\n", r.name)
+		} else {
+			utils.Warnf("Failed to install %s: %v\n", r.name, r.err)
+		}
+	}
+	errs := []error{}
+	for _, name := range names {
+		if done[name] != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", name, done[name]))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Install tools concurrently.
+func install(cmd *cobra.Command, args []string) error {
+	specs := make([]ToolSpec, 0, len(args))
+	for _, tool := range args {
+		specs = append(specs, ToolSpec{Name: tool, Flags: []FlagValue{{Name: "install", Values: []string{"true"}}}})
+	}
+	specs, cmds, err := resolveTools(cmd, specs)
+	if err != nil {
+		return err
+	}
+	for _, spec := range specs {
+		if !IsToolCmd(cmds[spec.Name]) {
+			return fmt.Errorf("%s is not a tool which can be installed", spec.Name)
+		}
+	}
+	// a plan is printed by this process, so tools are run in it one by one
+	if utils.IsDryRun() || len(specs) == 1 {
+		for _, spec := range specs {
+			if err := RunTool(cmds[spec.Name], spec); err != nil {
+				return fmt.Errorf("failed to install %s: %w", spec.Name, err)
+			}
+		}
+		return nil
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the executable of icon: %w", err)
+	}
+	// the sudo credential is validated (and cached) once here
+	// so that the password is not prompted for by the concurrent children at the same time
+	if _, err := utils.GetCommandPrefix(true, nil); err != nil {
+		return err
+	}
+	return installTools(exe, specs, forwardedFlags(cmd))
+}
+
+var installCmd = &cobra.Command{
+	Use:   "install <tool>...",
+	Short: "Install tools concurrently.",
+	Long: `Install tools concurrently (in dependency order), each in its own icon process.
+Output of each tool is prefixed with its name. At most --jobs tools are installed at the same time.
+Commands using the same package manager (e.g., apt) wait for each other instead of failing on its lock.
+Use icon apply to install tools with other flags than --install.`,
+	Example: "  icon install git neovim zellij jj",
+	Args:    cobra.MinimumNArgs(1),
+	RunE:    install,
+}
+
+func ConfigInstallCmd(rootCmd *cobra.Command) {
+	rootCmd.AddCommand(installCmd)
+}
@@ -0,0 +1,63 @@
+package icon
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/utils"
+)
+
+// formatTime formats a time for display, or returns "-" for the zero time.
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	return t.Local().Format(time.DateTime)
+}
+
+// ifEmpty returns def if str is empty, otherwise str.
+func ifEmpty(str, def string) string {
+	return utils.IfElseString(str == "", def, str)
+}
+
+// List tools installed or configured by icon.
+func list(_ *cobra.Command, _ []string) error {
+	states, err := utils.ReadAllToolStates()
+	if err != nil {
+		return err
+	}
+	if len(states) == 0 {
+		fmt.Println("No tool has been installed or configured by icon.")
+		return nil
+	}
+	//nolint:mnd // readable
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "TOOL\tSTATUS\tVERSION\tMETHODS\tINSTALLED\tCONFIGURED")
+	for _, state := range states {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			state.Name,
+			state.Status(),
+			ifEmpty(state.Version, "-"),
+			ifEmpty(strings.Join(state.Methods, ","), "-"),
+			formatTime(state.InstalledAt),
+			formatTime(state.ConfiguredAt),
+		)
+	}
+	return writer.Flush()
+}
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "List tools installed or configured by icon.",
+	Args:    cobra.NoArgs,
+	RunE:    list,
+}
+
+func ConfigListCmd(rootCmd *cobra.Command) {
+	rootCmd.AddCommand(listCmd)
+}
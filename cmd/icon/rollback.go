@@ -0,0 +1,65 @@
+package icon
+
+import (
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/utils"
+)
+
+// Roll back the last run of a tool (or of any tool if --last is specified).
+func rollback(cmd *cobra.Command, args []string) error {
+	last, err := utils.GetBoolFlag(cmd, "last")
+	if err != nil {
+		return err
+	}
+	var journal *utils.Journal
+	switch {
+	case last && len(args) > 0:
+		return errors.New("specify either a tool or --last but not both")
+	case last:
+		journal, err = utils.ReadLatestJournal()
+		if err != nil {
+			return err
+		}
+		if journal == nil {
+			fmt.Println("There is nothing to roll back.")
+			return nil
+		}
+	case len(args) == 1:
+		name := ResolveToolName(cmd.Root(), args[0])
+		journal, err = utils.ReadLastJournal(name)
+		if err != nil {
+			return err
+		}
+		if journal == nil {
+			fmt.Printf("There is nothing to roll back for %s.\n", name)
+			return nil
+		}
+	default:
+		return errors.New("specify a tool or --last")
+	}
+	log.Printf("Rolling back changes made to %s at %s ...\n", journal.Tool, formatTime(journal.Time))
+	kept, err := journal.Rollback()
+	logKeptPaths(kept)
+	return err
+}
+
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback [tool]",
+	Short: "Roll back configuration changes made by the last run of a tool.",
+	Long: `Roll back configuration changes made by the last run of a tool.
+Symbolic links and files placed by icon are removed,
+existing files modified by icon get their previous content back,
+and backups made (or paths removed) by icon are renamed back to their original paths.
+Running it again rolls back the run before, and so on.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: rollback,
+}
+
+func ConfigRollbackCmd(rootCmd *cobra.Command) {
+	rollbackCmd.Flags().Bool("last", false, "Roll back the last run of any tool.")
+	rootCmd.AddCommand(rollbackCmd)
+}
@@ -0,0 +1,77 @@
+package icon
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/utils"
+)
+
+// ResolveToolName resolves a name or an alias of a tool to the name of its command.
+//
+// @param rootCmd The root command.
+// @param name    The name or an alias of the tool.
+//
+// @return The name of the command of the tool, or name itself if no such command is found.
+func ResolveToolName(rootCmd *cobra.Command, name string) string {
+	if toolCmd := FindToolCmd(rootCmd, name); toolCmd != nil {
+		return toolCmd.Name()
+	}
+	return name
+}
+
+// Show what icon has done for a tool.
+func status(cmd *cobra.Command, args []string) error {
+	name := ResolveToolName(cmd.Root(), args[0])
+	state, err := utils.ReadToolState(name)
+	if err != nil {
+		return err
+	}
+	if state == nil {
+		fmt.Printf("%s has not been installed or configured by icon.\n", name)
+		return nil
+	}
+	fmt.Printf("Tool:       %s\n", state.Name)
+	fmt.Printf("Status:     %s\n", state.Status())
+	fmt.Printf("Version:    %s\n", ifEmpty(state.Version, "-"))
+	fmt.Printf("Methods:    %s\n", ifEmpty(strings.Join(state.Methods, ", "), "-"))
+	fmt.Printf("Installed:  %s\n", formatTime(state.InstalledAt))
+	fmt.Printf("Configured: %s\n", formatTime(state.ConfiguredAt))
+	if len(state.Files) > 0 {
+		fmt.Println("Files:")
+		for _, file := range state.Files {
+			fmt.Printf("  %s\n", file.Path)
+		}
+	}
+	if len(state.Symlinks) > 0 {
+		fmt.Println("Symbolic links:")
+		for _, link := range state.Symlinks {
+			fmt.Printf("  %s -> %s\n", link.Path, link.Target)
+		}
+	}
+	if len(state.Backups) > 0 {
+		fmt.Println("Backups:")
+		for _, backup := range state.Backups {
+			fmt.Printf("  %s -> %s\n", backup.Original, backup.Backup)
+		}
+	}
+	if drifts := state.Drifts(); len(drifts) > 0 {
+		fmt.Println("Drifted:")
+		for _, drift := range drifts {
+			fmt.Printf("  %s\n", drift)
+		}
+	}
+	return nil
+}
+
+var statusCmd = &cobra.Command{
+	Use:   "status <tool>",
+	Short: "Show what icon has installed and configured for a tool.",
+	Args:  cobra.ExactArgs(1),
+	RunE:  status,
+}
+
+func ConfigStatusCmd(rootCmd *cobra.Command) {
+	rootCmd.AddCommand(statusCmd)
+}
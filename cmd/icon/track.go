@@ -0,0 +1,133 @@
+package icon
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"legendu.net/icon/utils"
+)
+
+// IsToolCmd checks whether a command installs/configures a tool (rather than managing icon itself).
+//
+// @param cmd The command to check.
+//
+// @return true if the command has the --install flag, false otherwise.
+func IsToolCmd(cmd *cobra.Command) bool {
+	return cmd.Flags().Lookup("install") != nil
+}
+
+// getBoolFlagOrFalse returns the value of a bool flag, or false if the command does not have the flag.
+func getBoolFlagOrFalse(cmd *cobra.Command, flag string) bool {
+	val, err := cmd.Flags().GetBool(flag)
+	return err == nil && val
+}
+
+// TrackToolCmd wraps the command of a tool so that what it does
+// (files placed, symbolic links created, backups made, install methods used, etc.)
+// is recorded into the state of the tool under utils.StateDir().
+// If the command fails, changes it has made are rolled back.
+// When the tool is uninstalled, files and symbolic links recorded for it are removed too.
+//
+// @param cmd The command of the tool.
+func TrackToolCmd(cmd *cobra.Command) {
+	run := cmd.RunE
+	if run == nil {
+		return
+	}
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		utils.BeginToolRun(cmd.Name())
+		if getBoolFlagOrFalse(cmd, "install") {
+			utils.RecordFlags(installFlags(cmd))
+		}
+		utils.SetPhase(toolPhase(cmd))
+		defer utils.SetPhase("")
+		start := time.Now()
+		err := run(cmd, args)
+		slog.Debug("The tool run finished.", "duration_ms", time.Since(start).Milliseconds(), "ok", err == nil)
+		if err != nil {
+			// undo changes already made by the failed run so that the tool is left as it was
+			kept, errRollback := utils.RollbackToolRun()
+			logKeptPaths(kept)
+			if errRollback != nil {
+				utils.Warnf("Failed to roll back changes made by %s: %v\n", cmd.Name(), errRollback)
+			}
+		}
+		state, errState := utils.EndToolRun(
+			err == nil && getBoolFlagOrFalse(cmd, "install"),
+			err == nil && getBoolFlagOrFalse(cmd, "config"),
+		)
+		if err != nil {
+			return err
+		}
+		if errState != nil {
+			return errState
+		}
+		if !getBoolFlagOrFalse(cmd, "uninstall") || state == nil {
+			return nil
+		}
+		kept, err := state.RemoveRecordedPaths()
+		if err != nil {
+			return err
+		}
+		logKeptPaths(kept)
+		return utils.RemoveToolState(state.Name)
+	}
+}
+
+// toolPhase returns what a run of a tool command does, i.e., install, config and/or uninstall joined by +.
+func toolPhase(cmd *cobra.Command) string {
+	phases := []string{}
+	for _, phase := range []string{"install", "config", "uninstall"} {
+		if getBoolFlagOrFalse(cmd, phase) {
+			phases = append(phases, phase)
+		}
+	}
+	return strings.Join(phases, "+")
+}
+
+// installFlags returns flags (other than --install, --config and --uninstall) specified for a tool command.
+func installFlags(cmd *cobra.Command) map[string][]string {
+	var flags map[string][]string
+	cmd.LocalFlags().VisitAll(func(flag *pflag.Flag) {
+		if !flag.Changed || slices.Contains([]string{"install", "config", "uninstall"}, flag.Name) {
+			return
+		}
+		if flags == nil {
+			flags = map[string][]string{}
+		}
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			flags[flag.Name] = slice.GetSlice()
+		} else {
+			flags[flag.Name] = []string{flag.Value.String()}
+		}
+	})
+	return flags
+}
+
+// RecordedToolSpec returns the spec to reinstall a tool with the flags it was last installed with.
+//
+// @param state The state of the tool.
+//
+// @return The spec of the tool with --install and the recorded flags.
+func RecordedToolSpec(state *utils.ToolState) ToolSpec {
+	spec := ToolSpec{Name: state.Name, Flags: []FlagValue{{Name: "install", Values: []string{"true"}}}}
+	names := make([]string, 0, len(state.Flags))
+	for name := range state.Flags {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		spec.Flags = append(spec.Flags, FlagValue{Name: name, Values: state.Flags[name]})
+	}
+	return spec
+}
+
+func logKeptPaths(kept []string) {
+	for _, path := range kept {
+		utils.Warnf("%s is kept as it has been changed since icon placed it.\n", path)
+	}
+}
@@ -9,26 +9,33 @@ import (
 )
 
 // Update icon.
-func update(cmd *cobra.Command, _ []string) {
-	dir := utils.GetStringFlag(cmd, "install-dir")
+func update(cmd *cobra.Command, _ []string) error {
+	dir, err := utils.GetStringFlag(cmd, "install-dir")
+	if err != nil {
+		return err
+	}
 	if dir == "" {
 		dir = filepath.Dir(utils.LookPath("icon"))
 	}
+	prefix, err := utils.GetCommandPrefix(false, map[string]uint32{
+		dir: unix.W_OK | unix.R_OK,
+	})
+	if err != nil {
+		return err
+	}
 	command := utils.Format(`curl -sSL https://raw.githubusercontent.com/legendu-net/icon/main/install_icon.sh \
 			| {prefix} bash -s -- -d {dir}`, map[string]string{
-		"prefix": utils.GetCommandPrefix(false, map[string]uint32{
-			dir: unix.W_OK | unix.R_OK,
-		}),
-		"dir": dir,
+		"prefix": prefix,
+		"dir":    dir,
 	})
-	utils.RunCmd(command)
+	return utils.RunCmd(command)
 }
 
 var updateCmd = &cobra.Command{
 	Use:     "update",
 	Aliases: []string{"upd"},
 	Short:   "Update icon.",
-	Run:     update,
+	RunE:    update,
 }
 
 func ConfigUpdateCmd(rootCmd *cobra.Command) {
@@ -9,22 +9,37 @@ import (
 )
 
 // Install and configure Firenvim.
-func firenvim(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		setupNeovim(true, true, false, utils.GetBoolFlag(cmd, "brew"), "-y",
-			utils.ShouldBackup(cmd), utils.GetBoolFlag(cmd, "copy"))
-		network.InstallChromeExtension("egpjdkipkomnmjhjmdamaniclmdlobbo", "Firenvim")
-		utils.RunCmd(`nvim --headless +"call firenvim#install(0)" +qall`)
-		if utils.IsLinux() {
-			url := "https://www.legendu.net/drafts/2021/12/firenvim-brings-neovim-into-your-browser/#installation"
-			log.Printf("\nPlease follow step 5 in %s to configure a shortcut!\n", url)
-		} else {
-		}
-	}
-	if utils.GetBoolFlag(cmd, "config") {
-	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+func firenvim(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil || !install {
+		return err
 	}
+	brew, err := utils.GetBoolFlag(cmd, "brew")
+	if err != nil {
+		return err
+	}
+	backup, err := utils.ShouldBackup(cmd)
+	if err != nil {
+		return err
+	}
+	doCopy, err := utils.GetBoolFlag(cmd, "copy")
+	if err != nil {
+		return err
+	}
+	if err := setupNeovim(true, true, false, brew, true, backup, doCopy); err != nil {
+		return err
+	}
+	if err := network.InstallChromeExtension("egpjdkipkomnmjhjmdamaniclmdlobbo", "Firenvim"); err != nil {
+		return err
+	}
+	if err := utils.RunCmd(`nvim --headless +"call firenvim#install(0)" +qall`); err != nil {
+		return err
+	}
+	if utils.IsLinux() {
+		url := "https://www.legendu.net/drafts/2021/12/firenvim-brings-neovim-into-your-browser/#installation"
+		log.Printf("\nPlease follow step 5 in %s to configure a shortcut!\n", url)
+	}
+	return nil
 }
 
 var firenvimCmd = &cobra.Command{
@@ -32,7 +47,7 @@ var firenvimCmd = &cobra.Command{
 	Aliases: []string{"fvim"},
 	Short:   "Install and configure Firenvim.",
 	//Args:  cobra.ExactArgs(1),
-	Run: firenvim,
+	RunE: firenvim,
 }
 
 func ConfigFirenvimCmd(rootCmd *cobra.Command) {
@@ -5,71 +5,57 @@ import (
 	"legendu.net/icon/utils"
 )
 
+// helixPackages are packages of helix for package managers.
+var helixPackages = utils.Packages{
+	"apt":    {"helix"},
+	"dnf":    {"helix"},
+	"brew":   {"helix"},
+	"pacman": {"helix"},
+	"zypper": {"helix"},
+	"apk":    {"helix"},
+}
+
+func installHelix(yes bool) error {
+	pm, pkgs, err := utils.ResolvePackages("helix", helixPackages)
+	if err != nil {
+		return err
+	}
+	// helix is not in the official repositories of some distributions
+	switch {
+	case pm.Name() == "apt" && utils.IsUbuntuSeries():
+		err = utils.AddAptRepository("ppa:maveonair/helix-editor", yes)
+	case pm.Name() == "dnf":
+		err = utils.EnableCopr("varlad/helix", yes)
+	}
+	if err != nil {
+		return err
+	}
+	return pm.Install(yes, pkgs...)
+}
+
 // Install and configure helix.
-func helix(cmd *cobra.Command, _ []string) {
-	yesStr := utils.BuildYesFlag(cmd)
-	if utils.GetBoolFlag(cmd, "install") {
-		if utils.IsLinux() {
-			if utils.IsDebianUbuntuSeries() {
-				if utils.IsUbuntuSeries() {
-					command := utils.Format(`{prefix} add-apt-repository ppa:maveonair/helix-editor`, map[string]string{
-						"prefix": utils.GetCommandPrefix(
-							true,
-							map[string]uint32{},
-						),
-					})
-					utils.RunCmd(command)
-				}
-				command := utils.Format(`{prefix} apt-get {yesStr} update \
-						&& {prefix} apt-get {yesStr} install helix`, map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": yesStr,
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format(`{prefix} dnf {yesStr} copr enable varlad/helix \
-						&& {prefix} dnf {yesStr} install helix`, map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": yesStr,
-				})
-				utils.RunCmd(command)
-			}
-		} else {
-			utils.BrewInstallSafe([]string{"helix"})
-		}
+func helix(cmd *cobra.Command, _ []string) error {
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "config") {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		if utils.IsLinux() {
-			if utils.IsDebianUbuntuSeries() {
-				command := utils.Format("{prefix} apt-get {yesStr} purge helix", map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": yesStr,
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format("{prefix} dnf {yesStr} remove helix", map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-				})
-				utils.RunCmd(command)
-			}
-		} else {
-			utils.RunCmd("brew uninstall helix")
+	if install {
+		if err := installHelix(yes); err != nil {
+			return err
 		}
 	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return utils.RemovePackages("helix", helixPackages, yes)
+	}
+	return nil
 }
 
 var helixCmd = &cobra.Command{
@@ -77,7 +63,7 @@ var helixCmd = &cobra.Command{
 	Aliases: []string{"hx"},
 	Short:   "Install and configure helix.",
 	//Args:  cobra.ExactArgs(1),
-	Run: helix,
+	RunE: helix,
 }
 
 func ConfigHelixCmd(rootCmd *cobra.Command) {
@@ -0,0 +1,53 @@
+package ide
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/internal/testutil"
+)
+
+var testRoot = sync.OnceValue(func() *cobra.Command {
+	root := &cobra.Command{Use: "icon"}
+	ConfigHelixCmd(root)
+	ConfigNeovimCmd(root)
+	return root
+})
+
+func TestHelixInstall(t *testing.T) {
+	tests := []struct {
+		distro testutil.Distro
+		want   []string
+	}{
+		{testutil.Ubuntu, []string{
+			"sudo true",
+			"sudo add-apt-repository -y ppa:maveonair/helix-editor",
+			"sudo apt-get -y update",
+			"sudo apt-get -y install helix",
+		}},
+		{testutil.Debian, []string{
+			"sudo true",
+			"sudo apt-get -y update",
+			"sudo apt-get -y install helix",
+		}},
+		{testutil.Fedora, []string{
+			"sudo true",
+			"sudo dnf -y copr enable varlad/helix",
+			"sudo dnf -y install helix",
+		}},
+		{testutil.Arch, []string{
+			"sudo true",
+			"sudo pacman -S --needed --noconfirm helix",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.distro.Name, func(t *testing.T) {
+			env := testutil.New(t, tt.distro)
+			if err := env.Run(testRoot(), "helix", "--install", "--yes"); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands(tt.want...)
+		})
+	}
+}
@@ -1,79 +1,91 @@
 package ide
 
 import (
-	"runtime"
-
 	"github.com/spf13/cobra"
 	"legendu.net/icon/cmd/icon"
 	"legendu.net/icon/utils"
 )
 
 // Install and configure Neovim.
-func neovim(cmd *cobra.Command, _ []string) {
-	setupNeovim(
-		utils.GetBoolFlag(cmd, "install"),
-		utils.GetBoolFlag(cmd, "config"),
-		utils.GetBoolFlag(cmd, "uninstall"),
-		utils.GetBoolFlag(cmd, "brew"),
-		utils.BuildYesFlag(cmd),
-		utils.ShouldBackup(cmd), utils.GetBoolFlag(cmd, "copy"))
+func neovim(cmd *cobra.Command, _ []string) error {
+	flags := map[string]bool{}
+	for _, flag := range []string{"install", "config", "uninstall", "brew", "copy"} {
+		val, err := utils.GetBoolFlag(cmd, flag)
+		if err != nil {
+			return err
+		}
+		flags[flag] = val
+	}
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	backup, err := utils.ShouldBackup(cmd)
+	if err != nil {
+		return err
+	}
+	return setupNeovim(flags["install"], flags["config"], flags["uninstall"], flags["brew"],
+		yes, backup, flags["copy"])
+}
+
+// neovimPackages are packages of Neovim for package managers.
+var neovimPackages = utils.Packages{
+	"apt":    {"neovim"},
+	"dnf":    {"neovim"},
+	"brew":   {"neovim"},
+	"pacman": {"neovim"},
+	"zypper": {"neovim"},
+	"apk":    {"neovim"},
+}
+
+// resolveNeovimPackages returns the package manager (Homebrew if brew is true) and packages to install Neovim.
+func resolveNeovimPackages(brew bool) (utils.PackageManager, []string, error) {
+	if brew {
+		pm, err := utils.GetPackageManager("brew")
+		return pm, neovimPackages["brew"], err
+	}
+	return utils.ResolvePackages("Neovim", neovimPackages)
 }
 
-func setupNeovim(install, config, uninstall, brew bool, yesStr string, backup, doCopy bool) {
+func installNeovim(brew, yes bool) error {
+	pm, pkgs, err := resolveNeovimPackages(brew)
+	if err != nil {
+		return err
+	}
+	return pm.Install(yes, pkgs...)
+}
+
+func uninstallNeovim(brew, yes bool) error {
+	pm, pkgs, err := resolveNeovimPackages(brew)
+	if err != nil {
+		return err
+	}
+	return pm.Remove(yes, pkgs...)
+}
+
+func setupNeovim(install, config, uninstall, brew, yes, backup, doCopy bool) error {
 	if install {
-		if runtime.GOOS == "darwin" || brew || utils.IsUniversalBlue() {
-			utils.BrewInstallSafe([]string{"neovim"})
-		} else if utils.IsDebianUbuntuSeries() {
-			command := utils.Format(`{prefix} apt-get {yesStr} update \
-					&& {prefix} apt-get {yesStr} install neovim`, map[string]string{
-				"prefix": utils.GetCommandPrefix(
-					true,
-					map[string]uint32{},
-				),
-				"yesStr": yesStr,
-			})
-			utils.RunCmd(command)
-		} else if utils.IsFedoraSeries() {
-			command := utils.Format("{prefix} dnf {yesStr} install neovim", map[string]string{
-				"prefix": utils.GetCommandPrefix(
-					true,
-					map[string]uint32{},
-				),
-				"yesStr": yesStr,
-			})
-			utils.RunCmd(command)
+		if err := installNeovim(brew, yes); err != nil {
+			return err
 		}
 	}
 	if config {
-		icon.FetchConfigData(false, "")
+		if err := icon.FetchConfigData(false, ""); err != nil {
+			return err
+		}
 		src := "~/.config/icon-data/nvim"
 		dst := "~/.config/nvim"
-		utils.BackupOrRemove(dst, backup)
-		utils.CopyOrSymlink(src, dst, doCopy)
+		if err := utils.BackupOrRemove(dst, backup); err != nil {
+			return err
+		}
+		if err := utils.CopyOrSymlink(src, dst, doCopy); err != nil {
+			return err
+		}
 	}
 	if uninstall {
-		if runtime.GOOS == "darwin" || brew || utils.IsUniversalBlue() {
-			utils.RunCmd("brew uninstall neovim")
-		} else if utils.IsDebianUbuntuSeries() {
-			command := utils.Format("{prefix} apt-get {yesStr} purge neovim", map[string]string{
-				"prefix": utils.GetCommandPrefix(
-					true,
-					map[string]uint32{},
-				),
-				"yesStr": yesStr,
-			})
-			utils.RunCmd(command)
-		} else if utils.IsFedoraSeries() {
-			command := utils.Format("{prefix} dnf {yesStr} remove neovim", map[string]string{
-				"prefix": utils.GetCommandPrefix(
-					true,
-					map[string]uint32{},
-				),
-				"yesStr": yesStr,
-			})
-			utils.RunCmd(command)
-		}
+		return uninstallNeovim(brew, yes)
 	}
+	return nil
 }
 
 var neovimCmd = &cobra.Command{
@@ -81,7 +93,7 @@ var neovimCmd = &cobra.Command{
 	Aliases: []string{"nvim"},
 	Short:   "Install and configure Neovim.",
 	//Args:  cobra.ExactArgs(1),
-	Run: neovim,
+	RunE: neovim,
 }
 
 func ConfigNeovimCmd(rootCmd *cobra.Command) {
@@ -8,75 +8,116 @@ import (
 	"legendu.net/icon/utils"
 )
 
-// Install and configure Visual Studio Code.
-func vscode(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
+// vscodePackages are packages of Visual Studio Code for package managers.
+// Visual Studio Code is installed as a snap on Debian/Ubuntu.
+var vscodePackages = utils.Packages{
+	"apt":     {"code"},
+	"dnf":     {"code"},
+	"brew":    {"--cask visual-studio-code"},
+	"flatpak": {"com.visualstudio.code"},
+	"pacman":  {"code"},
+	"zypper":  {"code"},
+}
+
+func installVscode(yes bool) error {
+	pm, pkgs, err := utils.ResolvePackages("Visual Studio Code", vscodePackages)
+	if err != nil {
+		return err
+	}
+	if pm.Name() != "apt" {
+		return pm.Install(yes, pkgs...)
+	}
+	prefix, err := utils.GetCommandPrefix(true, map[string]uint32{})
+	if err != nil {
+		return err
+	}
+	command := utils.Format("{prefix} snap install --classic code", map[string]string{
+		"prefix": prefix,
+	})
+	return utils.RunCmd(command)
+}
+
+func configVscode(cmd *cobra.Command) error {
+	if err := icon.FetchConfigData(false, ""); err != nil {
+		return err
+	}
+	userDir, err := utils.GetStringFlag(cmd, "user-dir")
+	if err != nil {
+		return err
+	}
+	if userDir == "" {
 		if utils.IsLinux() {
-			if utils.IsDebianUbuntuSeries() {
-				command := utils.Format("{prefix} snap install --classic code", map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format("{prefix} dnf {yesStr} install vscode", map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			}
+			userDir = "~/.config/Code/User"
 		} else {
-			command := "brew cask install visual-studio-code"
-			utils.RunCmd(command)
+			userDir = "~/Library/Application Support/Code/User"
 		}
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
+	backup, err := utils.ShouldBackup(cmd)
+	if err != nil {
+		return err
+	}
+	doCopy, err := utils.GetBoolFlag(cmd, "copy")
+	if err != nil {
+		return err
+	}
+	src := "~/.config/icon-data/vscode/settings.json"
+	dst := filepath.Join(userDir, filepath.Base(src))
+	if err := utils.BackupOrRemove(dst, backup); err != nil {
+		return err
+	}
+	return utils.CopyOrSymlink(src, dst, doCopy)
+}
 
-		userDir := utils.GetStringFlag(cmd, "user-dir")
-		if userDir == "" {
-			if utils.IsLinux() {
-				userDir = "~/.config/Code/User"
-			} else {
-				userDir = "~/Library/Application Support/Code/User"
-			}
+func uninstallVscode(yes bool) error {
+	pm, pkgs, err := utils.ResolvePackages("Visual Studio Code", vscodePackages)
+	if err != nil {
+		return err
+	}
+	if pm.Name() != "apt" {
+		return pm.Remove(yes, pkgs...)
+	}
+	prefix, err := utils.GetCommandPrefix(true, map[string]uint32{})
+	if err != nil {
+		return err
+	}
+	command := utils.Format("{prefix} snap remove code", map[string]string{
+		"prefix": prefix,
+	})
+	return utils.RunCmd(command)
+}
+
+// Install and configure Visual Studio Code.
+func vscode(cmd *cobra.Command, _ []string) error {
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := installVscode(yes); err != nil {
+			return err
 		}
-		src := "~/.config/icon-data/vscode/settings.json"
-		dst := filepath.Join(userDir, filepath.Base(src))
-		utils.BackupOrRemove(dst, utils.ShouldBackup(cmd))
-		utils.CopyOrSymlink(src, dst, utils.GetBoolFlag(cmd, "copy"))
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		if utils.IsLinux() {
-			if utils.IsDebianUbuntuSeries() {
-				command := utils.Format("{prefix} apt-get {yesStr} purge vscode", map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format("{prefix} dnf {yesStr} remove vscode", map[string]string{
-					"prefix": utils.GetCommandPrefix(
-						true,
-						map[string]uint32{},
-					),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			}
-		} else {
-			command := "brew cask uninstall visual-studio-code"
-			utils.RunCmd(command)
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		if err := configVscode(cmd); err != nil {
+			return err
 		}
 	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return uninstallVscode(yes)
+	}
+	return nil
 }
 
 var vscodeCmd = &cobra.Command{
@@ -84,7 +125,7 @@ var vscodeCmd = &cobra.Command{
 	Aliases: []string{"vscode", "code"},
 	Short:   "Install and configure Visual Studio Code.",
 	//Args:  cobra.ExactArgs(1),
-	Run: vscode,
+	RunE: vscode,
 }
 
 func ConfigVscodeCmd(rootCmd *cobra.Command) {
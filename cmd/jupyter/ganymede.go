@@ -11,35 +11,45 @@ import (
 )
 
 // Install and configure Ganymede.
-func ganymede(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		tmpdir := utils.CreateTempDir("")
-		defer os.RemoveAll(tmpdir)
-		file := filepath.Join(tmpdir, "ganymede.jar")
-		network.DownloadGitHubRelease(
-			"allen-ball/ganymede",
-			"",
-			map[string][]string{"common": {"jar"}},
-			[]string{"asc"},
-			file,
-		)
-		command := utils.Format(`{prefix} java -jar {file} -i --sys-prefix \
-				&& {prefix} cp -r /usr/share/jupyter/kernels/ganymede-*-java-* /usr/local/share/jupyter/kernels/ \
-				&& {prefix} sed -i \
-					's_/usr/share/jupyter/kernels/_/usr/local/share/jupyter/kernels/_g' \
-					/usr/local/share/jupyter/kernels/ganymede*/kernel.json`, map[string]string{
-			"prefix": utils.GetCommandPrefix(false, map[string]uint32{
-				"/usr/share/jupyter/":       unix.W_OK | unix.R_OK,
-				"/usr/local/share/jupyter/": unix.W_OK | unix.R_OK,
-			}),
-			"file": file,
-		})
-		utils.RunCmd(command)
+func ganymede(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil || !install {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "config") {
+	tmpdir, err := utils.CreateTempDir("")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+	defer os.RemoveAll(tmpdir)
+	file := filepath.Join(tmpdir, "ganymede.jar")
+	err = network.DownloadGitHubRelease(
+		"allen-ball/ganymede",
+		"",
+		map[string][]string{"common": {"jar"}},
+		[]string{"asc"},
+		file,
+	)
+	if err != nil {
+		return err
 	}
+	prefix, err := utils.GetCommandPrefix(false, map[string]uint32{
+		"/usr/share/jupyter/":       unix.W_OK | unix.R_OK,
+		"/usr/local/share/jupyter/": unix.W_OK | unix.R_OK,
+	})
+	if err != nil {
+		return err
+	}
+	if err := utils.NewCommand("java", "-jar", file, "-i", "--sys-prefix").Sudo(prefix).Run(); err != nil {
+		return err
+	}
+	// globs need a shell, and the prefix is generated by GetCommandPrefix
+	command := utils.Format(`{prefix} cp -r /usr/share/jupyter/kernels/ganymede-*-java-* /usr/local/share/jupyter/kernels/ \
+			&& {prefix} sed -i \
+				's_/usr/share/jupyter/kernels/_/usr/local/share/jupyter/kernels/_g' \
+				/usr/local/share/jupyter/kernels/ganymede*/kernel.json`, map[string]string{
+		"prefix": prefix,
+	})
+	return utils.RunCmd(command)
 }
 
 var ganymedeCmd = &cobra.Command{
@@ -47,7 +57,7 @@ var ganymedeCmd = &cobra.Command{
 	Aliases: []string{"gmd"},
 	Short:   "Install and configure Ganymede.",
 	//Args:  cobra.ExactArgs(1),
-	Run: ganymede,
+	RunE: ganymede,
 }
 
 func ConfigGanymedeCmd(rootCmd *cobra.Command) {
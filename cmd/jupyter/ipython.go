@@ -8,43 +8,96 @@ import (
 	"legendu.net/icon/utils"
 )
 
+// Run "pip install" or "pip uninstall" on IPython.
+//
+// @param cmd    The cobra command whose flags are used to build the pip command.
+// @param action Either "install" or "uninstall".
+func pipIpython(cmd *cobra.Command, action string) error {
+	forceSudo, err := utils.GetBoolFlag(cmd, "sudo")
+	if err != nil {
+		return err
+	}
+	prefix, err := utils.GetCommandPrefix(forceSudo, map[string]uint32{})
+	if err != nil {
+		return err
+	}
+	var pip string
+	if action == "install" {
+		pip, err = utils.BuildPipInstall(cmd)
+	} else {
+		pip, err = utils.BuildPipUninstall(cmd)
+	}
+	if err != nil {
+		return err
+	}
+	command := utils.Format("{prefix} {pip} ipython", map[string]string{
+		"prefix": prefix,
+		"pip":    pip,
+	})
+	return utils.RunCmd(command)
+}
+
+func configIpython(cmd *cobra.Command) error {
+	if err := icon.FetchConfigData(false, ""); err != nil {
+		return err
+	}
+	profileDir, err := utils.GetStringFlag(cmd, "profile-dir")
+	if err != nil {
+		return err
+	}
+	profileDefault := filepath.Join(utils.NormalizePath(profileDir), "profile_default")
+	backup, err := utils.ShouldBackup(cmd)
+	if err != nil {
+		return err
+	}
+	doCopy, err := utils.GetBoolFlag(cmd, "copy")
+	if err != nil {
+		return err
+	}
+	src1 := "~/.config/icon-data/ipython/startup.ipy"
+	dst1 := filepath.Join(profileDefault, "startup", "startup.ipy")
+	if err := utils.BackupOrRemove(dst1, backup); err != nil {
+		return err
+	}
+	if err := utils.CopyOrSymlink(src1, dst1, doCopy); err != nil {
+		return err
+	}
+	src2 := "~/.config/icon-data/ipython/ipython_config.py"
+	dst2 := filepath.Join(profileDefault, filepath.Base(src2))
+	if err := utils.BackupOrRemove(dst2, backup); err != nil {
+		return err
+	}
+	return utils.CopyOrSymlink(src2, dst2, doCopy)
+}
+
 // Install and configure IPython.
-func ipython(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		command := utils.Format("{prefix} {pip_install} ipython", map[string]string{
-			"prefix": utils.GetCommandPrefix(
-				utils.GetBoolFlag(cmd, "sudo"),
-				map[string]uint32{},
-			),
-			"pip_install": utils.BuildPipInstall(cmd),
-		})
-		utils.RunCmd(command)
-	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
-		profileDir := utils.GetStringFlag(cmd, "profile-dir")
-		profileDefault := filepath.Join(profileDir, "profile_default")
-		backup := utils.ShouldBackup(cmd)
-		doCopy := utils.GetBoolFlag(cmd, "copy")
-		src1 := "~/.config/icon-data/ipython/startup.ipy"
-		dst1 := filepath.Join(profileDefault, "startup", "startup.ipy")
-		utils.BackupOrRemove(dst1, backup)
-		utils.CopyOrSymlink(src1, dst1, doCopy)
-		src2 := "~/.config/icon-data/ipython/ipython_config.py"
-		dst2 := filepath.Join(profileDefault, filepath.Base(src2))
-		utils.BackupOrRemove(dst2, backup)
-		utils.CopyOrSymlink(src2, dst2, doCopy)
-	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		command := utils.Format("{prefix} {pip_uninstall} ipython", map[string]string{
-			"prefix": utils.GetCommandPrefix(
-				utils.GetBoolFlag(cmd, "sudo"),
-				map[string]uint32{},
-			),
-			"pip_uninstall": utils.BuildPipUninstall(cmd),
-		})
-		utils.RunCmd(command)
+func ipython(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := pipIpython(cmd, "install"); err != nil {
+			return err
+		}
+	}
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		if err := configIpython(cmd); err != nil {
+			return err
+		}
+	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return pipIpython(cmd, "uninstall")
 	}
+	return nil
 }
 
 var ipythonCmd = &cobra.Command{
@@ -52,7 +105,7 @@ var ipythonCmd = &cobra.Command{
 	Aliases: []string{"ipy"},
 	Short:   "Install and configure IPython.",
 	//Args:  cobra.ExactArgs(1),
-	Run: ipython,
+	RunE: ipython,
 }
 
 func ConfigIpythonCmd(rootCmd *cobra.Command) {
@@ -62,7 +115,7 @@ func ConfigIpythonCmd(rootCmd *cobra.Command) {
 	ipythonCmd.Flags().Bool("no-backup", false, "Do not backup existing configuration files.")
 	ipythonCmd.Flags().Bool("copy", false, "Make copies (instead of symbolic links) of configuration files.")
 	ipythonCmd.Flags().Bool("sudo", false, "Force using sudo.")
-	ipythonCmd.Flags().String("profile-dir", filepath.Join(utils.UserHomeDir(), ".ipython"),
+	ipythonCmd.Flags().String("profile-dir", "~/.ipython",
 		"The directory for storing IPython configuration files.")
 	utils.AddPythonFlags(ipythonCmd)
 	rootCmd.AddCommand(ipythonCmd)
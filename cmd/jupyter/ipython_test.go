@@ -0,0 +1,81 @@
+package jupyter
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/internal/testutil"
+)
+
+var testRoot = sync.OnceValue(func() *cobra.Command {
+	root := &cobra.Command{Use: "icon"}
+	ConfigIpythonCmd(root)
+	ConfigJLabVimCmd(root)
+	return root
+})
+
+func TestIpython(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"install", []string{"ipython", "--install", "--user"}, []string{
+			"PIP_BREAK_SYSTEM_PACKAGES=1 python3 -m pip install --user ipython",
+		}},
+		{"install with sudo", []string{"ipython", "--install", "--sudo"}, []string{
+			"sudo true",
+			"sudo PIP_BREAK_SYSTEM_PACKAGES=1 python3 -m pip install ipython",
+		}},
+		{"uninstall", []string{"ipython", "--uninstall", "--python", "python3.12"}, []string{
+			"python3.12 -m pip uninstall ipython",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			env.Exec.AddCommands("python3", "python3.12")
+			if err := env.Run(testRoot(), tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands(tt.want...)
+		})
+	}
+}
+
+func TestIpythonConfig(t *testing.T) {
+	tests := []struct {
+		name     string
+		args     []string
+		wantLink bool
+	}{
+		{"symlink", []string{"ipython", "--config"}, true},
+		{"copy", []string{"ipython", "--config", "--copy"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			env.FakeConfigData(map[string]string{
+				"ipython/startup.ipy":       "%load_ext autoreload\n",
+				"ipython/ipython_config.py": "c = get_config()\n",
+			})
+			env.WriteFile("~/.ipython/profile_default/ipython_config.py", "# old\n")
+			if err := env.Run(testRoot(), tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands()
+			for dst, src := range map[string]string{
+				"~/.ipython/profile_default/startup/startup.ipy": "~/.config/icon-data/ipython/startup.ipy",
+				"~/.ipython/profile_default/ipython_config.py":   "~/.config/icon-data/ipython/ipython_config.py",
+			} {
+				if got := env.Readlink(dst) != ""; got != tt.wantLink {
+					t.Errorf("%s is a symbolic link: %t, want %t", dst, got, tt.wantLink)
+				}
+				if got, want := env.ReadFile(dst), env.ReadFile(src); got != want {
+					t.Errorf("%s = %q, want %q", dst, got, want)
+				}
+			}
+		})
+	}
+}
@@ -6,21 +6,38 @@ import (
 )
 
 // Install and configure jupyter_book.
-func jupyterBook(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
+func jupyterBook(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		pipInstall, err := utils.BuildPipInstall(cmd)
+		if err != nil {
+			return err
+		}
 		command := utils.Format("{pip_install} jupyter-book", map[string]string{
-			"pip_install": utils.BuildPipInstall(cmd),
+			"pip_install": pipInstall,
 		})
-		utils.RunCmd(command)
+		if err := utils.RunCmd(command); err != nil {
+			return err
+		}
 	}
-	if utils.GetBoolFlag(cmd, "config") {
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+	if uninstall {
+		pipUninstall, err := utils.BuildPipUninstall(cmd)
+		if err != nil {
+			return err
+		}
 		command := utils.Format("{pip_uninstall} jupyter_book", map[string]string{
-			"pip_uninstall": utils.BuildPipUninstall(cmd),
+			"pip_uninstall": pipUninstall,
 		})
-		utils.RunCmd(command)
+		return utils.RunCmd(command)
 	}
+	return nil
 }
 
 var jupyterBookCmd = &cobra.Command{
@@ -28,7 +45,7 @@ var jupyterBookCmd = &cobra.Command{
 	Aliases: []string{"jb", "jbook"},
 	Short:   "Install and configure jupyter_book.",
 	//Args:  cobra.ExactArgs(1),
-	Run: jupyterBook,
+	RunE: jupyterBook,
 }
 
 func ConfigJupyterBookCmd(rootCmd *cobra.Command) {
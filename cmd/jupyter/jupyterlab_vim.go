@@ -5,40 +5,72 @@ import (
 	"legendu.net/icon/utils"
 )
 
+// Enable or disable the jupyterlab_vim extension.
+//
+// @param prefix The command prefix ("sudo" or an empty string).
+// @param action Either "enable" or "disable".
+func toggleJupyterlabVim(prefix, action string) error {
+	command := utils.Format("{prefix} $(which jupyter) labextension {action} @axlair/jupyterlab_vim", map[string]string{
+		"prefix": prefix,
+		"action": action,
+	})
+	return utils.RunCmd(command)
+}
+
 // Install and configure the jupyterlab_vim extension for JupyterLab.
-func jupyterlabVim(cmd *cobra.Command, _ []string) {
-	prefix := utils.GetCommandPrefix(
-		utils.GetBoolFlag(cmd, "sudo"),
-		map[string]uint32{},
-	)
-	if utils.GetBoolFlag(cmd, "install") {
+func jupyterlabVim(cmd *cobra.Command, _ []string) error {
+	forceSudo, err := utils.GetBoolFlag(cmd, "sudo")
+	if err != nil {
+		return err
+	}
+	prefix, err := utils.GetCommandPrefix(forceSudo, map[string]uint32{})
+	if err != nil {
+		return err
+	}
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		pipInstall, err := utils.BuildPipInstall(cmd)
+		if err != nil {
+			return err
+		}
 		command := utils.Format("{prefix} {pip_install} jupyterlab_vim", map[string]string{
 			"prefix":      prefix,
-			"pip_install": utils.BuildPipInstall(cmd),
+			"pip_install": pipInstall,
 		})
-		utils.RunCmd(command)
+		if err := utils.RunCmd(command); err != nil {
+			return err
+		}
 	}
-	if utils.GetBoolFlag(cmd, "config") || utils.GetBoolFlag(cmd, "enable") || utils.GetBoolFlag(cmd, "disable") {
-		if utils.GetBoolFlag(cmd, "enable") {
-			command := utils.Format("{prefix} $(which jupyter) labextension enable @axlair/jupyterlab_vim", map[string]string{
-				"prefix": prefix,
-			})
-			utils.RunCmd(command)
+	for _, action := range []string{"enable", "disable"} {
+		toggle, err := utils.GetBoolFlag(cmd, action)
+		if err != nil {
+			return err
 		}
-		if utils.GetBoolFlag(cmd, "disable") {
-			command := utils.Format("{prefix} $(which jupyter) labextension disable @axlair/jupyterlab_vim", map[string]string{
-				"prefix": prefix,
-			})
-			utils.RunCmd(command)
+		if toggle {
+			if err := toggleJupyterlabVim(prefix, action); err != nil {
+				return err
+			}
 		}
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		python, err := utils.GetStringFlag(cmd, "python")
+		if err != nil {
+			return err
+		}
 		command := utils.Format("{prefix} {python} -m pip uninstall jupyterlab_vim", map[string]string{
 			"prefix": prefix,
-			"python": utils.GetStringFlag(cmd, "python"),
+			"python": python,
 		})
-		utils.RunCmd(command)
+		return utils.RunCmd(command)
 	}
+	return nil
 }
 
 var jLabVimCmd = &cobra.Command{
@@ -46,7 +78,7 @@ var jLabVimCmd = &cobra.Command{
 	Aliases: []string{"jlab_vim", "jlabvim", "jvim"},
 	Short:   "Install and configure the jupyterlab_vim extension for JupyterLab.",
 	//Args:  cobra.ExactArgs(1),
-	Run: jupyterlabVim,
+	RunE: jupyterlabVim,
 }
 
 func ConfigJLabVimCmd(rootCmd *cobra.Command) {
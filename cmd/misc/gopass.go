@@ -1,7 +1,7 @@
 package misc
 
 import (
-	"log"
+	"fmt"
 
 	"github.com/spf13/cobra"
 	"gopkg.in/yaml.v3"
@@ -16,81 +16,109 @@ type gitConfig struct {
 	GitURL string `yaml:"gitUrl"`
 }
 
-// runPackageCmd dispatches a package-manager command (install/uninstall) for
-// the current OS, filling in the sudo prefix and yes-flag for the given
-// apt-get/dnf templates, or running the brew command directly on macOS.
-func runPackageCmd(cmd *cobra.Command, debian, fedora, brew string) {
-	if utils.IsLinux() {
-		if utils.IsDebianUbuntuSeries() {
-			utils.RunCmd(utils.Format(debian, map[string]string{
-				"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-				"yesStr": utils.BuildYesFlag(cmd),
-			}))
-		} else if utils.IsFedoraSeries() {
-			utils.RunCmd(utils.Format(fedora, map[string]string{
-				"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-				"yesStr": utils.BuildYesFlag(cmd),
-			}))
-		}
-	} else {
-		utils.RunCmd(brew)
+// gopassPackages are packages of gopass for package managers.
+var gopassPackages = utils.Packages{
+	"apt":    {"gopass", "age"},
+	"dnf":    {"gopass", "age"},
+	"brew":   {"gopass", "age"},
+	"pacman": {"gopass", "age"},
+	"zypper": {"gopass", "age"},
+	"apk":    {"gopass", "age"},
+}
+
+// readGopassGitConfig reads the gopass git configuration from ~/.config/icon-data/gopass/git.yaml.
+func readGopassGitConfig() (gitConfig, error) {
+	var cfg gitConfig
+	gitConfigFile := "~/.config/icon-data/gopass/git.yaml"
+	if !utils.ExistsFile(gitConfigFile) {
+		return cfg, &utils.MissingConfigError{Path: gitConfigFile}
+	}
+	bytes, err := utils.ReadFile(gitConfigFile)
+	if err != nil {
+		return cfg, err
+	}
+	if err := yaml.Unmarshal(bytes, &cfg); err != nil {
+		return cfg, fmt.Errorf("error parsing %s: %w", gitConfigFile, err)
+	}
+	if cfg.GitURL == "" {
+		return cfg, &utils.MissingConfigError{Path: gitConfigFile, Key: "gitUrl"}
+	}
+	return cfg, nil
+}
+
+func configGopass(cmd *cobra.Command) error {
+	if err := icon.FetchConfigData(false, ""); err != nil {
+		return err
+	}
+	cfg, err := readGopassGitConfig()
+	if err != nil {
+		return err
+	}
+	user, err := utils.ReadUserConfig()
+	if err != nil {
+		return err
 	}
+	backup, err := utils.ShouldBackup(cmd)
+	if err != nil {
+		return err
+	}
+	store := "~/.local/share/gopass/stores/root"
+	if err := utils.BackupOrRemove(store, backup); err != nil {
+		return err
+	}
+	err = utils.NewCommand("gopass", "setup", "--crypto", "age", "--storage", "gitfs",
+		"--remote", cfg.GitURL,
+		"--name", user.UserName,
+		"--email", user.UserEmail,
+	).Run()
+	if err != nil {
+		return err
+	}
+	if err := utils.NewCommand("gopass", "config", "age.agent-enabled", "true").Run(); err != nil {
+		return err
+	}
+	return utils.NewCommand("gopass", "config", "age.agent-timeout", "3600").Run()
 }
 
 // Install and configure gopass.
-func gopass(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		runPackageCmd(cmd,
-			`{prefix} apt-get {yesStr} update \
-					&& {prefix} apt-get {yesStr} install gopass age`,
-			"{prefix} dnf {yesStr} install gopass age",
-			"brew install gopass age",
-		)
-	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
-		gitConfigFile := "~/.config/icon-data/gopass/git.yaml"
-		if !utils.ExistsFile(gitConfigFile) {
-			log.Fatalf("The gopass git configuration file %s does not exist.", gitConfigFile)
-		}
-		var cfg gitConfig
-		if err := yaml.Unmarshal(utils.ReadFile(gitConfigFile), &cfg); err != nil {
-			log.Fatalf("Error parsing %s: %v", gitConfigFile, err)
+func gopass(cmd *cobra.Command, _ []string) error {
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := utils.InstallPackages("gopass", gopassPackages, yes); err != nil {
+			return err
 		}
-		if cfg.GitURL == "" {
-			log.Fatalf("gitUrl is not configured in %s.", gitConfigFile)
+	}
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		if err := configGopass(cmd); err != nil {
+			return err
 		}
-		user := utils.ReadUserConfig()
-		store := "~/.local/share/gopass/stores/root"
-		utils.BackupOrRemove(store, utils.ShouldBackup(cmd))
-		utils.RunCmd(utils.Format(
-			`gopass setup --crypto age --storage gitfs \
-				--remote "{gitUrl}" \
-				--name "{userName}" \
-				--email "{userEmail}"`,
-			map[string]string{
-				"gitUrl":    cfg.GitURL,
-				"userName":  user.UserName,
-				"userEmail": user.UserEmail,
-			},
-		))
-		utils.RunCmd("gopass config age.agent-enabled true")
-		utils.RunCmd("gopass config age.agent-timeout 3600")
-	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		runPackageCmd(cmd,
-			"{prefix} apt-get {yesStr} purge gopass age",
-			"{prefix} dnf {yesStr} remove gopass age",
-			"brew uninstall gopass age",
-		)
 	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return utils.RemovePackages("gopass", gopassPackages, yes)
+	}
+	return nil
 }
 
 var gopassCmd = &cobra.Command{
 	Use:     "gopass",
 	Aliases: []string{},
 	Short:   "Install and configure gopass.",
-	Run:     gopass,
+	RunE:    gopass,
 }
 
 func ConfigGopassCmd(rootCmd *cobra.Command) {
@@ -0,0 +1,81 @@
+package misc
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/internal/testutil"
+)
+
+var testRoot = sync.OnceValue(func() *cobra.Command {
+	root := &cobra.Command{Use: "icon"}
+	ConfigGopassCmd(root)
+	ConfigKeepassXCCmd(root)
+	ConfigKeyboardCmd(root)
+	return root
+})
+
+func TestGopassInstall(t *testing.T) {
+	tests := []struct {
+		distro testutil.Distro
+		want   []string
+	}{
+		{testutil.Ubuntu, []string{
+			"sudo true",
+			"sudo apt-get -y update",
+			"sudo apt-get -y install gopass age",
+		}},
+		{testutil.Fedora, []string{
+			"sudo true",
+			"sudo dnf -y install gopass age",
+		}},
+		{testutil.Arch, []string{
+			"sudo true",
+			"sudo pacman -S --needed --noconfirm gopass age",
+		}},
+		{testutil.OpenSUSE, []string{
+			"sudo true",
+			"sudo zypper --non-interactive refresh",
+			"sudo zypper --non-interactive install gopass age",
+		}},
+		{testutil.Alpine, []string{
+			"sudo true",
+			"sudo apk update",
+			"sudo apk add gopass age",
+		}},
+		{testutil.MacOS, []string{
+			"brew install --force gopass age || brew link --overwrite --force gopass age",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.distro.Name, func(t *testing.T) {
+			env := testutil.New(t, tt.distro)
+			if err := env.Run(testRoot(), "gopass", "--install", "--yes"); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands(tt.want...)
+		})
+	}
+}
+
+func TestGopassConfig(t *testing.T) {
+	env := testutil.New(t, testutil.Ubuntu)
+	env.FakeConfigData(map[string]string{
+		"gopass/git.yaml": "gitUrl: git@github.com:tester/pass.git\n",
+		"user.yaml":       "userName: Jane O'Neil\nuserEmail: jane@example.com\n",
+	})
+	env.WriteFile("~/.local/share/gopass/stores/root/secret.age", "")
+	if err := env.Run(testRoot(), "gopass", "--config", "--no-backup"); err != nil {
+		t.Fatal(err)
+	}
+	env.AssertCommands(
+		`gopass setup --crypto age --storage gitfs --remote git@github.com:tester/pass.git `+
+			`--name 'Jane O'"'"'Neil' --email jane@example.com`,
+		"gopass config age.agent-enabled true",
+		"gopass config age.agent-timeout 3600",
+	)
+	if env.Exists("~/.local/share/gopass/stores/root") {
+		t.Error("the existing store is not removed")
+	}
+}
@@ -7,54 +7,60 @@ import (
 	"legendu.net/icon/utils"
 )
 
+// keepassXCPackages are packages of KeePassXC for package managers.
+var keepassXCPackages = utils.Packages{
+	"apt":     {"keepassxc"},
+	"dnf":     {"keepassxc"},
+	"brew":    {"--cask keepassxc"},
+	"flatpak": {"org.keepassxc.KeePassXC"},
+	"pacman":  {"keepassxc"},
+	"zypper":  {"keepassxc"},
+	"apk":     {"keepassxc"},
+}
+
 // Install and configure the KeepassXC terminal.
-func keepassxc(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		if utils.IsLinux() {
-			if utils.IsDebianUbuntuSeries() {
-				command := utils.Format(`{prefix} apt-get {yesStr} update \
-						&& {prefix} apt-get {yesStr} install keepassxc`, map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format("{prefix} dnf {yesStr} install keepassxc", map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			}
-		} else {
-			utils.RunCmd("brew install --cask keepassxc")
-		}
+func keepassxc(cmd *cobra.Command, _ []string) error {
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		if utils.IsLinux() {
-		} else {
-			src := "/Applications/KeePassXC.app/Contents/MacOS/keepassxc-cli"
-			utils.RemoveAll("~/.local/bin/" + filepath.Base(src))
-			utils.SymlinkIntoDir(src, "~/.local/bin")
+	if install {
+		if err := utils.InstallPackages("KeePassXC", keepassXCPackages, yes); err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		if utils.IsLinux() {
-			command := utils.Format("{prefix} apt-get {yesStr} purge keepassxc", map[string]string{
-				"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-				"yesStr": utils.BuildYesFlag(cmd),
-			})
-			utils.RunCmd(command)
-		} else {
-			utils.RunCmd("brew uninstall --cask keepassxc")
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config && !utils.IsLinux() {
+		src := "/Applications/KeePassXC.app/Contents/MacOS/keepassxc-cli"
+		if err := utils.RemoveAll("~/.local/bin/" + filepath.Base(src)); err != nil {
+			return err
+		}
+		if err := utils.SymlinkIntoDir(src, "~/.local/bin"); err != nil {
+			return err
 		}
 	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return utils.RemovePackages("KeePassXC", keepassXCPackages, yes)
+	}
+	return nil
 }
 
 var keepassXCCmd = &cobra.Command{
 	Use:     "keepassxc",
 	Aliases: []string{},
 	Short:   "Install and configure the KeepassXC terminal.",
-	Run:     keepassxc,
+	RunE:    keepassxc,
 }
 
 func ConfigKeepassXCCmd(rootCmd *cobra.Command) {
@@ -12,26 +12,31 @@ import (
 	"legendu.net/icon/utils"
 )
 
-func readDefaultKeybindings(file string) []string {
+func readDefaultKeybindings(file string) ([]string, error) {
 	defaultKeyBinding := []string{
 		"{",
 	}
 	if utils.ExistsFile(file) {
-		defaultKeyBinding = strings.Split(
-			strings.TrimSpace(utils.ReadFileAsString(file)), "\n")
+		text, err := utils.ReadFileAsString(file)
+		if err != nil {
+			return nil, err
+		}
+		defaultKeyBinding = strings.Split(strings.TrimSpace(text), "\n")
 		defaultKeyBinding = defaultKeyBinding[:len(defaultKeyBinding)-1]
 	}
-	return defaultKeyBinding
+	return defaultKeyBinding, nil
 }
 
-func readDefaultKeybindingsFromYaml() map[string]string {
-	var keyBindings map[string]string
-	err := yaml.Unmarshal(
-		utils.ReadFile("~/.config/icon-data/keyboard/DefaultKeyBinding.yaml"), &keyBindings)
+func readDefaultKeybindingsFromYaml() (map[string]string, error) {
+	bytes, err := utils.ReadFile("~/.config/icon-data/keyboard/DefaultKeyBinding.yaml")
 	if err != nil {
-		log.Fatalf("Error unmarshaling data: %v", err)
+		return nil, err
+	}
+	var keyBindings map[string]string
+	if err := yaml.Unmarshal(bytes, &keyBindings); err != nil {
+		return nil, fmt.Errorf("error unmarshaling data: %w", err)
 	}
-	return keyBindings
+	return keyBindings, nil
 }
 
 func hasAnyPrefix(kb string, keyBindings map[string]string) bool {
@@ -67,40 +72,50 @@ func addDefaultKeyBindings(defaultKeyBindings []string, keyBindings map[string]s
 	return append(defaultKeyBindings, lines...)
 }
 
-func configDefaultKeybindings() {
+func configDefaultKeybindings() error {
 	dir := "~/Library/KeyBindings"
-	utils.MkdirAll(dir, "700")
+	if err := utils.MkdirAll(dir, "700"); err != nil {
+		return err
+	}
 	file := filepath.Join(dir, "DefaultKeyBinding.dict")
-	defaultKeyBindings := readDefaultKeybindings(file)
-	keyBindings := readDefaultKeybindingsFromYaml()
+	defaultKeyBindings, err := readDefaultKeybindings(file)
+	if err != nil {
+		return err
+	}
+	keyBindings, err := readDefaultKeybindingsFromYaml()
+	if err != nil {
+		return err
+	}
 	defaultKeyBindings = removeDefaultKeyBindings(defaultKeyBindings, keyBindings)
 	defaultKeyBindings = addDefaultKeyBindings(defaultKeyBindings, keyBindings)
 	//nolint:mnd // readable
-	utils.WriteTextFile(file, strings.Join(defaultKeyBindings, "\n"), 0o600)
-	fmt.Printf("%s has been updated using keyboard/DefaultKeyBinding.yaml.\n", file)
+	if err := utils.WriteTextFile(file, strings.Join(defaultKeyBindings, "\n"), 0o600); err != nil {
+		return err
+	}
+	log.Printf("%s has been updated using keyboard/DefaultKeyBinding.yaml.\n", file)
+	return nil
 }
 
 // Configure keyboard.
-func keyboard(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		// nothing to install
+func keyboard(cmd *cobra.Command, _ []string) error {
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil || !config {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
-		if utils.IsLinux() {
-		} else {
-			configDefaultKeybindings()
-		}
+	if err := icon.FetchConfigData(false, ""); err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+	if !utils.IsLinux() {
+		return configDefaultKeybindings()
 	}
+	return nil
 }
 
 var keyboardCmd = &cobra.Command{
 	Use:     "keyboard",
 	Aliases: []string{"kb"},
 	Short:   "Configure keyboard related.",
-	Run:     keyboard,
+	RunE:    keyboard,
 }
 
 func ConfigKeyboardCmd(rootCmd *cobra.Command) {
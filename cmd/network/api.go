@@ -0,0 +1,232 @@
+package network
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"legendu.net/icon/utils"
+)
+
+// apiConfig specifies how to send requests to the API of a release provider.
+type apiConfig struct {
+	// headers are extra headers of requests, e.g., for authentication.
+	headers map[string]string
+	// tokenSource tells where the token for authentication is from,
+	// or is an empty string if requests are anonymous.
+	tokenSource string
+	// hint tells how to raise the rate limit if requests are anonymous.
+	hint string
+}
+
+// tokenFromEnv finds a token from environment variables.
+// return: The token (or an empty string if not found) and the name of the environment variable.
+func tokenFromEnv(names ...string) (string, string) {
+	for _, name := range names {
+		if token := strings.TrimSpace(os.Getenv(name)); token != "" {
+			return token, name
+		}
+	}
+	return "", ""
+}
+
+var (
+	githubTokenOnce   sync.Once
+	githubTokenValue  string
+	githubTokenSource string
+)
+
+// githubToken finds a token to authenticate requests to the GitHub API,
+// from the environment variable GITHUB_TOKEN or GH_TOKEN, or from `gh auth token --hostname github.com`.
+// return: The token (or an empty string if not found) and where it is from.
+func githubToken() (string, string) {
+	githubTokenOnce.Do(func() {
+		githubTokenValue, githubTokenSource = tokenFromEnv("GITHUB_TOKEN", "GH_TOKEN")
+		if githubTokenValue != "" || utils.LookPath("gh") == "" {
+			return
+		}
+		// the host is explicit so that a token for GH_HOST (e.g., GitHub Enterprise Server) is never used
+		out, err := utils.NewCommand("gh", "auth", "token", "--hostname", "github.com").Output()
+		if err == nil {
+			githubTokenValue, githubTokenSource = out, "gh auth token"
+		}
+	})
+	return githubTokenValue, githubTokenSource
+}
+
+var (
+	enterpriseTokensMu sync.Mutex
+	enterpriseTokens   = map[string][2]string{}
+)
+
+// enterpriseToken finds a token to authenticate requests to the API of a GitHub Enterprise Server,
+// from the environment variable GH_ENTERPRISE_TOKEN or GITHUB_ENTERPRISE_TOKEN,
+// or from `gh auth token --hostname <host>`.
+// The token for github.com is never used for GitHub Enterprise Server.
+// @param host: The host of the GitHub Enterprise Server.
+// return: The token (or an empty string if not found) and where it is from.
+func enterpriseToken(host string) (string, string) {
+	enterpriseTokensMu.Lock()
+	defer enterpriseTokensMu.Unlock()
+	if found, ok := enterpriseTokens[host]; ok {
+		return found[0], found[1]
+	}
+	token, source := tokenFromEnv("GH_ENTERPRISE_TOKEN", "GITHUB_ENTERPRISE_TOKEN")
+	if token == "" && utils.LookPath("gh") != "" {
+		out, err := utils.NewCommand("gh", "auth", "token", "--hostname", host).Output()
+		if err == nil && out != "" {
+			token, source = out, "gh auth token --hostname "+host
+		}
+	}
+	enterpriseTokens[host] = [2]string{token, source}
+	return token, source
+}
+
+// githubAPIConfig returns the configuration of requests to the GitHub API.
+// The token for github.com is only sent to api.github.com, never to other hosts.
+// Requests to the API (/api/v3) of a GitHub Enterprise Server are authenticated with a token of its own
+// (see enterpriseToken).
+// @param apiURL: The URL of the API.
+func githubAPIConfig(apiURL string) apiConfig {
+	config := apiConfig{
+		headers: map[string]string{
+			"Accept":               "application/vnd.github+json",
+			"X-GitHub-Api-Version": "2022-11-28",
+		},
+		hint: "set GITHUB_TOKEN or GH_TOKEN (or log in using `gh auth login`) to raise the rate limit",
+	}
+	u, err := url.Parse(apiURL)
+	if err != nil || u.Scheme != "https" {
+		config.hint = ""
+		return config
+	}
+	token, source := "", ""
+	switch {
+	case u.Host == "api.github.com":
+		token, source = githubToken()
+	case strings.HasPrefix(u.Path, "/api/v3/"):
+		token, source = enterpriseToken(u.Host)
+		config.hint = fmt.Sprintf(
+			"set GH_ENTERPRISE_TOKEN (or log in using `gh auth login --hostname %s`) to raise the rate limit", u.Host)
+	default:
+		config.hint = ""
+		return config
+	}
+	if token != "" {
+		config.headers["Authorization"] = "Bearer " + token
+		config.tokenSource = source
+	}
+	return config
+}
+
+// getAPI sends a GET request to the API of a release provider.
+// Requests are revalidated with the ETag of the cached response,
+// which does not count against the rate limit (of GitHub) if the response has not changed.
+// If the rate limit is exceeded, the cached response is used if there is one.
+// Otherwise, a *utils.RateLimitError telling when the rate limit resets is returned
+// unless it resets within utils.MaxRateLimitWait.
+// In offline mode (see utils.SetOffline), only the cached response is used.
+// @param url: The URL of the API.
+// @param config: How to send requests to the API.
+// return: The body of the response.
+func getAPI(url string, config apiConfig) ([]byte, error) {
+	cached := utils.ReadCachedResponse(url)
+	if utils.IsOffline() {
+		if cached != nil {
+			return []byte(cached.Body), nil
+		}
+		return nil, &utils.OfflineError{URL: url}
+	}
+	const numRetry = 3
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, http.NoBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create a HTTP GET request to the URL '%s' with context: %w", url, err)
+		}
+		for key, value := range config.headers {
+			req.Header.Set(key, value)
+		}
+		if cached != nil && cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		resp, err := utils.HTTPClient().Do(req)
+		if err != nil {
+			if attempt < numRetry {
+				waitBeforeRetry(attempt, err)
+				continue
+			}
+			if cached != nil {
+				utils.Warnf("The request to %s failed, so the cached response is used: %v", url, err)
+				return []byte(cached.Body), nil
+			}
+			return nil, fmt.Errorf("the HTTP GET request to the URL '%s' failed: %w", url, err)
+		}
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the response body: %w", err)
+		}
+		if resp.StatusCode == http.StatusNotModified && cached != nil {
+			return []byte(cached.Body), nil
+		}
+		if rateLimitErr := utils.RateLimitFromResponse(resp); rateLimitErr != nil {
+			if wait := time.Until(rateLimitErr.Reset); wait <= utils.MaxRateLimitWait && attempt < numRetry {
+				log.Printf("The rate limit of the API is exceeded. Waiting %s for it to reset ...", wait.Round(time.Second))
+				time.Sleep(wait)
+				continue
+			}
+			if cached != nil {
+				utils.Warnf("%v. The cached response is used.", rateLimitErr)
+				return []byte(cached.Body), nil
+			}
+			if config.tokenSource == "" {
+				rateLimitErr.Hint = config.hint
+			}
+			return nil, rateLimitErr
+		}
+		switch {
+		case resp.StatusCode == http.StatusUnauthorized && config.tokenSource != "":
+			return nil, fmt.Errorf("the token from %s is rejected by %s", config.tokenSource, url)
+		case resp.StatusCode >= http.StatusInternalServerError && attempt < numRetry:
+			waitBeforeRetry(attempt, fmt.Errorf("status code %d", resp.StatusCode))
+			continue
+		case utils.IsErrorHTTPResponse(resp):
+			return nil, fmt.Errorf("the HTTP GET request on the URL %s got an error response with the status code %d: %s",
+				url, resp.StatusCode, strings.TrimSpace(string(body)))
+		}
+		// responses are cached without ETags in prefetch mode so that they are available offline
+		if etag := resp.Header.Get("ETag"); etag != "" || utils.IsPrefetch() {
+			utils.WriteCachedResponse(&utils.CachedResponse{URL: url, ETag: etag, Body: string(body), Time: time.Now()})
+		}
+		return body, nil
+	}
+}
+
+// getAPIAsJSON sends a GET request to the API of a release provider and parses the JSON response.
+// @param url: The URL of the API.
+// @param config: How to send requests to the API.
+// @param v: A pointer to the value to parse the JSON response into.
+func getAPIAsJSON(url string, config apiConfig, v any) error {
+	bytes, err := getAPI(url, config)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(bytes, v); err != nil {
+		return fmt.Errorf("failed to parse JSON: %w", err)
+	}
+	return nil
+}
+
+func waitBeforeRetry(attempt int, err error) {
+	wait := time.Duration(1<<attempt) * time.Second
+	utils.Warnf("The request to the API failed (%v). Retrying in %s ...", err, wait)
+	time.Sleep(wait)
+}
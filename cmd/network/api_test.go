@@ -0,0 +1,33 @@
+package network
+
+import (
+	"testing"
+
+	"legendu.net/icon/internal/testutil"
+)
+
+func TestGithubAPIConfig(t *testing.T) {
+	env := testutil.New(t, testutil.Ubuntu)
+	t.Setenv("GITHUB_TOKEN", "github-token")
+	t.Setenv("GH_ENTERPRISE_TOKEN", "")
+	t.Setenv("GITHUB_ENTERPRISE_TOKEN", "")
+	env.Exec.AddCommands("gh")
+	env.Exec.Stub(`^gh auth token --hostname github.example.com$`, 0, "enterprise-token\n")
+	tests := []struct {
+		repo string
+		want string
+	}{
+		{"legendu-net/icon", "Bearer github-token"},
+		{"https://github.com/legendu-net/icon", "Bearer github-token"},
+		{"https://api.github.com/repos/legendu-net/icon/releases", "Bearer github-token"},
+		{"https://api.example.com/repos/legendu-net/icon/releases", ""},
+		{"https://github.example.com/legendu-net/icon", "Bearer enterprise-token"},
+		{"https://other.example.com/legendu-net/icon", ""},
+	}
+	for _, tt := range tests {
+		url := getReleaseURL(tt.repo)
+		if got := githubAPIConfig(url).headers["Authorization"]; got != tt.want {
+			t.Errorf("Authorization to %s = %q, want %q", url, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package network
+
+import (
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"legendu.net/icon/utils"
+)
+
+// defaultAssetPreferences rank assets when several of them match.
+// Statically linked (musl) binaries work on more Linux distributions
+// and tarballs preserve file permissions.
+var defaultAssetPreferences = []string{"musl", ".tar.gz", ".tgz", ".tar.xz", ".zip"}
+
+// expandAssetPattern expands placeholders in a pattern of asset names
+// into names used for the current OS and architecture.
+//   - {os}: linux or darwin.
+//   - {arch}: x86_64 or aarch64.
+//   - {goarch}: amd64 or arm64.
+func expandAssetPattern(pattern string) (string, error) {
+	goarch, err := utils.HostKernelArch()
+	if err != nil {
+		return "", err
+	}
+	arch := map[string]string{"amd64": "x86_64", "arm64": "aarch64"}[goarch]
+	return strings.NewReplacer("{os}", utils.CurrentPlatform().OS, "{arch}", arch, "{goarch}", goarch).Replace(pattern), nil
+}
+
+// compileAssetPattern compiles a pattern of asset names into a function matching names.
+// A pattern enclosed in slashes (e.g., /^tool-.*\.tar\.gz$/) is a regular expression.
+// Otherwise, it is a glob pattern (e.g., tool-*.tar.gz) matching whole names.
+// Placeholders are expanded by expandAssetPattern first.
+func compileAssetPattern(pattern string) (func(string) bool, error) {
+	if pattern == "" {
+		return func(string) bool { return true }, nil
+	}
+	pattern, err := expandAssetPattern(pattern)
+	if err != nil {
+		return nil, err
+	}
+	//nolint:mnd // readable
+	if len(pattern) > 2 && strings.HasPrefix(pattern, "/") && strings.HasSuffix(pattern, "/") {
+		re, err := regexp.Compile(pattern[1 : len(pattern)-1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid regular expression %s: %w", pattern, err)
+		}
+		return re.MatchString, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid glob pattern %s: %w", pattern, err)
+	}
+	return func(name string) bool {
+		matched, _ := path.Match(pattern, name)
+		return matched
+	}, nil
+}
+
+// selectAssets selects assets of a release matching keywords and a pattern.
+// Checksums and signatures are never selected as they are verified separately.
+// @param release: The release.
+// @param keywords: Keywords which the name of an asset must contain.
+// @param keywordsExclude: Keywords which the name of an asset must not contain.
+// @param pattern: A glob pattern or a regular expression (see compileAssetPattern), or an empty string.
+// @param prefer: Substrings of names in the order of preference to rank matching assets.
+// return: Candidates (assets except checksums and signatures) and the matching assets ranked by preference.
+func selectAssets(
+	release releaseInfo, keywords, keywordsExclude []string, pattern string, prefer []string,
+) ([]assetInfo, []assetInfo, error) {
+	match, err := compileAssetPattern(pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	candidates := []assetInfo{}
+	matched := []assetInfo{}
+	for _, asset := range release.Assets {
+		if isChecksumOrSignatureAsset(asset.Name) {
+			continue
+		}
+		candidates = append(candidates, asset)
+		if assetNameContainKeywords(asset.Name, keywords, keywordsExclude) && match(asset.Name) {
+			matched = append(matched, asset)
+		}
+	}
+	rankAssets(matched, prefer)
+	return candidates, matched, nil
+}
+
+// rankAssets sorts assets by preference.
+// An asset containing a more preferred substring ranks higher.
+// Ties are broken by less preferred substrings and then by the original order.
+func rankAssets(assets []assetInfo, prefer []string) {
+	slices.SortStableFunc(assets, func(a, b assetInfo) int {
+		for _, p := range prefer {
+			containA, containB := strings.Contains(a.Name, p), strings.Contains(b.Name, p)
+			if containA != containB {
+				if containA {
+					return -1
+				}
+				return 1
+			}
+		}
+		return 0
+	})
+}
+
+// printAssets prints candidate assets of a release with their sizes,
+// marking the matching ones with their ranks.
+func printAssets(w io.Writer, release releaseInfo, candidates, matched []assetInfo) error {
+	fmt.Fprintf(w, "Assets of the release %s:\n", release.TagName)
+	//nolint:mnd // readable
+	writer := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "RANK\tSIZE\tNAME")
+	for _, asset := range candidates {
+		rank := "-"
+		if idx := slices.IndexFunc(matched, func(a assetInfo) bool { return a.Name == asset.Name }); idx >= 0 {
+			rank = fmt.Sprint(idx + 1)
+		}
+		size := "-"
+		if asset.Size > 0 {
+			size = utils.FormatBytes(asset.Size)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", rank, size, asset.Name)
+	}
+	return writer.Flush()
+}
@@ -3,23 +3,28 @@ package network
 import (
 	"log"
 	"path/filepath"
-	"runtime"
 
 	"legendu.net/icon/utils"
 )
 
 func getExtensionDir() string {
-	if runtime.GOOS == "darwin" {
+	if !utils.IsLinux() {
 		return "~/Library/Application Support/Google/Chrome/Default/Extensions"
 	}
 	return "~/.config/google-chrome/Default/Extensions"
 }
 
-func InstallChromeExtension(id, name string) {
+func InstallChromeExtension(id, name string) error {
 	dir := getExtensionDir()
-	utils.MkdirAll(dir, "700")
+	if err := utils.MkdirAll(dir, "700"); err != nil {
+		return err
+	}
 	config := filepath.Join(dir, id+".json")
 	//nolint:mnd // readable
-	utils.WriteTextFile(config, `{"external_update_url": "https://clients2.google.com/service/update2/crx"}`, 0o600)
+	err := utils.WriteTextFile(config, `{"external_update_url": "https://clients2.google.com/service/update2/crx"}`, 0o600)
+	if err != nil {
+		return err
+	}
 	log.Printf("Installed %s (%s)", config, name)
+	return nil
 }
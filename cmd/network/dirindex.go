@@ -0,0 +1,98 @@
+package network
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/mcuadros/go-version"
+	"legendu.net/icon/utils"
+)
+
+// dirIndexProvider looks up releases in a directory index (an HTML page listing files) served over HTTP,
+// e.g., a mirror of releases.
+// Subdirectories named by versions (e.g., v1.2.3/) are releases.
+// If there is no such subdirectory, files in the directory are assets of a single release of unknown version.
+type dirIndexProvider struct{}
+
+// hrefPattern matches links in an HTML page.
+var hrefPattern = regexp.MustCompile(`(?i)href\s*=\s*["']([^"'?#]+)["']`)
+
+// versionDirPattern matches names of subdirectories which are releases.
+var versionDirPattern = regexp.MustCompile(`^v?\d+(\.\d+)+`)
+
+// listDirIndex lists files and subdirectories in a directory index.
+// @param dirURL: The URL of the directory index, ending with a slash.
+// return: Files (as assets) and names of subdirectories in the directory.
+func listDirIndex(dirURL string) ([]assetInfo, []string, error) {
+	base, err := url.Parse(dirURL)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse the URL %s: %w", dirURL, err)
+	}
+	const numRetry = 3
+	const initialWaitingSeconds = 2
+	html, err := utils.HTTPGetAsString(dirURL, numRetry, initialWaitingSeconds)
+	if err != nil {
+		return nil, nil, err
+	}
+	files := []assetInfo{}
+	dirs := []string{}
+	seen := map[string]bool{}
+	for _, match := range hrefPattern.FindAllStringSubmatch(html, -1) {
+		ref, err := url.Parse(match[1])
+		if err != nil {
+			continue
+		}
+		link := base.ResolveReference(ref).String()
+		// only entries directly in the directory, e.g., not links to parent directories
+		name, ok := strings.CutPrefix(link, base.String())
+		if !ok || name == "" || seen[name] || strings.Contains(strings.TrimSuffix(name, "/"), "/") {
+			continue
+		}
+		seen[name] = true
+		if unescaped, err := url.PathUnescape(name); err == nil {
+			name = unescaped
+		}
+		if dir, isDir := strings.CutSuffix(name, "/"); isDir {
+			dirs = append(dirs, dir)
+		} else {
+			files = append(files, assetInfo{Name: name, BrowserDownloadURL: link})
+		}
+	}
+	return files, dirs, nil
+}
+
+func (dirIndexProvider) Release(repo, ver string) (releaseInfo, error) {
+	dirURL := strings.TrimSuffix(repo, "/") + "/"
+	utils.Debugf("Release URL: %s\n", dirURL)
+	files, dirs, err := listDirIndex(dirURL)
+	if err != nil {
+		return releaseInfo{}, err
+	}
+	releases := []releaseInfo{}
+	for _, dir := range dirs {
+		if versionDirPattern.MatchString(dir) {
+			releases = append(releases, releaseInfo{TagName: dir})
+		}
+	}
+	if len(releases) == 0 {
+		if ver != "" {
+			return releaseInfo{}, fmt.Errorf("no subdirectory named by a version is found in %s", dirURL)
+		}
+		return releaseInfo{Assets: files}, nil
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		return version.CompareSimple(version.Normalize(releases[i].TagName), version.Normalize(releases[j].TagName)) > 0
+	})
+	release := releases[0]
+	if ver != "" {
+		release, err = matchRelease(releases, ver)
+		if err != nil {
+			return releaseInfo{}, err
+		}
+	}
+	release.Assets, _, err = listDirIndex(dirURL + url.PathEscape(release.TagName) + "/")
+	return release, err
+}
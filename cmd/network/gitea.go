@@ -0,0 +1,49 @@
+package network
+
+import (
+	"fmt"
+
+	"legendu.net/icon/utils"
+)
+
+// giteaProvider looks up releases on Gitea or Forgejo (e.g., Codeberg),
+// whose API returns releases in the same format as GitHub.
+// Requests are authenticated with the environment variable GITEA_TOKEN or FORGEJO_TOKEN if set.
+type giteaProvider struct {
+	// defaultHost is the host of repos specified without a host, or an empty string if the host is required.
+	defaultHost string
+}
+
+func giteaAPIConfig() apiConfig {
+	config := apiConfig{
+		headers: map[string]string{},
+		hint:    "set GITEA_TOKEN or FORGEJO_TOKEN to raise the rate limit",
+	}
+	if token, source := tokenFromEnv("GITEA_TOKEN", "FORGEJO_TOKEN"); token != "" {
+		config.headers["Authorization"] = "token " + token
+		config.tokenSource = source
+	}
+	return config
+}
+
+func (p giteaProvider) Release(repo, ver string) (releaseInfo, error) {
+	host, path := parseRepo(repo)
+	if host == "" {
+		host = p.defaultHost
+	}
+	if host == "" {
+		return releaseInfo{}, fmt.Errorf("the URL of the repo %s is required to find its releases on Gitea/Forgejo", repo)
+	}
+	releasesURL := fmt.Sprintf("https://%s/api/v1/repos/%s/releases", host, path)
+	utils.Debugf("Release URL: %s\n", releasesURL)
+	if ver == "" {
+		var release releaseInfo
+		err := getAPIAsJSON(releasesURL+"/latest", giteaAPIConfig(), &release)
+		return release, err
+	}
+	var releases []releaseInfo
+	if err := getAPIAsJSON(releasesURL, giteaAPIConfig(), &releases); err != nil {
+		return releaseInfo{}, err
+	}
+	return matchRelease(releases, ver)
+}
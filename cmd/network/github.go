@@ -1,16 +1,20 @@
 package network
 
 import (
-	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"regexp"
 	"strings"
 
-	"github.com/mcuadros/go-version"
 	"github.com/spf13/cobra"
 	"legendu.net/icon/utils"
 )
 
 // Get the release URL of a project on GitHub.
+// Repos on hosts other than github.com are on GitHub Enterprise Server.
 // @param repo: The repo name of the project on GitHub.
 // return: The release URL of the project on GitHub.
 func getReleaseURL(repo string) string {
@@ -18,20 +22,18 @@ func getReleaseURL(repo string) string {
 	if strings.HasPrefix(repo, "https://api.") {
 		return repo
 	}
-	if strings.HasPrefix(repo, "https://") {
-		lastIndex := strings.LastIndex(repo, "/")
-		index := strings.LastIndex(repo[0:lastIndex], "/")
-		repo = repo[index+1:]
-	} else if strings.HasPrefix(repo, "git@") {
-		index := strings.LastIndex(repo, ":")
-		repo = repo[index+1:]
+	host, path := parseRepo(repo)
+	if host != "" && host != "github.com" {
+		return "https://" + host + "/api/v3/repos/" + path + "/releases"
 	}
-	return "https://api.github.com/repos/" + repo + "/releases"
+	return "https://api.github.com/repos/" + path + "/releases"
 }
 
 type assetInfo struct {
 	Name               string `json:"name"`
 	BrowserDownloadURL string `json:"browser_download_url"`
+	// Size is the size of the asset in bytes, or 0 if unknown.
+	Size int64 `json:"size"`
 }
 
 type releaseInfo struct {
@@ -53,106 +55,420 @@ func assetNameContainKeywords(name string, keywords, keyworkdsExclude []string)
 	return true
 }
 
-func filterReleases(url, constraint string) releaseInfo {
-	log.Printf("Extracting release from %s with the constraint %s", url, constraint)
-	const numRetry = 3
-	const initialWaitingSeconds = 120
-	bytes, err := utils.HTTPGetAsBytes(url, numRetry, initialWaitingSeconds)
-	if err != nil {
-		log.Fatal(err)
+// githubProvider looks up releases on GitHub (or GitHub Enterprise Server).
+type githubProvider struct{}
+
+func (githubProvider) Release(repo, ver string) (releaseInfo, error) {
+	// form the release URL
+	releaseURL := getReleaseURL(repo)
+	utils.Debugf("Release URL: %s\n", releaseURL)
+	if ver == "" {
+		return getLatestRelease(releaseURL)
 	}
+	return filterReleases(releaseURL, ver)
+}
+
+func filterReleases(url, constraint string) (releaseInfo, error) {
+	utils.Debugf("Extracting release from %s with the constraint %s", url, constraint)
 	var releases []releaseInfo
-	err = json.Unmarshal(bytes, &releases)
-	if err != nil {
-		log.Fatalf("Failed to parse JSON: %v", err)
+	if err := getAPIAsJSON(url, githubAPIConfig(url), &releases); err != nil {
+		return releaseInfo{}, err
 	}
-	c := version.NewConstrainGroupFromString(constraint)
-	for _, release := range releases {
-		if c.Match(release.TagName) {
-			return release
-		}
-	}
-	log.Fatal("No release matching the version constraint is found!")
-	return releaseInfo{}
+	return matchRelease(releases, constraint)
 }
 
-func getLatestRelease(releaseURL string) releaseInfo {
-	url := releaseURL + "/latest"
-	const numRetry = 3
-	const initialWaitingSeconds = 120
-	bytes, err := utils.HTTPGetAsBytes(url, numRetry, initialWaitingSeconds)
-	if err != nil {
-		log.Fatal(err)
-	}
+func getLatestRelease(releaseURL string) (releaseInfo, error) {
 	var release releaseInfo
-	err = json.Unmarshal(bytes, &release)
-	if err != nil {
-		log.Fatal("ERROR - ", err)
-	}
-	return release
+	err := getAPIAsJSON(releaseURL+"/latest", githubAPIConfig(releaseURL), &release)
+	return release, err
 }
 
 // Download a release from GitHub.
 // @param args: The arguments to parse.
 // If None, the arguments from command-line are parsed.
-func downloadGitHubReleaseArgs(cmd *cobra.Command, _ []string) {
-	DownloadGitHubRelease(
-		utils.GetStringFlag(cmd, "repo"),
-		utils.GetStringFlag(cmd, "version"),
-		map[string][]string{"common": utils.GetStringSliceFlag(cmd, "kwd")},
-		utils.GetStringSliceFlag(cmd, "KWD"),
-		utils.GetStringFlag(cmd, "output"),
-	)
+func downloadGitHubReleaseArgs(cmd *cobra.Command, _ []string) error {
+	repo, err := utils.GetStringFlag(cmd, "repo")
+	if err != nil {
+		return err
+	}
+	ver, err := utils.GetStringFlag(cmd, "version")
+	if err != nil {
+		return err
+	}
+	kwd, err := utils.GetStringSliceFlag(cmd, "kwd")
+	if err != nil {
+		return err
+	}
+	kwdExclude, err := utils.GetStringSliceFlag(cmd, "KWD")
+	if err != nil {
+		return err
+	}
+	output, err := utils.GetStringFlag(cmd, "output")
+	if err != nil {
+		return err
+	}
+	method, err := utils.GetStringFlag(cmd, "signature")
+	if err != nil {
+		return err
+	}
+	key, err := utils.GetStringFlag(cmd, "signature-key")
+	if err != nil {
+		return err
+	}
+	provider, err := utils.GetStringFlag(cmd, "provider")
+	if err != nil {
+		return err
+	}
+	pattern, err := utils.GetStringFlag(cmd, "pattern")
+	if err != nil {
+		return err
+	}
+	prefer, err := utils.GetStringSliceFlag(cmd, "prefer")
+	if err != nil {
+		return err
+	}
+	all, err := utils.GetBoolFlag(cmd, "all")
+	if err != nil {
+		return err
+	}
+	list, err := utils.GetBoolFlag(cmd, "list")
+	if err != nil {
+		return err
+	}
+	installBin, err := utils.GetStringSliceFlag(cmd, "install-bin")
+	if err != nil {
+		return err
+	}
+	binDir, err := utils.GetStringFlag(cmd, "bin-dir")
+	if err != nil {
+		return err
+	}
+	if output == "" && !list && len(installBin) == 0 {
+		return errors.New("--output is required unless --list or --install-bin is specified")
+	}
+	return DownloadRelease(repo, ver, map[string][]string{"common": kwd}, kwdExclude, output, ReleaseOptions{
+		Provider:   provider,
+		Signature:  Signature{Method: method, Key: key},
+		Pattern:    pattern,
+		Prefer:     prefer,
+		All:        all,
+		List:       list,
+		InstallBin: installBin,
+		BinDir:     binDir,
+	})
 }
 
 // Download a release from GitHub.
+// The downloaded asset is verified against the checksum published in the release if there is one.
 // @param args: The arguments to parse.
 // If None, the arguments from command-line are parsed.
-func DownloadGitHubRelease(repo, ver string, keywords map[string][]string, keywordsExclude []string, output string) {
-	keywords_ := utils.BuildKernelOSKeywords(keywords)
-	log.Printf(`Download release from the GitHub repository %s satisfying the following conditions:
+func DownloadGitHubRelease(repo, ver string, keywords map[string][]string, keywordsExclude []string, output string) error {
+	return DownloadRelease(repo, ver, keywords, keywordsExclude, output, ReleaseOptions{})
+}
+
+// ReleaseOptions are optional settings for downloading a release.
+type ReleaseOptions struct {
+	// Provider is the name of the release provider (github, gitlab, gitea, forgejo, codeberg or dir),
+	// or an empty string to detect it from the host of the repo.
+	Provider string
+	// Signature specifies how to verify the signature of the asset. Its zero value skips verifying signatures.
+	Signature Signature
+	// Pattern is a glob pattern or a regular expression (see compileAssetPattern)
+	// which names of assets must match in addition to keywords.
+	Pattern string
+	// Prefer are substrings of names in the order of preference to rank matching assets.
+	// If empty, defaultAssetPreferences are used.
+	Prefer []string
+	// All downloads all matching assets into the directory output instead of the best matching one.
+	All bool
+	// List prints assets of the release instead of downloading them.
+	List bool
+	// InstallBin are names (or glob patterns of names) of executables to extract from the downloaded asset
+	// (see utils.InstallBinaries) and install into BinDir.
+	// If output is an empty string, the asset is downloaded into a temporary directory.
+	InstallBin []string
+	// BinDir is the directory to install executables into. It defaults to /usr/local/bin.
+	BinDir string
+}
+
+// Signature specifies how to verify the signature of a release asset.
+type Signature struct {
+	// Method is one of utils.SignatureMinisign, utils.SignatureGPG and utils.SignatureCosign,
+	// or an empty string to skip verifying signatures.
+	Method string
+	// Key is the public key (or the path of it) for minisign and cosign.
+	Key string
+}
+
+// signatureExtensions are extensions of signature assets for each verification method.
+var signatureExtensions = map[string][]string{
+	utils.SignatureMinisign: {".minisig"},
+	utils.SignatureGPG:      {".asc", ".sig", ".gpg"},
+	utils.SignatureCosign:   {".sig"},
+}
+
+// checksumAssetPattern matches names of checksum assets, e.g., SHA256SUMS, checksums.txt and <asset>.sha256.
+var checksumAssetPattern = regexp.MustCompile(`(?i)(\.sha256(sum)?|sha256sums?(\.txt)?|checksums?(\.txt)?)$`)
+
+// signatureAssetPattern matches names of signature assets.
+var signatureAssetPattern = regexp.MustCompile(`(?i)\.(asc|sig|gpg|minisig|pem|sigstore)$`)
+
+func isChecksumOrSignatureAsset(name string) bool {
+	return checksumAssetPattern.MatchString(name) || signatureAssetPattern.MatchString(name)
+}
+
+func findAsset(release releaseInfo, name string) (assetInfo, bool) {
+	for _, asset := range release.Assets {
+		if asset.Name == name {
+			return asset, true
+		}
+	}
+	return assetInfo{}, false
+}
+
+// findChecksum finds the SHA-256 checksum of an asset in checksum assets of the release.
+// Checksum assets dedicated to the asset (e.g., <asset>.sha256) are preferred
+// over those listing checksums of all assets (e.g., SHA256SUMS).
+// @param release: The release containing the asset.
+// @param asset: The asset whose checksum to find.
+// return: The checksum asset, its content and the checksum (an empty string if no checksum is found).
+func findChecksum(release releaseInfo, asset assetInfo) (assetInfo, string, string) {
+	candidates := []assetInfo{}
+	for _, a := range release.Assets {
+		if !checksumAssetPattern.MatchString(a.Name) {
+			continue
+		}
+		if strings.HasPrefix(a.Name, asset.Name+".") {
+			candidates = append([]assetInfo{a}, candidates...)
+		} else {
+			candidates = append(candidates, a)
+		}
+	}
+	for _, candidate := range candidates {
+		const numRetry = 1
+		const initialWaitingSeconds = 10
+		content, err := utils.HTTPGetAsString(candidate.BrowserDownloadURL, numRetry, initialWaitingSeconds)
+		if err != nil {
+			utils.Warnf("Failed to download the checksum asset %s: %v", candidate.Name, err)
+			continue
+		}
+		// a checksum asset dedicated to the asset might contain only the checksum
+		name := utils.IfElseString(strings.HasPrefix(candidate.Name, asset.Name+"."), "", asset.Name)
+		if checksum := utils.ParseSha256Checksum(content, name); checksum != "" {
+			return candidate, content, checksum
+		}
+	}
+	return assetInfo{}, "", ""
+}
+
+// verifySignature verifies the signature of a downloaded asset.
+// The signature of the asset itself (e.g., <asset>.minisig) is preferred.
+// Otherwise, the signature of the checksum asset (e.g., SHA256SUMS.asc) is verified,
+// which covers the asset as the asset has been verified against the checksum.
+func verifySignature(release releaseInfo, asset, checksumAsset assetInfo, checksumContent, output string, sig Signature) error {
+	exts, ok := signatureExtensions[sig.Method]
+	if !ok {
+		return fmt.Errorf("unknown signature verification method %s", sig.Method)
+	}
+	for _, name := range []string{asset.Name, checksumAsset.Name} {
+		if name == "" {
+			continue
+		}
+		for _, ext := range exts {
+			sigAsset, found := findAsset(release, name+ext)
+			if !found {
+				continue
+			}
+			log.Printf("Verifying %s using the signature %s ...", name, sigAsset.Name)
+			sigFile, err := utils.DownloadFile(sigAsset.BrowserDownloadURL, sigAsset.Name, true)
+			if err != nil {
+				return err
+			}
+			path := output
+			if name != asset.Name {
+				path = filepath.Join(filepath.Dir(sigFile), name)
+				//nolint:mnd // readable
+				if err := os.WriteFile(path, []byte(checksumContent), 0o600); err != nil && !utils.IsDryRun() {
+					return fmt.Errorf("failed to write the checksum asset %s: %w", name, err)
+				}
+			}
+			return utils.VerifySignature(sig.Method, path, sigFile, sig.Key)
+		}
+	}
+	return fmt.Errorf("no %s signature is published for the asset %s of the release %s",
+		sig.Method, asset.Name, release.TagName)
+}
+
+// DownloadRelease downloads a release from GitHub, GitLab, Gitea/Forgejo or a directory index
+// and verifies its checksum and signature.
+// @param repo: The repo, e.g., user_name/repo_name for GitHub or the URL of the repo.
+// @param ver: A version constraint, or an empty string for the latest release.
+// @param keywords: Keywords (by kernel/OS/architecture) which the name of the asset must contain.
+// @param keywordsExclude: Keywords which the name of the asset must not contain.
+// @param output: The output path for the downloaded asset.
+// It might be an empty string if opts.InstallBin is specified.
+// @param opts: Optional settings.
+func DownloadRelease(
+	repo, ver string, keywords map[string][]string, keywordsExclude []string, output string, opts ReleaseOptions,
+) error {
+	providerName, provider, err := getReleaseProvider(opts.Provider, repo)
+	if err != nil {
+		return err
+	}
+	keywords_, err := utils.BuildKernelOSKeywords(keywords)
+	if err != nil {
+		return err
+	}
+	utils.Debugf(`Download release from the repository %s satisfying the following conditions:
 	Version: %s
 	Contains: %s
 	Does not contain: %s
+	Pattern: %s
 	Write to: %s
-	`, repo, ver, strings.Join(keywords_, ", "), strings.Join(keywordsExclude, ", "), output)
-	// form the release URL
-	releaseURL := getReleaseURL(repo)
-	log.Printf("Release URL: %s\n", releaseURL)
-	var release releaseInfo
-	if ver == "" {
-		release = getLatestRelease(releaseURL)
-	} else {
-		release = filterReleases(releaseURL, ver)
+	`, repo, ver, strings.Join(keywords_, ", "), strings.Join(keywordsExclude, ", "), opts.Pattern, output)
+	release, err := provider.Release(repo, ver)
+	if err != nil {
+		return err
 	}
-	// parse browser download url
-	var browserDownloadURL string
-	for _, asset := range release.Assets {
-		if assetNameContainKeywords(asset.Name, keywords_, keywordsExclude) {
-			log.Printf("Asset %s is matched.", asset.Name)
-			browserDownloadURL = asset.BrowserDownloadURL
-			break
-		} else {
-			log.Printf("Asset %s is not matched.", asset.Name)
-		}
+	prefer := opts.Prefer
+	if len(prefer) == 0 {
+		prefer = defaultAssetPreferences
 	}
-	// download the asset
-	_, err := utils.DownloadFile(browserDownloadURL, output, false)
+	candidates, matched, err := selectAssets(release, keywords_, keywordsExclude, opts.Pattern, prefer)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	if opts.List {
+		return printAssets(os.Stdout, release, candidates, matched)
+	}
+	if len(matched) == 0 {
+		names := make([]string, 0, len(candidates))
+		for _, asset := range candidates {
+			names = append(names, asset.Name)
+		}
+		return &utils.NoAssetMatchedError{
+			Release:    release.TagName,
+			Criteria:   describeAssetCriteria(keywords_, keywordsExclude, opts.Pattern),
+			Candidates: names,
+		}
+	}
+	for idx, asset := range matched {
+		utils.Debugf("Asset %s is matched (rank %d).", asset.Name, idx+1)
+	}
+	if !opts.All {
+		matched = matched[:1]
 	}
+	if output == "" {
+		if len(opts.InstallBin) == 0 {
+			return errors.New("the output path is required unless executables are installed")
+		}
+		dir, err := utils.CreateTempDir("")
+		if err != nil {
+			return err
+		}
+		defer os.RemoveAll(dir)
+		output = utils.IfElseString(opts.All, dir, filepath.Join(dir, matched[0].Name))
+	}
+	if opts.All {
+		if err := utils.MkdirAll(output, ""); err != nil {
+			return err
+		}
+	}
+	// assets are downloaded concurrently but installed one by one
+	paths := make([]string, 0, len(matched))
+	tasks := make([]func() error, 0, len(matched))
+	for _, asset := range matched {
+		path := utils.IfElseString(opts.All, filepath.Join(output, asset.Name), output)
+		paths = append(paths, path)
+		tasks = append(tasks, func() error {
+			return downloadAsset(release, asset, path, opts.Signature)
+		})
+	}
+	if err := utils.RunParallel(tasks...); err != nil {
+		return err
+	}
+	if len(opts.InstallBin) > 0 {
+		binDir := utils.IfElseString(opts.BinDir == "", "/usr/local/bin", opts.BinDir)
+		// with --all, each name has to be found in one of the assets (instead of in each of them)
+		if _, err := utils.InstallBinariesFromArchives(paths, opts.InstallBin, binDir); err != nil {
+			return err
+		}
+	}
+	utils.RecordInstallMethod(providerName)
+	utils.RecordVersion(release.TagName)
+	utils.RecordRelease(utils.ReleaseRecord{
+		Repo:       repo,
+		Provider:   providerName,
+		Version:    release.TagName,
+		Constraint: ver,
+	})
+	return nil
+}
+
+// describeAssetCriteria describes criteria of matching assets for error messages.
+func describeAssetCriteria(keywords, keywordsExclude []string, pattern string) string {
+	criteria := []string{}
+	if len(keywords) > 0 {
+		criteria = append(criteria, "the keywords "+strings.Join(keywords, ", "))
+	}
+	if len(keywordsExclude) > 0 {
+		criteria = append(criteria, "excluding the keywords "+strings.Join(keywordsExclude, ", "))
+	}
+	if pattern != "" {
+		criteria = append(criteria, "the pattern "+pattern)
+	}
+	if len(criteria) == 0 {
+		return "anything"
+	}
+	return strings.Join(criteria, " and ")
+}
+
+// downloadAsset downloads an asset of a release and verifies its checksum and signature.
+// @param release: The release containing the asset.
+// @param asset: The asset to download.
+// @param output: The output path for the downloaded asset.
+// @param sig: How to verify the signature of the asset. Its zero value skips verifying signatures.
+func downloadAsset(release releaseInfo, asset assetInfo, output string, sig Signature) error {
+	checksumAsset, checksumContent, checksum := findChecksum(release, asset)
+	if checksum == "" {
+		if utils.IsChecksumRequired() {
+			return &utils.MissingChecksumError{Asset: asset.Name, Release: release.TagName}
+		}
+		utils.Warnf("No checksum is published for the asset %s, so it is not verified.", asset.Name)
+	}
+	// download the asset unless a file with the same checksum has been cached
+	if checksum == "" || !utils.CopyFromDownloadCache(checksum, output) {
+		if _, err := utils.DownloadFile(asset.BrowserDownloadURL, output, false); err != nil {
+			return err
+		}
+	}
+	if checksum != "" {
+		log.Printf("Verifying %s against the checksum in %s ...", asset.Name, checksumAsset.Name)
+		if err := utils.VerifySha256(output, checksum); err != nil {
+			utils.RemoveUnverified(output)
+			return err
+		}
+	}
+	if sig.Method != "" {
+		if err := verifySignature(release, asset, checksumAsset, checksumContent, output, sig); err != nil {
+			utils.RemoveUnverified(output)
+			return err
+		}
+	}
+	return nil
 }
 
 var downloadGitHubReleaseCmd = &cobra.Command{
 	Use:     "download_github_release",
 	Aliases: []string{"download_github", "from_github", "github_release"},
-	Short:   "Download file from GitHub.",
+	Short:   "Download file from GitHub (or GitLab, Gitea/Forgejo and directory indexes).",
 	//Args:  cobra.ExactArgs(1),
-	Run: downloadGitHubReleaseArgs,
+	RunE: downloadGitHubReleaseArgs,
 }
 
 func ConfigDownloadGitHubReleaseCmd(rootCmd *cobra.Command) {
-	downloadGitHubReleaseCmd.Flags().StringP("repo", "r", "", "A GitHub repo of the form 'user_name/repo_name'.")
+	downloadGitHubReleaseCmd.Flags().StringP("repo", "r", "",
+		"A GitHub repo of the form 'user_name/repo_name', or the URL of a repo (or of a directory index).")
 	err := downloadGitHubReleaseCmd.MarkFlagRequired("repo")
 	if err != nil {
 		log.Fatal("ERROR - ", err)
@@ -160,14 +476,27 @@ func ConfigDownloadGitHubReleaseCmd(rootCmd *cobra.Command) {
 	downloadGitHubReleaseCmd.Flags().StringP("version", "v", "", "The version of the release.")
 	downloadGitHubReleaseCmd.Flags().StringSliceP("kwd", "k", []string{}, "Keywords that the asset's name contains.")
 	downloadGitHubReleaseCmd.Flags().StringSliceP("KWD", "K", []string{}, "Keywords that the asset's name must not contain.")
-	err = downloadGitHubReleaseCmd.MarkFlagRequired("kwd")
-	if err != nil {
-		log.Fatal("ERROR - ", err)
-	}
-	downloadGitHubReleaseCmd.Flags().StringP("output", "o", "", "The output path for the downloaded asset.")
-	err = downloadGitHubReleaseCmd.MarkFlagRequired("output")
-	if err != nil {
-		log.Fatal("ERROR - ", err)
-	}
+	downloadGitHubReleaseCmd.Flags().StringP("pattern", "p", "",
+		"A glob pattern (or a regular expression enclosed in slashes) that the asset's name must match. "+
+			"The placeholders {os}, {arch} and {goarch} are replaced by names of the current OS and architecture.")
+	downloadGitHubReleaseCmd.Flags().StringSlice("prefer", []string{},
+		"Substrings of names in the order of preference to rank matching assets (default "+
+			strings.Join(defaultAssetPreferences, ",")+").")
+	downloadGitHubReleaseCmd.Flags().Bool("all", false, "Download all matching assets into the directory specified by --output.")
+	downloadGitHubReleaseCmd.Flags().Bool("list", false, "List assets of the release (with ranks of matching ones) instead of downloading.")
+	downloadGitHubReleaseCmd.Flags().StringP("output", "o", "",
+		"The output path for the downloaded asset (or the output directory if --all is specified).")
+	downloadGitHubReleaseCmd.Flags().StringSlice("install-bin", []string{},
+		"Names (or glob patterns of names) of executables to extract from the downloaded archive "+
+			"(tar.gz, tar.xz, tar.zst, zip, deb, rpm or a plain binary) and install into --bin-dir.")
+	downloadGitHubReleaseCmd.Flags().String("bin-dir", "/usr/local/bin",
+		"The directory to install executables specified by --install-bin into.")
+	downloadGitHubReleaseCmd.Flags().String("signature", "",
+		"Verify the signature of the asset (or of its checksum file) using minisign, gpg or cosign.")
+	downloadGitHubReleaseCmd.Flags().String("signature-key", "",
+		"The public key (or the path of it) to verify signatures with minisign or cosign.")
+	downloadGitHubReleaseCmd.Flags().String("provider", "", fmt.Sprintf(
+		"The release provider (%s). It is detected from the host of the repo by default.",
+		strings.Join(releaseProviderNames(), ", ")))
 	rootCmd.AddCommand(downloadGitHubReleaseCmd)
 }
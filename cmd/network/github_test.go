@@ -0,0 +1,41 @@
+package network
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"legendu.net/icon/internal/testutil"
+)
+
+func TestDownloadReleaseInstallBinAll(t *testing.T) {
+	binary := []byte("\x7fELF binary")
+	sum := sha256.Sum256(binary)
+	tests := []struct {
+		name    string
+		bins    []string
+		want    []string
+		wantErr bool
+	}{
+		{"binary", []string{"tool"}, []string{"sudo true", "sudo install -m 755 */tool /usr/local/bin/tool"}, false},
+		{"missing", []string{"tool", "other"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			env.HTTP.GitHubRelease("owner/tool", "v1.0.0", map[string][]byte{
+				"tool-linux-amd64":        binary,
+				"tool-linux-amd64.sha256": []byte(hex.EncodeToString(sum[:]) + "  tool-linux-amd64\n"),
+			})
+			err := DownloadRelease("owner/tool", "", nil, nil, "", ReleaseOptions{
+				Pattern:    "tool-linux-amd64*",
+				All:        true,
+				InstallBin: tt.bins,
+			})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want an error: %t", err, tt.wantErr)
+			}
+			env.AssertCommands(tt.want...)
+		})
+	}
+}
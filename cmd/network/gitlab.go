@@ -0,0 +1,75 @@
+package network
+
+import (
+	"fmt"
+	"net/url"
+
+	"legendu.net/icon/utils"
+)
+
+// gitlabProvider looks up releases on GitLab (gitlab.com or a self-managed instance).
+// Requests are authenticated with the environment variable GITLAB_TOKEN if set.
+type gitlabProvider struct{}
+
+type gitlabLink struct {
+	Name           string `json:"name"`
+	URL            string `json:"url"`
+	DirectAssetURL string `json:"direct_asset_url"`
+}
+
+type gitlabRelease struct {
+	TagName string `json:"tag_name"`
+	Assets  struct {
+		Links []gitlabLink `json:"links"`
+	} `json:"assets"`
+}
+
+func (r gitlabRelease) toReleaseInfo() releaseInfo {
+	release := releaseInfo{TagName: r.TagName}
+	for _, link := range r.Assets.Links {
+		downloadURL := link.DirectAssetURL
+		if downloadURL == "" {
+			downloadURL = link.URL
+		}
+		release.Assets = append(release.Assets, assetInfo{Name: link.Name, BrowserDownloadURL: downloadURL})
+	}
+	return release
+}
+
+func gitlabAPIConfig() apiConfig {
+	config := apiConfig{
+		headers: map[string]string{},
+		hint:    "set GITLAB_TOKEN to raise the rate limit",
+	}
+	if token, source := tokenFromEnv("GITLAB_TOKEN"); token != "" {
+		config.headers["PRIVATE-TOKEN"] = token
+		config.tokenSource = source
+	}
+	return config
+}
+
+func (gitlabProvider) Release(repo, ver string) (releaseInfo, error) {
+	host, path := parseRepo(repo)
+	if host == "" {
+		host = "gitlab.com"
+	}
+	// GitLab identifies a project by its URL-encoded path (which might contain subgroups)
+	releasesURL := fmt.Sprintf("https://%s/api/v4/projects/%s/releases", host, url.PathEscape(path))
+	utils.Debugf("Release URL: %s\n", releasesURL)
+	if ver == "" {
+		var release gitlabRelease
+		if err := getAPIAsJSON(releasesURL+"/permalink/latest", gitlabAPIConfig(), &release); err != nil {
+			return releaseInfo{}, err
+		}
+		return release.toReleaseInfo(), nil
+	}
+	var releases []gitlabRelease
+	if err := getAPIAsJSON(releasesURL, gitlabAPIConfig(), &releases); err != nil {
+		return releaseInfo{}, err
+	}
+	infos := make([]releaseInfo, 0, len(releases))
+	for _, release := range releases {
+		infos = append(infos, release.toReleaseInfo())
+	}
+	return matchRelease(infos, ver)
+}
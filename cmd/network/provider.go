@@ -0,0 +1,109 @@
+package network
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/mcuadros/go-version"
+)
+
+// releaseProvider looks up releases of repos hosted on a service, e.g., GitHub or GitLab.
+type releaseProvider interface {
+	// Release finds the latest release of a repo, or the latest release matching a version constraint.
+	// @param repo: The repo, e.g., user_name/repo_name or the URL of the repo.
+	// @param ver: A version constraint, or an empty string for the latest release.
+	// return: The release.
+	Release(repo, ver string) (releaseInfo, error)
+}
+
+// releaseProviders are the supported release providers by name.
+var releaseProviders = map[string]releaseProvider{
+	"github":   githubProvider{},
+	"gitlab":   gitlabProvider{},
+	"gitea":    giteaProvider{},
+	"forgejo":  giteaProvider{},
+	"codeberg": giteaProvider{defaultHost: "codeberg.org"},
+	"dir":      dirIndexProvider{},
+}
+
+// releaseProviderNames returns names of the supported release providers in order.
+func releaseProviderNames() []string {
+	names := make([]string, 0, len(releaseProviders))
+	for name := range releaseProviders {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// parseRepo parses the host and the path of a repo.
+// @param repo: The repo, e.g., user_name/repo_name, https://host/user_name/repo_name or git@host:user_name/repo_name.git.
+// return: The host (or an empty string if not specified) and the path of the repo.
+func parseRepo(repo string) (string, string) {
+	repo = strings.TrimSuffix(strings.TrimSuffix(repo, "/"), ".git")
+	switch {
+	case strings.HasPrefix(repo, "https://") || strings.HasPrefix(repo, "http://"):
+		u, err := url.Parse(repo)
+		if err != nil {
+			return "", repo
+		}
+		return u.Host, strings.Trim(u.Path, "/")
+	case strings.HasPrefix(repo, "git@"):
+		host, path, _ := strings.Cut(strings.TrimPrefix(repo, "git@"), ":")
+		return host, path
+	}
+	return "", repo
+}
+
+// detectReleaseProvider detects the release provider of a repo from its host.
+// Repos without a host are on GitHub
+// and URLs of unknown hosts are treated as directory indexes.
+// @param repo: The repo, e.g., user_name/repo_name or the URL of the repo.
+// return: The name of the release provider.
+func detectReleaseProvider(repo string) string {
+	host, _ := parseRepo(repo)
+	switch {
+	case host == "" || host == "github.com" || host == "api.github.com":
+		return "github"
+	case strings.Contains(host, "gitlab"):
+		return "gitlab"
+	case host == "codeberg.org":
+		return "codeberg"
+	case strings.Contains(host, "gitea"):
+		return "gitea"
+	case strings.Contains(host, "forgejo"):
+		return "forgejo"
+	}
+	return "dir"
+}
+
+// getReleaseProvider gets the release provider of a repo.
+// @param name: The name of the release provider, or an empty string to detect it from the repo.
+// @param repo: The repo, e.g., user_name/repo_name or the URL of the repo.
+// return: The name of the release provider and the release provider.
+func getReleaseProvider(name, repo string) (string, releaseProvider, error) {
+	if name == "" {
+		name = detectReleaseProvider(repo)
+	}
+	provider, ok := releaseProviders[name]
+	if !ok {
+		return "", nil, fmt.Errorf("unknown release provider %s (supported: %s)", name, strings.Join(releaseProviderNames(), ", "))
+	}
+	return name, provider, nil
+}
+
+// matchRelease finds the first release matching a version constraint.
+// @param releases: Releases ordered from the newest to the oldest.
+// @param constraint: A version constraint.
+// return: The newest release matching the version constraint.
+func matchRelease(releases []releaseInfo, constraint string) (releaseInfo, error) {
+	c := version.NewConstrainGroupFromString(constraint)
+	for _, release := range releases {
+		if c.Match(release.TagName) {
+			return release, nil
+		}
+	}
+	return releaseInfo{}, fmt.Errorf("no release matching the version constraint %s is found", constraint)
+}
@@ -9,23 +9,38 @@ import (
 	"legendu.net/icon/utils"
 )
 
-var sshHome = utils.NormalizePath("~/.ssh")
+const sshHome = "~/.ssh"
 
 // Install and configure SSH client.
-func SSHClient(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
+func SSHClient(cmd *cobra.Command, _ []string) error {
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
+	if config {
+		if err := icon.FetchConfigData(false, ""); err != nil {
+			return err
+		}
+		backup, err := utils.ShouldBackup(cmd)
+		if err != nil {
+			return err
+		}
 		dst := filepath.Join(sshHome, "config")
-		utils.BackupOrRemove(dst, utils.ShouldBackup(cmd))
-		utils.CopyFile("~/.config/icon-data/ssh/client/config", dst)
-		utils.MkdirAll("~/.local/share/ssh", "700")
-		utils.Chmod600(sshHome)
+		if err := utils.BackupOrRemove(dst, backup); err != nil {
+			return err
+		}
+		if err := utils.CopyFile("~/.config/icon-data/ssh/client/config", dst); err != nil {
+			return err
+		}
+		if err := utils.MkdirAll("~/.local/share/ssh", "700"); err != nil {
+			return err
+		}
+		if err := utils.Chmod600(sshHome); err != nil {
+			return err
+		}
 		log.Print("The permissions of ~/.ssh and its contents are correctly set.\n")
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-	}
+	return nil
 }
 
 var sshClientCmd = &cobra.Command{
@@ -33,7 +48,7 @@ var sshClientCmd = &cobra.Command{
 	Aliases: []string{"sshc"},
 	Short:   "Install and configure SSH client.",
 	//Args:  cobra.ExactArgs(1),
-	Run: SSHClient,
+	RunE: SSHClient,
 }
 
 func ConfigSSHClientCmd(rootCmd *cobra.Command) {
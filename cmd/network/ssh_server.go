@@ -5,35 +5,38 @@ import (
 	"legendu.net/icon/utils"
 )
 
+// sshServerPackages are packages of SSH server for package managers.
+var sshServerPackages = utils.Packages{
+	"apt":    {"openssh-server", "fail2ban"},
+	"dnf":    {"openssh-server", "fail2ban"},
+	"pacman": {"openssh", "fail2ban"},
+	"zypper": {"openssh-server", "fail2ban"},
+	"apk":    {"openssh-server", "fail2ban"},
+}
+
 // Install and configure SSH server.
-func sshServer(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		if utils.IsDebianUbuntuSeries() {
-			command := utils.Format(`{prefix} apt-get {yesStr} update \
-					&& {prefix} apt-get {yesStr} install openssh-server fail2ban`, map[string]string{
-				"prefix": utils.GetCommandPrefix(
-					true,
-					map[string]uint32{},
-				),
-				"yesStr": utils.BuildYesFlag(cmd),
-			})
-			utils.RunCmd(command)
-		}
+func sshServer(cmd *cobra.Command, _ []string) error {
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "config") {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		if utils.IsDebianUbuntuSeries() {
-			command := utils.Format("{prefix} apt-get {yesStr} purge openssh-server fail2ban", map[string]string{
-				"prefix": utils.GetCommandPrefix(
-					true,
-					map[string]uint32{},
-				),
-				"yesStr": utils.BuildYesFlag(cmd),
-			})
-			utils.RunCmd(command)
+	if install {
+		if err := utils.InstallPackages("SSH server", sshServerPackages, yes); err != nil {
+			return err
 		}
 	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return utils.RemovePackages("SSH server", sshServerPackages, yes)
+	}
+	return nil
 }
 
 var sshServerCmd = &cobra.Command{
@@ -41,7 +44,7 @@ var sshServerCmd = &cobra.Command{
 	Aliases: []string{"sshs"},
 	Short:   "Install and configure SSH server.",
 	//Args:  cobra.ExactArgs(1),
-	Run: sshServer,
+	RunE: sshServer,
 }
 
 func ConfigSSHServerCmd(rootCmd *cobra.Command) {
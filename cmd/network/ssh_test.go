@@ -0,0 +1,76 @@
+package network
+
+import (
+	"io/fs"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/internal/testutil"
+)
+
+var testRoot = sync.OnceValue(func() *cobra.Command {
+	root := &cobra.Command{Use: "icon"}
+	ConfigSSHClientCmd(root)
+	ConfigSSHServerCmd(root)
+	return root
+})
+
+func TestSSHServer(t *testing.T) {
+	tests := []struct {
+		distro testutil.Distro
+		args   []string
+		want   []string
+	}{
+		{testutil.Ubuntu, []string{"ssh_server", "--install", "--yes"}, []string{
+			"sudo true",
+			"sudo apt-get -y update",
+			"sudo apt-get -y install openssh-server fail2ban",
+		}},
+		{testutil.Arch, []string{"ssh_server", "--install", "--yes"}, []string{
+			"sudo true",
+			"sudo pacman -S --needed --noconfirm openssh fail2ban",
+		}},
+		{testutil.Fedora, []string{"ssh_server", "--uninstall", "--yes"}, []string{
+			"sudo true",
+			"sudo dnf -y remove openssh-server fail2ban",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.distro.Name+"/"+tt.args[1], func(t *testing.T) {
+			env := testutil.New(t, tt.distro)
+			if err := env.Run(testRoot(), tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands(tt.want...)
+		})
+	}
+}
+
+func TestSSHClientConfig(t *testing.T) {
+	env := testutil.New(t, testutil.Ubuntu)
+	env.FakeConfigData(map[string]string{"ssh/client/config": "Host *\n    ServerAliveInterval 60\n"})
+	env.WriteFile("~/.ssh/id_ed25519", "key\n")
+	if err := env.Run(testRoot(), "ssh_client", "--config"); err != nil {
+		t.Fatal(err)
+	}
+	env.AssertCommands()
+	if got, want := env.ReadFile("~/.ssh/config"), "Host *\n    ServerAliveInterval 60\n"; got != want {
+		t.Errorf("~/.ssh/config = %q, want %q", got, want)
+	}
+	for path, want := range map[string]fs.FileMode{
+		"/.ssh":             0o700,
+		"/.ssh/config":      0o600,
+		"/.ssh/id_ed25519":  0o600,
+		"/.local/share/ssh": 0o700,
+	} {
+		info, err := os.Stat(env.FS.RealPath(testutil.Home + path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("the mode of ~%s is %o, want %o", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,175 @@
+package network
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/cmd/icon"
+	"legendu.net/icon/utils"
+)
+
+// releaseStatus compares a release which a tool was installed from with the latest releases.
+type releaseStatus struct {
+	tool    string
+	release utils.ReleaseRecord
+	// wanted is the latest release satisfying the version constraint of the installation.
+	wanted string
+	// latest is the latest release.
+	latest string
+}
+
+// isOutdated checks whether a newer release satisfying the version constraint is available.
+func (s releaseStatus) isOutdated() bool {
+	return s.wanted != "" && s.wanted != s.release.Version
+}
+
+// checkReleases looks up the latest releases of repos which tools were installed from.
+// Failures of looking up a repo are logged and the repo is reported with unknown versions.
+// @param rootCmd: The root command.
+// @param tools: Names (or aliases) of tools to check, or an empty slice to check all tools.
+// return: Statuses of releases grouped by tool and the states of the tools.
+func checkReleases(rootCmd *cobra.Command, tools []string) ([]releaseStatus, map[string]*utils.ToolState, error) {
+	states, err := utils.ReadAllToolStates()
+	if err != nil {
+		return nil, nil, err
+	}
+	names := make([]string, 0, len(tools))
+	for _, tool := range tools {
+		names = append(names, icon.ResolveToolName(rootCmd, tool))
+	}
+	statuses := []releaseStatus{}
+	found := map[string]*utils.ToolState{}
+	for _, state := range states {
+		if len(names) > 0 && !slices.Contains(names, state.Name) {
+			continue
+		}
+		found[state.Name] = state
+		for _, release := range state.Releases {
+			status := releaseStatus{tool: state.Name, release: release}
+			_, provider, err := getReleaseProvider(release.Provider, release.Repo)
+			if err != nil {
+				utils.Warnf("Failed to check releases of %s: %v\n", release.Repo, err)
+				statuses = append(statuses, status)
+				continue
+			}
+			latest, err := provider.Release(release.Repo, "")
+			if err != nil {
+				utils.Warnf("Failed to check releases of %s: %v\n", release.Repo, err)
+				statuses = append(statuses, status)
+				continue
+			}
+			status.latest, status.wanted = latest.TagName, latest.TagName
+			if release.Constraint != "" {
+				wanted, err := provider.Release(release.Repo, release.Constraint)
+				if err != nil {
+					utils.Warnf("Failed to check releases of %s: %v\n", release.Repo, err)
+				}
+				status.wanted = wanted.TagName
+			}
+			statuses = append(statuses, status)
+		}
+	}
+	for _, name := range names {
+		if _, ok := found[name]; !ok {
+			return nil, nil, fmt.Errorf("%s has not been installed by icon", name)
+		}
+	}
+	return statuses, found, nil
+}
+
+// List tools installed from releases which have newer releases.
+func outdated(cmd *cobra.Command, args []string) error {
+	all, err := utils.GetBoolFlag(cmd, "all")
+	if err != nil {
+		return err
+	}
+	statuses, _, err := checkReleases(cmd.Root(), args)
+	if err != nil {
+		return err
+	}
+	if !all {
+		statuses = slices.DeleteFunc(statuses, func(s releaseStatus) bool { return !s.isOutdated() })
+	}
+	if len(statuses) == 0 {
+		fmt.Println("All tools installed from releases are up to date.")
+		return nil
+	}
+	//nolint:mnd // readable
+	writer := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(writer, "TOOL\tREPO\tCONSTRAINT\tINSTALLED\tWANTED\tLATEST")
+	for _, s := range statuses {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			s.tool,
+			s.release.Repo,
+			utils.IfElseString(s.release.Constraint == "", "-", s.release.Constraint),
+			s.release.Version,
+			utils.IfElseString(s.wanted == "", "?", s.wanted),
+			utils.IfElseString(s.latest == "", "?", s.latest),
+		)
+	}
+	return writer.Flush()
+}
+
+// Reinstall tools which have newer releases satisfying their version constraints.
+func upgrade(cmd *cobra.Command, args []string) error {
+	statuses, states, err := checkReleases(cmd.Root(), args)
+	if err != nil {
+		return err
+	}
+	tools := []string{}
+	for _, s := range statuses {
+		if s.isOutdated() && !slices.Contains(tools, s.tool) {
+			log.Printf("%s: %s %s -> %s\n", s.tool, s.release.Repo, s.release.Version, s.wanted)
+			tools = append(tools, s.tool)
+		}
+	}
+	if len(tools) == 0 {
+		fmt.Println("All tools installed from releases are up to date.")
+		return nil
+	}
+	errs := []error{}
+	for _, tool := range tools {
+		toolCmd := icon.FindToolCmd(cmd.Root(), tool)
+		if toolCmd == nil {
+			errs = append(errs, fmt.Errorf("unknown tool %s", tool))
+			continue
+		}
+		log.Printf("Upgrading %s ...\n", tool)
+		if err := icon.RunTool(toolCmd, icon.RecordedToolSpec(states[tool])); err != nil {
+			errs = append(errs, fmt.Errorf("failed to upgrade %s: %w", tool, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+var outdatedCmd = &cobra.Command{
+	Use:   "outdated [tool...]",
+	Short: "List tools installed from releases which have newer releases.",
+	Long: `List tools installed from releases (e.g., on GitHub) which have newer releases.
+WANTED is the latest release satisfying the version constraint (e.g., --version) the tool was installed with
+and LATEST is the latest release.`,
+	RunE: outdated,
+}
+
+var upgradeCmd = &cobra.Command{
+	Use:   "upgrade [tool...]",
+	Short: "Reinstall tools which have newer releases satisfying their version constraints.",
+	Long: `Reinstall tools installed from releases (e.g., on GitHub) which are behind the latest releases
+satisfying their version constraints.
+Tools are reinstalled with the flags (including version constraints) they were last installed with.`,
+	RunE: upgrade,
+}
+
+func ConfigOutdatedCmd(rootCmd *cobra.Command) {
+	outdatedCmd.Flags().BoolP("all", "a", false, "List up-to-date tools too.")
+	rootCmd.AddCommand(outdatedCmd)
+}
+
+func ConfigUpgradeCmd(rootCmd *cobra.Command) {
+	rootCmd.AddCommand(upgradeCmd)
+}
@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"log"
+	"log/slog"
 	"os"
 	"runtime"
 
@@ -17,14 +18,62 @@ import (
 	"legendu.net/icon/cmd/network"
 	"legendu.net/icon/cmd/shell"
 	"legendu.net/icon/cmd/virtualization"
+	"legendu.net/icon/utils"
 )
 
 var rootCmd = &cobra.Command{
 	Use:              "icon",
 	Short:            "Install and configure tools.",
 	TraverseChildren: true,
+	// errors returned by commands are not caused by wrong usage
+	SilenceUsage: true,
+	// errors are logged (see Execute) so that they are formatted as other logs
+	SilenceErrors: true,
+	PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+		dryRun, err := utils.GetBoolFlag(cmd, "dry-run")
+		if err != nil {
+			return err
+		}
+		utils.SetDryRun(dryRun)
+		verbose, err := utils.GetBoolFlag(cmd, "verbose")
+		if err != nil {
+			return err
+		}
+		quiet, err := utils.GetBoolFlag(cmd, "quiet")
+		if err != nil {
+			return err
+		}
+		logFormat, err := utils.GetStringFlag(cmd, "log-format")
+		if err != nil {
+			return err
+		}
+		if closeLog, err = utils.SetupLogging(verbose, quiet, logFormat); err != nil {
+			return err
+		}
+		platform, err := utils.GetStringFlag(cmd, "platform-override")
+		if err != nil {
+			return err
+		}
+		if err := utils.SetPlatformOverride(platform); err != nil {
+			return err
+		}
+		requireChecksum, err := utils.GetBoolFlag(cmd, "require-checksum")
+		if err != nil {
+			return err
+		}
+		utils.SetRequireChecksum(requireChecksum)
+		jobs, err := utils.GetIntFlag(cmd, "jobs")
+		if err != nil {
+			return err
+		}
+		utils.SetJobs(jobs)
+		return utils.LoadConfig()
+	},
 }
 
+// closeLog closes the log file of the run.
+var closeLog = func() error { return nil }
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -47,8 +96,18 @@ func Execute() {
 	dev.ConfigDenoCmd(rootCmd)
 	filesystem.ConfigRipCmd(rootCmd)
 	filesystem.ConfigDropboxCmd(rootCmd)
+	icon.ConfigApplyCmd(rootCmd)
+	icon.ConfigBackupsCmd(rootCmd)
+	icon.ConfigBundleCmd(rootCmd)
+	icon.ConfigCacheCmd(rootCmd)
 	icon.ConfigCompletionCmd(rootCmd)
 	icon.ConfigDataCmd(rootCmd)
+	icon.ConfigDiffCmd(rootCmd)
+	icon.ConfigDoctorCmd(rootCmd)
+	icon.ConfigInstallCmd(rootCmd)
+	icon.ConfigListCmd(rootCmd)
+	icon.ConfigRollbackCmd(rootCmd)
+	icon.ConfigStatusCmd(rootCmd)
 	icon.ConfigUpdateCmd(rootCmd)
 	icon.ConfigVersionCmd(rootCmd)
 	ide.ConfigFirenvimCmd(rootCmd)
@@ -64,8 +123,10 @@ func Execute() {
 	misc.ConfigKeepassXCCmd(rootCmd)
 	misc.ConfigKeyboardCmd(rootCmd)
 	network.ConfigDownloadGitHubReleaseCmd(rootCmd)
+	network.ConfigOutdatedCmd(rootCmd)
 	network.ConfigSSHClientCmd(rootCmd)
 	network.ConfigSSHServerCmd(rootCmd)
+	network.ConfigUpgradeCmd(rootCmd)
 	shell.ConfigAlacrittyCmd(rootCmd)
 	shell.ConfigAtuinCmd(rootCmd)
 	shell.ConfigBashItCmd(rootCmd)
@@ -77,8 +138,38 @@ func Execute() {
 	shell.ConfigZellijCmd(rootCmd)
 	virtualization.ConfigKVMCmd(rootCmd)
 	virtualization.ConfigDockerCmd(rootCmd)
+	for _, cmd := range rootCmd.Commands() {
+		if icon.IsToolCmd(cmd) {
+			icon.TrackToolCmd(cmd)
+		}
+	}
 
+	rootCmd.PersistentFlags().Bool(
+		"dry-run", false, "Print the plan (commands to run and paths to change) instead of executing it.")
+	rootCmd.PersistentFlags().Bool(
+		"require-checksum", false, "Fail instead of using downloads which cannot be verified against published checksums.")
+	rootCmd.PersistentFlags().Int(
+		"jobs", utils.DefaultJobs, "The maximum number of concurrent downloads and installs.")
+	rootCmd.PersistentFlags().Bool("verbose", false, "Print debug logs, e.g., every command run with its duration.")
+	rootCmd.PersistentFlags().Bool(
+		"quiet", false, "Print warnings and errors only, and the output of commands only if they fail.")
+	rootCmd.PersistentFlags().String("log-format", utils.LogFormatText, "The format (text or json) of logs.")
+	rootCmd.PersistentFlags().String("platform-override", "",
+		"Install and configure tools for another platform (<distro>[:<version>][/<arch>], e.g., fedora:41/arm64 or macos), "+
+			"which is useful with --dry-run. It overrides the environment variable "+utils.PlatformEnv+".")
 	err := rootCmd.Execute()
+	if utils.IsDryRun() {
+		utils.PrintPlan(os.Stdout)
+	}
+	if err != nil {
+		slog.Error(err.Error())
+	}
+	if file := utils.LogFile(); file != "" {
+		slog.Debug("The log is written into " + file + ".")
+	}
+	if errClose := closeLog(); errClose != nil {
+		log.Printf("Failed to close the log file: %v\n", errClose)
+	}
 	if err != nil {
 		os.Exit(1)
 	}
@@ -5,54 +5,68 @@ import (
 	"legendu.net/icon/utils"
 )
 
+// alacrittyBuildDependencies are packages required to build Alacritty on Linux for package managers.
+var alacrittyBuildDependencies = utils.Packages{
+	"apt": {
+		"cmake", "pkg-config", "python3",
+		"libfreetype6-dev", "libfontconfig1-dev", "libxcb-xfixes0-dev", "libxkbcommon-dev",
+	},
+	"dnf":    {"cmake", "g++", "freetype-devel", "fontconfig-devel", "libxcb-devel", "libxkbcommon-devel"},
+	"pacman": {"cmake", "pkgconf", "python", "freetype2", "fontconfig", "libxcb", "libxkbcommon"},
+	"zypper": {"cmake", "gcc-c++", "freetype-devel", "fontconfig-devel", "libxcb-devel", "libxkbcommon-devel"},
+	"apk":    {"cmake", "pkgconf", "python3", "freetype-dev", "fontconfig-dev", "libxcb-dev", "libxkbcommon-dev"},
+}
+
+func installAlacritty(yes bool) error {
+	if !utils.IsLinux() {
+		return utils.RunCmd("brew install --cask alacritty")
+	}
+	prefix, err := utils.GetCommandPrefix(true, map[string]uint32{})
+	if err != nil {
+		return err
+	}
+	if err := utils.InstallPackages("Alacritty", alacrittyBuildDependencies, yes); err != nil {
+		return err
+	}
+	if err := utils.RunCmd("cargo install alacritty"); err != nil {
+		return err
+	}
+	command := utils.Format(`{prefix} curl -sSL -o /usr/share/pixmaps/Alacritty.svg \
+			https://raw.githubusercontent.com/alacritty/alacritty/master/extra/logo/alacritty-term.svg \
+		&& curl -sSL -o /tmp/Alacritty.desktop \
+			https://raw.githubusercontent.com/alacritty/alacritty/master/extra/linux/Alacritty.desktop \
+		&& {prefix} mv ~/.cargo/bin/alacritty /usr/local/bin/ \
+		&& {prefix} desktop-file-install /tmp/Alacritty.desktop \
+		&& {prefix} update-desktop-database
+		`, map[string]string{
+		"prefix": prefix,
+	})
+	return utils.RunCmd(command)
+}
+
 // Install and configure the Alacritty terminal.
-func alacritty(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		if utils.IsLinux() {
-			if utils.IsDebianUbuntuSeries() {
-				command := utils.Format(`{prefix} apt-get {yesStr} update \
-				&& {prefix} apt-get {yesStr} install \
-					cmake pkg-config python3 \
-					libfreetype6-dev libfontconfig1-dev libxcb-xfixes0-dev libxkbcommon-dev
-				`, map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format(`{prefix} dnf {yesStr} install \ 
-				cmake g++ \
-				freetype-devel fontconfig-devel libxcb-devel libxkbcommon-devel 
-				`, map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			}
-			utils.RunCmd("cargo install alacritty")
-			command := utils.Format(`{prefix} curl -sSL -o /usr/share/pixmaps/Alacritty.svg \
-					https://raw.githubusercontent.com/alacritty/alacritty/master/extra/logo/alacritty-term.svg \
-				&& curl -sSL -o /tmp/Alacritty.desktop \
-					https://raw.githubusercontent.com/alacritty/alacritty/master/extra/linux/Alacritty.desktop \
-				&& {prefix} mv ~/.cargo/bin/alacritty /usr/local/bin/ \
-				&& {prefix} desktop-file-install /tmp/Alacritty.desktop \
-				&& {prefix} update-desktop-database
-				`, map[string]string{
-				"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-			})
-			utils.RunCmd(command)
-		} else {
-			utils.RunCmd("brew install --cask alacritty")
+func alacritty(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		yes, err := utils.GetBoolFlag(cmd, "yes")
+		if err != nil {
+			return err
+		}
+		if err := installAlacritty(yes); err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "config") {
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		if utils.IsLinux() {
-		} else {
-			utils.RunCmd("brew uninstall --cask alacritty")
-		}
+	if uninstall && !utils.IsLinux() {
+		return utils.RunCmd("brew uninstall --cask alacritty")
 	}
+	return nil
 }
 
 var alacrittyCmd = &cobra.Command{
@@ -60,7 +74,7 @@ var alacrittyCmd = &cobra.Command{
 	Aliases: []string{"alac"},
 	Short:   "Install and configure the Alacritty terminal.",
 	//Args:  cobra.ExactArgs(1),
-	Run: alacritty,
+	RunE: alacritty,
 }
 
 func ConfigAlacrittyCmd(rootCmd *cobra.Command) {
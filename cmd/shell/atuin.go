@@ -1,36 +1,41 @@
 package shell
 
 import (
-	"path/filepath"
-
 	"github.com/spf13/cobra"
 	"legendu.net/icon/utils"
 )
 
 // Install atuin.
-func atuin(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
+func atuin(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
 		command := "bash <(curl https://raw.githubusercontent.com/ellie/atuin/main/install.sh)"
-		utils.RunCmd(command)
+		if err := utils.RunCmd(command); err != nil {
+			return err
+		}
+	}
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil || !config {
+		return err
+	}
+	if err := utils.ConfigBash(); err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		utils.ConfigBash()
-		if utils.IsLinux() {
-			utils.ReplacePattern(utils.GetBashConfigFile(), `eval "$(atuin init bash)"`, "eval \"$(atuin init bash --disable-up-arrow)\"\n")
-		} else {
-			atuinBash := `
+	if utils.IsLinux() {
+		return utils.ReplacePattern(
+			utils.GetBashConfigFile(),
+			`eval "$(atuin init bash)"`,
+			"eval \"$(atuin init bash --disable-up-arrow)\"\n",
+		)
+	}
+	atuinBash := `
 [[ -f ~/.bash-preexec.sh ]] && source ~/.bash-preexec.sh
 eval "$(atuin init bash --disable-up-arrow)"
 `
-			utils.AppendToTextFile(
-				filepath.Join(utils.UserHomeDir(), ".bash_profile"),
-				atuinBash,
-				true,
-			)
-		}
-	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-	}
+	return utils.AppendToTextFile("~/.bash_profile", atuinBash, true)
 }
 
 var atuinCmd = &cobra.Command{
@@ -38,7 +43,7 @@ var atuinCmd = &cobra.Command{
 	Aliases: []string{"atuin"},
 	Short:   "Install and configure atuin.",
 	//Args:  cobra.ExactArgs(1),
-	Run: atuin,
+	RunE: atuin,
 }
 
 func ConfigAtuinCmd(rootCmd *cobra.Command) {
@@ -8,31 +8,66 @@ import (
 	"legendu.net/icon/utils"
 )
 
+// linkConfig backs up (or removes) dst and then copies or symlinks src to dst
+// according to the --no-backup and --copy flags of the command.
+func linkConfig(cmd *cobra.Command, src, dst string) error {
+	backup, err := utils.ShouldBackup(cmd)
+	if err != nil {
+		return err
+	}
+	doCopy, err := utils.GetBoolFlag(cmd, "copy")
+	if err != nil {
+		return err
+	}
+	if err := utils.BackupOrRemove(dst, backup); err != nil {
+		return err
+	}
+	return utils.CopyOrSymlink(src, dst, doCopy)
+}
+
 // Install bash-it, a community Bash framework.
 // For more details, please refer to https://github.com/Bash-it/bash-it#installation.
-func bashIt(cmd *cobra.Command, _ []string) {
-	home := utils.UserHomeDir()
-	if utils.GetBoolFlag(cmd, "install") {
-		dir := filepath.Join(home, ".bash_it")
-		utils.RemoveAll(dir)
-		command := utils.Format(`git clone --depth=1 https://github.com/Bash-it/bash-it.git {dir} \
-			&& {dir}/install.sh --silent -f`,
-			map[string]string{
-				"dir": dir,
-			})
-		utils.RunCmd(command)
-	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
-		utils.ConfigBash()
-		src := "~/.config/icon-data/bash-it"
-		dst := "~/.bash_it"
-		utils.BackupOrRemove(dst, utils.ShouldBackup(cmd))
-		utils.CopyOrSymlink(src, dst, utils.GetBoolFlag(cmd, "copy"))
-	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		utils.RunCmd("~/.bash_it/uninstall.sh && rm -rf ~/.bash_it/")
+func bashIt(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		dir := utils.NormalizePath("~/.bash_it")
+		if err := utils.RemoveAll(dir); err != nil {
+			return err
+		}
+		err := utils.NewCommand("git", "clone", "--depth=1", "https://github.com/Bash-it/bash-it.git", dir).Run()
+		if err != nil {
+			return err
+		}
+		if err := utils.NewCommand(filepath.Join(dir, "install.sh"), "--silent", "-f").Run(); err != nil {
+			return err
+		}
+	}
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		if err := icon.FetchConfigData(false, ""); err != nil {
+			return err
+		}
+		if err := utils.ConfigBash(); err != nil {
+			return err
+		}
+		if err := linkConfig(cmd, "~/.config/icon-data/bash-it", "~/.bash_it"); err != nil {
+			return err
+		}
+	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return utils.RunCmd("~/.bash_it/uninstall.sh && rm -rf ~/.bash_it/")
 	}
+	return nil
 }
 
 var bashItCmd = &cobra.Command{
@@ -40,7 +75,7 @@ var bashItCmd = &cobra.Command{
 	Aliases: []string{"bashit", "bit"},
 	Short:   "Install and configure bash-it.",
 	//Args:  cobra.ExactArgs(1),
-	Run: bashIt,
+	RunE: bashIt,
 }
 
 func ConfigBashItCmd(rootCmd *cobra.Command) {
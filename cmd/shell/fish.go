@@ -1,6 +1,7 @@
 package shell
 
 import (
+	"fmt"
 	"log"
 	"path/filepath"
 	"strings"
@@ -11,103 +12,150 @@ import (
 	"legendu.net/icon/utils"
 )
 
-func generateCompletions() {
+func generateCompletions() error {
 	dir := "~/.config/fish/completions/"
-	var cmdMap map[string]string
-	err := yaml.Unmarshal(utils.ReadFile(dir+"commands.yaml"), &cmdMap)
+	bytes, err := utils.ReadFile(dir + "commands.yaml")
 	if err != nil {
-		log.Fatalf("Error unmarshaling data: %v", err)
+		return err
+	}
+	var cmdMap map[string]string
+	if err := yaml.Unmarshal(bytes, &cmdMap); err != nil {
+		return fmt.Errorf("error unmarshaling data: %w", err)
 	}
 
 	for cmd, cmdCompletion := range cmdMap {
 		if utils.ExistsCommand(cmd) {
 			script := dir + cmd + ".fish"
-			utils.RunCmd(cmdCompletion + " > " + script)
+			if err := utils.RunCmd(cmdCompletion + " > " + script); err != nil {
+				return err
+			}
 		}
 	}
+	return nil
 }
 
-func generateCrazyCompletions() {
+func generateCrazyCompletions() error {
 	var uvx string
 	if utils.ExistsCommand("uvx") {
 		uvx = "uvx"
 	} else {
 		file := "~/.local/bin/uvx"
 		if !utils.ExistsCommand(file) {
-			utils.RunCmd("curl -LsSf https://astral.sh/uv/install.sh | sh")
+			if err := utils.RunCmd("curl -LsSf https://astral.sh/uv/install.sh | sh"); err != nil {
+				return err
+			}
 		}
-		uvx = file
+		uvx = utils.NormalizePath(file)
 	}
 	dir := "~/.config/fish/completions/"
 	dirCrazy := utils.NormalizePath(dir + "crazy_complete")
-	if utils.ExistsPath(dirCrazy) {
-		for _, entry := range utils.ReadDir(dirCrazy) {
-			fileName := entry.Name()
-			srcFile := filepath.Join(dirCrazy, fileName)
-			fileName = strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ".fish"
-			destFile := dir + fileName
-			command := utils.Format(`{uvx} --python '>=3.10' --with pyyaml \
-				--from git+https://github.com/dclong/crazy-complete \
-				crazy-complete --input-type=yaml fish {srcFile} > {destFile}`,
-				map[string]string{
-					"uvx":      uvx,
-					"srcFile":  srcFile,
-					"destFile": destFile,
-				})
-			utils.RunCmd(command)
+	if !utils.ExistsPath(dirCrazy) {
+		return nil
+	}
+	entries, err := utils.ReadDir(dirCrazy)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		fileName := entry.Name()
+		srcFile := filepath.Join(dirCrazy, fileName)
+		fileName = strings.TrimSuffix(fileName, filepath.Ext(fileName)) + ".fish"
+		destFile := filepath.Join(utils.NormalizePath(dir), fileName)
+		command := utils.FormatShell(`{uvx} --python '>=3.10' --with pyyaml \
+			--from git+https://github.com/dclong/crazy-complete \
+			crazy-complete --input-type=yaml fish {srcFile} > {destFile}`,
+			map[string]string{
+				"uvx":      uvx,
+				"srcFile":  srcFile,
+				"destFile": destFile,
+			})
+		if err := utils.RunCmd(command); err != nil {
+			return err
 		}
 	}
+	return nil
 }
 
-// Install and config the fish shell.
-func fish(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		if utils.IsLinux() {
-			if utils.IsDebianUbuntuSeries() {
-				if utils.IsUbuntuSeries() {
-					command := utils.Format("{prefix} add-apt-repository {yesStr} ppa:fish-shell/release-4", map[string]string{
-						"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-						"yesStr": utils.BuildYesFlag(cmd),
-					})
-					utils.RunCmd(command)
-				}
-				command := utils.Format(`{prefix} apt-get {yesStr} update \
-				&& {prefix} apt-get {yesStr} install fish`, map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format(`{prefix} dnf {yesStr} install fish`, map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			}
-			log.Printf("Successfully installed the fish shell.\n")
-		} else {
-			utils.RunCmd("brew install fish")
+// fishPackages are packages of the fish shell for package managers.
+var fishPackages = utils.Packages{
+	"apt":    {"fish"},
+	"dnf":    {"fish"},
+	"brew":   {"fish"},
+	"pacman": {"fish"},
+	"zypper": {"fish"},
+	"apk":    {"fish"},
+}
+
+func installFish(yes bool) error {
+	pm, pkgs, err := utils.ResolvePackages("fish", fishPackages)
+	if err != nil {
+		return err
+	}
+	if pm.Name() == "apt" && utils.IsUbuntuSeries() {
+		if err := utils.AddAptRepository("ppa:fish-shell/release-4", yes); err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
+	if err := pm.Install(yes, pkgs...); err != nil {
+		return err
+	}
+	log.Printf("Successfully installed the fish shell.\n")
+	return nil
+}
 
-		dir := "~/.config/fish"
-		utils.BackupOrRemove(dir, utils.ShouldBackup(cmd))
-		utils.CopyOrSymlink("~/.config/icon-data/fish", dir, utils.GetBoolFlag(cmd, "copy"))
+// Install and config the fish shell.
+func fish(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		yes, err := utils.GetBoolFlag(cmd, "yes")
+		if err != nil {
+			return err
+		}
+		if err := installFish(yes); err != nil {
+			return err
+		}
+	}
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		if err := configFish(cmd); err != nil {
+			return err
+		}
+	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil || !uninstall {
+		return err
+	}
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	return utils.RemovePackages("fish", fishPackages, yes)
+}
 
-		generateCompletions()
-		generateCrazyCompletions()
+func configFish(cmd *cobra.Command) error {
+	if err := icon.FetchConfigData(false, ""); err != nil {
+		return err
+	}
+	if err := linkConfig(cmd, "~/.config/icon-data/fish", "~/.config/fish"); err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+	if err := generateCompletions(); err != nil {
+		return err
 	}
+	return generateCrazyCompletions()
 }
 
 var fishCmd = &cobra.Command{
 	Use:     "fish",
 	Aliases: []string{},
 	Short:   "Install and configure the fish shell.",
-	Run:     fish,
+	RunE:    fish,
 }
 
 func ConfigFishCmd(rootCmd *cobra.Command) {
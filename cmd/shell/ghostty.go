@@ -1,7 +1,6 @@
 package shell
 
 import (
-	"log"
 	"os"
 	"path/filepath"
 
@@ -18,46 +17,75 @@ const ghosttyAppImageRepo = "pkgforge-dev/ghostty-appimage"
 // ~/Applications. The AppImage is used on all Linux distributions because
 // Ghostty has no single official package shared across the Debian/Ubuntu and
 // Fedora series.
-func installGhosttyAppImage() {
-	tmpdir := utils.CreateTempDir("")
+func installGhosttyAppImage(ver string) error {
+	tmpdir, err := utils.CreateTempDir("")
+	if err != nil {
+		return err
+	}
 	defer os.RemoveAll(tmpdir)
 	file := filepath.Join(tmpdir, "ghostty.AppImage")
-	network.DownloadGitHubRelease(ghosttyAppImageRepo, "", map[string][]string{
+	err = network.DownloadGitHubRelease(ghosttyAppImageRepo, ver, map[string][]string{
 		"common": {".AppImage"},
 		"amd64":  {"x86_64"},
 		"arm64":  {"aarch64"},
 	}, []string{".zsync"}, file)
+	if err != nil {
+		return err
+	}
 	dst := "~/Applications/ghostty.AppImage"
-	utils.CopyFile(file, dst)
-	utils.Chmod(dst, "+x")
+	if err := utils.CopyFile(file, dst); err != nil {
+		return err
+	}
+	return utils.Chmod(dst, "+x")
 }
 
 // Install and configure the Ghostty terminal.
-func ghostty(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
+func ghostty(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		ver, err := utils.GetStringFlag(cmd, "version")
+		if err != nil {
+			return err
+		}
 		if utils.IsLinux() {
-			installGhosttyAppImage()
+			err = installGhosttyAppImage(ver)
 		} else {
-			utils.RunCmd("brew install --cask ghostty")
+			err = utils.RunCmd("brew install --cask ghostty")
+		}
+		if err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		if err := icon.FetchConfigData(false, ""); err != nil {
+			return err
+		}
 		src := "~/.config/icon-data/ghostty/config.ghostty"
 		if !utils.ExistsFile(src) {
-			log.Fatalf("The Ghostty configuration file %s does not exist.", src)
+			return &utils.MissingConfigError{Path: src}
+		}
+		if err := linkConfig(cmd, src, "~/.config/ghostty/config"); err != nil {
+			return err
 		}
-		dst := "~/.config/ghostty/config"
-		utils.BackupOrRemove(dst, utils.ShouldBackup(cmd))
-		utils.CopyOrSymlink(src, dst, utils.GetBoolFlag(cmd, "copy"))
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
 		if utils.IsLinux() {
-			utils.RemoveAll("~/Applications/ghostty.AppImage")
-		} else {
-			utils.RunCmd("brew uninstall --cask ghostty")
+			return utils.RemoveAll("~/Applications/ghostty.AppImage")
 		}
+		return utils.RunCmd("brew uninstall --cask ghostty")
 	}
+	return nil
 }
 
 var ghosttyCmd = &cobra.Command{
@@ -65,13 +93,15 @@ var ghosttyCmd = &cobra.Command{
 	Aliases: []string{"ghost"},
 	Short:   "Install and configure the Ghostty terminal.",
 	//Args:  cobra.ExactArgs(1),
-	Run: ghostty,
+	RunE: ghostty,
 }
 
 func ConfigGhosttyCmd(rootCmd *cobra.Command) {
 	ghosttyCmd.Flags().BoolP("install", "i", false, "Install the Ghostty terminal.")
 	ghosttyCmd.Flags().Bool("uninstall", false, "Uninstall the Ghostty terminal.")
 	ghosttyCmd.Flags().BoolP("config", "c", false, "Configure the Ghostty terminal.")
+	ghosttyCmd.Flags().StringP("version", "v", "",
+		"A version constraint of the AppImage release to install on Linux (the latest by default).")
 	ghosttyCmd.Flags().Bool("no-backup", false, "Do not backup existing configuration files.")
 	ghosttyCmd.Flags().Bool("copy", false, "Make copies (instead of symbolic links) of configuration files.")
 	ghosttyCmd.Flags().BoolP("yes", "y", false, "Automatically yes to prompt questions.")
@@ -2,7 +2,6 @@ package shell
 
 import (
 	"log"
-	"runtime"
 
 	"github.com/spf13/cobra"
 	"legendu.net/icon/cmd/icon"
@@ -10,9 +9,9 @@ import (
 	"legendu.net/icon/utils"
 )
 
-func downloadHyperFromGitHub(version string) string {
+func downloadHyperFromGitHub(version string) (string, error) {
 	output := "/tmp/_hyper_js_terminal"
-	network.DownloadGitHubRelease("vercel/hyper", version, map[string][]string{
+	err := network.DownloadGitHubRelease("vercel/hyper", version, map[string][]string{
 		"common":             {},
 		"amd64":              {"amd64"},
 		"arm64":              {"arm64"},
@@ -20,55 +19,84 @@ func downloadHyperFromGitHub(version string) string {
 		"FedoraSeries":       {"rpm"},
 		"OtherLinux":         {"appimage"},
 	}, []string{}, output)
-	return output
+	if err != nil {
+		return "", err
+	}
+	return output, nil
 }
 
-// Install and configure the Hyper terminal.
-func hyper(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		switch runtime.GOOS {
-		case "linux":
-			file := downloadHyperFromGitHub(utils.GetStringFlag(cmd, "version"))
-			if utils.IsDebianUbuntuSeries() {
-				command := utils.Format(`{prefix} apt-get {yesStr} update \
-						&& {prefix} apt-get {yesStr} install {file}`, map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-					"file":   file,
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format("{prefix} dnf {yesStr} install {file}", map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-					"file":   file,
-				})
-				utils.RunCmd(command)
-			}
-		case "darwin":
-			utils.RunCmd("brew install --cask hyper")
+// hyperPackages are packages of the Hyper terminal for package managers.
+// The .deb and .rpm packages are downloaded from GitHub releases.
+var hyperPackages = utils.Packages{
+	"apt":  {"hyper"},
+	"dnf":  {"hyper"},
+	"brew": {"--cask hyper"},
+}
+
+func installHyper(cmd *cobra.Command, yes bool) error {
+	pm, pkgs, err := utils.ResolvePackages("Hyper", hyperPackages)
+	if err != nil {
+		return err
+	}
+	if pm.Name() == "brew" {
+		return pm.Install(yes, pkgs...)
+	}
+	version, err := utils.GetStringFlag(cmd, "version")
+	if err != nil {
+		return err
+	}
+	file, err := downloadHyperFromGitHub(version)
+	if err != nil {
+		return err
+	}
+	return pm.Install(yes, file)
+}
+
+func configHyper(cmd *cobra.Command) error {
+	if err := icon.FetchConfigData(false, ""); err != nil {
+		return err
+	}
+	for _, plugin := range []string{"hypercwd", "hyper-search", "hyper-pane", "hyperpower"} {
+		if err := utils.RunCmd("hyper i " + plugin); err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
+	log.Printf("Hyper plugins hypercwd, hyper-search, hyper-pane and hyperpower are installed.\n")
+	return linkConfig(cmd, "~/.config/icon-data/hyper/hyper.js", "~/.hyper.js")
+}
 
-		utils.RunCmd("hyper i hypercwd")
-		utils.RunCmd("hyper i hyper-search")
-		utils.RunCmd("hyper i hyper-pane")
-		utils.RunCmd("hyper i hyperpower")
-		log.Printf("Hyper plugins hypercwd, hyper-search, hyper-pane and hyperpower are installed.\n")
-		src := "~/.config/icon-data/hyper/hyper.js"
-		dst := "~/.hyper.js"
-		utils.BackupOrRemove(dst, utils.ShouldBackup(cmd))
-		utils.CopyOrSymlink(src, dst, utils.GetBoolFlag(cmd, "copy"))
+// Install and configure the Hyper terminal.
+func hyper(cmd *cobra.Command, _ []string) error {
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		switch runtime.GOOS {
-		case "linux":
-		case "darwin":
-			utils.RunCmd("brew uninstall --cask hyper")
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := installHyper(cmd, yes); err != nil {
+			return err
 		}
 	}
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		if err := configHyper(cmd); err != nil {
+			return err
+		}
+	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return utils.RemovePackages("Hyper", hyperPackages, yes)
+	}
+	return nil
 }
 
 var hyperCmd = &cobra.Command{
@@ -76,7 +104,7 @@ var hyperCmd = &cobra.Command{
 	Aliases: []string{},
 	Short:   "Install and configure the Hyper terminal.",
 	//Args:  cobra.ExactArgs(1),
-	Run: hyper,
+	RunE: hyper,
 }
 
 func ConfigHyperCmd(rootCmd *cobra.Command) {
@@ -8,39 +8,43 @@ import (
 	"legendu.net/icon/utils"
 )
 
-func downloadNushellFromGitHub(version string) string {
-	output := "/tmp/_nu.tar.gz"
-	network.DownloadGitHubRelease("nushell/nushell", version,
+func installNushellLinux(cmd *cobra.Command) error {
+	version, err := utils.GetStringFlag(cmd, "version")
+	if err != nil {
+		return err
+	}
+	dir, err := utils.GetStringFlag(cmd, "dir")
+	if err != nil {
+		return err
+	}
+	err = network.DownloadRelease("nushell/nushell", version,
 		map[string][]string{
 			"common": {"tar.gz"},
 			"linux":  {"unknown", "linux", "gnu"},
 			"darwin": {"apple", "darwin"},
 			"amd64":  {"x86_64"},
 			"arm64":  {"aarch64"},
-		}, []string{}, output)
-	return output
+		}, []string{}, "", network.ReleaseOptions{
+			InstallBin: []string{"nu", "nu_plugin_*"},
+			BinDir:     dir,
+		})
+	if err != nil {
+		return err
+	}
+	log.Printf("Nushell has been installed into %s.\n", dir)
+	return nil
 }
 
 // Install nushell.
-func nushell(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		if utils.IsLinux() {
-			file := downloadNushellFromGitHub(utils.GetStringFlag(cmd, "version"))
-			dir := utils.GetStringFlag(cmd, "dir")
-			utils.RunCmd(utils.Format(`mkdir -p {dir} \
-					&& tar -zxvf {file} -C {dir} --strip-components=1 --exclude=LICENSE --exclude='README.*'`, map[string]string{
-				"file": file,
-				"dir":  dir,
-			}))
-			log.Printf("Nushell has been installed into %s.\n", dir)
-		} else {
-			utils.BrewInstallSafe([]string{"nushell"})
-		}
-	}
-	if utils.GetBoolFlag(cmd, "config") {
+func nushell(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil || !install {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+	if utils.IsLinux() {
+		return installNushellLinux(cmd)
 	}
+	return utils.BrewInstallSafe([]string{"nushell"})
 }
 
 var nushellCmd = &cobra.Command{
@@ -48,7 +52,7 @@ var nushellCmd = &cobra.Command{
 	Aliases: []string{"nu"},
 	Short:   "Install and configure nushell.",
 	//Args:  cobra.ExactArgs(1),
-	Run: nushell,
+	RunE: nushell,
 }
 
 func ConfigNushellCmd(rootCmd *cobra.Command) {
@@ -57,5 +61,7 @@ func ConfigNushellCmd(rootCmd *cobra.Command) {
 	nushellCmd.Flags().BoolP("config", "c", false, "If specified, configure nushell.")
 	nushellCmd.Flags().Bool("no-backup", false, "Do not backup existing configuration files.")
 	nushellCmd.Flags().Bool("copy", false, "Make copies (instead of symbolic links) of configuration files.")
+	nushellCmd.Flags().StringP("version", "v", "", "The version of the release.")
+	nushellCmd.Flags().String("dir", "~/.local/bin", "The directory for installing nushell executables.")
 	rootCmd.AddCommand(nushellCmd)
 }
@@ -1,7 +1,7 @@
 package shell
 
 import (
-	"log"
+	"errors"
 	"os"
 	"path/filepath"
 
@@ -13,117 +13,153 @@ import (
 
 const wavetermRepo = "wavetermdev/waveterm"
 
-// installWavetermDeb downloads the Wave terminal .deb package from its GitHub
-// releases and installs it on the Debian/Ubuntu series.
-func installWavetermDeb(cmd *cobra.Command) {
-	tmpdir := utils.CreateTempDir("")
-	defer os.RemoveAll(tmpdir)
-	file := filepath.Join(tmpdir, "waveterm.deb")
-	network.DownloadGitHubRelease(wavetermRepo, "", map[string][]string{
+// downloadWaveterm downloads a Wave terminal release asset into a temporary directory.
+//
+// @param ver      A version constraint, or an empty string for the latest release.
+// @param keywords The keywords used to match the release asset.
+// @param name     The name of the downloaded file.
+//
+// @return The path to the temporary directory and the path to the downloaded file.
+func downloadWaveterm(ver string, keywords map[string][]string, name string) (string, string, error) {
+	tmpdir, err := utils.CreateTempDir("")
+	if err != nil {
+		return "", "", err
+	}
+	file := filepath.Join(tmpdir, name)
+	if err := network.DownloadGitHubRelease(wavetermRepo, ver, keywords, []string{}, file); err != nil {
+		os.RemoveAll(tmpdir)
+		return "", "", err
+	}
+	return tmpdir, file, nil
+}
+
+// wavetermPackages are packages of the Wave terminal for package managers.
+// The .deb and .rpm packages are downloaded from GitHub releases.
+var wavetermPackages = utils.Packages{
+	"apt":  {"waveterm"},
+	"dnf":  {"waveterm"},
+	"brew": {"--cask wave"},
+}
+
+// wavetermAssetKeywords are keywords to match release assets of the Wave terminal for package managers.
+var wavetermAssetKeywords = map[string]map[string][]string{
+	"apt": {
 		"common": {".deb"},
 		"amd64":  {"amd64"},
 		"arm64":  {"arm64"},
-	}, []string{}, file)
-	command := utils.Format("{prefix} apt-get {yesStr} install {file}", map[string]string{
-		"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-		"yesStr": utils.BuildYesFlag(cmd),
-		"file":   file,
-	})
-	utils.RunCmd(command)
-}
-
-// installWavetermRpm downloads the Wave terminal .rpm package from its GitHub
-// releases and installs it on the Fedora series.
-func installWavetermRpm(cmd *cobra.Command) {
-	tmpdir := utils.CreateTempDir("")
-	defer os.RemoveAll(tmpdir)
-	file := filepath.Join(tmpdir, "waveterm.rpm")
-	network.DownloadGitHubRelease(wavetermRepo, "", map[string][]string{
+	},
+	"dnf": {
 		"common": {".rpm"},
 		"amd64":  {"x86_64"},
 		"arm64":  {"aarch64"},
-	}, []string{}, file)
-	command := utils.Format("{prefix} dnf {yesStr} install {file}", map[string]string{
-		"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-		"yesStr": utils.BuildYesFlag(cmd),
-		"file":   file,
-	})
-	utils.RunCmd(command)
+	},
 }
 
 // installWavetermAppImage downloads the Wave terminal AppImage from its GitHub
 // releases and installs it into ~/Applications. AppImage is used on image-based
-// Universal Blue distributions where layering deb/rpm packages is undesirable.
-func installWavetermAppImage() {
-	tmpdir := utils.CreateTempDir("")
-	defer os.RemoveAll(tmpdir)
-	file := filepath.Join(tmpdir, "waveterm.AppImage")
-	network.DownloadGitHubRelease(wavetermRepo, "", map[string][]string{
+// Universal Blue distributions where layering deb/rpm packages is undesirable
+// and on distributions without a .deb or .rpm package.
+func installWavetermAppImage(ver string) error {
+	tmpdir, file, err := downloadWaveterm(ver, map[string][]string{
 		"common": {".AppImage"},
 		"amd64":  {"x86_64"},
 		"arm64":  {"arm64"},
-	}, []string{}, file)
+	}, "waveterm.AppImage")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpdir)
 	dst := "~/Applications/waveterm.AppImage"
-	utils.CopyFile(file, dst)
-	utils.Chmod(dst, "+x")
+	if err := utils.CopyFile(file, dst); err != nil {
+		return err
+	}
+	return utils.Chmod(dst, "+x")
 }
 
-// uninstallWavetermDeb removes the Wave terminal package on the Debian/Ubuntu series.
-func uninstallWavetermDeb(cmd *cobra.Command) {
-	command := utils.Format("{prefix} apt-get {yesStr} purge waveterm", map[string]string{
-		"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-		"yesStr": utils.BuildYesFlag(cmd),
-	})
-	utils.RunCmd(command)
+func installWaveterm(cmd *cobra.Command) error {
+	ver, err := utils.GetStringFlag(cmd, "version")
+	if err != nil {
+		return err
+	}
+	if utils.IsUniversalBlue() {
+		return installWavetermAppImage(ver)
+	}
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	pm, pkgs, err := utils.ResolvePackages("Wave terminal", wavetermPackages)
+	var unsupported *utils.UnsupportedDistroError
+	if errors.As(err, &unsupported) && utils.IsLinux() {
+		// the AppImage works on Linux distributions without a .deb or .rpm package
+		return installWavetermAppImage(ver)
+	}
+	if err != nil {
+		return err
+	}
+	keywords, ok := wavetermAssetKeywords[pm.Name()]
+	if !ok {
+		return pm.Install(yes, pkgs...)
+	}
+	tmpdir, file, err := downloadWaveterm(ver, keywords, "waveterm"+filepath.Ext(keywords["common"][0]))
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmpdir)
+	return pm.Install(yes, file)
 }
 
-// uninstallWavetermRpm removes the Wave terminal package on the Fedora series.
-func uninstallWavetermRpm(cmd *cobra.Command) {
-	command := utils.Format("{prefix} dnf {yesStr} remove waveterm", map[string]string{
-		"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-		"yesStr": utils.BuildYesFlag(cmd),
-	})
-	utils.RunCmd(command)
+func uninstallWaveterm(cmd *cobra.Command) error {
+	if utils.IsUniversalBlue() {
+		return utils.RemoveAll("~/Applications/waveterm.AppImage")
+	}
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	err = utils.RemovePackages("Wave terminal", wavetermPackages, yes)
+	var unsupported *utils.UnsupportedDistroError
+	if errors.As(err, &unsupported) && utils.IsLinux() {
+		return utils.RemoveAll("~/Applications/waveterm.AppImage")
+	}
+	return err
 }
 
 // Install and configure the Wave terminal.
-func waveterm(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		if utils.IsLinux() {
-			if utils.IsUniversalBlue() {
-				installWavetermAppImage()
-			} else if utils.IsDebianUbuntuSeries() {
-				installWavetermDeb(cmd)
-			} else if utils.IsFedoraSeries() {
-				installWavetermRpm(cmd)
-			}
-		} else {
-			utils.RunCmd("brew install --cask wave")
+func waveterm(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := installWaveterm(cmd); err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		if err := icon.FetchConfigData(false, ""); err != nil {
+			return err
+		}
 		src := "~/.config/icon-data/waveterm/settings.json"
 		if !utils.ExistsFile(src) {
-			log.Fatalf("The Wave terminal configuration file %s does not exist.", src)
+			return &utils.MissingConfigError{Path: src}
 		}
-		dst := "~/.config/waveterm/settings.json"
-		utils.BackupOrRemove(dst, utils.ShouldBackup(cmd))
-		utils.CopyOrSymlink(src, dst, utils.GetBoolFlag(cmd, "copy"))
-	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		if utils.IsLinux() {
-			if utils.IsUniversalBlue() {
-				utils.RemoveAll("~/Applications/waveterm.AppImage")
-			} else if utils.IsDebianUbuntuSeries() {
-				uninstallWavetermDeb(cmd)
-			} else if utils.IsFedoraSeries() {
-				uninstallWavetermRpm(cmd)
-			}
-		} else {
-			utils.RunCmd("brew uninstall --cask wave")
+		if err := linkConfig(cmd, src, "~/.config/waveterm/settings.json"); err != nil {
+			return err
 		}
 	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return uninstallWaveterm(cmd)
+	}
+	return nil
 }
 
 var wavetermCmd = &cobra.Command{
@@ -131,13 +167,15 @@ var wavetermCmd = &cobra.Command{
 	Aliases: []string{"wave"},
 	Short:   "Install and configure the Wave terminal.",
 	//Args:  cobra.ExactArgs(1),
-	Run: waveterm,
+	RunE: waveterm,
 }
 
 func ConfigWavetermCmd(rootCmd *cobra.Command) {
 	wavetermCmd.Flags().BoolP("install", "i", false, "Install the Wave terminal.")
 	wavetermCmd.Flags().Bool("uninstall", false, "Uninstall the Wave terminal.")
 	wavetermCmd.Flags().BoolP("config", "c", false, "Configure the Wave terminal.")
+	wavetermCmd.Flags().StringP("version", "v", "",
+		"A version constraint of the release to install from GitHub (the latest by default).")
 	wavetermCmd.Flags().Bool("no-backup", false, "Do not backup existing configuration files.")
 	wavetermCmd.Flags().Bool("copy", false, "Make copies (instead of symbolic links) of configuration files.")
 	wavetermCmd.Flags().BoolP("yes", "y", false, "Automatically yes to prompt questions.")
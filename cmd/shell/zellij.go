@@ -1,48 +1,47 @@
 package shell
 
 import (
-	"os"
-	"path/filepath"
-
 	"github.com/spf13/cobra"
-	"golang.org/x/sys/unix"
 	"legendu.net/icon/cmd/icon"
 	"legendu.net/icon/cmd/network"
 	"legendu.net/icon/utils"
 )
 
+func installZellij(cmd *cobra.Command) error {
+	dirBin, err := utils.GetStringFlag(cmd, "bin-dir")
+	if err != nil {
+		return err
+	}
+	ver, err := utils.GetStringFlag(cmd, "version")
+	if err != nil {
+		return err
+	}
+	return network.DownloadRelease("zellij-org/zellij", ver, nil, nil, "", network.ReleaseOptions{
+		Pattern:    "zellij-{arch}-*{os}*.tar.gz",
+		InstallBin: []string{"zellij"},
+		BinDir:     dirBin,
+	})
+}
+
 // Install and configure Ganymede.
-func zellij(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		tmpdir := utils.CreateTempDir("")
-		defer os.RemoveAll(tmpdir)
-		file := filepath.Join(tmpdir, "zellij.tar.gz")
-		network.DownloadGitHubRelease(
-			"zellij-org/zellij",
-			"",
-			map[string][]string{"common": {"tar.gz"}},
-			[]string{"sha256sum"},
-			file,
-		)
-		dirBin := utils.GetStringFlag(cmd, "bin-dir")
-		command := utils.Format(`{prefix} tar -zxvf {file} -C {dirBin}`, map[string]string{
-			"file":   file,
-			"dirBin": dirBin,
-			"prefix": utils.GetCommandPrefix(false, map[string]uint32{
-				dirBin: unix.W_OK | unix.R_OK,
-			}),
-		})
-		utils.RunCmd(command)
+func zellij(cmd *cobra.Command, _ []string) error {
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := installZellij(cmd); err != nil {
+			return err
+		}
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		icon.FetchConfigData(false, "")
-		src := "~/.config/icon-data/zellij/config.kdl"
-		dst := "~/.config/zellij/config.kdl"
-		utils.BackupOrRemove(dst, utils.ShouldBackup(cmd))
-		utils.CopyOrSymlink(src, dst, utils.GetBoolFlag(cmd, "copy"))
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil || !config {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
+	if err := icon.FetchConfigData(false, ""); err != nil {
+		return err
 	}
+	return linkConfig(cmd, "~/.config/icon-data/zellij/config.kdl", "~/.config/zellij/config.kdl")
 }
 
 var zellijCmd = &cobra.Command{
@@ -50,7 +49,7 @@ var zellijCmd = &cobra.Command{
 	Aliases: []string{"zj", "z"},
 	Short:   "Install and configure Zellij.",
 	//Args:  cobra.ExactArgs(1),
-	Run: zellij,
+	RunE: zellij,
 }
 
 func ConfigZellijCmd(rootCmd *cobra.Command) {
@@ -60,6 +59,7 @@ func ConfigZellijCmd(rootCmd *cobra.Command) {
 	zellijCmd.Flags().Bool("sudo", false, "Force using sudo.")
 	zellijCmd.Flags().Bool("no-backup", false, "Do not backup existing configuration files.")
 	zellijCmd.Flags().Bool("copy", false, "Make copies (instead of symbolic links) of configuration files.")
+	zellijCmd.Flags().StringP("version", "v", "", "A version constraint (e.g., 0.43.1 or >=0.43) of the release to install (the latest by default).")
 	zellijCmd.Flags().String("bin-dir", "/usr/local/bin", "The directory for installing Zellij executable.")
 	utils.AddPythonFlags(zellijCmd)
 	rootCmd.AddCommand(zellijCmd)
@@ -0,0 +1,57 @@
+package shell
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/internal/testutil"
+)
+
+var testRoot = sync.OnceValue(func() *cobra.Command {
+	root := &cobra.Command{Use: "icon"}
+	ConfigZellijCmd(root)
+	ConfigFishCmd(root)
+	return root
+})
+
+func TestZellij(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want []string
+	}{
+		{"system", []string{"zellij", "--install"}, []string{
+			"sudo true",
+			"sudo install -m 755 */zellij /usr/local/bin/zellij",
+		}},
+		{"user", []string{"zellij", "--install", "--bin-dir", "~/.local/bin"}, []string{
+			"install -m 755 */zellij " + testutil.Home + "/.local/bin/zellij",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			env.HTTP.GitHubRelease("zellij-org/zellij", "v0.43.1", map[string][]byte{
+				"zellij-x86_64-unknown-linux-musl.tar.gz":  testutil.TarGz(map[string]string{"zellij": "#!/bin/sh\n"}),
+				"zellij-aarch64-unknown-linux-musl.tar.gz": testutil.TarGz(map[string]string{"zellij": "#!/bin/sh\n"}),
+			})
+			if err := env.Run(testRoot(), tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands(tt.want...)
+		})
+	}
+}
+
+func TestZellijConfig(t *testing.T) {
+	env := testutil.New(t, testutil.Fedora)
+	env.FakeConfigData(map[string]string{"zellij/config.kdl": "theme \"nord\"\n"})
+	if err := env.Run(testRoot(), "zellij", "--config"); err != nil {
+		t.Fatal(err)
+	}
+	env.AssertCommands()
+	if got, want := env.Readlink("~/.config/zellij/config.kdl"), testutil.Home+"/.config/icon-data/zellij/config.kdl"; got != want {
+		t.Errorf("~/.config/zellij/config.kdl links to %q, want %q", got, want)
+	}
+}
@@ -7,76 +7,105 @@ import (
 	"legendu.net/icon/utils"
 )
 
-// Install and configure Docker container.
-func docker(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
-		if utils.IsLinux() {
-			if utils.IsUniversalBlue() {
-				utils.RunCmd("ujust devmode")
-			} else if utils.IsDebianUbuntuSeries() {
-				command := utils.Format(`{prefix} apt-get {yesStr} update \
-						&& {prefix} apt-get {yesStr} install docker.io docker-compose`, map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format("{prefix} dnf {yesStr} install docker docker-compose", map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			}
-			command := utils.Format("{prefix} chown root:docker /var/run/docker.sock", map[string]string{
-				"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-			})
-			utils.RunCmd(command)
-		} else {
-			utils.BrewInstallSafe([]string{"docker", "docker-compose", "bash-completion@2"})
+// dockerPackages are packages of Docker for package managers of Linux distributions.
+// Homebrew is used on macOS only (see installDocker and uninstallDocker).
+var dockerPackages = utils.Packages{
+	"apt":        {"docker.io", "docker-compose"},
+	"dnf":        {"docker", "docker-compose"},
+	"rpm-ostree": {"docker", "docker-compose"},
+	"pacman":     {"docker", "docker-compose"},
+	"zypper":     {"docker", "docker-compose"},
+	"apk":        {"docker", "docker-cli-compose"},
+}
+
+func installDocker(yes bool) error {
+	if !utils.IsLinux() {
+		return utils.BrewInstallSafe([]string{"docker", "docker-compose", "bash-completion@2"})
+	}
+	if utils.IsUniversalBlue() {
+		// Docker comes with the developer mode of Universal Blue distributions
+		if err := utils.RunCmd("ujust devmode"); err != nil {
+			return err
 		}
+	} else if err := utils.InstallPackages("Docker", dockerPackages, yes); err != nil {
+		return err
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		userToDocker := utils.GetStringFlag(cmd, "user-to-docker")
-		if userToDocker != "" {
-			if utils.IsLinux() {
-				if utils.IsDebianUbuntuSeries() {
-					command := utils.Format("{prefix} gpasswd -a {user_to_docker} docker", map[string]string{
-						"prefix":         utils.GetCommandPrefix(true, map[string]uint32{}),
-						"user_to_docker": userToDocker,
-					})
-					utils.RunCmd(command)
-					log.Printf("Please run the command 'newgrp docker' or logout/login to make the group 'docker' effective!\n")
-				}
-			} else {
-				command := utils.Format("{prefix} dseditgroup -o edit -a {user_to_docker} -t user staff", map[string]string{
-					"prefix":         utils.GetCommandPrefix(true, map[string]uint32{}),
-					"user_to_docker": userToDocker,
-				})
-				utils.RunCmd(command)
-			}
+	prefix, err := utils.GetCommandPrefix(true, map[string]uint32{})
+	if err != nil {
+		return err
+	}
+	command := utils.Format("{prefix} chown root:docker /var/run/docker.sock", map[string]string{
+		"prefix": prefix,
+	})
+	return utils.RunCmd(command)
+}
+
+func configDocker(userToDocker string) error {
+	if userToDocker == "" {
+		return nil
+	}
+	prefix, err := utils.GetCommandPrefix(true, map[string]uint32{})
+	if err != nil {
+		return err
+	}
+	if !utils.IsLinux() {
+		return utils.NewCommand("dseditgroup", "-o", "edit", "-a", userToDocker, "-t", "user", "staff").Sudo(prefix).Run()
+	}
+	// gpasswd is not available on Alpine Linux by default
+	command := utils.NewCommand("gpasswd", "-a", userToDocker, "docker")
+	if utils.IsAlpine() {
+		command = utils.NewCommand("addgroup", userToDocker, "docker")
+	}
+	if err := command.Sudo(prefix).Run(); err != nil {
+		return err
+	}
+	log.Printf("Please run the command 'newgrp docker' or logout/login to make the group 'docker' effective!\n")
+	return nil
+}
+
+func uninstallDocker(yes bool) error {
+	if !utils.IsLinux() {
+		return utils.RunCmd("brew uninstall docker docker-completion docker-compose docker-compose-completion")
+	}
+	return utils.RemovePackages("Docker", dockerPackages, yes)
+}
+
+// Install and configure Docker container.
+func docker(cmd *cobra.Command, _ []string) error {
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	install, err := utils.GetBoolFlag(cmd, "install")
+	if err != nil {
+		return err
+	}
+	if install {
+		if err := installDocker(yes); err != nil {
+			return err
 		}
 	}
-	if utils.GetBoolFlag(cmd, "uninstall") {
-		if utils.IsLinux() {
-			if utils.IsDebianUbuntuSeries() {
-				command := utils.Format("{prefix} apt-get {yesStr} purge docker docker-compose", map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format("{prefix} dnf {yesStr} remove docker docker-compose", map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			}
-		} else {
-			utils.RunCmd(
-				"brew uninstall docker docker-completion docker-compose docker-compose-completion",
-			)
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil {
+		return err
+	}
+	if config {
+		userToDocker, err := utils.GetStringFlag(cmd, "user-to-docker")
+		if err != nil {
+			return err
+		}
+		if err := configDocker(userToDocker); err != nil {
+			return err
 		}
 	}
+	uninstall, err := utils.GetBoolFlag(cmd, "uninstall")
+	if err != nil {
+		return err
+	}
+	if uninstall {
+		return uninstallDocker(yes)
+	}
+	return nil
 }
 
 var dockerCmd = &cobra.Command{
@@ -84,7 +113,7 @@ var dockerCmd = &cobra.Command{
 	Aliases: []string{},
 	Short:   "Install and configure Docker.",
 	//Args:  cobra.ExactArgs(1),
-	Run: docker,
+	RunE: docker,
 }
 
 func ConfigDockerCmd(rootCmd *cobra.Command) {
@@ -94,7 +123,10 @@ func ConfigDockerCmd(rootCmd *cobra.Command) {
 	dockerCmd.Flags().Bool("copy", false, "Make copies (instead of symbolic links) of configuration files.")
 	dockerCmd.Flags().BoolP("uninstall", "u", false, "Uninstall Docker.")
 	dockerCmd.Flags().BoolP("yes", "y", false, "Automatically yes to prompt questions.")
-	user := utils.GetCurrentUser().Username
+	user := ""
+	if currentUser, err := utils.GetCurrentUser(); err == nil {
+		user = currentUser.Username
+	}
 	dockerCmd.Flags().String("user-to-docker", utils.IfElseString(user == "root", "", user), "Add the specified user to the docker group.")
 	rootCmd.AddCommand(dockerCmd)
 }
@@ -0,0 +1,107 @@
+package virtualization
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"legendu.net/icon/internal/testutil"
+)
+
+var testRoot = sync.OnceValue(func() *cobra.Command {
+	root := &cobra.Command{Use: "icon"}
+	ConfigDockerCmd(root)
+	ConfigKVMCmd(root)
+	return root
+})
+
+func TestDocker(t *testing.T) {
+	tests := []struct {
+		distro testutil.Distro
+		args   []string
+		want   []string
+	}{
+		{testutil.Ubuntu, []string{"docker", "--install", "--yes"}, []string{
+			"sudo true",
+			"sudo apt-get -y update",
+			"sudo apt-get -y install docker.io docker-compose",
+			"sudo chown root:docker /var/run/docker.sock",
+		}},
+		{testutil.Fedora, []string{"docker", "--install", "--yes"}, []string{
+			"sudo true",
+			"sudo dnf -y install docker docker-compose",
+			"sudo chown root:docker /var/run/docker.sock",
+		}},
+		{testutil.UniversalBlue, []string{"docker", "--install"}, []string{
+			"ujust devmode",
+			"sudo true",
+			"sudo chown root:docker /var/run/docker.sock",
+		}},
+		{testutil.MacOS, []string{"docker", "--install"}, []string{
+			"brew install --force docker || brew link --overwrite --force docker",
+			"brew install --force docker-compose || brew link --overwrite --force docker-compose",
+			"brew install --force bash-completion@2 || brew link --overwrite --force bash-completion@2",
+		}},
+		{testutil.Ubuntu, []string{"docker", "--uninstall", "--yes"}, []string{
+			"sudo true",
+			"sudo apt-get -y purge docker.io docker-compose",
+		}},
+		{testutil.Fedora, []string{"docker", "--uninstall", "--yes"}, []string{
+			"sudo true",
+			"sudo dnf -y remove docker docker-compose",
+		}},
+		{testutil.UniversalBlue, []string{"docker", "--uninstall"}, []string{
+			"sudo true",
+			"sudo rpm-ostree uninstall docker docker-compose",
+		}},
+		{testutil.MacOS, []string{"docker", "--uninstall"}, []string{
+			"brew uninstall docker docker-completion docker-compose docker-compose-completion",
+		}},
+		{testutil.Ubuntu, []string{"docker", "--config", "--user-to-docker", "tester"}, []string{
+			"sudo true",
+			"sudo gpasswd -a tester docker",
+		}},
+		{testutil.Alpine, []string{"docker", "--config", "--user-to-docker", "tester"}, []string{
+			"sudo true",
+			"sudo addgroup tester docker",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.distro.Name+"/"+tt.args[1], func(t *testing.T) {
+			env := testutil.New(t, tt.distro)
+			if err := env.Run(testRoot(), tt.args...); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands(tt.want...)
+		})
+	}
+}
+
+func TestKVM(t *testing.T) {
+	tests := []struct {
+		name     string
+		exitCode int
+		want     []string
+	}{
+		{"QEMU guest", 0, []string{
+			"sudo true",
+			"sudo dmesg | grep -q 'DMI: QEMU'",
+			"sudo apt-get -y update",
+			"sudo apt-get -y install spice-vdagent",
+		}},
+		{"not a virtual machine", 1, []string{
+			"sudo true",
+			"sudo dmesg | grep -q 'DMI: QEMU'",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			env.Exec.Stub("dmesg", tt.exitCode, "")
+			if err := env.Run(testRoot(), "kvm", "--config", "--yes"); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands(tt.want...)
+		})
+	}
+}
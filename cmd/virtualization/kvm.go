@@ -1,41 +1,55 @@
 package virtualization
 
 import (
+	"errors"
+
 	"github.com/spf13/cobra"
 	"legendu.net/icon/utils"
 )
 
+// spiceVdagentPackages are packages of the SPICE guest agent for package managers.
+var spiceVdagentPackages = utils.Packages{
+	"apt":    {"spice-vdagent"},
+	"dnf":    {"spice-vdagent"},
+	"pacman": {"spice-vdagent"},
+	"zypper": {"spice-vdagent"},
+	"apk":    {"spice-vdagent"},
+}
+
 // Configure a KVM virtual machine.
-func kvm(cmd *cobra.Command, _ []string) {
-	if utils.GetBoolFlag(cmd, "install") {
+func kvm(cmd *cobra.Command, _ []string) error {
+	config, err := utils.GetBoolFlag(cmd, "config")
+	if err != nil || !config || !utils.IsLinux() {
+		return err
+	}
+	prefix, err := utils.GetCommandPrefix(true, map[string]uint32{})
+	if err != nil {
+		return err
+	}
+	yes, err := utils.GetBoolFlag(cmd, "yes")
+	if err != nil {
+		return err
+	}
+	command := utils.Format("{prefix} dmesg | grep -q 'DMI: QEMU'", map[string]string{
+		"prefix": prefix,
+	})
+	err = utils.RunCmd(command)
+	// grep exits with 1 if this is not a QEMU virtual machine
+	var cmdErr *utils.CommandError
+	if errors.As(err, &cmdErr) && cmdErr.ExitCode == 1 {
+		return nil
 	}
-	if utils.GetBoolFlag(cmd, "config") {
-		if utils.IsLinux() {
-			if utils.IsDebianUbuntuSeries() {
-				command := utils.Format(`{prefix} dmesg | grep -q 'DMI: QEMU' \
-							&& {prefix} apt-get {yesStr} update \
-							&& {prefix} apt-get {yesStr} install spice-vdagent`, map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			} else if utils.IsFedoraSeries() {
-				command := utils.Format(`{prefix} dmesg | grep -q 'DMI: QEMU' \
-							&& {prefix} dnf {yesStr} install spice-vdagent`, map[string]string{
-					"prefix": utils.GetCommandPrefix(true, map[string]uint32{}),
-					"yesStr": utils.BuildYesFlag(cmd),
-				})
-				utils.RunCmd(command)
-			}
-		}
+	if err != nil {
+		return err
 	}
+	return utils.InstallPackages("spice-vdagent", spiceVdagentPackages, yes)
 }
 
 var kvmCmd = &cobra.Command{
 	Use:     "kvm",
 	Aliases: []string{},
 	Short:   "Install and configure KVM related tools.",
-	Run:     kvm,
+	RunE:    kvm,
 }
 
 func ConfigKVMCmd(rootCmd *cobra.Command) {
@@ -8,16 +8,15 @@ require (
 	github.com/pelletier/go-toml/v2 v2.2.4
 	github.com/shirou/gopsutil v3.21.11+incompatible
 	github.com/spf13/cobra v1.10.1
+	github.com/spf13/pflag v1.0.10
 	golang.org/x/sys v0.38.0
-	periph.io/x/host/v3 v3.8.5
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
 	github.com/go-ole/go-ole v1.3.0 // indirect
 	github.com/inconshreveable/mousetrap v1.1.0 // indirect
-	github.com/spf13/pflag v1.0.10 // indirect
 	github.com/tklauser/go-sysconf v0.3.16 // indirect
 	github.com/tklauser/numcpus v0.11.0 // indirect
 	github.com/yusufpapurcu/wmi v1.2.4 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
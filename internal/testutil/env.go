@@ -0,0 +1,298 @@
+package testutil
+
+import (
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"legendu.net/icon/utils"
+)
+
+// Home is the home directory of the fake user.
+const Home = "/home/tester"
+
+// Distro is an operating system to emulate.
+type Distro struct {
+	// Name is the name of the distribution in test names.
+	Name string
+	// GOOS is the OS, i.e., linux or darwin.
+	GOOS string
+	// OSRelease is the content of /etc/os-release.
+	OSRelease string
+	// Commands are commands shipped with the distribution (besides bash and sudo).
+	Commands []string
+	// Files are (empty) files marking the distribution, e.g., /run/ostree-booted.
+	Files []string
+}
+
+// Distributions to emulate.
+var (
+	Ubuntu = Distro{
+		Name:      "ubuntu",
+		GOOS:      "linux",
+		OSRelease: "ID=ubuntu\nID_LIKE=debian\nVERSION_ID=\"24.04\"\n",
+		Commands:  []string{"apt-get", "dpkg"},
+	}
+	Debian = Distro{
+		Name:      "debian",
+		GOOS:      "linux",
+		OSRelease: "ID=debian\nVERSION_ID=\"12\"\n",
+		Commands:  []string{"apt-get", "dpkg"},
+	}
+	Fedora = Distro{
+		Name:      "fedora",
+		GOOS:      "linux",
+		OSRelease: "ID=fedora\nVERSION_ID=41\n",
+		Commands:  []string{"dnf", "rpm"},
+	}
+	// UniversalBlue is Aurora, an image-based Fedora with Homebrew.
+	UniversalBlue = Distro{
+		Name:      "aurora",
+		GOOS:      "linux",
+		OSRelease: "ID=aurora\nID_LIKE=\"fedora\"\nVERSION_ID=41\n",
+		Commands:  []string{"rpm-ostree", "rpm", "brew"},
+		Files:     []string{"/run/ostree-booted"},
+	}
+	Arch = Distro{
+		Name:      "arch",
+		GOOS:      "linux",
+		OSRelease: "ID=arch\n",
+		Commands:  []string{"pacman"},
+	}
+	OpenSUSE = Distro{
+		Name:      "opensuse",
+		GOOS:      "linux",
+		OSRelease: "ID=\"opensuse-tumbleweed\"\nID_LIKE=\"opensuse suse\"\n",
+		Commands:  []string{"zypper", "rpm"},
+	}
+	Alpine = Distro{
+		Name:      "alpine",
+		GOOS:      "linux",
+		OSRelease: "ID=alpine\n",
+		Commands:  []string{"apk"},
+	}
+	MacOS = Distro{
+		Name:     "macos",
+		GOOS:     "darwin",
+		Commands: []string{"brew"},
+	}
+)
+
+// systemDirs are directories which are read-only for the fake user, so that writing into them requires sudo.
+var systemDirs = []string{"/etc", "/usr/local/bin", "/usr/local/share", "/usr/share", "/opt"}
+
+// Env is a fake system emulating a distribution, in which commands of icon are run in tests.
+type Env struct {
+	t *testing.T
+	// FS is the file system rooted at a temporary directory.
+	FS *utils.BasePathFS
+	// Exec records commands run.
+	Exec *Executor
+	// HTTP serves HTTP requests.
+	HTTP *Transport
+}
+
+// New sets up a fake system emulating a distribution for a test, which is torn down when the test finishes.
+// The home directory of the fake user is Home. States, caches and logs of icon are written into temporary directories,
+// which are shared with the operating system (see utils.NewBasePathFS) as are temporary files.
+// Tests using it must not run in parallel.
+//
+// @param t      The test.
+// @param distro The distribution to emulate.
+//
+// @return The fake system.
+func New(t *testing.T, distro Distro) *Env {
+	t.Helper()
+	root := t.TempDir()
+	env := &Env{
+		t:    t,
+		FS:   utils.NewBasePathFS(root, os.TempDir()),
+		Exec: NewExecutor(append([]string{"bash", "sudo"}, distro.Commands...)...),
+		HTTP: NewTransport(),
+	}
+	t.Setenv("HOME", Home)
+	t.Setenv("XDG_STATE_HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", "")
+	t.Setenv("ICON_CACHE_DIR", t.TempDir())
+	t.Setenv("ICON_CONFIG", filepath.Join(t.TempDir(), "config.yaml"))
+	t.Cleanup(utils.SetFS(env.FS))
+	t.Cleanup(utils.SetExecutor(env.Exec))
+	t.Cleanup(utils.SetHTTPClient(&http.Client{Transport: env.HTTP}))
+	t.Setenv("container", "")
+	t.Setenv("WSL_DISTRO_NAME", "")
+	env.MkdirAll(Home)
+	if distro.OSRelease != "" {
+		env.WriteFile("/etc/os-release", distro.OSRelease)
+	}
+	for _, file := range distro.Files {
+		env.WriteFile(file, "")
+	}
+	// the platform is detected from the fake system
+	platform := utils.DetectPlatform()
+	if distro.GOOS != "linux" {
+		platform = utils.Platform{OS: distro.GOOS}
+	}
+	platform.Arch = "amd64"
+	t.Cleanup(utils.SetPlatform(platform))
+	for _, dir := range systemDirs {
+		env.MkdirAll(dir)
+		env.chmod(dir, 0o555)
+	}
+	// read-only directories are made writable again so that the temporary directory can be removed
+	t.Cleanup(func() {
+		for _, dir := range systemDirs {
+			env.chmod(dir, 0o755)
+		}
+	})
+	return env
+}
+
+func (e *Env) chmod(path string, mode fs.FileMode) {
+	e.t.Helper()
+	if err := os.Chmod(e.FS.RealPath(path), mode); err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+// MkdirAll creates a directory (with parents) in the fake file system.
+// A path starting with ~/ is in Home.
+//
+// @param path The path of the directory.
+func (e *Env) MkdirAll(path string) {
+	e.t.Helper()
+	//nolint:mnd // readable
+	if err := os.MkdirAll(e.FS.RealPath(e.path(path)), 0o755); err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+// WriteFile writes a file (creating its parent directories) in the fake file system.
+// A path starting with ~/ is in Home.
+//
+// @param path    The path of the file.
+// @param content The content of the file.
+func (e *Env) WriteFile(path, content string) {
+	e.t.Helper()
+	path = e.path(path)
+	e.MkdirAll(filepath.Dir(path))
+	//nolint:mnd // readable
+	if err := os.WriteFile(e.FS.RealPath(path), []byte(content), 0o644); err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+// ReadFile reads a file in the fake file system.
+//
+// @param path The path of the file.
+//
+// @return The content of the file.
+func (e *Env) ReadFile(path string) string {
+	e.t.Helper()
+	bytes, err := os.ReadFile(e.FS.RealPath(e.path(path)))
+	if err != nil {
+		e.t.Fatal(err)
+	}
+	return string(bytes)
+}
+
+// Readlink returns the target of a symbolic link in the fake file system.
+//
+// @param path The path of the symbolic link.
+//
+// @return The target, or an empty string if path is not a symbolic link.
+func (e *Env) Readlink(path string) string {
+	target, err := e.FS.Readlink(e.path(path))
+	if err != nil {
+		return ""
+	}
+	return target
+}
+
+// Exists checks whether a path exists in the fake file system.
+//
+// @param path The path.
+//
+// @return true if the path exists.
+func (e *Env) Exists(path string) bool {
+	_, err := e.FS.Lstat(e.path(path))
+	return err == nil
+}
+
+// FakeConfigData makes ~/.config/icon-data a Git repo so that it is not cloned (see icon.FetchConfigData),
+// and writes files (with paths relative to it) into it.
+//
+// @param files Contents of files by path relative to ~/.config/icon-data.
+func (e *Env) FakeConfigData(files map[string]string) {
+	e.t.Helper()
+	e.MkdirAll("~/.config/icon-data/.git")
+	for path, content := range files {
+		e.WriteFile(filepath.Join("~/.config/icon-data", path), content)
+	}
+}
+
+func (e *Env) path(path string) string {
+	if rest, ok := strings.CutPrefix(path, "~/"); ok {
+		return filepath.Join(Home, rest)
+	}
+	return path
+}
+
+// Run runs icon with arguments (e.g., git --install) from the root command of a command tree,
+// with all flags reset to their defaults first.
+//
+// @param root The root command.
+// @param args The arguments.
+//
+// @return The error of the command.
+func (e *Env) Run(root *cobra.Command, args ...string) error {
+	e.t.Helper()
+	resetFlags(root)
+	root.SilenceUsage, root.SilenceErrors = true, true
+	root.SetArgs(args)
+	return root.Execute()
+}
+
+// resetFlags resets flags of a command and its subcommands to their defaults,
+// as flags keep values from previous runs of the same command tree.
+func resetFlags(cmd *cobra.Command) {
+	reset := func(flag *pflag.Flag) {
+		if slice, ok := flag.Value.(pflag.SliceValue); ok {
+			values := []string{}
+			if def := strings.Trim(flag.DefValue, "[]"); def != "" {
+				values = strings.Split(def, ",")
+			}
+			_ = slice.Replace(values)
+		} else {
+			_ = flag.Value.Set(flag.DefValue)
+		}
+		flag.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+// AssertCommands checks that the commands run are exactly the expected ones.
+// A * in an expected command matches any sequence of non-space characters, e.g., a temporary path.
+//
+// @param want The expected commands (see Call.String).
+func (e *Env) AssertCommands(want ...string) {
+	e.t.Helper()
+	got := e.Exec.Commands()
+	ok := len(got) == len(want)
+	for i := 0; ok && i < len(got); i++ {
+		pattern := strings.ReplaceAll(regexp.QuoteMeta(want[i]), `\*`, `\S*`)
+		ok = regexp.MustCompile("^" + pattern + "$").MatchString(got[i])
+	}
+	if !ok {
+		e.t.Errorf("commands run:\n  %s\nwant:\n  %s", strings.Join(got, "\n  "), strings.Join(want, "\n  "))
+	}
+}
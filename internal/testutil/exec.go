@@ -0,0 +1,191 @@
+// Package testutil provides a fake system for testing commands of icon without touching the real one:
+// an executor recording commands instead of running them (Executor),
+// a file system rooted at a temporary directory (utils.BasePathFS),
+// an HTTP transport serving registered responses (Transport) and Linux distributions to emulate (Distro).
+package testutil
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+
+	"legendu.net/icon/utils"
+)
+
+// Call is a command run through the fake executor.
+type Call struct {
+	// Args are the program and its arguments, e.g., bash -c <script> for shell commands.
+	Args []string
+	// Dir is the working directory of the command.
+	Dir string
+	// Stdin is what is piped into the command (unless it reads the standard input of the terminal).
+	Stdin string
+}
+
+// String returns the command as it would be typed: the script of a shell command (with line continuations
+// and white spaces collapsed, so that templates with empty placeholders read naturally)
+// or arguments quoted by utils.ShellJoin.
+func (c Call) String() string {
+	cmd := utils.ShellJoin(c.Args...)
+	if len(c.Args) == 3 && c.Args[0] == "bash" && c.Args[1] == "-c" {
+		cmd = strings.Join(strings.Fields(strings.ReplaceAll(c.Args[2], "\\\n", " ")), " ")
+	}
+	if c.Dir != "" {
+		cmd = "cd " + utils.ShellQuote(c.Dir) + " && " + cmd
+	}
+	return cmd
+}
+
+// ExitError is the error of a command failing with an exit code.
+type ExitError struct {
+	Code int
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
+
+// ExitCode returns the exit code of the command.
+func (e *ExitError) ExitCode() int {
+	return e.Code
+}
+
+type stub struct {
+	pattern  *regexp.Regexp
+	exitCode int
+	stdout   string
+}
+
+// Executor is a fake utils.Executor which records commands instead of running them.
+// Commands succeed with no output unless stubbed (see Stub).
+type Executor struct {
+	mu    sync.Mutex
+	calls []Call
+	paths map[string]string
+	stubs []stub
+	user  *user.User
+}
+
+// NewExecutor creates a fake executor run by a regular user (tester with the UID 1000).
+//
+// @param commands Commands found on PATH (see AddCommands).
+//
+// @return The executor.
+func NewExecutor(commands ...string) *Executor {
+	e := &Executor{
+		paths: map[string]string{},
+		user:  &user.User{Uid: "1000", Gid: "1000", Username: "tester", Name: "Tester", HomeDir: Home},
+	}
+	e.AddCommands(commands...)
+	return e
+}
+
+// AddCommands makes commands found on PATH (as /usr/bin/<name>) by LookPath.
+//
+// @param commands Names of the commands.
+func (e *Executor) AddCommands(commands ...string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for _, command := range commands {
+		e.paths[command] = filepath.Join("/usr/bin", command)
+	}
+}
+
+// SetRoot makes the root user (instead of tester) run commands, who does not need sudo.
+func (e *Executor) SetRoot() {
+	e.user = &user.User{Uid: "0", Gid: "0", Username: "root", Name: "root", HomeDir: "/root"}
+}
+
+// Stub makes commands matching a regular expression (against Call.String) exit with a code and print output.
+// The last matching stub wins.
+//
+// @param pattern  The regular expression.
+// @param exitCode The exit code.
+// @param stdout   The standard output.
+func (e *Executor) Stub(pattern string, exitCode int, stdout string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.stubs = append(e.stubs, stub{regexp.MustCompile(pattern), exitCode, stdout})
+}
+
+// Run records a command and returns the result of its stub.
+func (e *Executor) Run(command *exec.Cmd) error {
+	call := Call{Args: command.Args, Dir: command.Dir}
+	if command.Stdin != nil && command.Stdin != os.Stdin {
+		stdin, err := io.ReadAll(command.Stdin)
+		if err != nil {
+			return err
+		}
+		call.Stdin = string(stdin)
+	}
+	e.mu.Lock()
+	e.calls = append(e.calls, call)
+	result := stub{}
+	for _, s := range e.stubs {
+		if s.pattern.MatchString(call.String()) {
+			result = s
+		}
+	}
+	e.mu.Unlock()
+	if result.stdout != "" && command.Stdout != nil {
+		if _, err := io.WriteString(command.Stdout, result.stdout); err != nil {
+			return err
+		}
+	}
+	if result.exitCode != 0 {
+		return &ExitError{Code: result.exitCode}
+	}
+	return nil
+}
+
+// LookPath finds commands added by AddCommands and files (in the fake file system) specified by absolute paths.
+func (e *Executor) LookPath(file string) (string, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if path, ok := e.paths[file]; ok {
+		return path, nil
+	}
+	if filepath.IsAbs(file) && utils.ExistsFile(file) {
+		return file, nil
+	}
+	return "", &exec.Error{Name: file, Err: exec.ErrNotFound}
+}
+
+// CurrentUser returns tester (or root, see SetRoot).
+func (e *Executor) CurrentUser() (*user.User, error) {
+	return e.user, nil
+}
+
+// Calls returns the commands which have been run.
+//
+// @return The commands in the order they were run.
+func (e *Executor) Calls() []Call {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]Call{}, e.calls...)
+}
+
+// Commands returns the commands which have been run as strings (see Call.String).
+//
+// @return The commands in the order they were run.
+func (e *Executor) Commands() []string {
+	calls := e.Calls()
+	commands := make([]string, 0, len(calls))
+	for _, call := range calls {
+		commands = append(commands, call.String())
+	}
+	return commands
+}
+
+// Reset forgets the commands which have been run.
+func (e *Executor) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls = nil
+}
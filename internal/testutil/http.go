@@ -0,0 +1,136 @@
+package testutil
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Transport is a fake http.RoundTripper serving registered responses.
+// Requests to unregistered URLs get 404 so that tests never reach the network.
+type Transport struct {
+	mu        sync.Mutex
+	responses map[string][]byte
+	requests  []string
+}
+
+// NewTransport creates a fake transport without responses.
+//
+// @return The transport.
+func NewTransport() *Transport {
+	return &Transport{responses: map[string][]byte{}}
+}
+
+// Handle registers the body of the response to GET requests to a URL.
+//
+// @param url  The URL.
+// @param body The body of the response.
+func (t *Transport) Handle(url string, body []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.responses[url] = body
+}
+
+// HandleJSON registers a value encoded as JSON as the response to GET requests to a URL.
+//
+// @param url The URL.
+// @param v   The value.
+func (t *Transport) HandleJSON(url string, v any) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	t.Handle(url, body)
+}
+
+// GitHubRelease registers a release of a GitHub repo (as the latest release) with its assets.
+//
+// @param repo   The repo, e.g., dandavison/delta.
+// @param tag    The tag of the release, e.g., 0.18.2.
+// @param assets Contents of assets by name.
+func (t *Transport) GitHubRelease(repo, tag string, assets map[string][]byte) {
+	names := make([]string, 0, len(assets))
+	for name := range assets {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	infos := make([]map[string]any, 0, len(assets))
+	for _, name := range names {
+		url := fmt.Sprintf("https://github.com/%s/releases/download/%s/%s", repo, tag, name)
+		t.Handle(url, assets[name])
+		infos = append(infos, map[string]any{"name": name, "browser_download_url": url, "size": len(assets[name])})
+	}
+	release := map[string]any{"tag_name": tag, "assets": infos}
+	api := "https://api.github.com/repos/" + repo + "/releases"
+	t.HandleJSON(api+"/latest", release)
+	t.HandleJSON(api, []any{release})
+}
+
+// RoundTrip serves a registered response or 404.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
+	t.mu.Lock()
+	t.requests = append(t.requests, url)
+	body, ok := t.responses[url]
+	t.mu.Unlock()
+	status := http.StatusOK
+	if !ok {
+		status, body = http.StatusNotFound, []byte("not found")
+	}
+	return &http.Response{
+		Status:        http.StatusText(status),
+		StatusCode:    status,
+		Header:        http.Header{},
+		Body:          io.NopCloser(bytes.NewReader(body)),
+		ContentLength: int64(len(body)),
+		Request:       req,
+	}, nil
+}
+
+// Requests returns URLs which have been requested.
+//
+// @return The URLs in the order they were requested.
+func (t *Transport) Requests() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return slices.Clone(t.requests)
+}
+
+// TarGz creates a .tar.gz archive of executable files, e.g., for release assets.
+//
+// @param files Contents of files by path in the archive.
+//
+// @return The archive.
+func TarGz(files map[string]string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	paths := make([]string, 0, len(files))
+	for path := range files {
+		paths = append(paths, path)
+	}
+	slices.Sort(paths)
+	for _, path := range paths {
+		header := &tar.Header{Name: path, Mode: 0o755, Size: int64(len(files[path])), Typeflag: tar.TypeReg}
+		if err := tw.WriteHeader(header); err != nil {
+			panic(err)
+		}
+		if _, err := io.Copy(tw, strings.NewReader(files[path])); err != nil {
+			panic(err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		panic(err)
+	}
+	if err := gz.Close(); err != nil {
+		panic(err)
+	}
+	return buf.Bytes()
+}
@@ -0,0 +1,654 @@
+package utils
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// archiveVisitor is called for each regular file in an archive.
+//
+// @param name The path of the file in the archive, or an empty string if the archive is a single file.
+// @param mode The file mode recorded in the archive (0 if unknown).
+// @param r    The content of the file.
+type archiveVisitor func(name string, mode fs.FileMode, r io.Reader) error
+
+// Magic numbers of supported formats.
+var (
+	magicGzip  = []byte{0x1f, 0x8b}
+	magicXz    = []byte{0xfd, '7', 'z', 'X', 'Z', 0x00}
+	magicZstd  = []byte{0x28, 0xb5, 0x2f, 0xfd}
+	magicBzip2 = []byte("BZh")
+	magicZip   = []byte("PK\x03\x04")
+	magicAr    = []byte("!<arch>\n")
+	magicRpm   = []byte{0xed, 0xab, 0xee, 0xdb}
+	magicCpio  = []byte("070701")
+)
+
+// decompress detects the compression of a stream by its magic number and decompresses it.
+// xz and zstd are decompressed by the commands xz and zstd as the standard library does not support them.
+//
+// @param r The stream.
+//
+// @return The decompressed stream, a function to release resources (which must be called)
+// and whether the stream is compressed.
+func decompress(r *bufio.Reader) (io.Reader, func() error, bool, error) {
+	noop := func() error { return nil }
+	//nolint:mnd // readable
+	head, _ := r.Peek(6)
+	switch {
+	case bytes.HasPrefix(head, magicGzip):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, noop, false, fmt.Errorf("failed to read the gzip stream: %w", err)
+		}
+		return gz, gz.Close, true, nil
+	case bytes.HasPrefix(head, magicBzip2):
+		return bzip2.NewReader(r), noop, true, nil
+	case bytes.HasPrefix(head, magicXz):
+		return decompressByCommand(r, "xz")
+	case bytes.HasPrefix(head, magicZstd):
+		return decompressByCommand(r, "zstd")
+	}
+	return r, noop, false, nil
+}
+
+func decompressByCommand(r io.Reader, name string) (io.Reader, func() error, bool, error) {
+	if LookPath(name) == "" {
+		return nil, func() error { return nil }, false, fmt.Errorf("%s is required to decompress the archive but it is not found", name)
+	}
+	command := exec.CommandContext(context.Background(), name, "-dc")
+	command.Stdin = r
+	command.Stderr = os.Stderr
+	out, err := command.StdoutPipe()
+	if err != nil {
+		return nil, func() error { return nil }, false, fmt.Errorf("failed to decompress the archive using %s: %w", name, err)
+	}
+	if err := command.Start(); err != nil {
+		return nil, func() error { return nil }, false, fmt.Errorf("failed to decompress the archive using %s: %w", name, err)
+	}
+	wait := func() error {
+		// drain the output so that the command does not block on writing
+		_, _ = io.Copy(io.Discard, out)
+		if err := command.Wait(); err != nil {
+			return fmt.Errorf("failed to decompress the archive using %s: %w", name, err)
+		}
+		return nil
+	}
+	return out, wait, true, nil
+}
+
+// isTar checks whether a stream is a tar archive.
+func isTar(r *bufio.Reader) bool {
+	//nolint:mnd // the magic "ustar" is at the offset 257 of a tar header
+	head, _ := r.Peek(262)
+	//nolint:mnd // readable
+	return len(head) == 262 && string(head[257:262]) == "ustar"
+}
+
+// walkStream walks a (possibly compressed) tar archive, a cpio archive or a single (possibly compressed) file.
+//
+// @param r     The stream.
+// @param visit The function to call for each regular file.
+func walkStream(r io.Reader, visit archiveVisitor) error {
+	decompressed, release, _, err := decompress(bufio.NewReader(r))
+	if err != nil {
+		return err
+	}
+	br := bufio.NewReader(decompressed)
+	switch {
+	case isTar(br):
+		err = walkTar(br, visit)
+	case func() bool { head, _ := br.Peek(len(magicCpio)); return bytes.Equal(head, magicCpio) }():
+		err = walkCpio(br, visit)
+	default:
+		err = visit("", 0, br)
+	}
+	if releaseErr := release(); err == nil {
+		err = releaseErr
+	}
+	return err
+}
+
+func walkTar(r io.Reader, visit archiveVisitor) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read the tar archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg {
+			if err := visit(header.Name, header.FileInfo().Mode(), tr); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// walkCpio walks a cpio archive in the "new ASCII" format (as used by RPM payloads).
+func walkCpio(r io.Reader, visit archiveVisitor) error {
+	br := bufio.NewReader(r)
+	const headerSize = 110
+	// names (including the terminating NUL) longer than PATH_MAX are rejected instead of being allocated
+	const maxNameSize = 4096
+	header := make([]byte, headerSize)
+	field := func(idx int) (int64, error) {
+		//nolint:mnd // fields are 8 hex digits following the 6-byte magic
+		return strconv.ParseInt(string(header[6+8*idx:6+8*idx+8]), 16, 64)
+	}
+	for {
+		if _, err := io.ReadFull(br, header); err != nil {
+			return fmt.Errorf("failed to read the cpio archive: %w", err)
+		}
+		if !bytes.HasPrefix(header, magicCpio) {
+			return errors.New("unsupported cpio archive format")
+		}
+		//nolint:mnd // indexes of fields mode, filesize and namesize
+		mode, err1 := field(1)
+		size, err2 := field(6)
+		nameSize, err3 := field(11)
+		if err := errors.Join(err1, err2, err3); err != nil {
+			return fmt.Errorf("failed to parse the cpio archive: %w", err)
+		}
+		if nameSize <= 0 || nameSize > maxNameSize || size < 0 {
+			return fmt.Errorf("failed to parse the cpio archive: invalid name size %d or file size %d", nameSize, size)
+		}
+		// the header and the name are padded to a multiple of 4 bytes
+		//nolint:mnd // readable
+		nameBytes := make([]byte, (headerSize+nameSize+3)/4*4-headerSize)
+		if _, err := io.ReadFull(br, nameBytes); err != nil {
+			return fmt.Errorf("failed to read the cpio archive: %w", err)
+		}
+		name := string(nameBytes[:nameSize-1])
+		if name == "TRAILER!!!" {
+			return nil
+		}
+		content := &io.LimitedReader{R: br, N: size}
+		//nolint:mnd // S_IFMT and S_IFREG
+		if mode&0o170000 == 0o100000 {
+			//nolint:gosec // the mode fits in 32 bits
+			if err := visit(name, fs.FileMode(mode&0o777), content); err != nil {
+				return err
+			}
+		}
+		// skip the unread data and the padding to a multiple of 4 bytes
+		//nolint:mnd // readable
+		if _, err := io.CopyN(io.Discard, br, content.N+(size+3)/4*4-size); err != nil {
+			return fmt.Errorf("failed to read the cpio archive: %w", err)
+		}
+	}
+}
+
+// walkZip walks a zip archive.
+func walkZip(file string, visit archiveVisitor) error {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return fmt.Errorf("failed to open the zip archive %s: %w", file, err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return fmt.Errorf("failed to read %s in the zip archive %s: %w", f.Name, file, err)
+		}
+		err = visit(f.Name, f.Mode(), rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walkDeb walks files installed by a Debian package, i.e., files in its member data.tar.*.
+func walkDeb(r *bufio.Reader, visit archiveVisitor) error {
+	if _, err := r.Discard(len(magicAr)); err != nil {
+		return fmt.Errorf("failed to read the Debian package: %w", err)
+	}
+	const headerSize = 60
+	header := make([]byte, headerSize)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return fmt.Errorf("no data.tar is found in the Debian package: %w", err)
+		}
+		//nolint:mnd // the name is in the first 16 bytes and the size is in the bytes 48 to 58
+		name := strings.TrimSuffix(strings.TrimSpace(string(header[:16])), "/")
+		//nolint:mnd // readable
+		size, err := strconv.ParseInt(strings.TrimSpace(string(header[48:58])), 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse the Debian package: %w", err)
+		}
+		if strings.HasPrefix(name, "data.tar") {
+			return walkStream(io.LimitReader(r, size), visit)
+		}
+		// members are padded to an even size
+		//nolint:mnd // readable
+		if _, err := r.Discard(int(size + size%2)); err != nil {
+			return fmt.Errorf("failed to read the Debian package: %w", err)
+		}
+	}
+}
+
+// walkRpm walks files installed by an RPM package, i.e., files in its (compressed) cpio payload.
+func walkRpm(r *bufio.Reader, visit archiveVisitor) error {
+	// the lead is followed by the signature header (padded to a multiple of 8 bytes) and the header
+	const leadSize = 96
+	if _, err := r.Discard(leadSize); err != nil {
+		return fmt.Errorf("failed to read the RPM package: %w", err)
+	}
+	for _, padded := range []bool{true, false} {
+		//nolint:mnd // the magic, the version and reserved bytes, the number of index entries and the size of data
+		intro := make([]byte, 16)
+		if _, err := io.ReadFull(r, intro); err != nil {
+			return fmt.Errorf("failed to read the RPM package: %w", err)
+		}
+		if !bytes.Equal(intro[:3], []byte{0x8e, 0xad, 0xe8}) {
+			return errors.New("failed to parse the RPM package: bad header magic")
+		}
+		//nolint:mnd // readable
+		size := int(16*binary.BigEndian.Uint32(intro[8:12]) + binary.BigEndian.Uint32(intro[12:16]))
+		if padded {
+			//nolint:mnd // readable
+			size = (size+16+7)/8*8 - 16
+		}
+		if _, err := r.Discard(size); err != nil {
+			return fmt.Errorf("failed to read the RPM package: %w", err)
+		}
+	}
+	return walkStream(r, visit)
+}
+
+// walkArchive walks regular files in an archive or a package.
+// Supported are tar archives (optionally compressed by gzip, bzip2, xz or zstd), zip archives,
+// Debian and RPM packages, and single (optionally compressed) files such as binaries and AppImages.
+// Streams compressed by xz or zstd (e.g., .tar.xz, .tar.zst and most RPM payloads) need the external commands xz or zstd
+// as the standard library cannot decompress them.
+//
+// @param file  The path of the archive.
+// @param visit The function to call for each regular file.
+func walkArchive(file string, visit archiveVisitor) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", file, err)
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	//nolint:mnd // readable
+	head, _ := r.Peek(8)
+	switch {
+	case bytes.HasPrefix(head, magicZip):
+		return walkZip(file, visit)
+	case bytes.HasPrefix(head, magicAr):
+		return walkDeb(r, visit)
+	case bytes.HasPrefix(head, magicRpm):
+		return walkRpm(r, visit)
+	}
+	return walkStream(r, visit)
+}
+
+// isExecutableContent checks whether content starts with the magic number of an ELF or Mach-O binary or a shebang.
+func isExecutableContent(head []byte) bool {
+	for _, magic := range [][]byte{
+		{0x7f, 'E', 'L', 'F'},
+		{0xcf, 0xfa, 0xed, 0xfe},
+		{0xca, 0xfe, 0xba, 0xbe},
+		[]byte("#!"),
+	} {
+		if bytes.HasPrefix(head, magic) {
+			return true
+		}
+	}
+	return false
+}
+
+// extractedFile is a file extracted from an archive.
+type extractedFile struct {
+	// name is the path of the file in the archive.
+	name string
+	// path is where the file is extracted to.
+	path string
+	// executable indicates whether the file is executable.
+	executable bool
+}
+
+// InstallBinaries extracts files with the specified names from an archive (see walkArchive)
+// and installs them into a directory.
+// Executables are installed with the mode 755 and other files (e.g., shared libraries) with the mode 644.
+// If several files in the archive have the same name, executables and files at shallower paths are preferred.
+// sudo is used only if the directory is not writable.
+// In dry-run mode, the installation is recorded into the plan.
+//
+// @param archive The path of the archive. A single file (e.g., a binary or an AppImage)
+// is installed as the first name.
+// @param names   Names (or glob patterns of names) of the files to install.
+// @param dir     The directory to install the files into.
+//
+// @return Paths of the installed files.
+func InstallBinaries(archive string, names []string, dir string) ([]string, error) {
+	return InstallBinariesFromArchives([]string{archive}, names, dir)
+}
+
+// InstallBinariesFromArchives is the same as InstallBinaries except that files are extracted from several archives,
+// e.g., assets of a release each containing some of the files.
+// Each name has to be found in one of the archives (instead of in each of them).
+//
+// @param archives Paths of the archives.
+// @param names    Names (or glob patterns of names) of the files to install.
+// @param dir      The directory to install the files into.
+//
+// @return Paths of the installed files.
+func InstallBinariesFromArchives(archives, names []string, dir string) ([]string, error) {
+	dir = NormalizePath(dir)
+	if dryRun {
+		recordStep("install", "%s from %s -> %s", strings.Join(names, ", "), strings.Join(archives, ", "), dir)
+		return nil, nil
+	}
+	tmpdir, err := CreateTempDir("")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpdir)
+	found := map[string]extractedFile{}
+	all := []string{}
+	for _, archive := range archives {
+		executables, err := extractFiles(archive, names, tmpdir, found, len(archives) > 1)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, executables...)
+	}
+	for _, pattern := range names {
+		matched := false
+		for base := range found {
+			if ok, _ := path.Match(pattern, base); ok {
+				matched = true
+			}
+		}
+		if !matched {
+			return nil, fmt.Errorf("no file named %s is found in %s. Executables in it are:\n  %s",
+				pattern, strings.Join(archives, ", "), strings.Join(all, "\n  "))
+		}
+	}
+	return installExtractedFiles(found, dir)
+}
+
+// extractFiles extracts files with the specified names from an archive into a directory,
+// keeping the preferred file (see betterExtractedFile) for each name in found.
+// A single file (instead of an archive) is extracted as the first name.
+//
+// @param multiple Whether the archive is one of several (e.g., assets of a release downloaded with --all),
+// in which case a single file is extracted only if it is executable
+// so that other assets (e.g., checksums and signatures) are never installed as the first name.
+//
+// @return Paths of executables in the archive.
+func extractFiles(archive string, names []string, tmpdir string, found map[string]extractedFile, multiple bool) ([]string, error) {
+	all := []string{}
+	err := walkArchive(archive, func(name string, mode fs.FileMode, r io.Reader) error {
+		br := bufio.NewReader(r)
+		//nolint:mnd // readable
+		head, _ := br.Peek(4)
+		executable := mode&0o111 != 0 || isExecutableContent(head)
+		base := path.Base(name)
+		single := name == ""
+		if single {
+			if multiple && !executable {
+				return nil
+			}
+			name, base = filepath.Base(archive), names[0]
+		}
+		if executable {
+			all = append(all, name)
+		}
+		if !single && !slices.ContainsFunc(names, func(pattern string) bool {
+			matched, _ := path.Match(pattern, base)
+			return matched
+		}) {
+			return nil
+		}
+		candidate := extractedFile{name: name, path: filepath.Join(tmpdir, base), executable: executable}
+		if previous, ok := found[base]; ok && !betterExtractedFile(candidate, previous) {
+			return nil
+		}
+		out, err := os.Create(candidate.path)
+		if err != nil {
+			return fmt.Errorf("failed to create file '%s': %w", candidate.path, err)
+		}
+		defer out.Close()
+		if _, err := io.Copy(out, br); err != nil {
+			return fmt.Errorf("failed to extract %s from %s: %w", name, archive, err)
+		}
+		found[base] = candidate
+		return nil
+	})
+	return all, err
+}
+
+// betterExtractedFile checks whether a file extracted from an archive is preferred over another one with the same name.
+func betterExtractedFile(a, b extractedFile) bool {
+	if a.executable != b.executable {
+		return a.executable
+	}
+	return strings.Count(a.name, "/") < strings.Count(b.name, "/")
+}
+
+func installExtractedFiles(found map[string]extractedFile, dir string) ([]string, error) {
+	if err := MkdirAll(dir, ""); err != nil {
+		return nil, err
+	}
+	prefix, err := GetCommandPrefix(false, map[string]uint32{
+		dir: unix.W_OK | unix.R_OK,
+	})
+	if err != nil {
+		return nil, err
+	}
+	bases := make([]string, 0, len(found))
+	for base := range found {
+		bases = append(bases, base)
+	}
+	slices.Sort(bases)
+	installed := []string{}
+	for _, base := range bases {
+		file := found[base]
+		dst := filepath.Join(dir, base)
+		mode := IfElseString(file.executable, "755", "644")
+		record := prepareWrite(dst)
+		if err := NewCommand("install", "-m", mode, file.path, dst).Sudo(prefix).Run(); err != nil {
+			return installed, err
+		}
+		record()
+		installed = append(installed, dst)
+	}
+	return installed, nil
+}
+
+// CreateTarball packs a directory into a tar archive, compressed by gzip if the name of the archive ends with .gz or .tgz.
+// Directories, regular files and symbolic links are packed with paths relative to the directory.
+// In dry-run mode, the creation is recorded into the plan.
+//
+// @param dir  The directory to pack.
+// @param file The path of the archive to create.
+func CreateTarball(dir, file string) error {
+	dir, file = NormalizePath(dir), NormalizePath(file)
+	if dryRun {
+		recordStep("write", "%s (a tar archive of %s)", file, dir)
+		return nil
+	}
+	out, err := os.Create(file)
+	if err != nil {
+		return fmt.Errorf("failed to create the archive %s: %w", file, err)
+	}
+	defer out.Close()
+	var w io.Writer = out
+	var gz *gzip.Writer
+	if strings.HasSuffix(file, ".gz") || strings.HasSuffix(file, ".tgz") {
+		gz = gzip.NewWriter(out)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	err = filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil || rel == "." {
+			return err
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		link := ""
+		if info.Mode()&fs.ModeSymlink != 0 {
+			if link, err = os.Readlink(path); err != nil {
+				return err
+			}
+		}
+		header, err := tar.FileInfoHeader(info, link)
+		if err != nil {
+			return err
+		}
+		header.Name = filepath.ToSlash(rel)
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() {
+			return nil
+		}
+		in, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer in.Close()
+		_, err = io.Copy(tw, in)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to pack %s into %s: %w", dir, file, err)
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to write the archive %s: %w", file, err)
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return fmt.Errorf("failed to write the archive %s: %w", file, err)
+		}
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write the archive %s: %w", file, err)
+	}
+	return nil
+}
+
+// ExtractTarball extracts directories, regular files and symbolic links
+// of a (possibly compressed) tar archive into a directory.
+// Entries with absolute paths or paths outside of the directory are rejected,
+// and so are symbolic links pointing outside of the directory.
+// An archive compressed by xz or zstd needs the external command xz or zstd.
+// The archive is extracted even in dry-run mode, so the directory should be a temporary one.
+//
+// @param file The path of the archive.
+// @param dir  The directory to extract the archive into.
+func ExtractTarball(file, dir string) error {
+	file, dir = NormalizePath(file), NormalizePath(dir)
+	in, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open the archive %s: %w", file, err)
+	}
+	defer in.Close()
+	decompressed, release, _, err := decompress(bufio.NewReader(in))
+	if err != nil {
+		return err
+	}
+	err = extractTar(decompressed, dir)
+	if releaseErr := release(); err == nil {
+		err = releaseErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to extract the archive %s: %w", file, err)
+	}
+	return nil
+}
+
+// extractTar extracts a tar archive into a directory through os.Root,
+// so that no entry is written outside of the directory (e.g., through a symbolic link extracted before).
+func extractTar(r io.Reader, dir string) error {
+	//nolint:mnd // readable
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	root, err := os.OpenRoot(dir)
+	if err != nil {
+		return err
+	}
+	defer root.Close()
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		name := filepath.FromSlash(path.Clean(header.Name))
+		if !filepath.IsLocal(name) {
+			return fmt.Errorf("the path %s is outside of the archive", header.Name)
+		}
+		//nolint:mnd // readable
+		if err := root.MkdirAll(filepath.Dir(name), 0o755); err != nil {
+			return err
+		}
+		mode := header.FileInfo().Mode().Perm()
+		switch header.Typeflag {
+		case tar.TypeDir:
+			err = root.MkdirAll(name, mode)
+		case tar.TypeSymlink:
+			target := filepath.FromSlash(header.Linkname)
+			if filepath.IsAbs(target) || !filepath.IsLocal(filepath.Join(filepath.Dir(name), target)) {
+				return fmt.Errorf("the symbolic link %s points to %s outside of the archive", header.Name, header.Linkname)
+			}
+			err = root.Symlink(target, name)
+		case tar.TypeReg:
+			err = extractRegularFile(tr, root, name, mode)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func extractRegularFile(r io.Reader, root *os.Root, name string, mode fs.FileMode) error {
+	out, err := root.OpenFile(name, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, mode)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, r); err != nil {
+		return err
+	}
+	return out.Close()
+}
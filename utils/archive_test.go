@@ -0,0 +1,117 @@
+package utils_test
+
+import (
+	"archive/tar"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"legendu.net/icon/internal/testutil"
+	"legendu.net/icon/utils"
+)
+
+func TestExtractTarball(t *testing.T) {
+	type entry struct {
+		name, link, content string
+	}
+	tests := []struct {
+		name    string
+		entries []entry
+		wantErr bool
+	}{
+		{"files and links", []entry{
+			{name: "bin/tool", content: "tool"},
+			{name: "tool", link: "bin/tool"},
+			{name: "bin/alias", link: "tool"},
+		}, false},
+		{"absolute link", []entry{{name: "x", link: "/etc"}}, true},
+		{"link outside", []entry{{name: "x", link: "../.."}}, true},
+		// d/l/up looks local but points outside as d/l points to the top directory
+		{"file through links", []entry{
+			{name: "d/l", link: ".."},
+			{name: "d/l/up", link: ".."},
+			{name: "d/l/up/escaped", content: "escaped"},
+		}, true},
+		{"path outside", []entry{{name: "../escaped", content: "escaped"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			tw := tar.NewWriter(&buf)
+			for _, e := range tt.entries {
+				header := &tar.Header{Name: e.name, Mode: 0o644, Size: int64(len(e.content)), Typeflag: tar.TypeReg}
+				if e.link != "" {
+					header = &tar.Header{Name: e.name, Linkname: e.link, Mode: 0o777, Typeflag: tar.TypeSymlink}
+				}
+				if err := tw.WriteHeader(header); err != nil {
+					t.Fatal(err)
+				}
+				if _, err := tw.Write([]byte(e.content)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tw.Close(); err != nil {
+				t.Fatal(err)
+			}
+			tmp := t.TempDir()
+			file := filepath.Join(tmp, "archive.tar")
+			if err := os.WriteFile(file, buf.Bytes(), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			dir := filepath.Join(tmp, "dir")
+			err := utils.ExtractTarball(file, dir)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want an error: %t", err, tt.wantErr)
+			}
+			if _, err := os.Lstat(filepath.Join(tmp, "escaped")); err == nil {
+				t.Error("a file is extracted outside of the directory")
+			}
+			if !tt.wantErr {
+				if got, err := os.ReadFile(filepath.Join(dir, "bin", "alias")); err != nil || string(got) != "tool" {
+					t.Errorf("bin/alias = %q, %v", got, err)
+				}
+			}
+		})
+	}
+}
+
+func TestInstallBinariesFromBadCpio(t *testing.T) {
+	testutil.New(t, testutil.Ubuntu)
+	header := func(nameSize string) string {
+		// magic, then 13 fields (of 8 hex digits) with namesize as the 12th
+		return "070701" + strings.Repeat("00000000", 11) + nameSize + "00000000"
+	}
+	for _, nameSize := range []string{"00000000", "7fffffff", "-0000001"} {
+		file := filepath.Join(t.TempDir(), "payload.cpio")
+		if err := os.WriteFile(file, []byte(header(nameSize)+"tool\x00\x00"), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := utils.InstallBinaries(file, []string{"tool"}, "/usr/local/bin"); err == nil {
+			t.Errorf("no error for the name size %s", nameSize)
+		}
+	}
+}
+
+func TestInstallBinariesFromArchives(t *testing.T) {
+	env := testutil.New(t, testutil.Ubuntu)
+	tmp := t.TempDir()
+	archives := []string{filepath.Join(tmp, "a.tar.gz"), filepath.Join(tmp, "b.tar.gz")}
+	for i, name := range []string{"a/tool", "b/tool-helper"} {
+		if err := os.WriteFile(archives[i], testutil.TarGz(map[string]string{name: "#!/bin/sh\n"}), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := utils.InstallBinariesFromArchives(archives, []string{"tool", "tool-helper"}, "/usr/local/bin"); err != nil {
+		t.Fatal(err)
+	}
+	env.AssertCommands(
+		"sudo true",
+		"sudo install -m 755 */tool /usr/local/bin/tool",
+		"sudo install -m 755 */tool-helper /usr/local/bin/tool-helper",
+	)
+	if _, err := utils.InstallBinariesFromArchives(archives, []string{"tool", "missing"}, "/usr/local/bin"); err == nil {
+		t.Error("no error for a name found in none of the archives")
+	}
+}
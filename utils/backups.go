@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"time"
+)
+
+// backupPattern matches paths of backups made by Backup, i.e., <original>_<RFC3339 time>[_<n>].
+var backupPattern = regexp.MustCompile(`^(.+)_(\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(?:Z|[+-]\d{2}:\d{2}))(?:_\d+)?$`)
+
+// BackupInfo is a backup found on disk.
+type BackupInfo struct {
+	// Original is the path which was backed up.
+	Original string
+	// Backup is the path of the backup.
+	Backup string
+	// Time is when the backup was made.
+	Time time.Time
+	// Tool is the tool whose run made the backup, or an empty string if unknown.
+	Tool string
+}
+
+// ParseBackupPath parses the original path and the time of a backup from its path.
+//
+// @param path The path of a backup made by Backup.
+//
+// @return The original path, the time of the backup
+// and whether the path looks like a backup made by Backup.
+func ParseBackupPath(path string) (string, time.Time, bool) {
+	match := backupPattern.FindStringSubmatch(path)
+	if match == nil {
+		return "", time.Time{}, false
+	}
+	t, err := time.Parse(time.RFC3339, match[2])
+	if err != nil {
+		return "", time.Time{}, false
+	}
+	return match[1], t, true
+}
+
+// FindBackups finds backups made by Backup.
+// Backups recorded in states of tools are found first
+// and then directories (including those containing recorded backups)
+// are scanned for paths looking like backups.
+//
+// @param dirs Directories to scan (non-recursively) for backups not recorded in states of tools.
+//
+// @return Existing backups sorted by original path and then from the newest to the oldest.
+func FindBackups(dirs []string) ([]BackupInfo, error) {
+	states, err := ReadAllToolStates()
+	if err != nil {
+		return nil, err
+	}
+	found := map[string]BackupInfo{}
+	for _, state := range states {
+		for _, backup := range state.Backups {
+			// other backups of the same path are likely next to it
+			dirs = append(dirs, filepath.Dir(backup.Original))
+			if lexists(backup.Backup) {
+				found[backup.Backup] = BackupInfo{
+					Original: backup.Original, Backup: backup.Backup, Time: backup.Time, Tool: state.Name,
+				}
+			}
+		}
+	}
+	scanned := map[string]bool{}
+	for _, dir := range dirs {
+		dir = NormalizePath(dir)
+		if scanned[dir] {
+			continue
+		}
+		scanned[dir] = true
+		entries, err := fsys.ReadDir(dir)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			path := filepath.Join(dir, entry.Name())
+			if _, ok := found[path]; ok {
+				continue
+			}
+			if original, t, ok := ParseBackupPath(path); ok {
+				found[path] = BackupInfo{Original: original, Backup: path, Time: t}
+			}
+		}
+	}
+	backups := make([]BackupInfo, 0, len(found))
+	for _, backup := range found {
+		backups = append(backups, backup)
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].Original != backups[j].Original {
+			return backups[i].Original < backups[j].Original
+		}
+		if !backups[i].Time.Equal(backups[j].Time) {
+			return backups[i].Time.After(backups[j].Time)
+		}
+		return backups[i].Backup > backups[j].Backup
+	})
+	return backups, nil
+}
+
+// SelectBackupsToPrune selects backups to remove according to a retention policy.
+// For each original path, the newest keep backups are always kept.
+// Other backups are removed if they are older than the specified age (or regardless of age if it is 0).
+//
+// @param backups   Backups sorted by original path and then from the newest to the oldest.
+// @param keep      The number of newest backups to keep for each original path.
+// @param olderThan Only backups older than this are removed. 0 means no limit.
+//
+// @return The backups to remove.
+func SelectBackupsToPrune(backups []BackupInfo, keep int, olderThan time.Duration) []BackupInfo {
+	pruned := []BackupInfo{}
+	count := map[string]int{}
+	now := time.Now()
+	for _, backup := range backups {
+		count[backup.Original]++
+		if count[backup.Original] <= keep {
+			continue
+		}
+		if olderThan > 0 && now.Sub(backup.Time) <= olderThan {
+			continue
+		}
+		pruned = append(pruned, backup)
+	}
+	return pruned
+}
+
+// ForgetBackups removes records of backups (which have been pruned or restored) from states of tools.
+// Nothing is changed in dry-run mode.
+//
+// @param paths Paths of the backups.
+func ForgetBackups(paths []string) error {
+	if dryRun || len(paths) == 0 {
+		return nil
+	}
+	states, err := ReadAllToolStates()
+	if err != nil {
+		return err
+	}
+	for _, state := range states {
+		n := len(state.Backups)
+		state.Backups = slices.DeleteFunc(state.Backups, func(b BackupRecord) bool {
+			return slices.Contains(paths, b.Backup)
+		})
+		if len(state.Backups) == n {
+			continue
+		}
+		if err := WriteToolState(state); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -11,10 +11,12 @@ import (
 // This function performs the following configurations to the Bash shell:
 //   - configure the shell's PATH environment variable smartly
 //   - set the environment variables VISUAL and EDITOR to nvim with a fallback to vim.
-func ConfigBash() {
+func ConfigBash() error {
 	bashConfigFile := GetBashConfigFile()
-	ConfigShellPath(bashConfigFile)
-	AppendToTextFile(
+	if err := ConfigShellPath(bashConfigFile); err != nil {
+		return err
+	}
+	err := AppendToTextFile(
 		bashConfigFile,
 		`
 if which nvim > /dev/null; then
@@ -27,6 +29,9 @@ fi
 `,
 		true,
 	)
+	if err != nil {
+		return err
+	}
 	if IsLinux() {
 		sourceIn := `
 # source in ~/.bashrc
@@ -34,22 +39,26 @@ if [[ -f $HOME/.bashrc ]]; then
 	. $HOME/.bashrc
 fi
 `
-		AppendToTextFile(
-			filepath.Join(UserHomeDir(), ".bash_profile"),
-			sourceIn,
-			true,
-		)
+		return AppendToTextFile("~/.bash_profile", sourceIn, true)
 	}
+	return nil
 }
 
 // Configure shell to add a path into the environment variable PATH.
 // @param paths: Absolute paths to add into PATH.
 // @param config_file: The path of a shell's configuration file.
-func ConfigShellPath(configFile string) {
+func ConfigShellPath(configFile string) error {
 	if GetLinuxDistID() == "idx" {
-		return
+		return nil
+	}
+	text := ""
+	if ExistsFile(configFile) {
+		var err error
+		text, err = ReadFileAsString(configFile)
+		if err != nil {
+			return err
+		}
 	}
-	text := ReadFileAsString(configFile)
 	if !strings.Contains(text, ". /scripts/path.sh") && !strings.Contains(text, "\n_PATHS=(\n") {
 		text = `
 # set $PATH
@@ -67,19 +76,21 @@ for ((_i=${#_PATHS[@]}-1; _i>=0; _i--)); do
 	fi
 done
 `
-		AppendToTextFile(configFile, text, true)
+		if err := AppendToTextFile(configFile, text, true); err != nil {
+			return err
+		}
 	}
 	log.Printf("%s is configured to insert common bin paths into $PATH.", configFile)
+	return nil
 }
 
 // GetBashConfigFile returns the path to the Bash configuration file based on the operating system.
 //
 // @return The path to the Bash configuration file as a string.
 func GetBashConfigFile() string {
-	home := UserHomeDir()
 	file := ".bash_profile"
 	if IsLinux() {
 		file = ".bashrc"
 	}
-	return filepath.Join(home, file)
+	return NormalizePath(filepath.Join("~", file))
 }
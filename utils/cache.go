@@ -0,0 +1,353 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// CachedDownload is a download recorded in the download cache.
+type CachedDownload struct {
+	// URL is the URL which the file was downloaded from.
+	URL string `yaml:"url"`
+	// ETag is the ETag header of the response, used to revalidate and resume the download.
+	ETag string `yaml:"etag,omitempty"`
+	// LastModified is the Last-Modified header of the response, used if there is no ETag.
+	LastModified string `yaml:"last_modified,omitempty"`
+	// Size is the size of the file (downloaded so far if the download is partial) in bytes.
+	Size int64 `yaml:"size"`
+	// Sha256 is the SHA-256 checksum of the file, or an empty string if the download is partial.
+	Sha256 string `yaml:"sha256,omitempty"`
+	// Time is when the file was downloaded or last revalidated.
+	Time time.Time `yaml:"time"`
+}
+
+// IsPartial checks whether the download has not been completed.
+//
+// @return true if the download is partial, false otherwise.
+func (d *CachedDownload) IsPartial() bool {
+	return d.Sha256 == ""
+}
+
+// CacheDir returns the directory where icon caches data,
+// i.e., $ICON_CACHE_DIR, $XDG_CACHE_HOME/icon or ~/.cache/icon.
+// Pointing ICON_CACHE_DIR of containers to a shared directory reuses downloads across them.
+//
+// @return The cache directory.
+func CacheDir() string {
+	if dir := os.Getenv("ICON_CACHE_DIR"); dir != "" {
+		return NormalizePath(dir)
+	}
+	if dir := os.Getenv("XDG_CACHE_HOME"); dir != "" {
+		return filepath.Join(dir, "icon")
+	}
+	return NormalizePath("~/.cache/icon")
+}
+
+var offline, prefetch bool
+
+// SetOffline turns the offline mode on or off.
+// In offline mode, downloads and responses are served from the cache only
+// and an *OfflineError is returned for URLs which have not been cached,
+// e.g., when installing tools from a bundle (icon bundle install).
+//
+// @param b Whether to turn on the offline mode.
+func SetOffline(b bool) {
+	offline = b
+}
+
+// IsOffline checks whether the offline mode is on.
+//
+// @return true if the offline mode is on, false otherwise.
+func IsOffline() bool {
+	return offline
+}
+
+// SetPrefetch turns the prefetch mode on or off.
+// In prefetch mode, downloads are fetched into the cache even in dry-run mode
+// and responses of HTTP GET requests are cached,
+// so that the cache is enough to run installers offline (see SetOffline).
+//
+// @param b Whether to turn on the prefetch mode.
+func SetPrefetch(b bool) {
+	prefetch = b
+}
+
+// IsPrefetch checks whether the prefetch mode is on.
+//
+// @return true if the prefetch mode is on, false otherwise.
+func IsPrefetch() bool {
+	return prefetch
+}
+
+// Downloads are cached in the following layout under CacheDir()/downloads.
+//   - index/<key>.yaml: the CachedDownload of a URL, where key is the SHA-256 checksum of the URL.
+//   - blobs/<sha256>: a completely downloaded file named by its SHA-256 checksum.
+//   - partial/<key>: a partially downloaded file which can be resumed.
+//   - partial/<key>.lock: a lock preventing concurrent downloads of the same URL.
+func downloadCacheDir(sub string) string {
+	return filepath.Join(CacheDir(), "downloads", sub)
+}
+
+func urlKey(url string) string {
+	sum := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(sum[:])
+}
+
+func cacheIndexFile(url string) string {
+	return filepath.Join(downloadCacheDir("index"), urlKey(url)+".yaml")
+}
+
+func cacheBlobFile(checksum string) string {
+	return filepath.Join(downloadCacheDir("blobs"), checksum)
+}
+
+func cachePartialFile(url string) string {
+	return filepath.Join(downloadCacheDir("partial"), urlKey(url))
+}
+
+func readCachedDownload(file string) (*CachedDownload, error) {
+	bytes, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the download cache entry %s: %w", file, err)
+	}
+	var d CachedDownload
+	if err := yaml.Unmarshal(bytes, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse the download cache entry %s: %w", file, err)
+	}
+	return &d, nil
+}
+
+func writeCachedDownload(d *CachedDownload) error {
+	bytes, err := yaml.Marshal(d)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the download cache entry of %s: %w", d.URL, err)
+	}
+	//nolint:mnd // readable
+	if err := os.WriteFile(cacheIndexFile(d.URL), bytes, 0o644); err != nil {
+		return fmt.Errorf("failed to write the download cache entry of %s: %w", d.URL, err)
+	}
+	return nil
+}
+
+// copyRegularFile copies a regular file without sudo and without recording it into the state of the tool.
+func copyRegularFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", src, err)
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return fmt.Errorf("failed to create file '%s': %w", dst, err)
+	}
+	defer out.Close()
+	if _, err := io.Copy(out, in); err != nil {
+		return fmt.Errorf("failed to copy %s to %s: %w", src, dst, err)
+	}
+	return out.Close()
+}
+
+// CopyFromDownloadCache copies a cached file with the specified SHA-256 checksum (no matter which URL it was downloaded from).
+// In dry-run mode, the copy is recorded into the plan.
+//
+// @param checksum The SHA-256 checksum of the file.
+// @param dst      The path to copy the cached file to.
+//
+// @return true if the file is found in the cache and copied, false otherwise.
+func CopyFromDownloadCache(checksum, dst string) bool {
+	blob := cacheBlobFile(strings.ToLower(checksum))
+	if !ExistsFile(blob) {
+		return false
+	}
+	if dryRun {
+		recordStep("download", "%s (cached) -> %s", blob, dst)
+		return true
+	}
+	Debugf("Copying the cached download %s to %s\n", blob, dst)
+	if err := copyRegularFile(blob, dst); err != nil {
+		Warnf("Failed to use the cached download: %v\n", err)
+		return false
+	}
+	return true
+}
+
+// ListDownloadCache lists downloads in the download cache.
+//
+// @return Cached downloads sorted from the newest to the oldest.
+func ListDownloadCache() ([]CachedDownload, error) {
+	entries, err := os.ReadDir(downloadCacheDir("index"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the download cache: %w", err)
+	}
+	downloads := []CachedDownload{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".yaml") {
+			continue
+		}
+		d, err := readCachedDownload(filepath.Join(downloadCacheDir("index"), entry.Name()))
+		if err != nil {
+			Warnf("%v", err)
+			continue
+		}
+		if d.IsPartial() {
+			if info, err := os.Stat(cachePartialFile(d.URL)); err == nil {
+				d.Size = info.Size()
+			}
+		} else if !ExistsFile(cacheBlobFile(d.Sha256)) {
+			continue
+		}
+		downloads = append(downloads, *d)
+	}
+	sort.Slice(downloads, func(i, j int) bool {
+		return downloads[i].Time.After(downloads[j].Time)
+	})
+	return downloads, nil
+}
+
+// CleanDownloadCache removes downloads from the download cache.
+// Files no longer referenced by any URL are removed too.
+// In dry-run mode, the removals are recorded into the plan.
+//
+// @param olderThan Only remove downloads older than this. 0 means removing all downloads.
+//
+// @return The number of downloads removed and the number of bytes freed.
+func CleanDownloadCache(olderThan time.Duration) (int, int64, error) {
+	downloads, err := ListDownloadCache()
+	if err != nil {
+		return 0, 0, err
+	}
+	kept := map[string]bool{}
+	count := 0
+	var freed int64
+	for _, d := range downloads {
+		if olderThan > 0 && time.Since(d.Time) <= olderThan {
+			kept[d.Sha256] = true
+			continue
+		}
+		if err := RemoveAll(cacheIndexFile(d.URL)); err != nil {
+			return count, freed, err
+		}
+		if d.IsPartial() {
+			if err := RemoveAll(cachePartialFile(d.URL)); err != nil {
+				return count, freed, err
+			}
+			freed += d.Size
+		}
+		count++
+	}
+	blobs, err := os.ReadDir(downloadCacheDir("blobs"))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return count, freed, fmt.Errorf("failed to read the download cache: %w", err)
+	}
+	for _, blob := range blobs {
+		if kept[blob.Name()] {
+			continue
+		}
+		if info, err := blob.Info(); err == nil {
+			freed += info.Size()
+		}
+		if err := RemoveAll(filepath.Join(downloadCacheDir("blobs"), blob.Name())); err != nil {
+			return count, freed, err
+		}
+	}
+	return count, freed, nil
+}
+
+// FormatBytes formats a number of bytes in a human-readable way, e.g., 12.3 MiB.
+//
+// @param n The number of bytes.
+//
+// @return The formatted string.
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// CachedResponse is the body of a response cached with its ETag for conditional requests.
+type CachedResponse struct {
+	// URL is the URL requested.
+	URL string `yaml:"url"`
+	// ETag is the ETag header of the response.
+	ETag string `yaml:"etag"`
+	// Body is the body of the response.
+	Body string `yaml:"body"`
+	// Time is when the response was received.
+	Time time.Time `yaml:"time"`
+}
+
+func cacheResponseFile(url string) string {
+	return filepath.Join(CacheDir(), "responses", urlKey(url)+".yaml")
+}
+
+// ReadCachedResponse reads the cached response of a URL.
+//
+// @param url The URL requested.
+//
+// @return The cached response, or nil if the response of the URL has not been cached.
+func ReadCachedResponse(url string) *CachedResponse {
+	bytes, err := os.ReadFile(cacheResponseFile(url))
+	if err != nil {
+		return nil
+	}
+	var r CachedResponse
+	if err := yaml.Unmarshal(bytes, &r); err != nil || r.URL != url {
+		return nil
+	}
+	return &r
+}
+
+// WriteCachedResponse caches a response so that it can be revalidated using its ETag.
+// Failures are logged instead of being returned as caching is an optimization.
+//
+// @param r The response to cache.
+func WriteCachedResponse(r *CachedResponse) {
+	bytes, err := yaml.Marshal(r)
+	if err != nil {
+		Warnf("Failed to serialize the response of %s: %v\n", r.URL, err)
+		return
+	}
+	file := cacheResponseFile(r.URL)
+	//nolint:mnd // readable
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		Warnf("Failed to create the response cache directory: %v\n", err)
+		return
+	}
+	//nolint:mnd // readable
+	if err := os.WriteFile(file, bytes, 0o600); err != nil {
+		Warnf("Failed to cache the response of %s: %v\n", r.URL, err)
+	}
+}
+
+// CleanResponseCache removes cached responses.
+// In dry-run mode, the removal is recorded into the plan.
+func CleanResponseCache() error {
+	dir := filepath.Join(CacheDir(), "responses")
+	if !ExistsDir(dir) {
+		return nil
+	}
+	return RemoveAll(dir)
+}
@@ -0,0 +1,289 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Command is a command built from argv, e.g., NewCommand("git", "clone", url, dir).
+// Arguments are passed to the program as they are instead of being pasted into a shell string,
+// so that values such as paths with spaces, user names or URLs can neither break nor inject into the command.
+// Use RunCmd (with FormatShell for values) only when a shell is really needed, e.g., for a pipeline.
+type Command struct {
+	args   []string
+	env    []string
+	dir    string
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+// NewCommand creates a command from a program and its arguments.
+//
+// @param args The program followed by its arguments.
+//
+// @return The command.
+func NewCommand(args ...string) *Command {
+	return &Command{args: args}
+}
+
+// Arg appends arguments to the command.
+//
+// @param args The arguments to append.
+//
+// @return The command itself for chaining.
+func (c *Command) Arg(args ...string) *Command {
+	c.args = append(c.args, args...)
+	return c
+}
+
+// Env sets environment variables (of the form KEY=VALUE) for the command.
+//
+// @param env The environment variables.
+//
+// @return The command itself for chaining.
+func (c *Command) Env(env ...string) *Command {
+	c.env = append(c.env, env...)
+	return c
+}
+
+// Dir sets the working directory of the command.
+//
+// @param dir The working directory.
+//
+// @return The command itself for chaining.
+func (c *Command) Dir(dir string) *Command {
+	c.dir = NormalizePath(dir)
+	return c
+}
+
+// Stdin sets the standard input of the command, which is the standard input of icon by default.
+//
+// @param stdin The standard input.
+//
+// @return The command itself for chaining.
+func (c *Command) Stdin(stdin io.Reader) *Command {
+	c.stdin = stdin
+	return c
+}
+
+// Stdout sets the standard output of the command, which is printed (or captured for logs) by default.
+//
+// @param stdout The standard output.
+//
+// @return The command itself for chaining.
+func (c *Command) Stdout(stdout io.Writer) *Command {
+	c.stdout = stdout
+	return c
+}
+
+// Sudo runs the command with a prefix returned by GetCommandPrefix.
+//
+// @param prefix "sudo" (with options) or an empty string, in which case the command is unchanged.
+//
+// @return The command itself for chaining.
+func (c *Command) Sudo(prefix string) *Command {
+	if prefix = strings.TrimSpace(prefix); prefix != "" {
+		// the prefix is generated by GetCommandPrefix and never contains quoted values
+		c.args = append(strings.Fields(prefix), c.args...)
+	}
+	return c
+}
+
+// String returns the command as a shell command (with arguments quoted) for logs and plans.
+//
+// @return The shell command.
+func (c *Command) String() string {
+	cmd := ShellJoin(c.args...)
+	for i := len(c.env) - 1; i >= 0; i-- {
+		key, value, _ := strings.Cut(c.env[i], "=")
+		cmd = key + "=" + ShellQuote(value) + " " + cmd
+	}
+	if c.dir != "" {
+		cmd = "cd " + ShellQuote(c.dir) + " && " + cmd
+	}
+	return cmd
+}
+
+// Run runs the command the same way as RunCmd, i.e., it is logged, recorded into the plan in dry-run mode,
+// waits for other installs using the same package manager and has URLs rewritten to mirrors
+// (only for download commands, see downloadCommands).
+//
+// @return A *CommandError if the command fails, nil otherwise.
+func (c *Command) Run() error {
+	if len(c.args) == 0 {
+		return errors.New("no command to run")
+	}
+	if isDownloadCommand(c.args) {
+		args := make([]string, 0, len(c.args))
+		for _, arg := range c.args {
+			args = append(args, rewriteURLsInCmd(arg))
+		}
+		c.args = args
+	}
+	args := c.args
+	if dryRun {
+		recordStep("run", "%s", c.String())
+		return nil
+	}
+	command := exec.CommandContext(context.Background(), args[0], args[1:]...)
+	command.Dir = c.dir
+	command.Env = append(os.Environ(), c.env...)
+	command.Stdin = c.stdin
+	command.Stdout = c.stdout
+	return runCommand(c.String(), command)
+}
+
+// runCommand runs a prepared command, printing its output (or capturing it in quiet mode or with JSON logs)
+// and logging it with its duration and exit code.
+// The standard input and output of icon are used unless they are set for the command.
+// If the output is captured, the command does not read the terminal so that it cannot wait for an invisible prompt.
+//
+// @param cmd     The command as a shell command for logs.
+// @param command The command to run.
+//
+// @return A *CommandError if the command fails, nil otherwise.
+func runCommand(cmd string, command *exec.Cmd) error {
+	unlock, err := lockPackageManagers(cmd)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+	start := time.Now()
+	capture := captureCommandOutput()
+	// a prompt of the command cannot be seen if its output is captured,
+	// so it is not attached to the terminal (and reads EOF instead of blocking invisibly)
+	detached := capture && command.Stdin == nil && isTerminal(os.Stdin)
+	if command.Stdin == nil && !detached {
+		command.Stdin = os.Stdin
+	}
+	command.Stderr = os.Stderr
+	// the output is captured instead of being printed in quiet mode or with JSON logs
+	var output *commandOutput
+	var stdout, stderr *outputWriter
+	if capture {
+		output = &commandOutput{}
+		stdout, stderr = output.writer("stdout"), output.writer("stderr")
+		command.Stderr = stderr
+		if command.Stdout == nil {
+			command.Stdout = stdout
+		}
+	}
+	if command.Stdout == nil {
+		command.Stdout = os.Stdout
+	}
+	err = executor.Run(command)
+	if output != nil {
+		stdout.Flush()
+		stderr.Flush()
+	}
+	exitCode := exitCodeOf(err)
+	logCommand(cmd, start, exitCode, output)
+	if err != nil {
+		if detached {
+			err = fmt.Errorf("%w (the command cannot prompt for input as its output is hidden by --quiet or --log-format json, "+
+				"so answer prompts with --yes or run it without them)", err)
+		}
+		return &CommandError{Cmd: cmd, ExitCode: exitCode, Err: err}
+	}
+	recordInstallMethodOfCmd(cmd)
+	return nil
+}
+
+// isTerminal checks whether a file is a terminal (or another character device).
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// exitCodeOf returns the exit code of a command from the error of running it.
+//
+// @param err The error returned by Executor.Run.
+//
+// @return 0 if err is nil, the exit code if err has one, or -1 otherwise (e.g., the command is not found).
+func exitCodeOf(err error) int {
+	if err == nil {
+		return 0
+	}
+	var exitErr interface{ ExitCode() int }
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode()
+	}
+	return -1
+}
+
+// Output runs a command which does not change the system (e.g., a query), even in dry-run mode,
+// and returns its standard output. The command is neither logged nor recorded.
+//
+// @return The standard output with leading and trailing white spaces trimmed.
+func (c *Command) Output() (string, error) {
+	if len(c.args) == 0 {
+		return "", errors.New("no command to run")
+	}
+	var stdout bytes.Buffer
+	command := exec.CommandContext(context.Background(), c.args[0], c.args[1:]...)
+	command.Dir = c.dir
+	command.Env = append(os.Environ(), c.env...)
+	command.Stdin = c.stdin
+	command.Stdout = &stdout
+	command.Stderr = os.Stderr
+	if err := executor.Run(command); err != nil {
+		return "", &CommandError{Cmd: c.String(), ExitCode: exitCodeOf(err), Err: err}
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// shellSafe matches strings which need no quoting in a shell.
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// ShellQuote quotes a string (with single quotes) so that a shell treats it as a single literal word.
+//
+// @param s The string to quote.
+//
+// @return The string itself if it is safe as it is, otherwise the quoted string.
+//
+// @example ShellQuote("~/Library/Application Support") // Returns '~/Library/Application Support'
+func ShellQuote(s string) string {
+	if shellSafe.MatchString(s) {
+		return s
+	}
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+// ShellJoin quotes strings using ShellQuote and joins them with spaces.
+//
+// @param args The strings to join.
+//
+// @return The shell command.
+func ShellJoin(args ...string) string {
+	quoted := make([]string, 0, len(args))
+	for _, arg := range args {
+		quoted = append(quoted, ShellQuote(arg))
+	}
+	return strings.Join(quoted, " ")
+}
+
+// FormatShell is like Format but quotes values using ShellQuote,
+// which is safe for pasting values (e.g., paths, user names or URLs) into a command run by RunCmd.
+// Placeholders must not be put into quotes in the command.
+//
+// @param cmd  The shell command with placeholders.
+// @param hmap A map where keys are placeholder names and values are their (unquoted) replacements.
+//
+// @return The shell command with placeholders replaced by quoted values.
+//
+// @example FormatShell("ls {dir} | wc -l", map[string]string{"dir": "/tmp/a b"}) // Returns "ls '/tmp/a b' | wc -l"
+func FormatShell(cmd string, hmap map[string]string) string {
+	quoted := make(map[string]string, len(hmap))
+	for key, val := range hmap {
+		quoted[key] = ShellQuote(val)
+	}
+	return Format(cmd, quoted)
+}
@@ -0,0 +1,150 @@
+package utils_test
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"legendu.net/icon/internal/testutil"
+	"legendu.net/icon/utils"
+)
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"/usr/local/bin", "/usr/local/bin"},
+		{"user@example.com", "user@example.com"},
+		{"", "''"},
+		{"a b", "'a b'"},
+		{"O'Neil", `'O'"'"'Neil'`},
+		{"$(rm -rf ~)", "'$(rm -rf ~)'"},
+		{"~/.config", "'~/.config'"},
+	}
+	for _, tt := range tests {
+		if got := utils.ShellQuote(tt.in); got != tt.want {
+			t.Errorf("ShellQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestFormatShell(t *testing.T) {
+	got := utils.FormatShell("ls {dir} | wc -l", map[string]string{"dir": "/tmp/a b"})
+	if want := "ls '/tmp/a b' | wc -l"; got != want {
+		t.Errorf("FormatShell = %s, want %s", got, want)
+	}
+}
+
+func TestCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		command *utils.Command
+		want    string
+	}{
+		{"args", utils.NewCommand("git", "clone", "https://github.com/a/b.git", "/tmp/a b"),
+			"git clone https://github.com/a/b.git '/tmp/a b'"},
+		{"sudo", utils.NewCommand("cp", "a", "b").Sudo("sudo"), "sudo cp a b"},
+		{"no sudo", utils.NewCommand("cp", "a", "b").Sudo(""), "cp a b"},
+		{"dir", utils.NewCommand("git", "submodule", "init").Dir("/tmp/a b"), "cd '/tmp/a b' && git submodule init"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			if got := tt.command.String(); got != tt.want {
+				t.Errorf("String() = %s, want %s", got, tt.want)
+			}
+			if err := tt.command.Run(); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands(tt.want)
+		})
+	}
+}
+
+func TestCommandMirrors(t *testing.T) {
+	env := testutil.New(t, testutil.Ubuntu)
+	t.Cleanup(utils.SetMirrors(map[string]string{"https://github.com/": "https://mirror.example.com/github/"}))
+	commands := []*utils.Command{
+		utils.NewCommand("curl", "-sSL", "https://github.com/a/b/releases/download/v1/b.tar.gz"),
+		utils.NewCommand("wget", "https://github.com/a/b.sh").Sudo("sudo --preserve-env=HTTPS_PROXY"),
+		utils.NewCommand("git", "clone", "https://github.com/a/b.git", "/tmp/b"),
+	}
+	for _, command := range commands {
+		if err := command.Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	env.AssertCommands(
+		"curl -sSL https://mirror.example.com/github/a/b/releases/download/v1/b.tar.gz",
+		"sudo --preserve-env=HTTPS_PROXY wget https://mirror.example.com/github/a/b.sh",
+		"git clone https://github.com/a/b.git /tmp/b",
+	)
+	env.Exec.Reset()
+	err := utils.RunCmd(`git clone https://github.com/a/b.git /tmp/b \
+		&& echo https://github.com/a >> /tmp/b/urls; curl -sSL \
+		https://github.com/a/b.sh | sudo bash`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.AssertCommands("git clone https://github.com/a/b.git /tmp/b && echo https://github.com/a >> /tmp/b/urls; " +
+		"curl -sSL https://mirror.example.com/github/a/b.sh | sudo bash")
+}
+
+func TestCommandError(t *testing.T) {
+	env := testutil.New(t, testutil.Ubuntu)
+	env.Exec.Stub("^grep", 1, "")
+	err := utils.RunCmd("grep -q QEMU /proc/cpuinfo")
+	var cmdErr *utils.CommandError
+	if !errors.As(err, &cmdErr) || cmdErr.ExitCode != 1 {
+		t.Fatalf("RunCmd returned %v, want a CommandError with the exit code 1", err)
+	}
+	env.Exec.Stub("^gh auth token", 0, "token\n")
+	if got, err := utils.NewCommand("gh", "auth", "token").Output(); err != nil || got != "token" {
+		t.Errorf("Output() = %q, %v, want token", got, err)
+	}
+}
+
+func TestCommandPromptHidden(t *testing.T) {
+	tests := []struct {
+		name  string
+		quiet bool
+		want  bool
+	}{
+		{"quiet", true, true},
+		{"not quiet", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			// a character device in place of the terminal
+			tty, err := os.Open(os.DevNull)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer tty.Close()
+			defer func(stdin *os.File) { os.Stdin = stdin }(os.Stdin)
+			os.Stdin = tty
+			closeLog, err := utils.SetupLogging(false, tt.quiet, utils.LogFormatText)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer func() {
+				_ = closeLog()
+				if closeLog, err := utils.SetupLogging(false, false, utils.LogFormatText); err == nil {
+					_ = closeLog()
+				}
+			}()
+			env.Exec.Stub("apt-get", 1, "")
+			err = utils.RunCmd("apt-get install git")
+			var cmdErr *utils.CommandError
+			if !errors.As(err, &cmdErr) {
+				t.Fatalf("RunCmd returned %v, want a CommandError", err)
+			}
+			if got := strings.Contains(err.Error(), "--yes"); got != tt.want {
+				t.Errorf("error = %v, want a hint about --yes: %t", err, tt.want)
+			}
+		})
+	}
+}
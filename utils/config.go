@@ -0,0 +1,322 @@
+package utils
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the global configuration of icon, read from ConfigFile(), e.g.,
+//
+//	network:
+//	  proxy: http://proxy.example.com:3128
+//	  noProxy: localhost,127.0.0.1,.example.com
+//	  caBundle: /etc/ssl/certs/ca-bundle-with-corporate-ca.pem
+//	  timeout: 30s
+//	  mirrors:
+//	    https://github.com/: https://artifactory.example.com/github/
+//	    https://go.dev/dl/: https://mirror.example.com/golang/
+type Config struct {
+	// Network configures HTTP requests made by icon and by commands it runs.
+	Network NetworkConfig `yaml:"network"`
+}
+
+// NetworkConfig configures HTTP requests made by icon and by commands it runs.
+type NetworkConfig struct {
+	// Proxy is the URL of an HTTP(S) or SOCKS5 (socks5://host:port) proxy for all requests.
+	Proxy string `yaml:"proxy,omitempty"`
+	// NoProxy is a comma-separated list of hosts (or domain suffixes) which are accessed without the proxy.
+	NoProxy string `yaml:"noProxy,omitempty"`
+	// CABundle is the path of a CA bundle (PEM) to trust, e.g., the system CAs plus the CA of a corporate proxy.
+	CABundle string `yaml:"caBundle,omitempty"`
+	// Timeout limits connecting, the TLS handshake and waiting for response headers (not transferring bodies).
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+	// Mirrors maps URL prefixes to the prefixes of their mirrors. The longest matching prefix wins.
+	Mirrors map[string]string `yaml:"mirrors,omitempty"`
+}
+
+var config Config
+
+// httpClient is the HTTP client configured by LoadConfig.
+var httpClient = http.DefaultClient
+
+// preservedEnv are environment variables set by LoadConfig which are preserved for commands run with sudo.
+var preservedEnv []string
+
+// ConfigFile returns the path of the global configuration file of icon,
+// i.e., $ICON_CONFIG, $XDG_CONFIG_HOME/icon/config.yaml or ~/.config/icon/config.yaml.
+//
+// @return The path of the configuration file.
+func ConfigFile() string {
+	if file := os.Getenv("ICON_CONFIG"); file != "" {
+		return NormalizePath(file)
+	}
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "icon", "config.yaml")
+	}
+	return NormalizePath("~/.config/icon/config.yaml")
+}
+
+// LoadConfig reads the global configuration file (if it exists) and applies it.
+// The proxy and the CA bundle are exported as environment variables
+// (HTTP_PROXY, HTTPS_PROXY, NO_PROXY, SSL_CERT_FILE, CURL_CA_BUNDLE, etc.)
+// so that commands run by icon use them too,
+// and mirrors are applied to Git via GIT_CONFIG_* (url.<mirror>.insteadOf).
+//
+// @return An error if the configuration file cannot be parsed or applied.
+func LoadConfig() error {
+	file := ConfigFile()
+	bytes, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read the configuration file %s: %w", file, err)
+	}
+	if err := yaml.Unmarshal(bytes, &config); err != nil {
+		return fmt.Errorf("failed to parse the configuration file %s: %w", file, err)
+	}
+	if err := applyNetworkEnv(config.Network); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	client, err := newHTTPClient(config.Network)
+	if err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	httpClient = client
+	return nil
+}
+
+// applyNetworkEnv exports the proxy, the CA bundle and mirrors of Git as environment variables.
+func applyNetworkEnv(cfg NetworkConfig) error {
+	env := map[string]string{}
+	if cfg.Proxy != "" {
+		for _, name := range []string{"HTTP_PROXY", "HTTPS_PROXY", "ALL_PROXY"} {
+			env[name] = cfg.Proxy
+			env[strings.ToLower(name)] = cfg.Proxy
+		}
+	}
+	if cfg.NoProxy != "" {
+		env["NO_PROXY"] = cfg.NoProxy
+		env["no_proxy"] = cfg.NoProxy
+	}
+	if cfg.CABundle != "" {
+		bundle := NormalizePath(cfg.CABundle)
+		if !ExistsFile(bundle) {
+			return fmt.Errorf("the CA bundle %s does not exist", bundle)
+		}
+		for _, name := range []string{
+			"SSL_CERT_FILE", "CURL_CA_BUNDLE", "REQUESTS_CA_BUNDLE", "PIP_CERT", "GIT_SSL_CAINFO", "NODE_EXTRA_CA_CERTS",
+		} {
+			env[name] = bundle
+		}
+	}
+	if len(cfg.Mirrors) > 0 {
+		count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+		for _, from := range mirrorPrefixes(cfg.Mirrors) {
+			idx := strconv.Itoa(count)
+			env["GIT_CONFIG_KEY_"+idx] = "url." + cfg.Mirrors[from] + ".insteadOf"
+			env["GIT_CONFIG_VALUE_"+idx] = from
+			count++
+		}
+		env["GIT_CONFIG_COUNT"] = strconv.Itoa(count)
+	}
+	for name, value := range env {
+		if err := os.Setenv(name, value); err != nil {
+			return fmt.Errorf("failed to set the environment variable %s: %w", name, err)
+		}
+		preservedEnv = append(preservedEnv, name)
+	}
+	slices.Sort(preservedEnv)
+	return nil
+}
+
+// newHTTPClient creates an HTTP client using the proxy (from environment variables),
+// the CA bundle, timeouts and mirrors.
+func newHTTPClient(cfg NetworkConfig) (*http.Client, error) {
+	transport, ok := http.DefaultTransport.(*http.Transport)
+	if !ok {
+		return nil, errors.New("the default HTTP transport is not an *http.Transport")
+	}
+	transport = transport.Clone()
+	transport.Proxy = http.ProxyFromEnvironment
+	if cfg.CABundle != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(NormalizePath(cfg.CABundle))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA bundle %s: %w", cfg.CABundle, err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate is found in the CA bundle %s", cfg.CABundle)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}
+	}
+	if cfg.Timeout > 0 {
+		transport.DialContext = (&net.Dialer{Timeout: cfg.Timeout, KeepAlive: cfg.Timeout}).DialContext
+		transport.TLSHandshakeTimeout = cfg.Timeout
+		transport.ResponseHeaderTimeout = cfg.Timeout
+	}
+	var roundTripper http.RoundTripper = transport
+	if len(cfg.Mirrors) > 0 {
+		roundTripper = mirrorTransport{next: transport}
+	}
+	return &http.Client{Transport: roundTripper}, nil
+}
+
+// mirrorTransport sends requests to mirrors of their URLs.
+type mirrorTransport struct {
+	next http.RoundTripper
+}
+
+func (t mirrorTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	url := req.URL.String()
+	if mirrored := RewriteURL(url); mirrored != url {
+		clone := req.Clone(req.Context())
+		parsed, err := req.URL.Parse(mirrored)
+		if err != nil {
+			return nil, fmt.Errorf("invalid mirror %s of %s: %w", mirrored, url, err)
+		}
+		clone.URL, clone.Host = parsed, parsed.Host
+		// credentials for the original host must not be sent to the mirror
+		clone.Header.Del("Authorization")
+		clone.Header.Del("Private-Token")
+		req = clone
+	}
+	return t.next.RoundTrip(req)
+}
+
+// HTTPClient returns the HTTP client configured by the global configuration file.
+// All HTTP requests made by icon should use it.
+//
+// @return The HTTP client.
+func HTTPClient() *http.Client {
+	return httpClient
+}
+
+// SetHTTPClient replaces the HTTP client, e.g., with one serving fake responses in tests.
+//
+// @param client The new HTTP client.
+//
+// @return A function restoring the previous HTTP client.
+func SetHTTPClient(client *http.Client) func() {
+	previous := httpClient
+	httpClient = client
+	return func() {
+		httpClient = previous
+	}
+}
+
+// mirrorPrefixes returns URL prefixes which have mirrors, longest first.
+func mirrorPrefixes(mirrors map[string]string) []string {
+	prefixes := make([]string, 0, len(mirrors))
+	for from := range mirrors {
+		prefixes = append(prefixes, from)
+	}
+	slices.SortFunc(prefixes, func(a, b string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return strings.Compare(a, b)
+	})
+	return prefixes
+}
+
+// RewriteURL rewrites a URL to its mirror configured in the global configuration file.
+//
+// @param url The URL.
+//
+// @return The URL of the mirror, or url itself if no mirror is configured for it.
+func RewriteURL(url string) string {
+	for _, from := range mirrorPrefixes(config.Network.Mirrors) {
+		if rest, ok := strings.CutPrefix(url, from); ok {
+			return config.Network.Mirrors[from] + rest
+		}
+	}
+	return url
+}
+
+// SetMirrors replaces the mirrors configured in the global configuration file, e.g., in tests.
+//
+// @param mirrors The new mirrors (URL prefixes mapped to those of their mirrors).
+//
+// @return A function restoring the previous mirrors.
+func SetMirrors(mirrors map[string]string) func() {
+	previous := config.Network.Mirrors
+	config.Network.Mirrors = mirrors
+	return func() {
+		config.Network.Mirrors = previous
+	}
+}
+
+// downloadCommands are commands whose arguments are rewritten to mirrors by Command.Run.
+// Other commands keep URLs in their arguments as they might store them,
+// e.g., git clone would make the mirror the origin of the clone (Git gets mirrors via insteadOf instead).
+var downloadCommands = []string{"curl", "wget"}
+
+// isDownloadCommand checks whether argv (possibly prefixed with environment variables, sudo and its options)
+// runs a download command.
+func isDownloadCommand(args []string) bool {
+	for _, arg := range args {
+		if arg == "sudo" || strings.HasPrefix(arg, "-") || strings.Contains(arg, "=") {
+			continue
+		}
+		return slices.Contains(downloadCommands, filepath.Base(arg))
+	}
+	return false
+}
+
+// shellSeparator matches operators separating commands in a shell command.
+var shellSeparator = regexp.MustCompile(`\|\||&&|[|;&\n]`)
+
+// rewriteURLsInShell rewrites URLs to their mirrors in download commands (see downloadCommands) of a shell command,
+// e.g., in curl of "curl -sSL <url> | sh" but not in git of "git clone <url>".
+func rewriteURLsInShell(cmd string) string {
+	if len(config.Network.Mirrors) == 0 {
+		return cmd
+	}
+	// line continuations do not separate commands
+	seps := shellSeparator.FindAllStringIndex(strings.ReplaceAll(cmd, "\\\n", "  "), -1)
+	var b strings.Builder
+	start := 0
+	for _, sep := range append(seps, []int{len(cmd), len(cmd)}) {
+		segment := cmd[start:sep[0]]
+		if isDownloadCommand(strings.Fields(strings.ReplaceAll(segment, "\\\n", " "))) {
+			segment = rewriteURLsInCmd(segment)
+		}
+		b.WriteString(segment)
+		b.WriteString(cmd[sep[0]:sep[1]])
+		start = sep[1]
+	}
+	return b.String()
+}
+
+// rewriteURLsInCmd rewrites URLs in a shell command to their mirrors.
+func rewriteURLsInCmd(cmd string) string {
+	if len(config.Network.Mirrors) == 0 {
+		return cmd
+	}
+	pairs := []string{}
+	for _, from := range mirrorPrefixes(config.Network.Mirrors) {
+		pairs = append(pairs, from, config.Network.Mirrors[from])
+	}
+	rewritten := strings.NewReplacer(pairs...).Replace(cmd)
+	if rewritten != cmd {
+		Debugf("URLs in the command are rewritten to mirrors: %s\n", rewritten)
+	}
+	return rewritten
+}
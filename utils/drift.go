@@ -0,0 +1,179 @@
+package utils
+
+import (
+	"bytes"
+	"io/fs"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// Statuses of configurations compared with their sources (see CompareConfig).
+const (
+	// ConfigInSync means the configuration matches its source.
+	ConfigInSync = "in-sync"
+	// ConfigMissing means the configuration has been removed.
+	ConfigMissing = "missing"
+	// ConfigBrokenSymlink means the configuration is a symbolic link to a path which does not exist.
+	ConfigBrokenSymlink = "broken-symlink"
+	// ConfigReplaced means the configuration has been replaced by something else,
+	// e.g., a file in place of a symbolic link or a symbolic link pointing elsewhere.
+	ConfigReplaced = "replaced"
+	// ConfigModified means the copied configuration has been changed.
+	ConfigModified = "modified"
+	// ConfigSourceMissing means the source of the configuration has been removed.
+	ConfigSourceMissing = "source-missing"
+)
+
+// ConfigDrift is a configuration compared with its source.
+type ConfigDrift struct {
+	ConfigRecord
+	// Status is one of ConfigInSync, ConfigMissing, ConfigBrokenSymlink, ConfigReplaced, ConfigModified
+	// and ConfigSourceMissing.
+	Status string
+	// Target is the path the configuration points to if it is a symbolic link.
+	Target string
+	// Files are paths (relative to the configuration) of files which differ, are added or are removed
+	// if the configuration is a copied directory.
+	Files []string
+}
+
+// ManagedConfigs returns configurations placed from their sources for the tool,
+// including symbolic links into ~/.config/icon-data recorded before sources of configurations were recorded.
+//
+// @return The configurations.
+func (s *ToolState) ManagedConfigs() []ConfigRecord {
+	configs := slices.Clone(s.Configs)
+	dataDir := NormalizePath("~/.config/icon-data") + string(filepath.Separator)
+	for _, link := range s.Symlinks {
+		if !strings.HasPrefix(link.Target, dataDir) {
+			continue
+		}
+		if !slices.ContainsFunc(configs, func(c ConfigRecord) bool { return c.Path == link.Path }) {
+			configs = append(configs, ConfigRecord{Path: link.Path, Source: link.Target})
+		}
+	}
+	return configs
+}
+
+// CompareConfig compares a configuration with its source.
+//
+// @param config The configuration.
+//
+// @return How the configuration differs from its source.
+func CompareConfig(config ConfigRecord) (ConfigDrift, error) {
+	drift := ConfigDrift{ConfigRecord: config, Status: ConfigInSync}
+	info, err := fsys.Lstat(config.Path)
+	if err != nil {
+		drift.Status = IfElseString(ExistsPath(config.Source), ConfigMissing, ConfigSourceMissing)
+		return drift, nil
+	}
+	if info.Mode()&fs.ModeSymlink != 0 {
+		drift.Target, err = fsys.Readlink(config.Path)
+		if err != nil {
+			return drift, err
+		}
+		switch {
+		case !ExistsPath(config.Path):
+			drift.Status = ConfigBrokenSymlink
+		case config.Copy || drift.Target != config.Source:
+			drift.Status = ConfigReplaced
+		}
+		return drift, nil
+	}
+	source, err := fsys.Stat(config.Source)
+	switch {
+	case err != nil:
+		drift.Status = ConfigSourceMissing
+	case !config.Copy || source.IsDir() != info.IsDir():
+		drift.Status = ConfigReplaced
+	case source.IsDir():
+		drift.Files, err = diffDirs(config.Source, config.Path)
+		if len(drift.Files) > 0 {
+			drift.Status = ConfigModified
+		}
+	default:
+		var same bool
+		same, err = sameContent(config.Source, config.Path)
+		if !same {
+			drift.Status = ConfigModified
+		}
+	}
+	return drift, err
+}
+
+// sameContent checks whether two files have the same content.
+func sameContent(file1, file2 string) (bool, error) {
+	bytes1, err := fsys.ReadFile(file1)
+	if err != nil {
+		return false, err
+	}
+	bytes2, err := fsys.ReadFile(file2)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(bytes1, bytes2), nil
+}
+
+// diffDirs finds regular files (as copied by CopyDirRegular) which differ between two directories.
+//
+// @return Sorted paths (relative to the directories) of files which differ or exist in only one of the directories.
+func diffDirs(dir1, dir2 string) ([]string, error) {
+	files1, err := regularFiles(dir1, "")
+	if err != nil {
+		return nil, err
+	}
+	files2, err := regularFiles(dir2, "")
+	if err != nil {
+		return nil, err
+	}
+	diffs := []string{}
+	for _, file := range files1 {
+		if !slices.Contains(files2, file) {
+			diffs = append(diffs, file)
+			continue
+		}
+		same, err := sameContent(filepath.Join(dir1, file), filepath.Join(dir2, file))
+		if err != nil {
+			return nil, err
+		}
+		if !same {
+			diffs = append(diffs, file)
+		}
+	}
+	for _, file := range files2 {
+		if !slices.Contains(files1, file) {
+			diffs = append(diffs, file)
+		}
+	}
+	slices.Sort(diffs)
+	return diffs, nil
+}
+
+// regularFiles lists regular files in a directory recursively.
+//
+// @param dir    The directory.
+// @param prefix The prefix of the returned paths, i.e., the path of dir relative to the top directory.
+//
+// @return Paths of the regular files relative to the top directory.
+func regularFiles(dir, prefix string) ([]string, error) {
+	entries, err := fsys.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, entry := range entries {
+		path := filepath.Join(prefix, entry.Name())
+		switch {
+		case entry.IsDir():
+			subFiles, err := regularFiles(filepath.Join(dir, entry.Name()), path)
+			if err != nil {
+				return nil, err
+			}
+			files = append(files, subFiles...)
+		case entry.Type().IsRegular():
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,153 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// CommandError is returned when a shell command fails.
+type CommandError struct {
+	// Cmd is the shell command which failed.
+	Cmd string
+	// ExitCode is the exit code of the command, or -1 if the command did not exit normally.
+	ExitCode int
+	// Err is the underlying error.
+	Err error
+}
+
+func (e *CommandError) Error() string {
+	return fmt.Sprintf("the command exited with code %d: %v\n%s", e.ExitCode, e.Err, e.Cmd)
+}
+
+func (e *CommandError) Unwrap() error {
+	return e.Err
+}
+
+// OfflineError is returned in offline mode (see SetOffline) when a URL has not been cached.
+type OfflineError struct {
+	// URL is the URL requested.
+	URL string
+}
+
+func (e *OfflineError) Error() string {
+	return fmt.Sprintf("%s is not available offline (it is not in the bundle or the cache)", e.URL)
+}
+
+// MissingConfigError is returned when a configuration file or a required key in it is missing.
+type MissingConfigError struct {
+	// Path is the path of the configuration file.
+	Path string
+	// Key is the missing key, or an empty string if the file itself is missing.
+	Key string
+}
+
+func (e *MissingConfigError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("the configuration file %s does not exist", e.Path)
+	}
+	return fmt.Sprintf("%s is not configured in %s", e.Key, e.Path)
+}
+
+// UnsupportedDistroError is returned when a tool does not support the current OS or Linux distribution.
+type UnsupportedDistroError struct {
+	// Tool is the name of the tool.
+	Tool string
+	// OS is the operating system, e.g., linux or darwin.
+	OS string
+	// Distro is the ID of the Linux distribution, or an empty string on other OSes.
+	Distro string
+}
+
+// NewUnsupportedDistroError creates an UnsupportedDistroError for the current OS and Linux distribution.
+//
+// @param tool The name of the tool.
+//
+// @return A pointer to an UnsupportedDistroError.
+func NewUnsupportedDistroError(tool string) *UnsupportedDistroError {
+	e := &UnsupportedDistroError{
+		Tool: tool,
+		OS:   CurrentPlatform().OS,
+	}
+	if IsLinux() {
+		e.Distro = GetLinuxDistID()
+	}
+	return e
+}
+
+func (e *UnsupportedDistroError) Error() string {
+	if e.Distro == "" {
+		return fmt.Sprintf("%s is not supported on %s", e.Tool, e.OS)
+	}
+	return fmt.Sprintf("%s is not supported on the Linux distribution %s", e.Tool, e.Distro)
+}
+
+// ChecksumMismatchError is returned when a downloaded file does not match its checksum.
+type ChecksumMismatchError struct {
+	// Path is the path of the downloaded file.
+	Path string
+	// Expected is the checksum published for the file.
+	Expected string
+	// Actual is the checksum of the downloaded file.
+	Actual string
+}
+
+func (e *ChecksumMismatchError) Error() string {
+	return fmt.Sprintf("the SHA-256 checksum of %s is %s but %s is expected", e.Path, e.Actual, e.Expected)
+}
+
+// MissingChecksumError is returned when checksums are required but no checksum is published for a download.
+type MissingChecksumError struct {
+	// Asset is the name of the asset downloaded.
+	Asset string
+	// Release is the release containing the asset, e.g., the tag name.
+	Release string
+}
+
+func (e *MissingChecksumError) Error() string {
+	return fmt.Sprintf("no checksum is published for the asset %s of the release %s (required by --require-checksum)",
+		e.Asset, e.Release)
+}
+
+// RateLimitError is returned when the rate limit of an API is exceeded.
+type RateLimitError struct {
+	// URL is the URL requested.
+	URL string
+	// Limit is the number of requests allowed per window, or an empty string if unknown.
+	Limit string
+	// Reset is when the rate limit resets.
+	Reset time.Time
+	// Hint tells how to raise the rate limit, or is an empty string.
+	Hint string
+}
+
+func (e *RateLimitError) Error() string {
+	msg := fmt.Sprintf("the rate limit of the API is exceeded when requesting %s", e.URL)
+	if e.Limit != "" {
+		msg += fmt.Sprintf(" (%s requests allowed)", e.Limit)
+	}
+	msg += fmt.Sprintf("; it resets at %s (in %s)",
+		e.Reset.Local().Format(time.DateTime), time.Until(e.Reset).Round(time.Second))
+	if e.Hint != "" {
+		msg += "; " + e.Hint
+	}
+	return msg
+}
+
+// NoAssetMatchedError is returned when no asset of a release matches the criteria.
+type NoAssetMatchedError struct {
+	// Release is the release, e.g., the tag name.
+	Release string
+	// Criteria describes the criteria, e.g., keywords and the pattern.
+	Criteria string
+	// Candidates are names of the assets of the release.
+	Candidates []string
+}
+
+func (e *NoAssetMatchedError) Error() string {
+	if len(e.Candidates) == 0 {
+		return fmt.Sprintf("the release %s has no asset", e.Release)
+	}
+	return fmt.Sprintf("no asset of the release %s matches %s. The candidates are:\n  %s",
+		e.Release, e.Criteria, strings.Join(e.Candidates, "\n  "))
+}
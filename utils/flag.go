@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"log"
+	"fmt"
 
 	"github.com/spf13/cobra"
 )
@@ -12,12 +12,12 @@ import (
 // @param flag The name of the flag to retrieve.
 //
 // @return The boolean value of the flag.
-func GetBoolFlag(cmd *cobra.Command, flag string) bool {
+func GetBoolFlag(cmd *cobra.Command, flag string) (bool, error) {
 	b, err := cmd.Flags().GetBool(flag)
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return false, fmt.Errorf("failed to get the value of the flag --%s: %w", flag, err)
 	}
-	return b
+	return b, nil
 }
 
 // GetIntFlag retrieves the integer value of a flag from a Cobra command.
@@ -26,12 +26,12 @@ func GetBoolFlag(cmd *cobra.Command, flag string) bool {
 // @param flag The name of the flag to retrieve.
 //
 // @return The integer value of the flag.
-func GetIntFlag(cmd *cobra.Command, flag string) int {
+func GetIntFlag(cmd *cobra.Command, flag string) (int, error) {
 	i, err := cmd.Flags().GetInt(flag)
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return 0, fmt.Errorf("failed to get the value of the flag --%s: %w", flag, err)
 	}
-	return i
+	return i, nil
 }
 
 // GetStringFlag retrieves the string value of a flag from a Cobra command.
@@ -40,12 +40,12 @@ func GetIntFlag(cmd *cobra.Command, flag string) int {
 // @param flag The name of the flag to retrieve.
 //
 // @return The string value of the flag.
-func GetStringFlag(cmd *cobra.Command, flag string) string {
+func GetStringFlag(cmd *cobra.Command, flag string) (string, error) {
 	s, err := cmd.Flags().GetString(flag)
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return "", fmt.Errorf("failed to get the value of the flag --%s: %w", flag, err)
 	}
-	return s
+	return s, nil
 }
 
 // GetStringSliceFlag retrieves the string slice value of a flag from a Cobra command.
@@ -54,17 +54,18 @@ func GetStringFlag(cmd *cobra.Command, flag string) string {
 // @param flag The name of the flag to retrieve.
 //
 // @return The string slice value of the flag.
-func GetStringSliceFlag(cmd *cobra.Command, flag string) []string {
+func GetStringSliceFlag(cmd *cobra.Command, flag string) ([]string, error) {
 	ss, err := cmd.Flags().GetStringSlice(flag)
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return nil, fmt.Errorf("failed to get the value of the flag --%s: %w", flag, err)
 	}
-	return ss
+	return ss, nil
 }
 
 // ShouldBackup returns true if the "no-backup" flag is not set.
-func ShouldBackup(cmd *cobra.Command) bool {
-	return !GetBoolFlag(cmd, "no-backup")
+func ShouldBackup(cmd *cobra.Command) (bool, error) {
+	noBackup, err := GetBoolFlag(cmd, "no-backup")
+	return !noBackup, err
 }
 
 // BuildYesFlag constructs a string flag for commands that require confirmation.
@@ -72,6 +73,7 @@ func ShouldBackup(cmd *cobra.Command) bool {
 // @param cmd A pointer to a Cobra command object.
 //
 // @return "-y" if the "yes" flag is set, otherwise an empty string.
-func BuildYesFlag(cmd *cobra.Command) string {
-	return IfElseString(GetBoolFlag(cmd, "yes"), "-y", "")
+func BuildYesFlag(cmd *cobra.Command) (string, error) {
+	yes, err := GetBoolFlag(cmd, "yes")
+	return IfElseString(yes, "-y", ""), err
 }
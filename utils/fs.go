@@ -1,11 +1,10 @@
 package utils
 
 import (
+	"fmt"
 	"io"
 	"io/fs"
-	"log"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strings"
 )
@@ -13,17 +12,16 @@ import (
 // GetFileMode retrieves the file mode (permissions) of a given file.
 //
 // This function uses os.Stat to get file information and returns the file mode.
-// If an error occurs during the file stat, the function will terminate with a fatal log.
 //
 // @param file The path to the file.
 // @return The file mode (fs.FileMode).
-func getFileMode(file string) fs.FileMode {
+func getFileMode(file string) (fs.FileMode, error) {
 	file = NormalizePath(file)
-	fileInfo, err := os.Stat(file)
+	fileInfo, err := fsys.Stat(file)
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return 0, fmt.Errorf("failed to stat %s: %w", file, err)
 	}
-	return fileInfo.Mode()
+	return fileInfo.Mode(), nil
 }
 
 func dir(path string) string {
@@ -37,10 +35,10 @@ func dir(path string) string {
 //
 // @param sourceFile      The path to the source file.
 // @param destinationDir The path to the destination directory where the source file will be copied.
-func CopyFileIntoDir(sourceFile, destinationDir string) {
+func CopyFileIntoDir(sourceFile, destinationDir string) error {
 	sourceFile = NormalizePath(sourceFile)
 	destinationDir = NormalizePath(destinationDir)
-	CopyFile(sourceFile, filepath.Join(destinationDir, filepath.Base(sourceFile)))
+	return CopyFile(sourceFile, filepath.Join(destinationDir, filepath.Base(sourceFile)))
 }
 
 // CopyDirRegular recursively copies a source directory to a destination directory.
@@ -53,38 +51,58 @@ func CopyFileIntoDir(sourceFile, destinationDir string) {
 // @param destinationDir The path to the destination directory where the source directory
 //
 //	and its contents will be copied.
-func CopyDirRegular(sourceDir, destinationDir string) {
-	MkdirAll(destinationDir, "")
-	for _, entry := range ReadDir(sourceDir) {
+func CopyDirRegular(sourceDir, destinationDir string) error {
+	if dryRun {
+		recordStep("copy", "%s -> %s", NormalizePath(sourceDir), NormalizePath(destinationDir))
+		return nil
+	}
+	if err := MkdirAll(destinationDir, ""); err != nil {
+		return err
+	}
+	entries, err := ReadDir(sourceDir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
 		if entry.IsDir() {
 			srcDir := filepath.Join(sourceDir, entry.Name())
 			dstDir := filepath.Join(destinationDir, entry.Name())
-			CopyDirRegular(srcDir, dstDir)
+			if err := CopyDirRegular(srcDir, dstDir); err != nil {
+				return err
+			}
 		} else {
 			sourceFile := filepath.Join(sourceDir, entry.Name())
 			if entry.Type().IsRegular() {
-				CopyFile(sourceFile, filepath.Join(destinationDir, entry.Name()))
+				if err := CopyFile(sourceFile, filepath.Join(destinationDir, entry.Name())); err != nil {
+					return err
+				}
 			}
 		}
 	}
+	return nil
 }
 
 // NormalizePath normalizes a given path string.
 //
 // This function handles paths that start with "~" which represents the user's home directory.
 // If the path starts with "~", it replaces it with the user's home directory obtained
-// from UserHomeDir(). Otherwise, it returns the path as is.
+// from UserHomeDir(). Otherwise (or if the home directory cannot be determined),
+// it returns the path as is.
 //
 // @param path The path string to normalize.
 // @return The normalized path string.
 func NormalizePath(path string) string {
-	if path == "~" {
-		return UserHomeDir()
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return path
 	}
-	if strings.HasPrefix(path, "~/") {
-		return filepath.Join(UserHomeDir(), path[2:])
+	home, err := UserHomeDir()
+	if err != nil {
+		return path
 	}
-	return path
+	if path == "~" {
+		return home
+	}
+	return filepath.Join(home, path[2:])
 }
 
 // CreateTempDir creates a new temporary directory.
@@ -92,20 +110,19 @@ func NormalizePath(path string) string {
 // This function creates a new temporary directory in the default directory for temporary files.
 // The directory name is generated with the given pattern.
 // If the pattern includes a "*", the "*" will be replaced with random characters.
-// If an error occurs during the directory creation, the function will terminate with a fatal log.
 //
 // @param pattern The pattern for generating the directory name.
 //
 // @return The path to the created temporary directory.
 //
 // @example
-// dir := CreateTempDir("my-temp-dir-*")
-func CreateTempDir(pattern string) string {
+// dir, err := CreateTempDir("my-temp-dir-*")
+func CreateTempDir(pattern string) (string, error) {
 	dir, err := os.MkdirTemp("", pattern)
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return "", fmt.Errorf("failed to create a temporary directory: %w", err)
 	}
-	return dir
+	return dir, nil
 }
 
 // ReplacePattern updates a text file by replacing patterns with specified substitutions.
@@ -116,7 +133,7 @@ func CreateTempDir(pattern string) string {
 // It leverages `ReadFileAsString`, `strings.ReplaceAll`, `WriteTextFile`, and
 // `GetFileMode` internally to perform the read, modify, and write operations.
 // If any error occurs during these steps, such as file reading or writing errors,
-// the function will terminate with a fatal log message.
+// the error is returned.
 //
 // @param path    The path to the text file to modify.
 // @param pattern The string pattern to search for and replace within the file.
@@ -126,10 +143,26 @@ func CreateTempDir(pattern string) string {
 //
 //	ReplacePattern("/tmp/myfile.txt", "old_text", "new_text")
 //	// Replaces all occurrences of "old_text" with "new_text" in /tmp/myfile.txt.
-func ReplacePattern(path, pattern, repl string) {
-	text := ReadFileAsString(path)
+func ReplacePattern(path, pattern, repl string) error {
+	text, err := ReadFileAsString(path)
+	if err != nil {
+		return err
+	}
 	text = strings.ReplaceAll(text, pattern, repl)
-	WriteTextFile(path, text, getFileMode(path))
+	mode, err := getFileMode(path)
+	if err != nil {
+		return err
+	}
+	return WriteTextFile(path, text, mode)
+}
+
+// Stat returns information of a file (following symbolic links).
+//
+// @param path The path to the file.
+//
+// @return Information of the file.
+func Stat(path string) (fs.FileInfo, error) {
+	return fsys.Stat(NormalizePath(path))
 }
 
 // ExistsPath checks if a file or directory exists at the specified path.
@@ -139,7 +172,7 @@ func ReplacePattern(path, pattern, repl string) {
 // @return true if the file or directory exists, false otherwise.
 func ExistsPath(path string) bool {
 	path = NormalizePath(path)
-	_, err := os.Stat(path)
+	_, err := fsys.Stat(path)
 	return !os.IsNotExist(err)
 }
 
@@ -149,7 +182,7 @@ func ExistsPath(path string) bool {
 //
 // @return true if a directory exists at the path, false otherwise.
 func ExistsDir(path string) bool {
-	stat, err := os.Stat(NormalizePath(path))
+	stat, err := fsys.Stat(NormalizePath(path))
 	if os.IsNotExist(err) {
 		return false
 	}
@@ -163,7 +196,7 @@ func ExistsDir(path string) bool {
 // @return true if a file exists at the path, false otherwise.
 func ExistsFile(path string) bool {
 	path = NormalizePath(path)
-	stat, err := os.Stat(path)
+	stat, err := fsys.Stat(path)
 	if os.IsNotExist(err) {
 		return false
 	}
@@ -173,40 +206,38 @@ func ExistsFile(path string) bool {
 // Getwd returns the current working directory.
 //
 // @return The current working directory as a string.
-func Getwd() string {
+func Getwd() (string, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return "", fmt.Errorf("failed to get the current working directory: %w", err)
 	}
-	return cwd
+	return cwd, nil
 }
 
 // UserHomeDir returns the current user's home directory.
 //
 // @return The path to the current user's home directory as a string.
-func UserHomeDir() string {
+func UserHomeDir() (string, error) {
 	home, err := os.UserHomeDir()
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return "", fmt.Errorf("failed to get the home directory of the current user: %w", err)
 	}
-	return home
+	return home, nil
 }
 
 // ReadDir reads the named directory and returns
 // a list of directory entries sorted by filename.
-// If an error occurs during the directory reading, the function
-// will terminate with a fatal log.
 //
 // @param dir The name of the directory to read.
 //
 // @return A slice of DirEntry representing the directory's contents.
-func ReadDir(dir string) []os.DirEntry {
+func ReadDir(dir string) ([]os.DirEntry, error) {
 	dir = NormalizePath(dir)
-	entries, err := os.ReadDir(dir)
+	entries, err := fsys.ReadDir(dir)
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return nil, fmt.Errorf("failed to read the directory %s: %w", dir, err)
 	}
-	return entries
+	return entries, nil
 }
 
 // ReadAllAsText reads all data from an io.ReadCloser and returns it as a string.
@@ -214,13 +245,13 @@ func ReadDir(dir string) []os.DirEntry {
 // @param readCloser The io.ReadCloser from which to read the data.
 //
 // @return The read data as a string.
-func ReadAllAsText(readCloser io.ReadCloser) string {
+func ReadAllAsText(readCloser io.ReadCloser) (string, error) {
 	bytes, err := io.ReadAll(readCloser)
 	readCloser.Close()
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return "", fmt.Errorf("failed to read all data: %w", err)
 	}
-	return string(bytes)
+	return string(bytes), nil
 }
 
 // ReadFile reads the entire content of a file and returns it as a byte slice.
@@ -228,19 +259,20 @@ func ReadAllAsText(readCloser io.ReadCloser) string {
 // @param path The path to the file to be read.
 //
 // @return The content of the file as a slice of bytes.
-func ReadFile(path string) []byte {
-	bytes, err := os.ReadFile(NormalizePath(path))
+func ReadFile(path string) ([]byte, error) {
+	bytes, err := fsys.ReadFile(NormalizePath(path))
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return nil, fmt.Errorf("failed to read the file %s: %w", path, err)
 	}
-	return bytes
+	return bytes, nil
 }
 
 // ReadFileAsString reads the entire content of a file and returns it as a string.
 //
 // @param path The path to the file to be read.
-func ReadFileAsString(path string) string {
-	return string(ReadFile(path))
+func ReadFileAsString(path string) (string, error) {
+	bytes, err := ReadFile(path)
+	return string(bytes), err
 }
 
 // WriteFile writes a byte slice to a file with the specified permissions.
@@ -248,12 +280,19 @@ func ReadFileAsString(path string) string {
 // @param fileName The name of the file to write to.
 // @param data     The byte slice to write to the file.
 // @param perm     The file mode (permissions) to set for the file.
-func WriteFile(fileName string, data []byte, perm fs.FileMode) {
+func WriteFile(fileName string, data []byte, perm fs.FileMode) error {
 	fileName = NormalizePath(fileName)
-	err := os.WriteFile(fileName, data, perm)
+	if dryRun {
+		recordStep("write", "%s (%d bytes, mode %s)", fileName, len(data), perm)
+		return nil
+	}
+	record := prepareWrite(fileName)
+	err := fsys.WriteFile(fileName, data, perm)
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return fmt.Errorf("failed to write the file %s: %w", fileName, err)
 	}
+	record()
+	return nil
 }
 
 // WriteTextFile writes a string to a file with the specified permissions.
@@ -261,12 +300,12 @@ func WriteFile(fileName string, data []byte, perm fs.FileMode) {
 // @param path The path to the file to write to.
 // @param text The string to write to the file.
 // @param perm The file mode (permissions) to set for the file.
-func WriteTextFile(path, text string, perm fs.FileMode) {
-	WriteFile(path, []byte(text), perm)
+func WriteTextFile(path, text string, perm fs.FileMode) error {
+	return WriteFile(path, []byte(text), perm)
 }
 
 func LookPath(cmd string) string {
-	path, err := exec.LookPath(cmd)
+	path, err := executor.LookPath(cmd)
 	if err != nil {
 		return ""
 	}
@@ -1,201 +1,460 @@
 package utils
 
 import (
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
+	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"time"
 
 	"golang.org/x/sys/unix"
 )
 
-// Chmod changes the mode of the named file to mode.
-func Chmod(path, mode string) {
+// Chmod changes the mode of the named file (recursively if it is a directory) to mode.
+// The change is made in Go, or using chmod with sudo if the file is not writable by the current user.
+//
+// @param path The path to the file or directory.
+// @param mode An octal mode (e.g., 600) or a symbolic mode (e.g., +x or u+rw,go-w).
+func Chmod(path, mode string) error {
 	path = NormalizePath(path)
-	prefix := GetCommandPrefix(false, map[string]uint32{
+	prefix, err := GetCommandPrefix(false, map[string]uint32{
 		path: unix.W_OK | unix.R_OK,
 	})
-	cmd := Format("{prefix} chmod -R {mode} {path}", map[string]string{
-		"prefix": prefix,
-		"mode":   mode,
-		"path":   path,
-	})
-	RunCmd(cmd)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		recordStep("chmod", "%s %s %s", prefix, mode, path)
+		return nil
+	}
+	if prefix != "" {
+		return NewCommand("chmod", "-R", mode, path).Sudo(prefix).Run()
+	}
+	return chmodAll(path, mode)
 }
 
 // Chmod600 recursively changes file modes of files under a directory to 600.
 //
 // @param path The path to the file or directory.
-func Chmod600(path string) {
-	if ExistsDir(path) {
-		Chmod(path, "700")
-		for _, entry := range ReadDir(path) {
-			Chmod600(filepath.Join(path, entry.Name()))
+func Chmod600(path string) error {
+	if !ExistsDir(path) {
+		return Chmod(path, "600")
+	}
+	if err := Chmod(path, "700"); err != nil {
+		return err
+	}
+	entries, err := ReadDir(path)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := Chmod600(filepath.Join(path, entry.Name())); err != nil {
+			return err
 		}
-	} else {
-		Chmod(path, "600")
 	}
+	return nil
 }
 
-// copyFile copies a file from the source path to the destination path.
+// CopyFile copies a file from the source path to the destination path.
+// The file is copied in Go, or using cp with sudo if the paths are not accessible by the current user.
 //
 // @param sourceFile      The path to the source file.
 // @param destinationFile The path to the destination file where the source file will be copied.
-func CopyFile(sourceFile, destinationFile string) {
+func CopyFile(sourceFile, destinationFile string) error {
 	sourceFile = NormalizePath(sourceFile)
 	destinationFile = NormalizePath(destinationFile)
-	MkdirAll(dir(destinationFile), "")
-
-	prefix := GetCommandPrefix(false, map[string]uint32{
+	if !dryRun {
+		if err := MkdirAll(dir(destinationFile), ""); err != nil {
+			return err
+		}
+	}
+	prefix, err := GetCommandPrefix(false, map[string]uint32{
 		sourceFile:      unix.R_OK,
 		destinationFile: unix.R_OK | unix.W_OK,
 	})
-	cmd := Format("{prefix} cp {sourceFile} {destinationFile}", map[string]string{
-		"prefix":          prefix,
-		"sourceFile":      sourceFile,
-		"destinationFile": destinationFile,
-	})
-	RunCmd(cmd)
-	log.Printf("%s is copied to %s.\n", sourceFile, destinationFile)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		recordStep("copy", "%s %s -> %s", prefix, sourceFile, destinationFile)
+		return nil
+	}
+	record := prepareWrite(destinationFile)
+	if prefix != "" {
+		err = NewCommand("cp", sourceFile, destinationFile).Sudo(prefix).Run()
+	} else {
+		err = copyFileContent(sourceFile, destinationFile)
+	}
+	if err != nil {
+		return err
+	}
+	record()
+	Debugf("%s is copied to %s.\n", sourceFile, destinationFile)
+	return nil
 }
 
 // RemoveAll removes the specified path and any children it contains.
 //
 // @param path The path to the file or directory to remove.
-func RemoveAll(path string) {
+func RemoveAll(path string) error {
 	path = NormalizePath(path)
-	prefix := GetCommandPrefix(false, map[string]uint32{
+	prefix, err := GetCommandPrefix(false, map[string]uint32{
 		path: unix.W_OK | unix.R_OK,
 	})
-	cmd := Format("{prefix} rm -rf {path}", map[string]string{
-		"prefix": prefix,
-		"path":   path,
-	})
-	RunCmd(cmd)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		recordStep("remove", "%s %s", prefix, path)
+		return nil
+	}
+	if prefix != "" {
+		return NewCommand("rm", "-rf", path).Sudo(prefix).Run()
+	}
+	if err := fsys.RemoveAll(path); err != nil {
+		return fmt.Errorf("failed to remove %s: %w", path, err)
+	}
+	return nil
 }
 
 // MkdirAll creates a directory and all necessary parent directories.
 //
 // @param path The path of the directory to create.
 // @param perm The file mode (permissions) to set for the newly created directories.
-func MkdirAll(path, perm string) {
+func MkdirAll(path, perm string) error {
 	perm = strings.TrimSpace(perm)
 	path = NormalizePath(path)
-	prefix := GetCommandPrefix(false, map[string]uint32{
+	if perm == "" && ExistsDir(path) {
+		return nil
+	}
+	prefix, err := GetCommandPrefix(false, map[string]uint32{
 		path: unix.R_OK | unix.W_OK | unix.X_OK,
 	})
-	cmd := "{prefix} mkdir -p {path}"
-	if perm != "" {
-		cmd += " && {prefix} chmod -R {perm} {path}"
-	}
-	cmd = Format(cmd, map[string]string{
-		"prefix": prefix,
-		"path":   path,
-		"perm":   perm,
-	})
-	RunCmd(cmd)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		recordStep("mkdir", "%s %s %s", prefix, path, perm)
+		return nil
+	}
+	if prefix != "" {
+		if err := NewCommand("mkdir", "-p", path).Sudo(prefix).Run(); err != nil {
+			return err
+		}
+	} else if err := fsys.MkdirAll(path, 0o777); err != nil { //nolint:mnd // readable
+		return fmt.Errorf("failed to create the directory %s: %w", path, err)
+	}
+	if perm == "" {
+		return nil
+	}
+	return Chmod(path, perm)
 }
 
 // BackupOrRemove backs up the path if backup is true, otherwise removes it.
+// During a run of a tool, a removed path is moved into the journal of the run so that it is restored if the run fails,
+// and it is deleted when the run ends.
 // Call this before copying or symlinking to prepare the destination.
-func BackupOrRemove(path string, backup bool) {
+func BackupOrRemove(path string, backup bool) error {
 	path = NormalizePath(path)
 	if backup {
-		Backup(path, "")
-	} else {
-		RemoveAll(path)
+		return Backup(path, "")
 	}
+	if journal != nil && !dryRun && lexists(path) {
+		return removeJournaled(path)
+	}
+	return RemoveAll(path)
 }
 
 // Symlink is a wrapper of os.Symlink with error handling.
+// ln is run with sudo instead if the paths are not accessible by the current user.
 //
 // @param path The path to the source file/directory.
 // @param dstLink The path where the symbolic link will be created.
-func Symlink(path, dstLink string) {
+func Symlink(path, dstLink string) error {
 	path = NormalizePath(path)
 	dstLink = NormalizePath(dstLink)
-	MkdirAll(filepath.Dir(dstLink), "")
-	prefix := GetCommandPrefix(false, map[string]uint32{
+	if !dryRun {
+		if err := MkdirAll(filepath.Dir(dstLink), ""); err != nil {
+			return err
+		}
+	}
+	prefix, err := GetCommandPrefix(false, map[string]uint32{
 		path:    unix.R_OK,
 		dstLink: unix.W_OK | unix.R_OK,
 	})
-	cmd := Format("{prefix} ln -sv {path} {dstLink}", map[string]string{
-		"prefix":  prefix,
-		"path":    path,
-		"dstLink": dstLink,
-	})
-	RunCmd(cmd)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		recordStep("symlink", "%s %s -> %s", prefix, dstLink, path)
+		return nil
+	}
+	if prefix != "" {
+		if err := NewCommand("ln", "-sn", path, dstLink).Sudo(prefix).Run(); err != nil {
+			return err
+		}
+	} else if err := fsys.Symlink(path, dstLink); err != nil {
+		return fmt.Errorf("failed to create the symbolic link %s: %w", dstLink, err)
+	}
+	recordSymlink(dstLink, path)
+	Debugf("%s -> %s is created.\n", dstLink, path)
+	return nil
 }
 
-func SymlinkIntoDir(path, dstDir string) {
-	Symlink(path, filepath.Join(dstDir, filepath.Base(path)))
+func SymlinkIntoDir(path, dstDir string) error {
+	return Symlink(path, filepath.Join(dstDir, filepath.Base(path)))
 }
 
 // CopyOrSymlink copies src to dst (using CopyFile or CopyDirRegular) when doCopy
 // is true, otherwise creates a symlink at dst pointing to src.
-func CopyOrSymlink(src, dst string, doCopy bool) {
+// The configuration is recorded for the current tool so that its drift from src can be checked (see CompareConfig).
+func CopyOrSymlink(src, dst string, doCopy bool) error {
 	src = NormalizePath(src)
 	dst = NormalizePath(dst)
-	if doCopy {
-		if ExistsDir(src) {
-			CopyDirRegular(src, dst)
-		} else {
-			CopyFile(src, dst)
-		}
-	} else {
-		Symlink(src, dst)
+	var err error
+	switch {
+	case !doCopy:
+		err = Symlink(src, dst)
+	case ExistsDir(src):
+		err = CopyDirRegular(src, dst)
+	default:
+		err = CopyFile(src, dst)
+	}
+	if err == nil && !dryRun {
+		recordConfig(dst, src, doCopy)
 	}
+	return err
 }
 
-func Rename(originalPath, newPath string) {
-	prefix := GetCommandPrefix(false, map[string]uint32{
+// Rename renames (moves) a file or directory.
+// It is done in Go, or using mv (with sudo if the paths are not accessible by the current user)
+// if the paths are on different file systems.
+//
+// @param originalPath The path to rename.
+// @param newPath      The new path.
+func Rename(originalPath, newPath string) error {
+	prefix, err := GetCommandPrefix(false, map[string]uint32{
 		originalPath: unix.W_OK | unix.R_OK,
 		newPath:      unix.W_OK | unix.R_OK,
 	})
-	cmd := Format("{prefix} mv {originalPath} {newPath}", map[string]string{
-		"prefix":       prefix,
-		"originalPath": originalPath,
-		"newPath":      newPath,
-	})
-	RunCmd(cmd)
-	fmt.Printf("The path %s has been renamed to %s.\n", originalPath, newPath)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		recordStep("rename", "%s %s -> %s", prefix, originalPath, newPath)
+		return nil
+	}
+	if prefix == "" {
+		err = fsys.Rename(originalPath, newPath)
+		if err != nil && !errors.Is(err, unix.EXDEV) {
+			return fmt.Errorf("failed to rename %s to %s: %w", originalPath, newPath, err)
+		}
+	}
+	if prefix != "" || err != nil {
+		if err := NewCommand("mv", originalPath, newPath).Sudo(prefix).Run(); err != nil {
+			return err
+		}
+	}
+	log.Printf("The path %s has been renamed to %s.\n", originalPath, newPath)
+	return nil
 }
 
-func Backup(original, backup string) {
+func Backup(original, backup string) error {
 	original = NormalizePath(original)
 	backup = NormalizePath(backup)
-	if ExistsPath(original) {
-		if backup == "" {
-			backup = filepath.Clean(original) + "_" + time.Now().Format(time.RFC3339)
+	if !ExistsPath(original) {
+		return nil
+	}
+	if backup == "" {
+		backup = filepath.Clean(original) + "_" + time.Now().Format(time.RFC3339)
+		// do not overwrite a backup made earlier within the same second
+		for i := 1; lexists(backup); i++ {
+			backup = fmt.Sprintf("%s_%s_%d", filepath.Clean(original), time.Now().Format(time.RFC3339), i)
 		}
-		Rename(original, backup)
 	}
+	if dryRun {
+		recordStep("backup", "%s -> %s", original, backup)
+		return nil
+	}
+	if err := Rename(original, backup); err != nil {
+		return err
+	}
+	recordBackup(original, backup)
+	return nil
 }
 
-// AppendToTextFile appends text to a file.
+// AppendToTextFile appends text (followed by a line break) to a file.
+// The text is written in Go as it is, or piped into tee with sudo if the file is not writable by the current user.
 //
 // @param path           The path to the file to append to.
 // @param text           The text to append to the file.
 // @param checkExistence If true, checks if the text already exists in the file before appending.
-func AppendToTextFile(path, text string, checkExistence bool) {
+func AppendToTextFile(path, text string, checkExistence bool) error {
+	path = NormalizePath(path)
 	if checkExistence {
 		fileContent := ""
 		if ExistsFile(path) {
-			fileContent = ReadFileAsString(path)
+			var err error
+			fileContent, err = ReadFileAsString(path)
+			if err != nil {
+				return err
+			}
 		}
-		if !strings.Contains(fileContent, strings.TrimSpace(text)) {
-			AppendToTextFile(path, text, false)
+		if strings.Contains(fileContent, strings.TrimSpace(text)) {
+			return nil
 		}
-		return
 	}
-	prefix := GetCommandPrefix(false, map[string]uint32{
+	prefix, err := GetCommandPrefix(false, map[string]uint32{
 		path: unix.R_OK | unix.W_OK,
 	})
-	cmd := Format(`echo -e {text} | {prefix} tee -a {path} > /dev/null`, map[string]string{
-		"text":   text,
-		"prefix": prefix,
-		"path":   path,
-	})
-	RunCmd(cmd)
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		recordStep("append", "%s %s\n%s", prefix, path, text)
+		return nil
+	}
+	text += "\n"
+	// only the previous content of an existing file is saved as appending does not place a file
+	record := func() {}
+	if lexists(path) {
+		record = prepareWrite(path)
+	}
+	if prefix != "" {
+		if err := NewCommand("tee", "-a", path).Sudo(prefix).Stdin(strings.NewReader(text)).Stdout(io.Discard).Run(); err != nil {
+			return err
+		}
+		record()
+		return nil
+	}
+	//nolint:mnd // readable
+	file, err := fsys.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	if _, err := file.WriteString(text); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to append to %s: %w", path, err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", path, err)
+	}
+	record()
+	return nil
+}
+
+// copyFileContent copies the content of a file into another file,
+// which is created with the permission of the source file if it does not exist.
+func copyFileContent(sourceFile, destinationFile string) error {
+	in, err := fsys.OpenFile(sourceFile, os.O_RDONLY, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", sourceFile, err)
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", sourceFile, err)
+	}
+	out, err := fsys.OpenFile(destinationFile, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, info.Mode().Perm())
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", destinationFile, err)
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return fmt.Errorf("failed to copy %s to %s: %w", sourceFile, destinationFile, err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", destinationFile, err)
+	}
+	return nil
+}
+
+// chmodAll changes the mode of a file or, recursively, of a directory and its contents like chmod -R,
+// i.e., symbolic links inside a directory are left alone.
+func chmodAll(path, mode string) error {
+	info, err := fsys.Stat(path)
+	if err != nil {
+		return fmt.Errorf("failed to stat %s: %w", path, err)
+	}
+	newMode, err := parseMode(mode, info.Mode())
+	if err != nil {
+		return err
+	}
+	// entries are read before the mode (e.g., 600) might forbid it
+	var entries []fs.DirEntry
+	if info.IsDir() {
+		if entries, err = fsys.ReadDir(path); err != nil {
+			return fmt.Errorf("failed to read the directory %s: %w", path, err)
+		}
+	}
+	if err := fsys.Chmod(path, newMode); err != nil {
+		return fmt.Errorf("failed to change the mode of %s: %w", path, err)
+	}
+	for _, entry := range entries {
+		if entry.Type()&fs.ModeSymlink != 0 {
+			continue
+		}
+		if err := chmodAll(filepath.Join(path, entry.Name()), mode); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseMode computes the new permission of a file from an octal mode (e.g., 755)
+// or a symbolic mode (e.g., +x or u+rw,go-w) of chmod.
+// Unlike chmod, the umask is not applied to symbolic modes without users (e.g., +x).
+func parseMode(mode string, current fs.FileMode) (fs.FileMode, error) {
+	if octal, err := strconv.ParseUint(mode, 8, 32); err == nil {
+		if octal > uint64(fs.ModePerm) {
+			return 0, fmt.Errorf("unsupported file mode %s", mode)
+		}
+		return fs.FileMode(octal), nil
+	}
+	perm := current.Perm()
+	for _, clause := range strings.Split(mode, ",") {
+		who := strings.TrimLeft(clause, "ugoa")
+		users := clause[:len(clause)-len(who)]
+		if users == "" || strings.Contains(users, "a") {
+			users = "ugo"
+		}
+		if who == "" || !strings.ContainsRune("+-=", rune(who[0])) || strings.Trim(who[1:], "rwxX") != "" {
+			return 0, fmt.Errorf("unsupported file mode %s", mode)
+		}
+		var bits fs.FileMode
+		for _, p := range who[1:] {
+			switch p {
+			case 'r':
+				bits |= 0o4
+			case 'w':
+				bits |= 0o2
+			case 'x':
+				bits |= 0o1
+			case 'X':
+				if current.IsDir() || current&0o111 != 0 {
+					bits |= 0o1
+				}
+			}
+		}
+		var mask, value fs.FileMode
+		for _, u := range users {
+			shift := map[rune]int{'u': 6, 'g': 3, 'o': 0}[u] //nolint:mnd // readable
+			mask |= 0o7 << shift
+			value |= bits << shift
+		}
+		switch who[0] {
+		case '+':
+			perm |= value
+		case '-':
+			perm &^= value
+		default:
+			perm = perm&^mask | value
+		}
+	}
+	return perm, nil
 }
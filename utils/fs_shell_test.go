@@ -0,0 +1,194 @@
+package utils_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"legendu.net/icon/internal/testutil"
+	"legendu.net/icon/utils"
+)
+
+func TestFileOperations(t *testing.T) {
+	tests := []struct {
+		name string
+		run  func() error
+		want []string
+	}{
+		{"copy into home", func() error {
+			return utils.CopyFile("~/a b.txt", "~/dir/c.txt")
+		}, nil},
+		{"copy into a system directory", func() error {
+			return utils.CopyFile("~/a b.txt", "/usr/local/share/c.txt")
+		}, []string{
+			"sudo true",
+			"sudo cp '" + testutil.Home + "/a b.txt' /usr/local/share/c.txt",
+		}},
+		{"mkdir in a system directory", func() error {
+			return utils.MkdirAll("/opt/tool", "755")
+		}, []string{
+			"sudo true",
+			"sudo mkdir -p /opt/tool",
+			"sudo chmod -R 755 /opt/tool",
+		}},
+		{"symlink into a system directory", func() error {
+			return utils.Symlink("~/a b.txt", "/usr/local/bin/ab")
+		}, []string{
+			"sudo true",
+			"sudo ln -sn '" + testutil.Home + "/a b.txt' /usr/local/bin/ab",
+		}},
+		{"append to a system file", func() error {
+			return utils.AppendToTextFile("/etc/environment", "X=$(id)", false)
+		}, []string{
+			"sudo true",
+			"sudo tee -a /etc/environment",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			env.WriteFile("~/a b.txt", "hello\n")
+			if err := tt.run(); err != nil {
+				t.Fatal(err)
+			}
+			env.AssertCommands(tt.want...)
+		})
+	}
+}
+
+func TestFileOperationsInHome(t *testing.T) {
+	env := testutil.New(t, testutil.Ubuntu)
+	env.WriteFile("~/a.txt", "hello\n")
+	if err := utils.CopyFile("~/a.txt", "~/dir/b.txt"); err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.Symlink("~/dir/b.txt", "~/link"); err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.AppendToTextFile("~/link", "echo $(id) 'quoted'", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.MkdirAll("~/private", "700"); err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.Chmod("~/dir", "u+x,go-rwx"); err != nil {
+		t.Fatal(err)
+	}
+	env.AssertCommands()
+	if got, want := env.ReadFile("~/dir/b.txt"), "hello\necho $(id) 'quoted'\n"; got != want {
+		t.Errorf("~/dir/b.txt = %q, want %q", got, want)
+	}
+	if got, want := env.Readlink("~/link"), testutil.Home+"/dir/b.txt"; got != want {
+		t.Errorf("~/link links to %q, want %q", got, want)
+	}
+	for path, want := range map[string]fs.FileMode{"/private": 0o700, "/dir": 0o700, "/dir/b.txt": 0o700} {
+		info, err := os.Stat(env.FS.RealPath(testutil.Home + path))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := info.Mode().Perm(); got != want {
+			t.Errorf("the mode of ~%s is %o, want %o", path, got, want)
+		}
+	}
+}
+
+func TestToolRunRecordsPlacedFiles(t *testing.T) {
+	env := testutil.New(t, testutil.Ubuntu)
+	env.WriteFile("~/.bashrc", "# bashrc\nexport X=1\n")
+	env.MkdirAll("~/.config/atuin")
+	utils.BeginToolRun("atuin")
+	if err := utils.ReplacePattern("~/.bashrc", "X=1", "X=2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.WriteTextFile("~/.config/atuin/config.toml", "sync = false\n", 0o644); err != nil {
+		t.Fatal(err)
+	}
+	state, err := utils.EndToolRun(true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(state.Files) != 1 || state.Files[0].Path != testutil.Home+"/.config/atuin/config.toml" {
+		t.Errorf("recorded files: %v, want only config.toml", state.Files)
+	}
+	// a file placed by a previous run is still owned when it is overwritten
+	utils.BeginToolRun("atuin")
+	if err := utils.WriteTextFile("~/.config/atuin/config.toml", "sync = true\n", 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if state, err = utils.EndToolRun(false, true); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := state.RemoveRecordedPaths(); err != nil {
+		t.Fatal(err)
+	}
+	if env.Exists("~/.config/atuin/config.toml") {
+		t.Error("config.toml placed by icon is not removed on uninstall")
+	}
+	if got, want := env.ReadFile("~/.bashrc"), "# bashrc\nexport X=2\n"; got != want {
+		t.Errorf("~/.bashrc = %q, want %q", got, want)
+	}
+}
+
+func TestRollbackRestoresExistingFiles(t *testing.T) {
+	env := testutil.New(t, testutil.Ubuntu)
+	env.WriteFile("~/.bashrc", "export X=1\n")
+	env.WriteFile("~/.profile", "# profile\n")
+	env.WriteFile("~/.config/tool/config", "mine\n")
+	utils.BeginToolRun("tool")
+	if err := utils.ReplacePattern("~/.bashrc", "X=1", "X=2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.AppendToTextFile("~/.profile", "export Y=1", false); err != nil {
+		t.Fatal(err)
+	}
+	if err := utils.BackupOrRemove("~/.config/tool/config", false); err != nil {
+		t.Fatal(err)
+	}
+	if env.Exists("~/.config/tool/config") {
+		t.Fatal("~/.config/tool/config is not removed")
+	}
+	if err := utils.WriteTextFile("~/.config/tool/config", "icon\n", 0o644); err != nil {
+		t.Fatal(err)
+	}
+	kept, err := utils.RollbackToolRun()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(kept) > 0 {
+		t.Errorf("kept: %v", kept)
+	}
+	if _, err := utils.EndToolRun(false, false); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"~/.bashrc":             "export X=1\n",
+		"~/.profile":            "# profile\n",
+		"~/.config/tool/config": "mine\n",
+	} {
+		if got := env.ReadFile(path); got != want {
+			t.Errorf("%s = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestToolRunDeletesRemovedPaths(t *testing.T) {
+	env := testutil.New(t, testutil.Ubuntu)
+	env.WriteFile("~/.config/tool/data/big.bin", "data\n")
+	utils.BeginToolRun("tool")
+	if err := utils.BackupOrRemove("~/.config/tool", false); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := utils.EndToolRun(false, true); err != nil {
+		t.Fatal(err)
+	}
+	if env.Exists("~/.config/tool") {
+		t.Error("~/.config/tool is not removed")
+	}
+	if env.Exists(filepath.Join(utils.StateDir(), "journal", "tool", "snapshots")) {
+		t.Error("the removed path is kept in the journal directory after the run succeeds")
+	}
+	if journal, err := utils.ReadLastJournal("tool"); err != nil || journal != nil {
+		t.Errorf("journal = %v, %v, want none", journal, err)
+	}
+}
@@ -0,0 +1,397 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"golang.org/x/sys/unix"
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of changes recorded in a journal.
+const (
+	// JournalFile is a file placed by icon.
+	JournalFile = "file"
+	// JournalSymlink is a symbolic link created by icon.
+	JournalSymlink = "symlink"
+	// JournalBackup is a path renamed to a backup by icon.
+	JournalBackup = "backup"
+	// JournalModified is an existing file overwritten or edited in place by icon,
+	// whose previous content is saved into the journal directory.
+	JournalModified = "modified"
+	// JournalRemoved is a path removed by icon without a backup, which is moved into the journal directory
+	// until the run ends (so that it is restored if the run fails).
+	JournalRemoved = "removed"
+)
+
+// JournalEntry is a change made by icon during a run of a tool.
+type JournalEntry struct {
+	// Kind is one of JournalFile, JournalSymlink, JournalBackup, JournalModified and JournalRemoved.
+	Kind string `yaml:"kind"`
+	// Path is the file or symbolic link placed, the file modified, or the original path which was backed up or removed.
+	Path string `yaml:"path"`
+	// Target is what the symbolic link points to, the path of the backup,
+	// or the path of the saved previous content of the modified or removed path.
+	Target string `yaml:"target,omitempty"`
+	// Sha256 is the SHA-256 checksum of the file when it was placed or modified.
+	Sha256 string `yaml:"sha256,omitempty"`
+}
+
+// Journal is the ordered list of changes made by icon during a run of a tool.
+type Journal struct {
+	// Tool is the name of the command of the tool.
+	Tool string `yaml:"tool"`
+	// Time is when the run started.
+	Time time.Time `yaml:"time"`
+	// Entries are the changes in the order they were made.
+	Entries []JournalEntry `yaml:"entries"`
+	// file is the path of the journal on disk, or an empty string if it has not been saved.
+	file string
+}
+
+// journal is the journal of the tool which is running.
+var journal *Journal
+
+func journalDir(tool string) string {
+	return filepath.Join(StateDir(), "journal", tool)
+}
+
+// beginJournal starts a journal for a run of a tool.
+func beginJournal(tool string) {
+	journal = &Journal{Tool: tool, Time: time.Now()}
+}
+
+// endJournal stops the journal of the current run and saves it if any change has been made.
+func endJournal() error {
+	run := journal
+	journal = nil
+	if run == nil || dryRun {
+		return nil
+	}
+	run.pruneRemoved()
+	if len(run.Entries) == 0 {
+		return nil
+	}
+	return run.save()
+}
+
+// pruneRemoved deletes paths removed (without a backup) by the run,
+// which are kept in the journal directory only to be restored if the run fails (see RollbackToolRun).
+// It is called when the run ends, after a failed run has been rolled back.
+func (j *Journal) pruneRemoved() {
+	j.Entries = slices.DeleteFunc(j.Entries, func(entry JournalEntry) bool {
+		if entry.Kind != JournalRemoved {
+			return false
+		}
+		if err := RemoveAll(entry.Target); err != nil {
+			Warnf("Failed to remove %s (removed from %s): %v\n", entry.Target, entry.Path, err)
+			return false
+		}
+		return true
+	})
+	dir := filepath.Join(journalDir(j.Tool), "snapshots")
+	if entries, err := fsys.ReadDir(dir); err == nil && len(entries) == 0 {
+		_ = fsys.RemoveAll(dir)
+	}
+}
+
+func appendJournal(entry JournalEntry) {
+	if journal != nil {
+		journal.Entries = append(journal.Entries, entry)
+	}
+}
+
+// snapshotPath returns a new path in the journal directory of the current run to save the previous content of a path.
+func snapshotPath(path string) (string, error) {
+	dir := filepath.Join(journalDir(journal.Tool), "snapshots")
+	//nolint:mnd // readable
+	if err := fsys.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("failed to create the snapshot directory: %w", err)
+	}
+	return filepath.Join(dir, fmt.Sprintf("%d_%s", time.Now().UnixNano(), filepath.Base(path))), nil
+}
+
+// saveSnapshot saves the content of an existing file into the journal directory of the current run
+// before the file is overwritten or edited in place.
+//
+// @param path The path of the file.
+//
+// @return The path of the saved content.
+func saveSnapshot(path string) (string, error) {
+	snapshot, err := snapshotPath(path)
+	if err != nil {
+		return "", err
+	}
+	if err := copyFileContent(path, snapshot); err != nil {
+		return "", err
+	}
+	return snapshot, nil
+}
+
+// removeJournaled removes a path during a run of a tool by moving it into the journal directory
+// so that it can be restored on rollback.
+//
+// @param path The path to remove.
+func removeJournaled(path string) error {
+	snapshot, err := snapshotPath(path)
+	if err != nil {
+		return err
+	}
+	if err := Rename(path, snapshot); err != nil {
+		return err
+	}
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	appendJournal(JournalEntry{Kind: JournalRemoved, Path: path, Target: snapshot})
+	return nil
+}
+
+// restoreSnapshot writes saved content back into a file,
+// using cp with sudo if the file is not writable by the current user.
+func restoreSnapshot(snapshot, path string) error {
+	prefix, err := GetCommandPrefix(false, map[string]uint32{
+		path: unix.W_OK,
+	})
+	if err != nil {
+		return err
+	}
+	if dryRun {
+		recordStep("restore", "%s %s -> %s", prefix, snapshot, path)
+		return nil
+	}
+	if prefix != "" {
+		err = NewCommand("cp", snapshot, path).Sudo(prefix).Run()
+	} else {
+		err = copyFileContent(snapshot, path)
+	}
+	if err != nil {
+		return err
+	}
+	return RemoveAll(snapshot)
+}
+
+func (j *Journal) save() error {
+	dir := journalDir(j.Tool)
+	//nolint:mnd // readable
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("failed to create the journal directory: %w", err)
+	}
+	bytes, err := yaml.Marshal(j)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the journal of %s: %w", j.Tool, err)
+	}
+	// names of journals sort in the order of time
+	j.file = filepath.Join(dir, j.Time.UTC().Format("20060102T150405.000000000Z")+".yaml")
+	//nolint:mnd // readable
+	if err := os.WriteFile(j.file, bytes, 0o600); err != nil {
+		return fmt.Errorf("failed to write the journal of %s: %w", j.Tool, err)
+	}
+	return nil
+}
+
+func readJournal(file string) (*Journal, error) {
+	bytes, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the journal %s: %w", file, err)
+	}
+	var j Journal
+	if err := yaml.Unmarshal(bytes, &j); err != nil {
+		return nil, fmt.Errorf("failed to parse the journal %s: %w", file, err)
+	}
+	j.file = file
+	return &j, nil
+}
+
+// ReadLastJournal reads the journal of the last run of a tool which has not been rolled back.
+//
+// @param tool The name of the tool.
+//
+// @return The journal, or nil if there is no journal for the tool.
+func ReadLastJournal(tool string) (*Journal, error) {
+	entries, err := os.ReadDir(journalDir(tool))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journals of %s: %w", tool, err)
+	}
+	names := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".yaml") {
+			names = append(names, entry.Name())
+		}
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	sort.Strings(names)
+	return readJournal(filepath.Join(journalDir(tool), names[len(names)-1]))
+}
+
+// ReadLatestJournal reads the journal of the last run of any tool which has not been rolled back.
+//
+// @return The journal, or nil if there is no journal at all.
+func ReadLatestJournal() (*Journal, error) {
+	entries, err := os.ReadDir(filepath.Join(StateDir(), "journal"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the journal directory: %w", err)
+	}
+	var latest *Journal
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		j, err := ReadLastJournal(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if j != nil && (latest == nil || j.Time.After(latest.Time)) {
+			latest = j
+		}
+	}
+	return latest, nil
+}
+
+// lexists checks whether a path exists without following symbolic links.
+func lexists(path string) bool {
+	_, err := fsys.Lstat(path)
+	return err == nil
+}
+
+// Rollback undoes the changes in the journal in the reverse order they were made.
+// Symbolic links and files icon placed are removed (unless they have been changed since),
+// files icon modified get their previous content back (unless they have been changed since),
+// and backups and removed paths are renamed back to their original paths.
+//
+// @return Paths which are kept because they have been changed since icon placed them.
+func (j *Journal) Rollback() ([]string, error) {
+	kept := []string{}
+	for _, entry := range slices.Backward(j.Entries) {
+		switch entry.Kind {
+		case JournalSymlink:
+			target, err := fsys.Readlink(entry.Path)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil || target != entry.Target {
+				kept = append(kept, entry.Path)
+				continue
+			}
+			if err := RemoveAll(entry.Path); err != nil {
+				return kept, err
+			}
+		case JournalFile:
+			if !lexists(entry.Path) {
+				continue
+			}
+			if entry.Sha256 != "" {
+				if checksum, err := Sha256File(entry.Path); err != nil || checksum != entry.Sha256 {
+					kept = append(kept, entry.Path)
+					continue
+				}
+			}
+			if err := RemoveAll(entry.Path); err != nil {
+				return kept, err
+			}
+		case JournalModified:
+			if entry.Target == "" || !lexists(entry.Target) {
+				Warnf("The previous content of %s was not saved and cannot be restored.\n", entry.Path)
+				kept = append(kept, entry.Path)
+				continue
+			}
+			if checksum, err := Sha256File(entry.Path); err != nil || checksum != entry.Sha256 {
+				kept = append(kept, entry.Path)
+				continue
+			}
+			if err := restoreSnapshot(entry.Target, entry.Path); err != nil {
+				return kept, err
+			}
+		case JournalRemoved:
+			if !lexists(entry.Target) {
+				Warnf("The removed path %s was not saved and cannot be restored.\n", entry.Path)
+				continue
+			}
+			if lexists(entry.Path) {
+				Warnf("The removed path %s is not restored as it exists again (saved at %s).\n", entry.Path, entry.Target)
+				continue
+			}
+			if err := Rename(entry.Target, entry.Path); err != nil {
+				return kept, err
+			}
+		case JournalBackup:
+			if !lexists(entry.Target) {
+				Warnf("The backup %s of %s no longer exists.\n", entry.Target, entry.Path)
+				continue
+			}
+			if lexists(entry.Path) {
+				Warnf("The backup %s is not restored as %s exists.\n", entry.Target, entry.Path)
+				continue
+			}
+			if err := Rename(entry.Target, entry.Path); err != nil {
+				return kept, err
+			}
+		}
+	}
+	forgetJournal(j)
+	if j.file != "" && !dryRun {
+		if err := os.Remove(j.file); err != nil {
+			return kept, fmt.Errorf("failed to remove the journal %s: %w", j.file, err)
+		}
+	}
+	return kept, nil
+}
+
+// forgetJournal removes changes in the journal from the records of the current run
+// or from the saved state of the tool so that they are not removed again on uninstall.
+func forgetJournal(j *Journal) {
+	forget := func(state *ToolState) {
+		for _, entry := range j.Entries {
+			switch entry.Kind {
+			case JournalFile, JournalSymlink:
+				state.forget(entry.Path)
+			case JournalModified:
+				// a file placed by a previous run of the tool is still placed by it after its content is restored
+				for i, file := range state.Files {
+					if file.Path == entry.Path {
+						state.Files[i].Sha256, _ = Sha256File(entry.Path)
+					}
+				}
+			case JournalBackup:
+				state.Backups = slices.DeleteFunc(state.Backups, func(b BackupRecord) bool { return b.Backup == entry.Target })
+			}
+		}
+	}
+	if current != nil && current.Name == j.Tool {
+		forget(current)
+		return
+	}
+	state, err := ReadToolState(j.Tool)
+	if err != nil || state == nil {
+		return
+	}
+	forget(state)
+	if err := WriteToolState(state); err != nil {
+		Warnf("Failed to update the state of %s: %v\n", j.Tool, err)
+	}
+}
+
+// RollbackToolRun rolls back changes made so far by the current run of a tool.
+//
+// @return Paths which are kept because they have been changed since icon placed them.
+func RollbackToolRun() ([]string, error) {
+	if journal == nil || len(journal.Entries) == 0 {
+		return nil, nil
+	}
+	kept, err := journal.Rollback()
+	journal.Entries = nil
+	return kept, err
+}
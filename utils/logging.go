@@ -0,0 +1,350 @@
+package utils
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Formats of logs printed to the standard error.
+const (
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+)
+
+// maxLogFiles is the number of the most recent log files kept under LogDir().
+const maxLogFiles = 50
+
+// maxOutputLines is the number of the last lines of the output of a failed command attached to its log record.
+const maxOutputLines = 20
+
+var (
+	logFormat = LogFormatText
+	quiet     bool
+	logFile   string
+	// phase is what the current tool is doing, e.g., install, config or uninstall.
+	phase string
+)
+
+// LogDir returns the directory of log files, i.e., StateDir()/logs.
+//
+// @return The path of the directory.
+func LogDir() string {
+	return filepath.Join(StateDir(), "logs")
+}
+
+// SetupLogging sets up the default slog logger, which log.Printf also logs through (at the info level).
+// Logs are printed to the standard error in the specified format
+// and written (at all levels, as JSON) into a new log file under LogDir() unless in dry-run mode.
+// Each record carries the tool being run and its phase (see SetPhase).
+//
+// @param verbose Whether to print debug logs, e.g., details of releases and every command run.
+// @param quiet   Whether to print warnings and errors only and hide the output of commands unless they fail.
+// @param format  The format (LogFormatText or LogFormatJSON) of logs printed to the standard error.
+//
+// @return A function closing the log file.
+func SetupLogging(verbose, quietMode bool, format string) (func() error, error) {
+	noop := func() error { return nil }
+	if verbose && quietMode {
+		return noop, errors.New("--verbose and --quiet cannot be used together")
+	}
+	level := slog.LevelInfo
+	switch {
+	case verbose:
+		level = slog.LevelDebug
+	case quietMode:
+		level = slog.LevelWarn
+	}
+	var console slog.Handler
+	switch format {
+	case LogFormatText:
+		console = &textHandler{out: os.Stderr, level: level, mu: &sync.Mutex{}}
+	case LogFormatJSON:
+		console = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: level})
+	default:
+		return noop, fmt.Errorf("unsupported log format %s (text or json)", format)
+	}
+	logFormat, quiet = format, quietMode
+	handlers := []slog.Handler{console}
+	closeFile := noop
+	if !dryRun {
+		file, err := createLogFile()
+		if err != nil {
+			return noop, err
+		}
+		handlers = append(handlers, slog.NewJSONHandler(file, &slog.HandlerOptions{Level: slog.LevelDebug}))
+		logFile, closeFile = file.Name(), file.Close
+	}
+	slog.SetDefault(slog.New(contextHandler{fanoutHandler(handlers)}))
+	return closeFile, nil
+}
+
+// createLogFile creates the log file of this run and removes old log files.
+func createLogFile() (*os.File, error) {
+	dir := LogDir()
+	//nolint:mnd // readable
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create the log directory: %w", err)
+	}
+	// names of log files sort in the order of time
+	name := fmt.Sprintf("%s_%d.log", time.Now().UTC().Format("20060102T150405Z"), os.Getpid())
+	//nolint:mnd // readable
+	file, err := os.OpenFile(filepath.Join(dir, name), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the log file: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err == nil && len(entries) > maxLogFiles {
+		for _, entry := range entries[:len(entries)-maxLogFiles] {
+			_ = os.Remove(filepath.Join(dir, entry.Name()))
+		}
+	}
+	return file, nil
+}
+
+// LogFile returns the path of the log file of this run.
+//
+// @return The path, or an empty string if no log file is written.
+func LogFile() string {
+	return logFile
+}
+
+// LogFormat returns the format of logs printed to the standard error.
+//
+// @return LogFormatText or LogFormatJSON.
+func LogFormat() string {
+	return logFormat
+}
+
+// IsQuiet checks whether the quiet mode is on.
+//
+// @return true if only warnings and errors are printed, false otherwise.
+func IsQuiet() bool {
+	return quiet
+}
+
+// SetPhase sets what the current tool is doing, which is attached to log records.
+//
+// @param p The phase, e.g., install, config or uninstall, or an empty string.
+func SetPhase(p string) {
+	phase = p
+}
+
+// Debugf logs a message (formatted as fmt.Sprintf) at the debug level, which is printed only with --verbose.
+func Debugf(format string, args ...any) {
+	slog.Debug(strings.TrimSpace(fmt.Sprintf(format, args...)))
+}
+
+// Warnf logs a message (formatted as fmt.Sprintf) at the warning level, which is printed even with --quiet.
+func Warnf(format string, args ...any) {
+	slog.Warn(strings.TrimSpace(fmt.Sprintf(format, args...)))
+}
+
+// contextHandler attaches the current tool and its phase to records.
+type contextHandler struct {
+	next slog.Handler
+}
+
+func (h contextHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if tool := CurrentTool(); tool != "" {
+		r.AddAttrs(slog.String("tool", tool))
+		if phase != "" {
+			r.AddAttrs(slog.String("phase", phase))
+		}
+	}
+	return h.next.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.next.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.next.WithGroup(name)}
+}
+
+// fanoutHandler sends records to multiple handlers, each with its own level.
+type fanoutHandler []slog.Handler
+
+func (h fanoutHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return slices.ContainsFunc(h, func(handler slog.Handler) bool { return handler.Enabled(ctx, level) })
+}
+
+func (h fanoutHandler) Handle(ctx context.Context, r slog.Record) error {
+	errs := []error{}
+	for _, handler := range h {
+		if handler.Enabled(ctx, r.Level) {
+			errs = append(errs, handler.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (h fanoutHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	handlers := make(fanoutHandler, 0, len(h))
+	for _, handler := range h {
+		handlers = append(handlers, handler.WithAttrs(attrs))
+	}
+	return handlers
+}
+
+func (h fanoutHandler) WithGroup(name string) slog.Handler {
+	handlers := make(fanoutHandler, 0, len(h))
+	for _, handler := range h {
+		handlers = append(handlers, handler.WithGroup(name))
+	}
+	return handlers
+}
+
+// textHandler prints records for humans, i.e., the time, the level (unless info), the message and attributes.
+// The tool and the phase are omitted as they are clear from the context.
+type textHandler struct {
+	out    io.Writer
+	level  slog.Leveler
+	mu     *sync.Mutex
+	attrs  []slog.Attr
+	prefix string
+}
+
+func (h *textHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= h.level.Level()
+}
+
+func (h *textHandler) Handle(_ context.Context, r slog.Record) error {
+	var buf bytes.Buffer
+	buf.WriteString(r.Time.Format(time.TimeOnly))
+	if r.Level != slog.LevelInfo {
+		buf.WriteString(" " + r.Level.String())
+	}
+	buf.WriteString(" " + r.Message)
+	appendAttr := func(attr slog.Attr) bool {
+		if attr.Key != "tool" && attr.Key != "phase" && attr.Key != "output" {
+			value := attr.Value.String()
+			if value == "" || strings.ContainsAny(value, " \t\n\"=") {
+				value = strconv.Quote(value)
+			}
+			fmt.Fprintf(&buf, " %s%s=%s", h.prefix, attr.Key, value)
+		}
+		return true
+	}
+	for _, attr := range h.attrs {
+		appendAttr(attr)
+	}
+	output := ""
+	r.Attrs(func(attr slog.Attr) bool {
+		if attr.Key == "output" {
+			output = attr.Value.String()
+		}
+		return appendAttr(attr)
+	})
+	buf.WriteByte('\n')
+	// the output of a failed command is printed as it is for readability
+	if output != "" {
+		buf.WriteString(output + "\n")
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, err := h.out.Write(buf.Bytes())
+	return err
+}
+
+func (h *textHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	clone := *h
+	clone.attrs = append(slices.Clone(h.attrs), attrs...)
+	return &clone
+}
+
+func (h *textHandler) WithGroup(name string) slog.Handler {
+	clone := *h
+	clone.prefix = h.prefix + name + "."
+	return &clone
+}
+
+// commandOutput captures the output of a command line by line as debug records when the output is not printed
+// (in quiet mode or with JSON logs) and keeps its last lines for the record of the command if it fails.
+type commandOutput struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+// writer returns a writer capturing a stream (stdout or stderr) of the command.
+func (o *commandOutput) writer(stream string) *outputWriter {
+	return &outputWriter{output: o, stream: stream}
+}
+
+// tail returns the last lines of the output.
+func (o *commandOutput) tail() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return strings.Join(o.lines, "\n")
+}
+
+func (o *commandOutput) add(stream, line string) {
+	slog.Debug(line, "stream", stream)
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.lines = append(o.lines, line)
+	if len(o.lines) > maxOutputLines {
+		o.lines = o.lines[len(o.lines)-maxOutputLines:]
+	}
+}
+
+type outputWriter struct {
+	output *commandOutput
+	stream string
+	buf    []byte
+}
+
+func (w *outputWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		idx := bytes.IndexAny(w.buf, "\r\n")
+		if idx < 0 {
+			return len(p), nil
+		}
+		if line := strings.TrimSpace(string(w.buf[:idx])); line != "" {
+			w.output.add(w.stream, line)
+		}
+		w.buf = w.buf[idx+1:]
+	}
+}
+
+// Flush captures the last line if it does not end with a line break.
+func (w *outputWriter) Flush() {
+	if line := strings.TrimSpace(string(w.buf)); line != "" {
+		w.output.add(w.stream, line)
+	}
+	w.buf = nil
+}
+
+// captureCommandOutput checks whether the output of commands is captured instead of being printed.
+func captureCommandOutput() bool {
+	return quiet || logFormat == LogFormatJSON
+}
+
+// logCommand logs a command which has been run with its duration and exit code.
+func logCommand(cmd string, start time.Time, exitCode int, output *commandOutput) {
+	attrs := []any{"cmd", cmd, "duration_ms", time.Since(start).Milliseconds(), "exit_code", exitCode}
+	if exitCode == 0 {
+		slog.Debug("The command succeeded.", attrs...)
+		return
+	}
+	if output != nil {
+		attrs = append(attrs, "output", output.tail())
+	}
+	slog.Warn("The command failed.", attrs...)
+}
@@ -2,37 +2,50 @@ package utils
 
 import (
 	"context"
-	"log"
+	"fmt"
+	"io"
 	"os"
 	"os/exec"
 	"os/user"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"sync"
 
 	"github.com/elliotchance/orderedmap/v2"
 	"golang.org/x/sys/unix"
 )
 
-// RunCmd executes a command (using pwsh or bash based on the OS) in the terminal.
+// RunCmd executes a shell command (using bash) in the terminal.
+// Prefer NewCommand unless a shell is really needed (e.g., for a pipeline),
+// and quote values pasted into the command using FormatShell.
 //
 // @param cmd The command to execute as a string.
 // @param env Optional environment variables to set for the command execution.
 //
+// @return A *CommandError if the command fails, nil otherwise.
+//
+// The command is logged with its duration and exit code (see SetupLogging).
+// In dry-run mode, the command is recorded into the plan instead of being run.
+// URLs in download commands (e.g., curl) of the shell command are rewritten to mirrors.
+//
 // @example RunCmd("ls -l", "MY_VAR=myvalue")
-func RunCmd(cmd string, env ...string) {
+func RunCmd(cmd string, env ...string) error {
+	cmd = rewriteURLsInShell(cmd)
+	if dryRun {
+		if len(env) > 0 {
+			cmd = strings.Join(env, " ") + " " + strings.TrimSpace(cmd)
+		}
+		recordStep("run", "%s", cmd)
+		return nil
+	}
 	command := exec.CommandContext(context.Background(), "bash", "-c", cmd)
 	command.Env = append(os.Environ(), env...)
-	command.Stdin = os.Stdin
-	command.Stdout = os.Stdout
-	command.Stderr = os.Stderr
-	err := command.Run()
-	if err != nil {
-		log.Fatal("ERROR - ", err, ": when running the command:\n", cmd)
-	}
+	return runCommand(cmd, command)
 }
 
 // Format replaces placeholders in a string with values from a map.
+// Values are pasted as they are, so use FormatShell for values in shell commands.
 //
 // This function takes a command string `cmd` and a map `hmap` as input. It
 // iterates through the map, replacing each occurrence of a placeholder in
@@ -68,21 +81,57 @@ func Max(x, y int) int {
 // GetCurrentUser retrieves the current user's information.
 //
 // @return A pointer to a `user.User` struct representing the current user.
-func GetCurrentUser() *user.User {
-	currentUser, err := user.Current()
+func GetCurrentUser() (*user.User, error) {
+	currentUser, err := executor.CurrentUser()
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return nil, fmt.Errorf("failed to get the current user: %w", err)
 	}
-	return currentUser
+	return currentUser, nil
 }
 
+var (
+	sudoMu sync.Mutex
+	// sudoValidated indicates whether the sudo credential has been validated in this run.
+	sudoValidated bool
+)
+
 // Returns "sudo" or "" depending on whether sudo is accessible by the current user.
-func sudo() string {
+// Environment variables set from the global configuration file (e.g., proxies) are preserved by sudo.
+// The sudo credential is validated once per run and not in dry-run mode.
+func sudo() (string, error) {
+	if LookPath("sudo") == "" {
+		return "", nil
+	}
+	prefix := "sudo"
+	if len(preservedEnv) > 0 {
+		prefix += " --preserve-env=" + strings.Join(preservedEnv, ",")
+	}
+	if dryRun {
+		return prefix, nil
+	}
+	sudoMu.Lock()
+	defer sudoMu.Unlock()
+	if !sudoValidated {
+		if err := NewCommand("sudo", "true").Run(); err != nil {
+			return "", fmt.Errorf("failed to validate the sudo credential: %w", err)
+		}
+		sudoValidated = true
+	}
+	return prefix, nil
+}
+
+// CanSudoWithoutPassword checks whether the current user can run commands with sudo without being prompted,
+// i.e., no password is required or the sudo credential is cached.
+// Unlike sudo (used by GetCommandPrefix), it never prompts and prints nothing.
+//
+// @return true if sudo is found and runs non-interactively, false otherwise.
+func CanSudoWithoutPassword() bool {
 	if LookPath("sudo") == "" {
-		return ""
+		return false
 	}
-	RunCmd("sudo true")
-	return "sudo"
+	command := exec.CommandContext(context.Background(), "sudo", "-n", "true")
+	command.Stdout, command.Stderr = io.Discard, io.Discard
+	return executor.Run(command) == nil
 }
 
 // GetCommandPrefix determines the appropriate command prefix for running commands.
@@ -91,8 +140,12 @@ func sudo() string {
 // @param pathPerms A map where keys are file paths and values are permission
 //
 // @return The appropriate command prefix ("sudo" or an empty string).
-func GetCommandPrefix(forceSudo bool, pathPerms map[string]uint32) string {
-	if GetCurrentUser().Uid != "0" {
+func GetCommandPrefix(forceSudo bool, pathPerms map[string]uint32) (string, error) {
+	currentUser, err := GetCurrentUser()
+	if err != nil {
+		return "", err
+	}
+	if currentUser.Uid != "0" {
 		if forceSudo {
 			return sudo()
 		}
@@ -102,12 +155,12 @@ func GetCommandPrefix(forceSudo bool, pathPerms map[string]uint32) string {
 				path = filepath.Dir(path)
 				perm |= unix.X_OK
 			}
-			if unix.Access(path, perm) != nil {
+			if fsys.Access(path, perm) != nil {
 				return sudo()
 			}
 		}
 	}
-	return ""
+	return "", nil
 }
 
 // ExistsCommand checks if a command exists in the system's PATH.
@@ -137,13 +190,16 @@ func IfElseString(b bool, t, f string) string {
 // Using Homebrew to install packages.
 //
 // @param pkgs A slice of strings representing the packages to install.
-func BrewInstallSafe(pkgs []string) {
+func BrewInstallSafe(pkgs []string) error {
 	for _, pkg := range pkgs {
 		command := Format("brew install --force {pkg} || brew link --overwrite --force {pkg}", map[string]string{
 			"pkg": pkg,
 		})
-		RunCmd(command)
+		if err := RunCmd(command); err != nil {
+			return err
+		}
 	}
+	return nil
 }
 
 // Update map1 using map2.
@@ -177,12 +233,12 @@ func UpdateMap(map1, map2 orderedmap.OrderedMap[string, any]) {
 // @param str The string to convert to an int.
 //
 // @return The int representation of the string.
-func Atoi(str string) int {
+func Atoi(str string) (int, error) {
 	i, err := strconv.Atoi(str)
 	if err != nil {
-		log.Fatalf("Error converting string to int: %v\n", err)
+		return 0, fmt.Errorf("error converting string to int: %w", err)
 	}
-	return i
+	return i, nil
 }
 
 // ParseInt converts a string to an int64.
@@ -190,10 +246,10 @@ func Atoi(str string) int {
 // @param str The string to convert to an int64.
 //
 // @return The int64 representation of the string.
-func ParseInt(str string) int64 {
+func ParseInt(str string) (int64, error) {
 	i, err := strconv.ParseInt(str, 10, 64)
 	if err != nil {
-		log.Fatalf("Error converting string to int64: %v\n", err)
+		return 0, fmt.Errorf("error converting string to int64: %w", err)
 	}
-	return i
+	return i, nil
 }
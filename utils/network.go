@@ -2,6 +2,7 @@ package utils
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -9,6 +10,8 @@ import (
 	"os"
 	"path/filepath"
 	"time"
+
+	"golang.org/x/sys/unix"
 )
 
 func IsErrorHTTPResponse(resp *http.Response) bool {
@@ -19,38 +22,59 @@ func IsErrorHTTPResponse(resp *http.Response) bool {
 // HTTPGetAsBytes performs an HTTP GET request to the specified URL and returns the response body as a byte slice.
 //
 // @param url The URL to send the HTTP GET request to.
-// @param retry The number of times to retry the request if it fails or encounters a rate limit.
+// @param retry The number of times to retry the request if it fails.
 // @param initialWaitingSeconds The initial number of seconds to wait before retrying the request.
 //
 // @return The response body as a byte slice.
+// A *RateLimitError is returned if the rate limit is exceeded and does not reset within MaxRateLimitWait.
+// In offline mode, the response is read from the cache. In prefetch mode, the response is cached.
 func HTTPGetAsBytes(url string, retry int8, initialWaitingSeconds int32) ([]byte, error) {
+	if offline {
+		if cached := ReadCachedResponse(url); cached != nil {
+			return []byte(cached.Body), nil
+		}
+		return []byte{}, &OfflineError{URL: url}
+	}
+	body, err := httpGet(url, retry, initialWaitingSeconds)
+	if err == nil && prefetch {
+		WriteCachedResponse(&CachedResponse{URL: url, Body: string(body), Time: time.Now()})
+	}
+	return body, err
+}
+
+// httpGet performs an HTTP GET request with retries and returns the response body.
+func httpGet(url string, retry int8, initialWaitingSeconds int32) ([]byte, error) {
 	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, http.NoBody)
 	if err != nil {
 		if retry > 0 {
 			time.Sleep(time.Duration(initialWaitingSeconds) * time.Second)
-			return HTTPGetAsBytes(url, retry-1, initialWaitingSeconds*2)
+			return httpGet(url, retry-1, initialWaitingSeconds*2)
 		}
 		return []byte{}, fmt.Errorf("failed to create a HTTP GET request to the URL '%s' with context: %w", url, err)
 	}
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := HTTPClient().Do(req)
 	if err != nil {
 		if retry > 0 {
 			time.Sleep(time.Duration(initialWaitingSeconds) * time.Second)
-			return HTTPGetAsBytes(url, retry-1, initialWaitingSeconds*2)
+			return httpGet(url, retry-1, initialWaitingSeconds*2)
 		}
 		return []byte{}, fmt.Errorf("the HTTP GET request to the URL '%s' failed: %w", url, err)
 	}
 	if IsErrorHTTPResponse(resp) {
-		if resp.Header.Get("x-ratelimit-remaining") == "0" {
-			rateLimitResetInSeconds := ParseInt(resp.Header.Get("x-ratelimit-reset"))
-			//nolint:mnd // good readability in the context
-			rateLimitResetTime := time.Unix(rateLimitResetInSeconds+10, 0)
-			time.Sleep(time.Until(rateLimitResetTime))
-			return HTTPGetAsBytes(url, retry, initialWaitingSeconds)
+		if rateLimitErr := RateLimitFromResponse(resp); rateLimitErr != nil {
+			resp.Body.Close()
+			// wait only if the rate limit resets soon instead of hanging silently for up to an hour
+			wait := time.Until(rateLimitErr.Reset)
+			if wait > MaxRateLimitWait {
+				return []byte{}, rateLimitErr
+			}
+			log.Printf("The rate limit is exceeded. Waiting %s for it to reset ...\n", wait.Round(time.Second))
+			time.Sleep(wait)
+			return httpGet(url, retry, initialWaitingSeconds)
 		}
 		if retry > 0 {
 			time.Sleep(time.Duration(initialWaitingSeconds) * time.Second)
-			return HTTPGetAsBytes(url, retry-1, initialWaitingSeconds*2)
+			return httpGet(url, retry-1, initialWaitingSeconds*2)
 		}
 		return []byte{}, fmt.Errorf(
 			`the HTTP GET request on the URL %s got an error response with the status code %d.
@@ -61,7 +85,7 @@ func HTTPGetAsBytes(url string, retry int8, initialWaitingSeconds int32) ([]byte
 			resp.StatusCode,
 			resp.Header.Get("x-ratelimit-limit"),
 			resp.Header.Get("x-ratelimit-remaining"),
-			time.Unix(ParseInt(resp.Header.Get("x-ratelimit-reset")), 0).Local().Format(time.RFC3339),
+			formatRateLimitReset(resp.Header.Get("x-ratelimit-reset")),
 		)
 	}
 	defer resp.Body.Close()
@@ -69,13 +93,56 @@ func HTTPGetAsBytes(url string, retry int8, initialWaitingSeconds int32) ([]byte
 	if err != nil {
 		if retry > 0 {
 			time.Sleep(time.Duration(initialWaitingSeconds) * time.Second)
-			return HTTPGetAsBytes(url, retry-1, initialWaitingSeconds*2)
+			return httpGet(url, retry-1, initialWaitingSeconds*2)
 		}
 		return []byte{}, fmt.Errorf("failed to read the response body: %w", err)
 	}
 	return body, nil
 }
 
+// MaxRateLimitWait is the longest time to wait for a rate limit to reset before giving up with a *RateLimitError.
+const MaxRateLimitWait = time.Minute
+
+// RateLimitFromResponse checks whether a response indicates that the rate limit is exceeded,
+// using the headers x-ratelimit-remaining and x-ratelimit-reset (as used by GitHub)
+// or the header Retry-After (for secondary rate limits).
+//
+// @param resp The HTTP response.
+//
+// @return A *RateLimitError if the rate limit is exceeded, nil otherwise.
+func RateLimitFromResponse(resp *http.Response) *RateLimitError {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return nil
+	}
+	e := &RateLimitError{
+		URL:   resp.Request.URL.String(),
+		Limit: resp.Header.Get("x-ratelimit-limit"),
+	}
+	if seconds, err := ParseInt(resp.Header.Get("retry-after")); err == nil {
+		e.Reset = time.Now().Add(time.Duration(seconds) * time.Second)
+		return e
+	}
+	if resp.Header.Get("x-ratelimit-remaining") != "0" {
+		return nil
+	}
+	if seconds, err := ParseInt(resp.Header.Get("x-ratelimit-reset")); err == nil {
+		e.Reset = time.Unix(seconds, 0)
+	} else {
+		// the reset time is unknown, so give up instead of waiting
+		e.Reset = time.Now().Add(time.Hour)
+	}
+	return e
+}
+
+// formatRateLimitReset formats the value (seconds since epoch) of the header x-ratelimit-reset as a local time.
+func formatRateLimitReset(header string) string {
+	seconds, err := ParseInt(header)
+	if err != nil {
+		return header
+	}
+	return time.Unix(seconds, 0).Local().Format(time.RFC3339)
+}
+
 // HttpGetAsString performs an HTTP GET request to the specified URL and returns the response body as a string.
 //
 // @param url The URL to send the HTTP GET request to.
@@ -83,45 +150,193 @@ func HTTPGetAsBytes(url string, retry int8, initialWaitingSeconds int32) ([]byte
 // @param initialWaitingSeconds The initial number of seconds to wait before retrying the request.
 //
 // @return The response body as a string.
-func HTTPGetAsString(url string, retry int8, initialWaitingSeconds int32) string {
+func HTTPGetAsString(url string, retry int8, initialWaitingSeconds int32) (string, error) {
 	bytes, err := HTTPGetAsBytes(url, retry, initialWaitingSeconds)
-	if err != nil {
-		log.Fatal(err)
-	}
-	return string(bytes)
+	return string(bytes), err
 }
 
 // Download file from the given URL.
+// Downloads are cached (see CacheDir) by URL and revalidated using the ETag or Last-Modified header,
+// so that an unchanged file is not downloaded again and an interrupted download is resumed.
 //
 // @param url The URL of the file to download.
 // @param name The desired name of the file when saved locally.
 // @param useTempDir If true, the file will be saved to a temporary directory.
 //
 // @return The local path where the downloaded file is saved.
+// In dry-run mode, the download is recorded into the plan and nothing is written
+// (except that the file is downloaded into the cache in prefetch mode).
 func DownloadFile(url, name string, useTempDir bool) (string, error) {
-	var out *os.File
-	var err error
+	if dryRun {
+		if prefetch {
+			if _, err := downloadToCache(url); err != nil {
+				return "", err
+			}
+		}
+		if useTempDir {
+			name = filepath.Join(os.TempDir(), name)
+		}
+		recordStep("download", "%s -> %s", url, name)
+		return name, nil
+	}
 	if useTempDir {
-		name = filepath.Join(CreateTempDir(""), name)
+		tmpdir, err := CreateTempDir("")
+		if err != nil {
+			return "", err
+		}
+		name = filepath.Join(tmpdir, name)
 	}
-	out, err = os.Create(name)
+	log.Printf("Downloading %s to %s\n", url, name)
+	blob, err := downloadToCache(url)
 	if err != nil {
-		return "", fmt.Errorf("failed to create file '%s': %w", name, err)
+		return "", err
 	}
-	defer out.Close()
-	log.Printf("Downloading %s to %s\n", url, out.Name())
+	if err := copyRegularFile(blob, name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+// downloadToCache downloads a file into the download cache.
+//
+// @param url The URL of the file to download.
+//
+// @return The path of the cached file.
+func downloadToCache(url string) (string, error) {
+	for _, sub := range []string{"index", "blobs", "partial"} {
+		//nolint:mnd // readable
+		if err := os.MkdirAll(downloadCacheDir(sub), 0o755); err != nil {
+			return "", fmt.Errorf("failed to create the download cache directory: %w", err)
+		}
+	}
+	// other processes (e.g., in containers sharing the cache) might be downloading the same URL
+	//nolint:mnd // readable
+	lock, err := os.OpenFile(cachePartialFile(url)+".lock", os.O_CREATE|os.O_RDWR, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to lock the download cache: %w", err)
+	}
+	defer lock.Close()
+	if err := unix.Flock(int(lock.Fd()), unix.LOCK_EX); err != nil {
+		return "", fmt.Errorf("failed to lock the download cache: %w", err)
+	}
+	//nolint:errcheck // the lock is released on close anyway
+	defer unix.Flock(int(lock.Fd()), unix.LOCK_UN)
+	const numRetry = 3
+	for attempt := 0; ; attempt++ {
+		blob, err := fetchIntoCache(url)
+		if err == nil || attempt >= numRetry || !errors.Is(err, errInterrupted) {
+			return blob, err
+		}
+		Warnf("%v. Resuming the download ...\n", err)
+		time.Sleep(time.Duration(attempt+1) * time.Second)
+	}
+}
+
+// errInterrupted indicates that a download was interrupted and can be resumed.
+var errInterrupted = errors.New("the download was interrupted")
+
+// fetchIntoCache sends a single request to download a file into the download cache.
+// A cached file is revalidated using a conditional request
+// and a partially downloaded file is resumed using a range request.
+func fetchIntoCache(url string) (string, error) {
+	cached, err := readCachedDownload(cacheIndexFile(url))
+	if err != nil {
+		Warnf("%v", err)
+		cached = nil
+	}
+	if offline {
+		if cached != nil && !cached.IsPartial() && ExistsFile(cacheBlobFile(cached.Sha256)) {
+			return cacheBlobFile(cached.Sha256), nil
+		}
+		return "", &OfflineError{URL: url}
+	}
+	partial := cachePartialFile(url)
+	var offset int64
 	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, url, http.NoBody)
 	if err != nil {
 		return "", fmt.Errorf("failed to create a HTTP GET request to the URL '%s' with context: %w", url, err)
 	}
-	resp, err := http.DefaultClient.Do(req)
+	switch {
+	case cached != nil && !cached.IsPartial() && ExistsFile(cacheBlobFile(cached.Sha256)):
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	case cached != nil && cached.IsPartial() && (cached.ETag != "" || cached.LastModified != ""):
+		if info, err := os.Stat(partial); err == nil && info.Size() > 0 {
+			offset = info.Size()
+			req.Header.Set("Range", fmt.Sprintf("bytes=%d-", offset))
+			// the whole file is sent instead if it has changed
+			req.Header.Set("If-Range", IfElseString(cached.ETag != "", cached.ETag, cached.LastModified))
+		}
+	}
+	resp, err := HTTPClient().Do(req)
 	if err != nil {
+		if cached != nil && !cached.IsPartial() {
+			Warnf("Failed to revalidate the cached download of %s, so it is used as it is: %v\n", url, err)
+			return cacheBlobFile(cached.Sha256), nil
+		}
 		return "", fmt.Errorf("the HTTP GET request to the URL '%s' failed: %w", url, err)
 	}
 	defer resp.Body.Close()
-	_, err = io.Copy(out, resp.Body)
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		Debugf("The cached download of %s is up to date.\n", url)
+		cached.Time = time.Now()
+		return cacheBlobFile(cached.Sha256), writeCachedDownload(cached)
+	case IsErrorHTTPResponse(resp):
+		return "", fmt.Errorf("the HTTP GET request on the URL %s got an error response with the status code %d", url, resp.StatusCode)
+	case resp.StatusCode != http.StatusPartialContent:
+		offset = 0
+	}
+	download := &CachedDownload{
+		URL:          url,
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
+		Time:         time.Now(),
+	}
+	// record the partial download first so that it can be resumed if interrupted
+	if err := writeCachedDownload(download); err != nil {
+		return "", err
+	}
+	flag := os.O_TRUNC
+	if offset > 0 {
+		flag = os.O_APPEND
+	}
+	//nolint:mnd // readable
+	out, err := os.OpenFile(partial, os.O_CREATE|os.O_WRONLY|flag, 0o644)
+	if err != nil {
+		return "", fmt.Errorf("failed to create file '%s': %w", partial, err)
+	}
+	defer out.Close()
+	total := int64(-1)
+	if resp.ContentLength >= 0 {
+		total = offset + resp.ContentLength
+	}
+	progress := newProgressBar(filepath.Base(url), offset, total)
+	_, err = io.Copy(out, io.TeeReader(resp.Body, progress))
+	progress.Finish()
+	if err != nil {
+		return "", fmt.Errorf("%w: %w", errInterrupted, err)
+	}
+	if err := out.Close(); err != nil {
+		return "", fmt.Errorf("failed to write the response body to '%s': %w", partial, err)
+	}
+	checksum, err := Sha256File(partial)
+	if err != nil {
+		return "", err
+	}
+	blob := cacheBlobFile(checksum)
+	if err := os.Rename(partial, blob); err != nil {
+		return "", fmt.Errorf("failed to move the download into the cache: %w", err)
+	}
+	info, err := os.Stat(blob)
 	if err != nil {
-		return "", fmt.Errorf("failed to write the response body to '%s': %w", name, err)
+		return "", fmt.Errorf("failed to stat %s: %w", blob, err)
 	}
-	return out.Name(), nil
+	download.Sha256 = checksum
+	download.Size = info.Size()
+	return blob, writeCachedDownload(download)
 }
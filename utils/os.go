@@ -1,19 +1,19 @@
 package utils
 
 import (
-	"log"
-	"runtime"
+	"fmt"
 	"slices"
+	"strconv"
+	"strings"
 
 	"github.com/shirou/gopsutil/cpu"
 	"github.com/shirou/gopsutil/host"
 	"github.com/shirou/gopsutil/mem"
-	"periph.io/x/host/v3/distro"
 )
 
-// IsLinux checks if the current operating system is Linux.
+// IsLinux checks if the OS of the current platform (see CurrentPlatform) is Linux.
 func IsLinux() bool {
-	switch runtime.GOOS {
+	switch CurrentPlatform().OS {
 	case "linux":
 		return true
 	default:
@@ -21,16 +21,37 @@ func IsLinux() bool {
 	}
 }
 
-// GetLinuxDistId retrieves the distribution ID of the current Linux system.
+// osRelease parses /etc/os-release (or /usr/lib/os-release) of the current Linux system.
+//
+// @return Fields of os-release, or an empty map if not found.
+func osRelease() map[string]string {
+	fields := map[string]string{}
+	bytes, err := fsys.ReadFile("/etc/os-release")
+	if err != nil {
+		if bytes, err = fsys.ReadFile("/usr/lib/os-release"); err != nil {
+			return fields
+		}
+	}
+	for line := range strings.Lines(string(bytes)) {
+		key, value, found := strings.Cut(strings.TrimSpace(line), "=")
+		if !found || strings.HasPrefix(key, "#") {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, "'")
+		}
+		fields[key] = value
+	}
+	return fields
+}
+
+// GetLinuxDistId retrieves the distribution ID of the current platform (see CurrentPlatform).
 //
 // @return The distribution ID of the current Linux system, or an empty string if not found.
 func GetLinuxDistID() string {
-	m := distro.OSRelease()
-	distID, found := m["ID"]
-	if found {
-		return distID
-	}
-	return ""
+	return CurrentPlatform().ID
 }
 
 // IsUbuntu checks if the current Linux distribution is Ubuntu.
@@ -47,11 +68,29 @@ func IsDebian() bool {
 	return GetLinuxDistID() == "debian"
 }
 
+// GetLinuxDistIDLike retrieves IDs of distributions the current Linux distribution is derived from
+// (the ID_LIKE field of os-release).
+//
+// @return IDs of parent distributions, or nil if not found.
+func GetLinuxDistIDLike() []string {
+	return CurrentPlatform().IDLike
+}
+
 func IsLinuxSeries(ids []string) bool {
 	distID := GetLinuxDistID()
 	return slices.Contains(ids, distID)
 }
 
+// IsLinuxLike checks if the current Linux distribution is or is derived from one of the specified distributions
+// (using both ID and ID_LIKE of os-release).
+//
+// @param ids IDs of distributions.
+//
+// @return true if the ID or an ID_LIKE of the current OS is in ids, false otherwise.
+func IsLinuxLike(ids []string) bool {
+	return CurrentPlatform().IsLike(ids...)
+}
+
 // IsDebianSeries checks if the current Linux distribution belongs to the Debian series.
 //
 // @return true if the current OS is part of the Debian series, false otherwise.
@@ -65,9 +104,11 @@ func IsDebianSeries() bool {
 
 // IsDebianUbuntuSeries checks if the current Linux distribution belongs to the Debian or Ubuntu series.
 //
+// Derivatives declaring debian or ubuntu in ID_LIKE are included.
+//
 // @return true if the current OS is part of the Debian or Ubuntu series, false otherwise.
 func IsDebianUbuntuSeries() bool {
-	return IsLinuxSeries([]string{
+	return IsLinuxLike([]string{
 		"debian",
 		"antix",
 		"lmde",
@@ -86,13 +127,40 @@ func IsUbuntuSeries() bool {
 
 // IsFedoraSeries checks if the current Linux distribution belongs to the Fedora series.
 //
+// Derivatives declaring fedora, centos or rhel in ID_LIKE are included.
+//
 // @return true if the current OS is part of the Fedora series, false otherwise.
 func IsFedoraSeries() bool {
-	return IsLinuxSeries([]string{
+	return IsLinuxLike([]string{
 		"fedora", "centos", "rhel",
 	})
 }
 
+// IsArchSeries checks if the current Linux distribution belongs to the Arch Linux series.
+//
+// @return true if the current OS is Arch Linux or is derived from it (e.g., Manjaro and EndeavourOS), false otherwise.
+func IsArchSeries() bool {
+	return IsLinuxLike([]string{
+		"arch", "manjaro", "endeavouros",
+	})
+}
+
+// IsSuseSeries checks if the current Linux distribution belongs to the SUSE series.
+//
+// @return true if the current OS is openSUSE, SLES or is derived from them, false otherwise.
+func IsSuseSeries() bool {
+	return IsLinuxLike([]string{
+		"suse", "opensuse", "opensuse-leap", "opensuse-tumbleweed", "sles",
+	})
+}
+
+// IsAlpine checks if the current Linux distribution is Alpine Linux.
+//
+// @return true if the current OS is Alpine Linux, false otherwise.
+func IsAlpine() bool {
+	return IsLinuxLike([]string{"alpine"})
+}
+
 // IsAurora checks if the current Linux distribution is Aurora (a Universal Blue distribution).
 //
 // @return true if the current OS is Aurora, false otherwise.
@@ -106,12 +174,11 @@ func IsUniversalBlue() bool {
 	})
 }
 
+// IsAtomicLinux checks if the current Linux distribution is image-based (e.g., Fedora Atomic Desktops).
+//
+// @return true if the current OS is booted from an OSTree image or has rpm-ostree (see DetectPlatform), false otherwise.
 func IsAtomicLinux() bool {
-	if LookPath("rpm-ostree") != "" {
-		return true
-	}
-	// TODO: add more
-	return false
+	return CurrentPlatform().Atomic
 }
 
 // BuildKernelOSKeywords constructs a list of keywords based on kernel architecture and operating system.
@@ -132,16 +199,20 @@ func IsAtomicLinux() bool {
 //		"FedoraSeries":       {"fedora_keyword"},
 //		"OtherLinux":         {"other_linux_keyword"},
 //	}
-//	result := BuildKernelOSKeywords(keywords)
+//	result, err := BuildKernelOSKeywords(keywords)
 //	// result might contain a combination of the above keywords based on the OS and architecture
 
-func BuildKernelOSKeywords(keywords map[string][]string) []string {
+func BuildKernelOSKeywords(keywords map[string][]string) ([]string, error) {
+	arch, err := HostKernelArch()
+	if err != nil {
+		return nil, err
+	}
 	kwds := keywords["common"]
-	k, found := keywords[HostKernelArch()]
+	k, found := keywords[arch]
 	if found {
 		kwds = append(kwds, k...)
 	}
-	k, found = keywords[runtime.GOOS]
+	k, found = keywords[CurrentPlatform().OS]
 	if found {
 		kwds = append(kwds, k...)
 	}
@@ -161,46 +232,42 @@ func BuildKernelOSKeywords(keywords map[string][]string) []string {
 			kwds = append(kwds, otherLinux...)
 		}
 	}
-	return kwds
+	return kwds, nil
 }
 
-func HostInfo() *host.InfoStat {
+func HostInfo() (*host.InfoStat, error) {
 	info, err := host.Info()
 	if err != nil {
-		log.Fatal(err)
+		return nil, fmt.Errorf("failed to get host information: %w", err)
 	}
-	return info
+	return info, nil
 }
 
-func HostKernelArch() string {
-	switch HostInfo().KernelArch {
-	case "x86_64", "amd64":
-		return "amd64"
-	case "arm64", "aarch64":
-		return "arm64"
-	default:
-		return "_other"
-	}
+// HostKernelArch returns the architecture of the current platform (see CurrentPlatform).
+//
+// @return The architecture, i.e., amd64, arm64 or _other.
+func HostKernelArch() (string, error) {
+	return CurrentPlatform().Arch, nil
 }
 
 // VirtualMemory retrieves information about the system's virtual memory.
 //
 // @return A pointer to a `mem.VirtualMemoryStat` struct representing the system's virtual memory information.
-func VirtualMemory() *mem.VirtualMemoryStat {
+func VirtualMemory() (*mem.VirtualMemoryStat, error) {
 	memStat, err := mem.VirtualMemory()
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return nil, fmt.Errorf("failed to get virtual memory information: %w", err)
 	}
-	return memStat
+	return memStat, nil
 }
 
 // CPUInfo retrieves information about the system's CPU.
 //
 // @return A slice of `cpu.InfoStat` structs, each representing information about a logical CPU core.
-func CPUInfo() []cpu.InfoStat {
+func CPUInfo() ([]cpu.InfoStat, error) {
 	cpuInfo, err := cpu.Info()
 	if err != nil {
-		log.Fatal("ERROR - ", err)
+		return nil, fmt.Errorf("failed to get CPU information: %w", err)
 	}
-	return cpuInfo
+	return cpuInfo, nil
 }
@@ -0,0 +1,57 @@
+package utils_test
+
+import (
+	"slices"
+	"testing"
+
+	"legendu.net/icon/internal/testutil"
+	"legendu.net/icon/utils"
+)
+
+func TestLinuxDistribution(t *testing.T) {
+	tests := []struct {
+		distro     testutil.Distro
+		wantID     string
+		wantLike   []string
+		wantSeries []string
+	}{
+		{testutil.Ubuntu, "ubuntu", []string{"debian"}, []string{"debian", "ubuntu"}},
+		{testutil.Debian, "debian", nil, []string{"debian"}},
+		{testutil.Fedora, "fedora", nil, []string{"fedora"}},
+		{testutil.UniversalBlue, "aurora", []string{"fedora"}, []string{"fedora", "universal blue", "atomic"}},
+		{testutil.Arch, "arch", nil, []string{"arch"}},
+		{testutil.OpenSUSE, "opensuse-tumbleweed", []string{"opensuse", "suse"}, []string{"suse"}},
+		{testutil.Alpine, "alpine", nil, []string{"alpine"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.distro.Name, func(t *testing.T) {
+			testutil.New(t, tt.distro)
+			if got := utils.GetLinuxDistID(); got != tt.wantID {
+				t.Errorf("GetLinuxDistID() = %q, want %q", got, tt.wantID)
+			}
+			if got := utils.GetLinuxDistIDLike(); !slices.Equal(got, tt.wantLike) {
+				t.Errorf("GetLinuxDistIDLike() = %q, want %q", got, tt.wantLike)
+			}
+			series := []string{}
+			for name, is := range map[string]func() bool{
+				"debian":         utils.IsDebianUbuntuSeries,
+				"ubuntu":         utils.IsUbuntuSeries,
+				"fedora":         utils.IsFedoraSeries,
+				"universal blue": utils.IsUniversalBlue,
+				"atomic":         utils.IsAtomicLinux,
+				"arch":           utils.IsArchSeries,
+				"suse":           utils.IsSuseSeries,
+				"alpine":         utils.IsAlpine,
+			} {
+				if is() {
+					series = append(series, name)
+				}
+			}
+			slices.Sort(series)
+			want := slices.Sorted(slices.Values(tt.wantSeries))
+			if !slices.Equal(series, want) {
+				t.Errorf("the distribution is in %q, want %q", series, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"golang.org/x/sys/unix"
+)
+
+// DefaultJobs is the default maximum number of concurrent downloads and installs.
+const DefaultJobs = 4
+
+var jobs = DefaultJobs
+
+// SetJobs sets the maximum number of concurrent downloads and installs.
+//
+// @param n The maximum number, which is at least 1.
+func SetJobs(n int) {
+	jobs = max(n, 1)
+}
+
+// Jobs returns the maximum number of concurrent downloads and installs.
+//
+// @return The maximum number.
+func Jobs() int {
+	return jobs
+}
+
+// RunParallel runs tasks concurrently with at most Jobs() tasks running at the same time.
+// All tasks are run even if some of them fail.
+//
+// @param tasks The tasks to run.
+//
+// @return The errors of failed tasks joined.
+func RunParallel(tasks ...func() error) error {
+	errs := make([]error, len(tasks))
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+	for i, task := range tasks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			errs[i] = task()
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+// packageManagerLocks maps names of locks to patterns of shell commands
+// using package managers which must not run concurrently.
+var packageManagerLocks = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"apt", regexp.MustCompile(`\b(apt|apt-get|dpkg)\s`)},
+	{"dnf", regexp.MustCompile(`\b(dnf|yum|rpm-ostree)\s`)},
+	{"zypper", regexp.MustCompile(`\bzypper\s`)},
+	{"pacman", regexp.MustCompile(`\bpacman\s`)},
+	{"apk", regexp.MustCompile(`\bapk\s`)},
+	{"brew", regexp.MustCompile(`\bbrew\s`)},
+	{"snap", regexp.MustCompile(`\bsnap\s`)},
+	{"flatpak", regexp.MustCompile(`\bflatpak\s`)},
+}
+
+// lockPackageManagers acquires (file) locks of package managers used by a shell command,
+// so that concurrent installs (in this or other icon processes) use a package manager one at a time
+// instead of failing on its own lock.
+//
+// @param cmd The shell command.
+//
+// @return A function releasing the locks.
+func lockPackageManagers(cmd string) (func(), error) {
+	files := []*os.File{}
+	unlock := func() {
+		for _, file := range files {
+			//nolint:errcheck // the lock is released on close anyway
+			unix.Flock(int(file.Fd()), unix.LOCK_UN)
+			file.Close()
+		}
+	}
+	dir := filepath.Join(CacheDir(), "locks")
+	for _, lock := range packageManagerLocks {
+		if !lock.pattern.MatchString(cmd) {
+			continue
+		}
+		//nolint:mnd // readable
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			unlock()
+			return nil, fmt.Errorf("failed to create the lock directory: %w", err)
+		}
+		//nolint:mnd // readable
+		file, err := os.OpenFile(filepath.Join(dir, lock.name+".lock"), os.O_CREATE|os.O_RDWR, 0o644)
+		if err != nil {
+			unlock()
+			return nil, fmt.Errorf("failed to lock %s: %w", lock.name, err)
+		}
+		files = append(files, file)
+		if unix.Flock(int(file.Fd()), unix.LOCK_EX|unix.LOCK_NB) == nil {
+			continue
+		}
+		log.Printf("Waiting for another install using %s to finish ...\n", lock.name)
+		if err := unix.Flock(int(file.Fd()), unix.LOCK_EX); err != nil {
+			unlock()
+			return nil, fmt.Errorf("failed to lock %s: %w", lock.name, err)
+		}
+	}
+	return unlock, nil
+}
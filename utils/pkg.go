@@ -0,0 +1,292 @@
+package utils
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// PackageManager installs and removes packages using a package manager of the system.
+type PackageManager interface {
+	// Name returns the name of the package manager, e.g., apt or brew.
+	Name() string
+	// Install installs packages in one batch.
+	Install(yes bool, pkgs ...string) error
+	// Remove removes packages in one batch.
+	Remove(yes bool, pkgs ...string) error
+	// IsInstalled checks whether a package is installed.
+	IsInstalled(pkg string) bool
+	// Version returns the installed version of a package.
+	Version(pkg string) (string, error)
+}
+
+// Packages maps names of package managers (apt, dnf, rpm-ostree, brew, pacman, zypper, apk and flatpak)
+// to names of packages of a tool for them.
+// A brew package might be prefixed with "--cask " for a cask (which is skipped on Linux).
+type Packages map[string][]string
+
+// cliPackageManager is a PackageManager running command templates.
+// The templates might use the placeholders {prefix} (sudo or an empty string),
+// {yesStr} (the flag to answer yes to prompts), {pkgs} (space-separated packages) and {pkg} (a single package).
+type cliPackageManager struct {
+	name string
+	// sudo indicates whether commands changing packages require sudo.
+	sudo bool
+	// yesFlag is the flag to automatically answer yes to prompts.
+	yesFlag string
+	// update refreshes metadata of packages. It is run at most once per run of icon.
+	update  string
+	install string
+	remove  string
+	// query exits with 0 if and only if the package is installed.
+	query string
+	// version prints the installed version of the package.
+	version string
+	updated bool
+}
+
+// packageManagers are the supported package managers by name.
+var packageManagers = map[string]*cliPackageManager{
+	"apt": {
+		name:    "apt",
+		sudo:    true,
+		yesFlag: "-y",
+		update:  "{prefix} apt-get {yesStr} update",
+		install: "{prefix} apt-get {yesStr} install {pkgs}",
+		remove:  "{prefix} apt-get {yesStr} purge {pkgs}",
+		query:   `dpkg-query -W -f='${Status}' {pkg} 2>/dev/null | grep -q 'install ok installed'`,
+		version: "dpkg-query -W -f='${Version}' {pkg}",
+	},
+	"dnf": {
+		name:    "dnf",
+		sudo:    true,
+		yesFlag: "-y",
+		install: "{prefix} dnf {yesStr} install {pkgs}",
+		remove:  "{prefix} dnf {yesStr} remove {pkgs}",
+		query:   "rpm -q {pkg} > /dev/null 2>&1",
+		version: "rpm -q --qf '%{VERSION}-%{RELEASE}' {pkg}",
+	},
+	"rpm-ostree": {
+		name:    "rpm-ostree",
+		sudo:    true,
+		yesFlag: "-y",
+		install: "{prefix} rpm-ostree install {yesStr} --idempotent {pkgs}",
+		remove:  "{prefix} rpm-ostree uninstall {pkgs}",
+		query:   "rpm -q {pkg} > /dev/null 2>&1",
+		version: "rpm -q --qf '%{VERSION}-%{RELEASE}' {pkg}",
+	},
+	"brew": {
+		name:    "brew",
+		install: "brew install --force {pkgs} || brew link --overwrite --force {pkgs}",
+		remove:  "brew uninstall {pkgs}",
+		query:   "brew list --versions {pkg} > /dev/null 2>&1",
+		version: "brew list --versions {pkg} | awk '{print $NF}'",
+	},
+	"pacman": {
+		name:    "pacman",
+		sudo:    true,
+		yesFlag: "--noconfirm",
+		install: "{prefix} pacman -S --needed {yesStr} {pkgs}",
+		remove:  "{prefix} pacman -Rs {yesStr} {pkgs}",
+		query:   "pacman -Q {pkg} > /dev/null 2>&1",
+		version: "pacman -Q {pkg} | awk '{print $2}'",
+	},
+	"zypper": {
+		name:    "zypper",
+		sudo:    true,
+		yesFlag: "--non-interactive",
+		update:  "{prefix} zypper {yesStr} refresh",
+		install: "{prefix} zypper {yesStr} install {pkgs}",
+		remove:  "{prefix} zypper {yesStr} remove {pkgs}",
+		query:   "rpm -q {pkg} > /dev/null 2>&1",
+		version: "rpm -q --qf '%{VERSION}-%{RELEASE}' {pkg}",
+	},
+	"apk": {
+		name:    "apk",
+		sudo:    true,
+		update:  "{prefix} apk update",
+		install: "{prefix} apk add {pkgs}",
+		remove:  "{prefix} apk del {pkgs}",
+		query:   "apk info -e {pkg} > /dev/null 2>&1",
+		version: "apk info -e -v {pkg} | sed 's/^{pkg}-//'",
+	},
+	"flatpak": {
+		name:    "flatpak",
+		yesFlag: "-y",
+		install: "flatpak install {yesStr} flathub {pkgs}",
+		remove:  "flatpak uninstall {yesStr} {pkgs}",
+		query:   "flatpak info {pkg} > /dev/null 2>&1",
+		version: "flatpak info {pkg} | awk '/Version:/ {print $2}'",
+	},
+}
+
+func (pm *cliPackageManager) Name() string {
+	return pm.name
+}
+
+func (pm *cliPackageManager) run(template string, yes bool, pkgs []string) error {
+	prefix := ""
+	if pm.sudo {
+		var err error
+		prefix, err = GetCommandPrefix(true, map[string]uint32{})
+		if err != nil {
+			return err
+		}
+	}
+	return RunCmd(Format(template, map[string]string{
+		"prefix": prefix,
+		"yesStr": IfElseString(yes, pm.yesFlag, ""),
+		"pkgs":   strings.Join(pkgs, " "),
+	}))
+}
+
+func (pm *cliPackageManager) Install(yes bool, pkgs ...string) error {
+	if len(pkgs) == 0 {
+		return nil
+	}
+	if pm.update != "" && !pm.updated {
+		if err := pm.run(pm.update, yes, nil); err != nil {
+			return err
+		}
+		pm.updated = true
+	}
+	return pm.run(pm.install, yes, pkgs)
+}
+
+func (pm *cliPackageManager) Remove(yes bool, pkgs ...string) error {
+	if len(pkgs) == 0 {
+		return nil
+	}
+	return pm.run(pm.remove, yes, pkgs)
+}
+
+func (pm *cliPackageManager) IsInstalled(pkg string) bool {
+	_, err := queryCmd(Format(pm.query, map[string]string{"pkg": pkg}))
+	return err == nil
+}
+
+func (pm *cliPackageManager) Version(pkg string) (string, error) {
+	if !pm.IsInstalled(pkg) {
+		return "", fmt.Errorf("the package %s is not installed by %s", pkg, pm.name)
+	}
+	return queryCmd(Format(pm.version, map[string]string{"pkg": pkg}))
+}
+
+// queryCmd runs a command which does not change the system (even in dry-run mode) and returns its output.
+func queryCmd(cmd string) (string, error) {
+	out, err := NewCommand("bash", "-c", cmd).Output()
+	if err != nil {
+		return "", fmt.Errorf("failed to run the command %s: %w", cmd, err)
+	}
+	return out, nil
+}
+
+// GetPackageManager returns a package manager by name.
+//
+// @param name The name of the package manager, e.g., apt or brew.
+//
+// @return The package manager.
+func GetPackageManager(name string) (PackageManager, error) {
+	pm, ok := packageManagers[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown package manager %s", name)
+	}
+	return pm, nil
+}
+
+// SystemPackageManagers returns names of package managers available on the current OS
+// in the order of preference.
+//
+// @return Names of package managers.
+func SystemPackageManagers() []string {
+	if !IsLinux() {
+		return []string{"brew"}
+	}
+	names := []string{}
+	switch {
+	case IsUniversalBlue():
+		// the system image of Universal Blue distributions is immutable and ships with Homebrew
+		names = append(names, "brew", "rpm-ostree")
+	case IsDebianUbuntuSeries():
+		names = append(names, "apt")
+	case IsFedoraSeries():
+		names = append(names, IfElseString(IsAtomicLinux(), "rpm-ostree", "dnf"))
+	case IsArchSeries():
+		names = append(names, "pacman")
+	case IsSuseSeries():
+		names = append(names, "zypper")
+	case IsAlpine():
+		names = append(names, "apk")
+	}
+	for _, name := range []string{"brew", "flatpak"} {
+		if LookPath(name) != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// ResolvePackages picks the most preferred package manager of the system which packages of a tool are declared for.
+//
+// @param tool The name of the tool.
+// @param pkgs Packages of the tool for package managers.
+//
+// @return The package manager and the packages to use with it,
+// or an *UnsupportedDistroError if no package of the tool is declared for package managers of the system.
+func ResolvePackages(tool string, pkgs Packages) (PackageManager, []string, error) {
+	for _, name := range SystemPackageManagers() {
+		names, ok := pkgs[name]
+		// casks of Homebrew are for macOS only
+		if !ok || name == "brew" && IsLinux() && slices.ContainsFunc(names, isCask) {
+			continue
+		}
+		return packageManagers[name], names, nil
+	}
+	return nil, nil, NewUnsupportedDistroError(tool)
+}
+
+func isCask(pkg string) bool {
+	return strings.HasPrefix(pkg, "--cask ")
+}
+
+// InstallPackages installs packages of a tool using the most preferred package manager of the system.
+//
+// @param tool The name of the tool.
+// @param pkgs Packages of the tool for package managers.
+// @param yes  Whether to automatically answer yes to prompts.
+func InstallPackages(tool string, pkgs Packages, yes bool) error {
+	pm, names, err := ResolvePackages(tool, pkgs)
+	if err != nil {
+		return err
+	}
+	return pm.Install(yes, names...)
+}
+
+// RemovePackages removes packages of a tool using the most preferred package manager of the system.
+//
+// @param tool The name of the tool.
+// @param pkgs Packages of the tool for package managers.
+// @param yes  Whether to automatically answer yes to prompts.
+func RemovePackages(tool string, pkgs Packages, yes bool) error {
+	pm, names, err := ResolvePackages(tool, pkgs)
+	if err != nil {
+		return err
+	}
+	return pm.Remove(yes, names...)
+}
+
+// AddAptRepository adds an APT repository, e.g., a PPA on Ubuntu.
+//
+// @param repo The repository, e.g., ppa:fish-shell/release-4.
+// @param yes  Whether to automatically answer yes to prompts.
+func AddAptRepository(repo string, yes bool) error {
+	return packageManagers["apt"].run("{prefix} add-apt-repository {yesStr} "+repo, yes, nil)
+}
+
+// EnableCopr enables a Copr repository for DNF.
+//
+// @param repo The Copr repository, e.g., varlad/helix.
+// @param yes  Whether to automatically answer yes to prompts.
+func EnableCopr(repo string, yes bool) error {
+	return packageManagers["dnf"].run("{prefix} dnf {yesStr} copr enable "+repo, yes, nil)
+}
@@ -0,0 +1,87 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// PlanStep is an action recorded (instead of being performed) in dry-run mode.
+type PlanStep struct {
+	// Kind is the type of the action, e.g., "run", "write", "symlink" or "backup".
+	Kind string
+	// Detail describes the action, e.g., the shell command or the paths involved.
+	Detail string
+}
+
+var dryRun bool
+
+var plan []PlanStep
+
+// planMu guards plan, which might be recorded into concurrently.
+var planMu sync.Mutex
+
+// SetDryRun turns the dry-run mode on or off.
+// In dry-run mode, shell commands are not run and nothing on disk is changed.
+// Instead, the actions are recorded into a plan which can be printed using PrintPlan.
+//
+// @param b Whether to turn on the dry-run mode.
+func SetDryRun(b bool) {
+	dryRun = b
+}
+
+// IsDryRun checks whether the dry-run mode is on.
+//
+// @return true if the dry-run mode is on, false otherwise.
+func IsDryRun() bool {
+	return dryRun
+}
+
+// recordStep appends an action into the plan.
+//
+// @param kind   The type of the action.
+// @param format A format string (as used by fmt.Sprintf) describing the action.
+// @param args   Arguments for the format string.
+func recordStep(kind, format string, args ...any) {
+	planMu.Lock()
+	defer planMu.Unlock()
+	plan = append(plan, PlanStep{
+		Kind:   kind,
+		Detail: strings.TrimSpace(fmt.Sprintf(format, args...)),
+	})
+}
+
+// Plan returns the actions recorded so far in dry-run mode.
+//
+// @return A slice of PlanStep in the order they were recorded.
+func Plan() []PlanStep {
+	planMu.Lock()
+	defer planMu.Unlock()
+	return slices.Clone(plan)
+}
+
+// PrintPlan prints the actions recorded in dry-run mode as an ordered list.
+//
+// @param w The writer to print the plan to.
+func PrintPlan(w io.Writer) {
+	plan := Plan()
+	if len(plan) == 0 {
+		fmt.Fprintln(w, "Nothing to do (dry run).")
+		return
+	}
+	if p := CurrentPlatform(); p.Overridden {
+		fmt.Fprintf(w, "Plan (dry run) for %s:\n", p)
+	} else {
+		fmt.Fprintln(w, "Plan (dry run):")
+	}
+	width := len(fmt.Sprint(len(plan)))
+	for idx, step := range plan {
+		lines := strings.Split(strings.TrimSpace(step.Detail), "\n")
+		fmt.Fprintf(w, "%*d. %-9s %s\n", width, idx+1, "["+step.Kind+"]", lines[0])
+		for _, line := range lines[1:] {
+			fmt.Fprintf(w, "%*s %s\n", width+11, "", strings.TrimSpace(line))
+		}
+	}
+}
@@ -0,0 +1,38 @@
+package utils_test
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+
+	"legendu.net/icon/internal/testutil"
+	"legendu.net/icon/utils"
+)
+
+func TestPlanConcurrent(t *testing.T) {
+	testutil.New(t, testutil.Ubuntu)
+	utils.SetDryRun(true)
+	defer utils.SetDryRun(false)
+	before := len(utils.Plan())
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if err := utils.RunCmd("true"); err != nil {
+				t.Error(err)
+			}
+			_ = utils.Plan()
+			utils.PrintPlan(&bytes.Buffer{})
+		})
+	}
+	wg.Wait()
+	plan := utils.Plan()
+	if got := len(plan) - before; got != 8 {
+		t.Errorf("%d steps are recorded, want 8", got)
+	}
+	// the returned plan is a copy
+	plan[len(plan)-1].Detail = "changed"
+	if got := utils.Plan()[len(plan)-1].Detail; strings.Contains(got, "changed") {
+		t.Errorf("the plan is changed through the returned slice: %s", got)
+	}
+}
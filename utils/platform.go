@@ -0,0 +1,250 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// PlatformEnv is the environment variable overriding the platform (see ParsePlatform),
+// which is overridden by the option --platform-override in turn.
+const PlatformEnv = "ICON_PLATFORM"
+
+// Platform is the machine which icon installs and configures tools for.
+// It is detected once (see DetectPlatform) and can be overridden (see SetPlatform and ParsePlatform),
+// e.g., to see what icon would do on Fedora using --dry-run on Ubuntu.
+type Platform struct {
+	// OS is the operating system as GOOS, i.e., linux or darwin.
+	OS string `json:"os"`
+	// ID is the ID (in os-release) of the Linux distribution, e.g., ubuntu or fedora.
+	ID string `json:"id,omitempty"`
+	// IDLike are IDs (ID_LIKE in os-release) of distributions the Linux distribution is derived from.
+	IDLike []string `json:"idLike,omitempty"`
+	// Version is the version (VERSION_ID in os-release) of the Linux distribution, e.g., 24.04 or 41.
+	Version string `json:"version,omitempty"`
+	// Arch is the architecture, i.e., amd64, arm64 or _other.
+	Arch string `json:"arch"`
+	// Atomic is whether the Linux distribution is image-based (e.g., Fedora Atomic Desktops and Universal Blue).
+	Atomic bool `json:"atomic"`
+	// Container is whether icon runs in a container (e.g., Docker or Podman).
+	Container bool `json:"container"`
+	// VM is whether icon runs in a virtual machine.
+	VM bool `json:"vm"`
+	// WSL is whether icon runs in the Windows Subsystem for Linux.
+	WSL bool `json:"wsl"`
+	// Overridden is whether the platform is specified instead of being detected.
+	Overridden bool `json:"overridden"`
+}
+
+// String returns the platform in the form accepted by ParsePlatform, e.g., fedora:41/arm64 or macos/arm64.
+func (p Platform) String() string {
+	name := p.ID
+	switch {
+	case p.OS == "darwin":
+		name = "macos"
+	case name == "":
+		name = p.OS
+	}
+	if p.Version != "" {
+		name += ":" + p.Version
+	}
+	return name + "/" + p.Arch
+}
+
+// IsLike checks whether the platform is or is derived from one of the specified Linux distributions.
+//
+// @param ids IDs of distributions.
+//
+// @return true if the ID or an ID_LIKE of the platform is in ids, false otherwise.
+func (p Platform) IsLike(ids ...string) bool {
+	return slices.Contains(ids, p.ID) || slices.ContainsFunc(p.IDLike, func(id string) bool {
+		return slices.Contains(ids, id)
+	})
+}
+
+var (
+	platform   *Platform
+	platformMu sync.Mutex
+)
+
+// CurrentPlatform returns the platform which icon installs and configures tools for.
+// It is detected (see DetectPlatform) on first use
+// unless it is overridden by SetPlatform or SetPlatformOverride.
+//
+// @return The platform.
+func CurrentPlatform() Platform {
+	platformMu.Lock()
+	defer platformMu.Unlock()
+	if platform == nil {
+		p := DetectPlatform()
+		platform = &p
+	}
+	return *platform
+}
+
+// SetPlatform overrides the platform which icon installs and configures tools for.
+//
+// @param p The platform.
+//
+// @return A function restoring the previous platform.
+func SetPlatform(p Platform) func() {
+	platformMu.Lock()
+	defer platformMu.Unlock()
+	previous := platform
+	platform = &p
+	return func() {
+		platformMu.Lock()
+		defer platformMu.Unlock()
+		platform = previous
+	}
+}
+
+// SetPlatformOverride overrides the platform by a specification (see ParsePlatform),
+// i.e., the value of the option --platform-override or else of the environment variable ICON_PLATFORM.
+//
+// @param spec The specification of the platform,
+// or an empty string to use ICON_PLATFORM (or keep the current platform if it is not set either).
+//
+// @return An error if the specification is invalid.
+func SetPlatformOverride(spec string) error {
+	source := "--platform-override"
+	if spec == "" {
+		spec, source = os.Getenv(PlatformEnv), PlatformEnv
+	}
+	if spec == "" {
+		return nil
+	}
+	p, err := ParsePlatform(spec, CurrentPlatform())
+	if err != nil {
+		return fmt.Errorf("invalid %s: %w", source, err)
+	}
+	SetPlatform(p)
+	if !dryRun {
+		Warnf("The platform is overridden to %s by %s without --dry-run, "+
+			"so commands for it are run on this machine.\n", p, source)
+	}
+	return nil
+}
+
+// knownDistros are ID_LIKE and whether image-based of well-known Linux distributions,
+// which are filled in for platforms specified by ParsePlatform.
+var knownDistros = map[string]struct {
+	idLike []string
+	atomic bool
+}{
+	"ubuntu":              {[]string{"debian"}, false},
+	"debian":              {nil, false},
+	"linuxmint":           {[]string{"ubuntu", "debian"}, false},
+	"pop":                 {[]string{"ubuntu", "debian"}, false},
+	"fedora":              {nil, false},
+	"centos":              {[]string{"rhel", "fedora"}, false},
+	"rhel":                {[]string{"fedora"}, false},
+	"rocky":               {[]string{"rhel", "centos", "fedora"}, false},
+	"almalinux":           {[]string{"rhel", "centos", "fedora"}, false},
+	"aurora":              {[]string{"fedora"}, true},
+	"bazzite":             {[]string{"fedora"}, true},
+	"bluefin":             {[]string{"fedora"}, true},
+	"arch":                {nil, false},
+	"manjaro":             {[]string{"arch"}, false},
+	"endeavouros":         {[]string{"arch"}, false},
+	"opensuse-tumbleweed": {[]string{"opensuse", "suse"}, false},
+	"opensuse-leap":       {[]string{"opensuse", "suse"}, false},
+	"alpine":              {nil, false},
+}
+
+// ParsePlatform parses a platform specified in the form <distro>[:<version>][/<arch>],
+// e.g., fedora:41/arm64, ubuntu:24.04, aurora or macos/arm64.
+// ID_LIKE and whether image-based are filled in for well-known distributions.
+//
+// @param spec The specification of the platform.
+// @param host The detected platform, whose architecture is used if not specified
+// and whose ID_LIKE is used if the distribution is the same.
+//
+// @return The platform.
+func ParsePlatform(spec string, host Platform) (Platform, error) {
+	name, arch, _ := strings.Cut(strings.TrimSpace(spec), "/")
+	id, version, _ := strings.Cut(name, ":")
+	id = strings.ToLower(id)
+	p := Platform{OS: "linux", ID: id, Version: version, Arch: host.Arch, Overridden: true}
+	switch arch {
+	case "":
+	case "amd64", "x86_64":
+		p.Arch = "amd64"
+	case "arm64", "aarch64":
+		p.Arch = "arm64"
+	default:
+		return Platform{}, fmt.Errorf("unsupported architecture %s in the platform %s (amd64 or arm64)", arch, spec)
+	}
+	switch id {
+	case "":
+		return Platform{}, fmt.Errorf("no distribution is specified in the platform %s", spec)
+	case "macos", "darwin":
+		p.OS, p.ID = "darwin", ""
+	case "linux":
+		p.ID = ""
+	default:
+		if distro, ok := knownDistros[id]; ok {
+			p.IDLike, p.Atomic = distro.idLike, distro.atomic
+		} else if id == host.ID {
+			p.IDLike, p.Atomic = host.IDLike, host.Atomic
+		} else {
+			return Platform{}, fmt.Errorf("unknown Linux distribution %s in the platform %s", id, spec)
+		}
+	}
+	return p, nil
+}
+
+// DetectPlatform detects the platform of the current machine.
+//
+// @return The platform.
+func DetectPlatform() Platform {
+	p := Platform{OS: runtime.GOOS, Arch: detectArch()}
+	if p.OS != "linux" {
+		return p
+	}
+	fields := osRelease()
+	p.ID, p.Version = fields["ID"], fields["VERSION_ID"]
+	p.IDLike = strings.Fields(fields["ID_LIKE"])
+	p.Atomic = LookPath("rpm-ostree") != "" || ExistsPath("/run/ostree-booted")
+	p.Container = os.Getenv("container") != "" || ExistsPath("/.dockerenv") || ExistsPath("/run/.containerenv")
+	p.VM = detectVM()
+	version, _ := fsys.ReadFile("/proc/version")
+	p.WSL = os.Getenv("WSL_DISTRO_NAME") != "" || strings.Contains(strings.ToLower(string(version)), "microsoft")
+	return p
+}
+
+// detectArch detects the architecture of the kernel, which differs from GOARCH for a binary emulated by Rosetta.
+func detectArch() string {
+	arch := runtime.GOARCH
+	if info, err := HostInfo(); err == nil {
+		arch = info.KernelArch
+	}
+	switch arch {
+	case "x86_64", "amd64":
+		return "amd64"
+	case "arm64", "aarch64":
+		return "arm64"
+	default:
+		return "_other"
+	}
+}
+
+// detectVM checks whether the current Linux machine is a virtual machine using its DMI information.
+func detectVM() bool {
+	for _, file := range []string{"/sys/class/dmi/id/sys_vendor", "/sys/class/dmi/id/product_name"} {
+		bytes, err := fsys.ReadFile(file)
+		if err != nil {
+			continue
+		}
+		vendor := strings.ToLower(string(bytes))
+		for _, hypervisor := range []string{"qemu", "kvm", "vmware", "virtualbox", "microsoft corporation", "xen", "parallels"} {
+			if strings.Contains(vendor, hypervisor) {
+				return true
+			}
+		}
+	}
+	return false
+}
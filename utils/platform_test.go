@@ -0,0 +1,134 @@
+package utils_test
+
+import (
+	"reflect"
+	"testing"
+
+	"legendu.net/icon/internal/testutil"
+	"legendu.net/icon/utils"
+)
+
+func TestParsePlatform(t *testing.T) {
+	host := utils.Platform{OS: "linux", ID: "gentoo", IDLike: []string{"gentoo-base"}, Arch: "amd64"}
+	tests := []struct {
+		spec    string
+		want    utils.Platform
+		wantErr bool
+	}{
+		{"fedora:41/arm64", utils.Platform{OS: "linux", ID: "fedora", Version: "41", Arch: "arm64", Overridden: true}, false},
+		{"ubuntu:24.04", utils.Platform{
+			OS: "linux", ID: "ubuntu", IDLike: []string{"debian"}, Version: "24.04", Arch: "amd64", Overridden: true,
+		}, false},
+		{"Aurora/aarch64", utils.Platform{
+			OS: "linux", ID: "aurora", IDLike: []string{"fedora"}, Arch: "arm64", Atomic: true, Overridden: true,
+		}, false},
+		{"macos/arm64", utils.Platform{OS: "darwin", Arch: "arm64", Overridden: true}, false},
+		{"gentoo", utils.Platform{
+			OS: "linux", ID: "gentoo", IDLike: []string{"gentoo-base"}, Arch: "amd64", Overridden: true,
+		}, false},
+		{"slackware", utils.Platform{}, true},
+		{"fedora/riscv64", utils.Platform{}, true},
+		{":41", utils.Platform{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			got, err := utils.ParsePlatform(tt.spec, host)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error: %t", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParsePlatform(%q) = %+v, want %+v", tt.spec, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlatformString(t *testing.T) {
+	for _, spec := range []string{"fedora:41/arm64", "aurora/amd64", "macos/arm64"} {
+		p, err := utils.ParsePlatform(spec, utils.Platform{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := p.String(); got != spec {
+			t.Errorf("String() = %s, want %s", got, spec)
+		}
+	}
+}
+
+func TestDetectPlatform(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		env   map[string]string
+		want  func(p utils.Platform) bool
+	}{
+		{"bare metal", nil, nil, func(p utils.Platform) bool { return !p.Container && !p.VM && !p.WSL }},
+		{"docker", map[string]string{"/.dockerenv": ""}, nil, func(p utils.Platform) bool { return p.Container }},
+		{"podman", nil, map[string]string{"container": "podman"}, func(p utils.Platform) bool { return p.Container }},
+		{"qemu", map[string]string{"/sys/class/dmi/id/sys_vendor": "QEMU\n"}, nil, func(p utils.Platform) bool {
+			return p.VM
+		}},
+		{"wsl", map[string]string{
+			"/proc/version": "Linux version 5.15.167.4-microsoft-standard-WSL2",
+		}, nil, func(p utils.Platform) bool { return p.WSL }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := testutil.New(t, testutil.Ubuntu)
+			for path, content := range tt.files {
+				env.WriteFile(path, content)
+			}
+			for key, value := range tt.env {
+				t.Setenv(key, value)
+			}
+			p := utils.DetectPlatform()
+			if p.ID != "ubuntu" || p.Version != "24.04" || p.Atomic || !tt.want(p) {
+				t.Errorf("DetectPlatform() = %+v", p)
+			}
+		})
+	}
+}
+
+func TestPlatformOverride(t *testing.T) {
+	env := testutil.New(t, testutil.Ubuntu)
+	utils.SetDryRun(true)
+	defer utils.SetDryRun(false)
+	if err := utils.SetPlatformOverride("fedora:41"); err != nil {
+		t.Fatal(err)
+	}
+	if !utils.IsFedoraSeries() || utils.IsDebianUbuntuSeries() {
+		t.Errorf("the platform is %s", utils.CurrentPlatform())
+	}
+	env.Exec.AddCommands("dnf")
+	if got := utils.SystemPackageManagers(); !reflect.DeepEqual(got, []string{"dnf"}) {
+		t.Errorf("SystemPackageManagers() = %q, want [dnf]", got)
+	}
+}
+
+func TestPlatformOverrideFromEnv(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		env     string
+		want    string
+		wantErr bool
+	}{
+		{"env", "", "fedora:41", "fedora", false},
+		{"option over env", "arch", "fedora:41", "arch", false},
+		{"invalid env", "", "fedora/sparc", "ubuntu", true},
+		{"none", "", "", "ubuntu", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testutil.New(t, testutil.Ubuntu)
+			t.Setenv(utils.PlatformEnv, tt.env)
+			err := utils.SetPlatformOverride(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, want an error: %t", err, tt.wantErr)
+			}
+			if got := utils.CurrentPlatform().ID; got != tt.want {
+				t.Errorf("the platform is %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
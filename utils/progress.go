@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// activeBars is the number of progress bars of concurrent downloads.
+var activeBars atomic.Int32
+
+// progressBar shows the progress of a download on the standard error if it is a terminal.
+// Progress is shown only while a single download is active as concurrent progress bars would overwrite each other.
+type progressBar struct {
+	name    string
+	current int64
+	total   int64
+	offset  int64
+	start   time.Time
+	shown   time.Time
+	enabled bool
+}
+
+// newProgressBar creates a progress bar.
+//
+// @param name   The name of the file being downloaded.
+// @param offset The number of bytes already downloaded (e.g., before resuming).
+// @param total  The total number of bytes, or -1 if unknown.
+func newProgressBar(name string, offset, total int64) *progressBar {
+	activeBars.Add(1)
+	return &progressBar{
+		name:    name,
+		current: offset,
+		total:   total,
+		offset:  offset,
+		start:   time.Now(),
+		enabled: isTerminal(os.Stderr) && !captureCommandOutput(),
+	}
+}
+
+func (p *progressBar) Write(b []byte) (int, error) {
+	p.current += int64(len(b))
+	//nolint:mnd // readable
+	if p.enabled && activeBars.Load() == 1 && time.Since(p.shown) >= 200*time.Millisecond {
+		p.show()
+	}
+	return len(b), nil
+}
+
+func (p *progressBar) show() {
+	p.shown = time.Now()
+	speed := ""
+	if seconds := time.Since(p.start).Seconds(); seconds > 0 {
+		speed = FormatBytes(int64(float64(p.current-p.offset)/seconds)) + "/s"
+	}
+	if p.total <= 0 {
+		fmt.Fprintf(os.Stderr, "\r%s %s %s\033[K", p.name, FormatBytes(p.current), speed)
+		return
+	}
+	const width = 30
+	done := min(width, int(width*p.current/p.total))
+	fmt.Fprintf(os.Stderr, "\r%s [%s%s] %3d%% %s/%s %s\033[K",
+		p.name,
+		strings.Repeat("=", done),
+		strings.Repeat(" ", width-done),
+		//nolint:mnd // readable
+		100*p.current/p.total,
+		FormatBytes(p.current),
+		FormatBytes(p.total),
+		speed,
+	)
+}
+
+// Finish shows the final progress and ends the line.
+func (p *progressBar) Finish() {
+	if activeBars.Add(-1) == 0 && p.enabled {
+		p.show()
+		fmt.Fprintln(os.Stderr)
+	}
+}
@@ -1,6 +1,8 @@
 package utils
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
 )
 
@@ -9,11 +11,14 @@ import (
 // @param cmd A pointer to a Cobra command object.
 //
 // @return A string representing the pip uninstall command.
-func BuildPipUninstall(cmd *cobra.Command) string {
-	python := GetStringFlag(cmd, "python")
+func BuildPipUninstall(cmd *cobra.Command) (string, error) {
+	python, err := GetStringFlag(cmd, "python")
+	if err != nil {
+		return "", err
+	}
 	return Format("{python} -m pip uninstall", map[string]string{
 		"python": python,
-	})
+	}), nil
 }
 
 // BuildPipInstall constructs a command to install a Python package using pip.
@@ -21,16 +26,23 @@ func BuildPipUninstall(cmd *cobra.Command) string {
 // @param cmd A pointer to a Cobra command object.
 //
 // @return A string representing the pip install command.
-func BuildPipInstall(cmd *cobra.Command) string {
-	python := GetStringFlag(cmd, "python")
+func BuildPipInstall(cmd *cobra.Command) (string, error) {
+	python, err := GetStringFlag(cmd, "python")
+	if err != nil {
+		return "", err
+	}
 	if LookPath(python) == "" {
-		return ""
+		return "", fmt.Errorf("the Python command %s is not found", python)
+	}
+	userFlag, err := GetBoolFlag(cmd, "user")
+	if err != nil {
+		return "", err
 	}
-	user := ""
-	if GetBoolFlag(cmd, "user") {
-		user = "--user"
+	user := IfElseString(userFlag, "--user", "")
+	extraPipOptions, err := GetStringSliceFlag(cmd, "extra-pip-options")
+	if err != nil {
+		return "", err
 	}
-	extraPipOptions := GetStringSliceFlag(cmd, "extra-pip-options")
 	options := ""
 	for _, option := range extraPipOptions {
 		options += "--" + option
@@ -39,7 +51,7 @@ func BuildPipInstall(cmd *cobra.Command) string {
 		"python":  python,
 		"user":    user,
 		"options": options,
-	})
+	}), nil
 }
 
 // AddPythonFlags adds common Python-related flags to a Cobra command.
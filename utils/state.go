@@ -0,0 +1,574 @@
+package utils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// FileRecord is a file placed by icon.
+type FileRecord struct {
+	// Path is the absolute path of the file.
+	Path string `yaml:"path"`
+	// Sha256 is the SHA-256 checksum of the file when it was placed.
+	Sha256 string `yaml:"sha256,omitempty"`
+}
+
+// SymlinkRecord is a symbolic link created by icon.
+type SymlinkRecord struct {
+	// Path is the absolute path of the symbolic link.
+	Path string `yaml:"path"`
+	// Target is the path the symbolic link points to.
+	Target string `yaml:"target"`
+}
+
+// ConfigRecord is a configuration file (or directory) placed by CopyOrSymlink from its source,
+// e.g., a file in ~/.config/icon-data.
+type ConfigRecord struct {
+	// Path is the absolute path of the configuration.
+	Path string `yaml:"path"`
+	// Source is the absolute path of the source of the configuration.
+	Source string `yaml:"source"`
+	// Copy is whether the configuration is a copy (instead of a symbolic link) of the source.
+	Copy bool `yaml:"copy,omitempty"`
+}
+
+// BackupRecord is a backup made by icon before overwriting a path.
+type BackupRecord struct {
+	// Original is the path which was backed up.
+	Original string `yaml:"original"`
+	// Backup is the path of the backup.
+	Backup string `yaml:"backup"`
+	// Time is when the backup was made.
+	Time time.Time `yaml:"time"`
+}
+
+// ReleaseRecord is a release (e.g., on GitHub) which a tool (or a component of it) was installed from.
+type ReleaseRecord struct {
+	// Repo is the repo of the release, e.g., user_name/repo_name for GitHub or the URL of the repo.
+	Repo string `yaml:"repo"`
+	// Provider is the name of the release provider, e.g., github or gitlab.
+	Provider string `yaml:"provider"`
+	// Version is the tag of the installed release.
+	Version string `yaml:"version"`
+	// Constraint is the version constraint which the release was picked with, or an empty string for the latest release.
+	Constraint string `yaml:"constraint,omitempty"`
+}
+
+// ToolState is what icon has done for a tool.
+type ToolState struct {
+	// Name is the name of the command of the tool.
+	Name string `yaml:"name"`
+	// Version is the installed version of the tool if known.
+	Version string `yaml:"version,omitempty"`
+	// Methods are the methods (apt, dnf, brew, pacman, github, pip, cargo, etc.) used to install the tool.
+	Methods []string `yaml:"methods,omitempty"`
+	// InstalledAt is when the tool was installed, or the zero time if not installed by icon.
+	InstalledAt time.Time `yaml:"installedAt,omitempty"`
+	// ConfiguredAt is when the tool was configured, or the zero time if not configured by icon.
+	ConfiguredAt time.Time `yaml:"configuredAt,omitempty"`
+	// Files are files placed by icon.
+	Files []FileRecord `yaml:"files,omitempty"`
+	// Symlinks are symbolic links created by icon.
+	Symlinks []SymlinkRecord `yaml:"symlinks,omitempty"`
+	// Configs are configuration files placed (as copies or symbolic links) from their sources.
+	Configs []ConfigRecord `yaml:"configs,omitempty"`
+	// Backups are backups made by icon.
+	Backups []BackupRecord `yaml:"backups,omitempty"`
+	// Releases are releases which the tool (or components of it) was installed from.
+	Releases []ReleaseRecord `yaml:"releases,omitempty"`
+	// Flags are flags (other than --install, --config and --uninstall) which the tool was last installed with,
+	// so that it can be reinstalled the same way (e.g., by icon upgrade).
+	Flags map[string][]string `yaml:"flags,omitempty"`
+}
+
+// current is the state being recorded for the tool which is running.
+var current *ToolState
+
+// ownedFiles are paths of files recorded for the current tool by previous runs.
+var ownedFiles map[string]bool
+
+// recordMu guards recording into current, which might happen concurrently (e.g., from parallel downloads).
+var recordMu sync.Mutex
+
+// installMethodPatterns maps install methods to patterns of shell commands using them.
+var installMethodPatterns = []struct {
+	method  string
+	pattern *regexp.Regexp
+}{
+	{"apt", regexp.MustCompile(`\bapt(-get)?\s+(-\S+\s+)*install\b`)},
+	{"dnf", regexp.MustCompile(`\bdnf\s+(-\S+\s+)*install\b`)},
+	{"rpm-ostree", regexp.MustCompile(`\brpm-ostree\s+install\b`)},
+	{"brew", regexp.MustCompile(`\bbrew\s+install\b`)},
+	{"pip", regexp.MustCompile(`\bpip3?\s+install\b|-m\s+pip\s+install\b`)},
+	{"cargo", regexp.MustCompile(`\bcargo\s+(b?install)\b`)},
+	{"pacman", regexp.MustCompile(`\bpacman\s+-S\b`)},
+	{"zypper", regexp.MustCompile(`\bzypper\s+(-\S+\s+)*install\b`)},
+	{"apk", regexp.MustCompile(`\bapk\s+add\b`)},
+	{"flatpak", regexp.MustCompile(`\bflatpak\s+install\b`)},
+	{"snap", regexp.MustCompile(`\bsnap\s+install\b`)},
+	{"script", regexp.MustCompile(`\bcurl\b.*\|\s*(ba)?sh\b`)},
+}
+
+// StateDir returns the directory storing the state of tools,
+// i.e., $XDG_STATE_HOME/icon or ~/.local/state/icon.
+//
+// @return The path to the state directory.
+func StateDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "icon")
+	}
+	return NormalizePath("~/.local/state/icon")
+}
+
+func toolStateFile(name string) string {
+	return filepath.Join(StateDir(), "tools", name+".yaml")
+}
+
+// ReadToolState reads the recorded state of a tool.
+//
+// @param name The name of the tool.
+//
+// @return The state of the tool, or nil if nothing has been recorded for the tool.
+func ReadToolState(name string) (*ToolState, error) {
+	bytes, err := os.ReadFile(toolStateFile(name))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the state of %s: %w", name, err)
+	}
+	var state ToolState
+	if err := yaml.Unmarshal(bytes, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse the state of %s: %w", name, err)
+	}
+	return &state, nil
+}
+
+// ReadAllToolStates reads the recorded states of all tools.
+//
+// @return States of tools sorted by name.
+func ReadAllToolStates() ([]*ToolState, error) {
+	entries, err := os.ReadDir(filepath.Join(StateDir(), "tools"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the state directory: %w", err)
+	}
+	states := []*ToolState{}
+	for _, entry := range entries {
+		name, ok := strings.CutSuffix(entry.Name(), ".yaml")
+		if !ok || entry.IsDir() {
+			continue
+		}
+		state, err := ReadToolState(name)
+		if err != nil {
+			return nil, err
+		}
+		if state != nil {
+			states = append(states, state)
+		}
+	}
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	return states, nil
+}
+
+// WriteToolState saves the state of a tool.
+// The state is written directly (rather than via WriteFile) so that it is not recorded itself.
+// Nothing is written in dry-run mode.
+//
+// @param state The state of the tool.
+func WriteToolState(state *ToolState) error {
+	if dryRun {
+		return nil
+	}
+	file := toolStateFile(state.Name)
+	//nolint:mnd // readable
+	if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+		return fmt.Errorf("failed to create the state directory: %w", err)
+	}
+	bytes, err := yaml.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to serialize the state of %s: %w", state.Name, err)
+	}
+	// write into a temporary file and rename it so that the state is never half-written
+	tmp := file + ".tmp"
+	//nolint:mnd // readable
+	if err := os.WriteFile(tmp, bytes, 0o600); err != nil {
+		return fmt.Errorf("failed to write the state of %s: %w", state.Name, err)
+	}
+	if err := os.Rename(tmp, file); err != nil {
+		return fmt.Errorf("failed to write the state of %s: %w", state.Name, err)
+	}
+	return nil
+}
+
+// RemoveToolState removes the recorded state of a tool.
+// Nothing is removed in dry-run mode.
+//
+// @param name The name of the tool.
+func RemoveToolState(name string) error {
+	if dryRun {
+		return nil
+	}
+	if err := os.Remove(toolStateFile(name)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove the state of %s: %w", name, err)
+	}
+	return nil
+}
+
+// BeginToolRun starts recording files, symbolic links, backups and install methods for a tool.
+// Changes are also journaled in order so that the run can be rolled back.
+//
+// @param name The name of the tool.
+func BeginToolRun(name string) {
+	current = &ToolState{Name: name}
+	ownedFiles = map[string]bool{}
+	if state, err := ReadToolState(name); err == nil && state != nil {
+		for _, file := range state.Files {
+			ownedFiles[file.Path] = true
+		}
+	}
+	beginJournal(name)
+}
+
+// CurrentTool returns the name of the tool which is being recorded.
+//
+// @return The name of the tool, or an empty string if no tool is being recorded.
+func CurrentTool() string {
+	if current == nil {
+		return ""
+	}
+	return current.Name
+}
+
+// EndToolRun stops recording for the current tool, saves the journal of the run
+// and merges what has been recorded into its saved state.
+//
+// @param installed  Whether the tool has been installed successfully.
+// @param configured Whether the tool has been configured successfully.
+//
+// @return The merged state of the tool.
+func EndToolRun(installed, configured bool) (*ToolState, error) {
+	if err := endJournal(); err != nil {
+		current = nil
+		return nil, err
+	}
+	run := current
+	current = nil
+	ownedFiles = nil
+	if run == nil {
+		return nil, nil
+	}
+	state, err := ReadToolState(run.Name)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		state = &ToolState{Name: run.Name}
+	}
+	now := time.Now()
+	if installed {
+		state.InstalledAt = now
+		if len(run.Methods) > 0 {
+			state.Methods = run.Methods
+		}
+		if run.Version != "" {
+			state.Version = run.Version
+		}
+		for _, release := range run.Releases {
+			state.Releases = slices.DeleteFunc(state.Releases, func(r ReleaseRecord) bool { return r.Repo == release.Repo })
+			state.Releases = append(state.Releases, release)
+		}
+		state.Flags = run.Flags
+	}
+	if configured {
+		state.ConfiguredAt = now
+	}
+	// a path placed in this run supersedes whatever was recorded for the same path before
+	for _, file := range run.Files {
+		state.forget(file.Path)
+		state.Files = append(state.Files, file)
+	}
+	for _, link := range run.Symlinks {
+		state.forget(link.Path)
+		state.Symlinks = append(state.Symlinks, link)
+	}
+	for _, config := range run.Configs {
+		state.Configs = slices.DeleteFunc(state.Configs, func(c ConfigRecord) bool { return c.Path == config.Path })
+		state.Configs = append(state.Configs, config)
+	}
+	state.Backups = append(state.Backups, run.Backups...)
+	if len(run.Files)+len(run.Symlinks)+len(run.Configs)+len(run.Backups) == 0 && !installed && !configured {
+		return state, nil
+	}
+	return state, WriteToolState(state)
+}
+
+// forget removes the file or symbolic link recorded for a path.
+func (s *ToolState) forget(path string) {
+	s.Files = slices.DeleteFunc(s.Files, func(f FileRecord) bool { return f.Path == path })
+	s.Symlinks = slices.DeleteFunc(s.Symlinks, func(l SymlinkRecord) bool { return l.Path == path })
+}
+
+// RecordFile records a file placed (e.g., extracted from an archive by a shell command) for the current tool.
+//
+// @param path The path of the file.
+func RecordFile(path string) {
+	if current == nil {
+		return
+	}
+	path = NormalizePath(path)
+	checksum, _ := Sha256File(path)
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	current.Files = append(current.Files, FileRecord{Path: path, Sha256: checksum})
+	appendJournal(JournalEntry{Kind: JournalFile, Path: path, Sha256: checksum})
+}
+
+// ownsFile checks whether a file has been recorded for the current tool (in this run or a previous one).
+func ownsFile(path string) bool {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	if current == nil {
+		return false
+	}
+	return ownedFiles[path] || slices.ContainsFunc(current.Files, func(f FileRecord) bool { return f.Path == path })
+}
+
+// prepareWrite prepares to write a file for the current tool.
+// A file which exists before being written (e.g., ~/.bashrc edited in place) is not placed by icon,
+// so it is not recorded for the tool (and never removed on uninstall) unless the tool placed it before.
+// The previous content of an existing file is saved so that it is restored on rollback.
+//
+// @param path The (normalized) path of the file.
+//
+// @return A function to call after the file has been written, which records the file.
+func prepareWrite(path string) func() {
+	if current == nil || !lexists(path) {
+		return func() { RecordFile(path) }
+	}
+	owned := ownsFile(path)
+	snapshot := ""
+	if journal != nil && !dryRun {
+		var err error
+		if snapshot, err = saveSnapshot(path); err != nil {
+			Warnf("The previous content of %s is not saved for rollback: %v\n", path, err)
+		}
+	}
+	return func() {
+		checksum, _ := Sha256File(path)
+		recordMu.Lock()
+		defer recordMu.Unlock()
+		if current == nil {
+			return
+		}
+		if owned {
+			current.Files = append(current.Files, FileRecord{Path: path, Sha256: checksum})
+		}
+		appendJournal(JournalEntry{Kind: JournalModified, Path: path, Target: snapshot, Sha256: checksum})
+	}
+}
+
+// RecordVersion records the installed version of the current tool.
+//
+// @param version The version of the tool.
+func RecordVersion(version string) {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	if current != nil {
+		current.Version = version
+	}
+}
+
+// RecordRelease records a release which the current tool (or a component of it) is installed from.
+//
+// @param release The release.
+func RecordRelease(release ReleaseRecord) {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	if current == nil {
+		return
+	}
+	current.Releases = slices.DeleteFunc(current.Releases, func(r ReleaseRecord) bool { return r.Repo == release.Repo })
+	current.Releases = append(current.Releases, release)
+}
+
+// RecordFlags records flags which the current tool is installed with.
+//
+// @param flags Names of flags (without the leading --) and their values.
+func RecordFlags(flags map[string][]string) {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	if current != nil {
+		current.Flags = flags
+	}
+}
+
+// RecordInstallMethod records a method used to install the current tool.
+//
+// @param method The install method, e.g., apt, brew or github.
+func RecordInstallMethod(method string) {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	if current != nil && !slices.Contains(current.Methods, method) {
+		current.Methods = append(current.Methods, method)
+	}
+}
+
+func recordSymlink(path, target string) {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	if current != nil {
+		current.Symlinks = append(current.Symlinks, SymlinkRecord{Path: path, Target: target})
+		appendJournal(JournalEntry{Kind: JournalSymlink, Path: path, Target: target})
+	}
+}
+
+func recordConfig(path, source string, doCopy bool) {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	if current != nil {
+		current.Configs = append(current.Configs, ConfigRecord{Path: path, Source: source, Copy: doCopy})
+	}
+}
+
+func recordBackup(original, backup string) {
+	recordMu.Lock()
+	defer recordMu.Unlock()
+	if current != nil {
+		current.Backups = append(current.Backups, BackupRecord{Original: original, Backup: backup, Time: time.Now()})
+		appendJournal(JournalEntry{Kind: JournalBackup, Path: original, Target: backup})
+	}
+}
+
+// recordInstallMethodOfCmd records the install method used by a shell command (if any) for the current tool.
+func recordInstallMethodOfCmd(cmd string) {
+	if current == nil {
+		return
+	}
+	for _, p := range installMethodPatterns {
+		if p.pattern.MatchString(cmd) {
+			RecordInstallMethod(p.method)
+		}
+	}
+}
+
+// Sha256File computes the SHA-256 checksum of a file.
+//
+// @param path The path of the file.
+//
+// @return The checksum as a hex string.
+func Sha256File(path string) (string, error) {
+	file, err := fsys.OpenFile(NormalizePath(path), os.O_RDONLY, 0)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer file.Close()
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// Drifts checks whether files and symbolic links recorded in the state still match the disk.
+//
+// @return Descriptions of paths which have been changed or removed since icon placed them.
+func (s *ToolState) Drifts() []string {
+	drifts := []string{}
+	for _, file := range s.Files {
+		if !ExistsPath(file.Path) {
+			drifts = append(drifts, file.Path+": removed")
+			continue
+		}
+		if file.Sha256 == "" {
+			continue
+		}
+		if checksum, err := Sha256File(file.Path); err == nil && checksum != file.Sha256 {
+			drifts = append(drifts, file.Path+": modified")
+		}
+	}
+	for _, link := range s.Symlinks {
+		target, err := fsys.Readlink(link.Path)
+		switch {
+		case errors.Is(err, os.ErrNotExist):
+			drifts = append(drifts, link.Path+": removed")
+		case err != nil:
+			drifts = append(drifts, link.Path+": no longer a symbolic link")
+		case target != link.Target:
+			drifts = append(drifts, fmt.Sprintf("%s: points to %s instead of %s", link.Path, target, link.Target))
+		}
+	}
+	return drifts
+}
+
+// Status summarizes the state as "installed", "configured", "installed, configured" or "recorded",
+// with ", drifted" appended if any recorded path no longer matches the disk.
+//
+// @return The status of the tool.
+func (s *ToolState) Status() string {
+	statuses := []string{}
+	if !s.InstalledAt.IsZero() {
+		statuses = append(statuses, "installed")
+	}
+	if !s.ConfiguredAt.IsZero() {
+		statuses = append(statuses, "configured")
+	}
+	if len(statuses) == 0 {
+		statuses = append(statuses, "recorded")
+	}
+	if len(s.Drifts()) > 0 {
+		statuses = append(statuses, "drifted")
+	}
+	return strings.Join(statuses, ", ")
+}
+
+// RemoveRecordedPaths removes files and symbolic links recorded in the state.
+// Symbolic links pointing elsewhere and files modified since icon placed them are kept.
+//
+// @return Paths which are kept.
+func (s *ToolState) RemoveRecordedPaths() ([]string, error) {
+	kept := []string{}
+	for _, link := range s.Symlinks {
+		target, err := fsys.Readlink(link.Path)
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil || target != link.Target {
+			kept = append(kept, link.Path)
+			continue
+		}
+		if err := RemoveAll(link.Path); err != nil {
+			return kept, err
+		}
+	}
+	for _, file := range s.Files {
+		if !ExistsPath(file.Path) {
+			continue
+		}
+		if file.Sha256 != "" {
+			if checksum, err := Sha256File(file.Path); err != nil || checksum != file.Sha256 {
+				kept = append(kept, file.Path)
+				continue
+			}
+		}
+		if err := RemoveAll(file.Path); err != nil {
+			return kept, err
+		}
+	}
+	return kept, nil
+}
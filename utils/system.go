@@ -0,0 +1,311 @@
+package utils
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// Executor runs commands on behalf of icon.
+// It is replaced (see SetExecutor) by a fake recording commands in tests.
+type Executor interface {
+	// Run runs a prepared command (with its arguments, working directory, environment and standard streams)
+	// and waits for it to finish. A failed command returns an error with an ExitCode() int method (e.g., *exec.ExitError).
+	Run(command *exec.Cmd) error
+	// LookPath searches for an executable in the directories of the environment variable PATH.
+	LookPath(file string) (string, error)
+	// CurrentUser returns the user running commands.
+	CurrentUser() (*user.User, error)
+}
+
+// osExecutor runs commands in the operating system.
+type osExecutor struct{}
+
+func (osExecutor) Run(command *exec.Cmd) error {
+	return command.Run()
+}
+
+func (osExecutor) LookPath(file string) (string, error) {
+	return exec.LookPath(file)
+}
+
+func (osExecutor) CurrentUser() (*user.User, error) {
+	return user.Current()
+}
+
+var executor Executor = osExecutor{}
+
+// SetExecutor replaces the executor of commands.
+// Information cached about the system (e.g., a validated sudo credential) is reset.
+//
+// @param e The new executor.
+//
+// @return A function restoring the previous executor.
+func SetExecutor(e Executor) func() {
+	previous := executor
+	executor = e
+	resetSystemCache()
+	return func() {
+		executor = previous
+		resetSystemCache()
+	}
+}
+
+// resetSystemCache forgets information cached about the system, which differs between executors.
+func resetSystemCache() {
+	sudoMu.Lock()
+	sudoValidated = false
+	sudoMu.Unlock()
+	for _, pm := range packageManagers {
+		pm.updated = false
+	}
+}
+
+// FS is the file system which helpers of files (e.g., CopyFile, Symlink, AppendToTextFile and ExistsPath) work on.
+// It is replaced (see SetFS) by a BasePathFS rooted at a temporary directory in tests.
+// Files of icon itself (e.g., states, caches and logs) are not accessed through it.
+type FS interface {
+	Stat(name string) (fs.FileInfo, error)
+	Lstat(name string) (fs.FileInfo, error)
+	ReadDir(name string) ([]fs.DirEntry, error)
+	ReadFile(name string) ([]byte, error)
+	WriteFile(name string, data []byte, perm fs.FileMode) error
+	OpenFile(name string, flag int, perm fs.FileMode) (*os.File, error)
+	MkdirAll(path string, perm fs.FileMode) error
+	RemoveAll(path string) error
+	Rename(oldpath, newpath string) error
+	Symlink(oldname, newname string) error
+	Readlink(name string) (string, error)
+	Chmod(name string, mode fs.FileMode) error
+	// Access checks whether the current user has permissions (unix.R_OK, unix.W_OK and unix.X_OK) on a path.
+	Access(path string, mode uint32) error
+}
+
+// osFS is the file system of the operating system.
+type osFS struct{}
+
+func (osFS) Stat(name string) (fs.FileInfo, error) {
+	return os.Stat(name)
+}
+
+func (osFS) Lstat(name string) (fs.FileInfo, error) {
+	return os.Lstat(name)
+}
+
+func (osFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	return os.ReadDir(name)
+}
+
+func (osFS) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(name)
+}
+
+func (osFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return os.WriteFile(name, data, perm)
+}
+
+func (osFS) OpenFile(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	return os.OpenFile(name, flag, perm)
+}
+
+func (osFS) MkdirAll(path string, perm fs.FileMode) error {
+	return os.MkdirAll(path, perm)
+}
+
+func (osFS) RemoveAll(path string) error {
+	return os.RemoveAll(path)
+}
+
+func (osFS) Rename(oldpath, newpath string) error {
+	return os.Rename(oldpath, newpath)
+}
+
+func (osFS) Symlink(oldname, newname string) error {
+	return os.Symlink(oldname, newname)
+}
+
+func (osFS) Readlink(name string) (string, error) {
+	return os.Readlink(name)
+}
+
+func (osFS) Chmod(name string, mode fs.FileMode) error {
+	return os.Chmod(name, mode)
+}
+
+func (osFS) Access(path string, mode uint32) error {
+	return unix.Access(path, mode)
+}
+
+var fsys FS = osFS{}
+
+// SetFS replaces the file system which helpers of files work on.
+//
+// @param f The new file system.
+//
+// @return A function restoring the previous file system.
+func SetFS(f FS) func() {
+	previous := fsys
+	fsys = f
+	return func() {
+		fsys = previous
+	}
+}
+
+// BasePathFS is a file system (like afero.BasePathFs) which maps all paths into a root directory,
+// e.g., /etc/os-release into <root>/etc/os-release.
+// Relative paths are relative to the root too.
+// Targets of symbolic links are mapped the same way so that links resolve inside the root.
+// Paths in shared directories (e.g., the directory of temporary files) are not mapped.
+type BasePathFS struct {
+	root   string
+	shared []string
+}
+
+// NewBasePathFS creates a file system rooted at a directory.
+//
+// @param root   The root directory.
+// @param shared Directories which are shared with the operating system instead of being mapped,
+// e.g., os.TempDir() so that temporary files and downloads are accessible to both.
+//
+// @return The file system.
+func NewBasePathFS(root string, shared ...string) *BasePathFS {
+	b := &BasePathFS{root: filepath.Clean(root)}
+	for _, dir := range shared {
+		b.shared = append(b.shared, filepath.Clean(dir))
+	}
+	return b
+}
+
+// isShared checks whether a path is in a shared directory.
+func (b *BasePathFS) isShared(name string) bool {
+	for _, dir := range b.shared {
+		if name == dir || strings.HasPrefix(name, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// RealPath returns the path in the operating system which a path is mapped to.
+//
+// @param name The path in the file system.
+//
+// @return The path in the operating system.
+func (b *BasePathFS) RealPath(name string) string {
+	name = filepath.Clean("/" + name)
+	if b.isShared(name) {
+		return name
+	}
+	return filepath.Join(b.root, name)
+}
+
+// virtualPath returns the path in the file system which a path in the operating system is mapped from.
+// Paths outside the root (e.g., in shared directories) are returned as they are.
+func (b *BasePathFS) virtualPath(real string) string {
+	if real == b.root {
+		return "/"
+	}
+	if rest, ok := strings.CutPrefix(real, b.root+"/"); ok {
+		return "/" + rest
+	}
+	return real
+}
+
+// pathError replaces paths in the operating system with paths in the file system in an error.
+func (b *BasePathFS) pathError(err error) error {
+	var pathErr *fs.PathError
+	if errors.As(err, &pathErr) {
+		return &fs.PathError{Op: pathErr.Op, Path: b.virtualPath(pathErr.Path), Err: pathErr.Err}
+	}
+	var linkErr *os.LinkError
+	if errors.As(err, &linkErr) {
+		return &os.LinkError{Op: linkErr.Op, Old: b.virtualPath(linkErr.Old), New: b.virtualPath(linkErr.New), Err: linkErr.Err}
+	}
+	return err
+}
+
+func (b *BasePathFS) Stat(name string) (fs.FileInfo, error) {
+	info, err := os.Stat(b.RealPath(name))
+	return info, b.pathError(err)
+}
+
+func (b *BasePathFS) Lstat(name string) (fs.FileInfo, error) {
+	info, err := os.Lstat(b.RealPath(name))
+	return info, b.pathError(err)
+}
+
+func (b *BasePathFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	entries, err := os.ReadDir(b.RealPath(name))
+	return entries, b.pathError(err)
+}
+
+func (b *BasePathFS) ReadFile(name string) ([]byte, error) {
+	data, err := os.ReadFile(b.RealPath(name))
+	return data, b.pathError(err)
+}
+
+func (b *BasePathFS) WriteFile(name string, data []byte, perm fs.FileMode) error {
+	return b.pathError(os.WriteFile(b.RealPath(name), data, perm))
+}
+
+func (b *BasePathFS) OpenFile(name string, flag int, perm fs.FileMode) (*os.File, error) {
+	file, err := os.OpenFile(b.RealPath(name), flag, perm)
+	return file, b.pathError(err)
+}
+
+func (b *BasePathFS) MkdirAll(path string, perm fs.FileMode) error {
+	return b.pathError(os.MkdirAll(b.RealPath(path), perm))
+}
+
+func (b *BasePathFS) RemoveAll(path string) error {
+	return b.pathError(os.RemoveAll(b.RealPath(path)))
+}
+
+func (b *BasePathFS) Rename(oldpath, newpath string) error {
+	return b.pathError(os.Rename(b.RealPath(oldpath), b.RealPath(newpath)))
+}
+
+func (b *BasePathFS) Symlink(oldname, newname string) error {
+	if filepath.IsAbs(oldname) {
+		oldname = b.RealPath(oldname)
+	}
+	return b.pathError(os.Symlink(oldname, b.RealPath(newname)))
+}
+
+func (b *BasePathFS) Readlink(name string) (string, error) {
+	target, err := os.Readlink(b.RealPath(name))
+	if err != nil {
+		return "", b.pathError(err)
+	}
+	return b.virtualPath(target), nil
+}
+
+func (b *BasePathFS) Chmod(name string, mode fs.FileMode) error {
+	return b.pathError(os.Chmod(b.RealPath(name), mode))
+}
+
+// Access checks permissions of the owner of a path,
+// as all files under the root are owned by the user running tests (who might be root).
+// So a read-only directory (e.g., /usr/local/bin with the mode 555) requires sudo to write into.
+func (b *BasePathFS) Access(path string, mode uint32) error {
+	info, err := os.Stat(b.RealPath(path))
+	if err != nil {
+		return b.pathError(err)
+	}
+	perm := info.Mode().Perm()
+	for _, check := range []struct {
+		mode uint32
+		bit  fs.FileMode
+	}{{unix.R_OK, 0o400}, {unix.W_OK, 0o200}, {unix.X_OK, 0o100}} {
+		if mode&check.mode != 0 && perm&check.bit == 0 {
+			return &fs.PathError{Op: "access", Path: path, Err: fs.ErrPermission}
+		}
+	}
+	return nil
+}
@@ -1,7 +1,7 @@
 package utils
 
 import (
-	"log"
+	"fmt"
 
 	"gopkg.in/yaml.v3"
 )
@@ -14,22 +14,26 @@ type UserConfig struct {
 }
 
 // ReadUserConfig reads and validates the shared user identity from
-// ~/.config/icon-data/user.yaml. It terminates the program if the file is
-// missing, cannot be parsed, or does not define both userName and userEmail.
-func ReadUserConfig() UserConfig {
+// ~/.config/icon-data/user.yaml. It returns a *MissingConfigError if the file
+// is missing or does not define both userName and userEmail.
+func ReadUserConfig() (UserConfig, error) {
 	file := "~/.config/icon-data/user.yaml"
+	var cfg UserConfig
 	if !ExistsFile(file) {
-		log.Fatalf("The user configuration file %s does not exist.", file)
+		return cfg, &MissingConfigError{Path: file}
 	}
-	var cfg UserConfig
-	if err := yaml.Unmarshal(ReadFile(file), &cfg); err != nil {
-		log.Fatalf("Error parsing %s: %v", file, err)
+	bytes, err := ReadFile(file)
+	if err != nil {
+		return cfg, err
+	}
+	if err := yaml.Unmarshal(bytes, &cfg); err != nil {
+		return cfg, fmt.Errorf("error parsing %s: %w", file, err)
 	}
 	if cfg.UserName == "" {
-		log.Fatalf("userName is not configured in %s.", file)
+		return cfg, &MissingConfigError{Path: file, Key: "userName"}
 	}
 	if cfg.UserEmail == "" {
-		log.Fatalf("userEmail is not configured in %s.", file)
+		return cfg, &MissingConfigError{Path: file, Key: "userEmail"}
 	}
-	return cfg
+	return cfg, nil
 }
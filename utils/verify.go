@@ -0,0 +1,138 @@
+package utils
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+var requireChecksum bool
+
+// SetRequireChecksum turns the policy of requiring checksums on or off.
+// When it is on, downloads without a checksum to verify against fail instead of being used unverified.
+//
+// @param b Whether to require checksums.
+func SetRequireChecksum(b bool) {
+	requireChecksum = b
+}
+
+// IsChecksumRequired checks whether downloads must be verified against checksums.
+//
+// @return true if checksums are required, false otherwise.
+func IsChecksumRequired() bool {
+	return requireChecksum
+}
+
+// sha256Pattern matches a SHA-256 checksum as a hex string.
+var sha256Pattern = regexp.MustCompile(`^[0-9a-fA-F]{64}$`)
+
+// bsdChecksumPattern matches a line of a checksum file in the BSD style, i.e., SHA256 (<name>) = <checksum>.
+var bsdChecksumPattern = regexp.MustCompile(`^SHA256 \((.+)\) = ([0-9a-fA-F]{64})$`)
+
+// ParseSha256Checksum finds the SHA-256 checksum of a file in the content of a checksum file.
+// Both the GNU style (<checksum>  <name>, as output by sha256sum)
+// and the BSD style (SHA256 (<name>) = <checksum>) are supported.
+//
+// @param content The content of a checksum file.
+// @param name    The name of the file whose checksum to find,
+// or an empty string for the first checksum (e.g., in <name>.sha256 which might contain only a checksum).
+//
+// @return The checksum in lower case, or an empty string if it is not found.
+func ParseSha256Checksum(content, name string) string {
+	scanner := bufio.NewScanner(strings.NewReader(content))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if match := bsdChecksumPattern.FindStringSubmatch(line); match != nil {
+			if name == "" || filepath.Base(match[1]) == name {
+				return strings.ToLower(match[2])
+			}
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 || !sha256Pattern.MatchString(fields[0]) {
+			continue
+		}
+		// sha256sum prefixes names of files read in binary mode with *
+		if name == "" || len(fields) > 1 && filepath.Base(strings.TrimPrefix(fields[1], "*")) == name {
+			return strings.ToLower(fields[0])
+		}
+	}
+	return ""
+}
+
+// VerifySha256 verifies a file against its SHA-256 checksum.
+// In dry-run mode, the verification is recorded into the plan.
+//
+// @param path     The path of the file.
+// @param expected The expected checksum as a hex string.
+//
+// @return A *ChecksumMismatchError if the checksum of the file does not match.
+func VerifySha256(path, expected string) error {
+	if dryRun {
+		recordStep("verify", "sha256 of %s is %s", path, expected)
+		return nil
+	}
+	actual, err := Sha256File(path)
+	if err != nil {
+		return err
+	}
+	if !strings.EqualFold(actual, expected) {
+		return &ChecksumMismatchError{Path: path, Expected: strings.ToLower(expected), Actual: actual}
+	}
+	return nil
+}
+
+// Signature verification methods.
+const (
+	// SignatureMinisign verifies signatures (*.minisig) using minisign and a public key.
+	SignatureMinisign = "minisign"
+	// SignatureGPG verifies signatures (*.asc or *.sig) using gpg and the keyring of the user.
+	SignatureGPG = "gpg"
+	// SignatureCosign verifies signatures (*.sig) using cosign and a public key.
+	SignatureCosign = "cosign"
+)
+
+// VerifySignature verifies the signature of a file using minisign, gpg or cosign.
+// In dry-run mode, the verification is recorded into the plan.
+//
+// @param method    One of SignatureMinisign, SignatureGPG and SignatureCosign.
+// @param path      The path of the file.
+// @param signature The path of the signature file.
+// @param key       The public key (or the path of it) for minisign and cosign. It is ignored by gpg.
+func VerifySignature(method, path, signature, key string) error {
+	var command *Command
+	switch method {
+	case SignatureMinisign:
+		// minisign accepts either a key file (-p) or a base64 encoded key (-P)
+		keyOption := IfElseString(ExistsFile(key), "-p", "-P")
+		command = NewCommand("minisign", "-V", "-m", path, "-x", signature, keyOption, key)
+	case SignatureGPG:
+		command = NewCommand("gpg", "--verify", signature, path)
+	case SignatureCosign:
+		command = NewCommand("cosign", "verify-blob", "--key", key, "--signature", signature, path)
+	default:
+		return fmt.Errorf("unknown signature verification method %s", method)
+	}
+	if !dryRun && LookPath(method) == "" {
+		return fmt.Errorf("%s is required to verify the signature of %s but it is not found", method, path)
+	}
+	if err := command.Run(); err != nil {
+		return fmt.Errorf("failed to verify the signature of %s using %s: %w", path, method, err)
+	}
+	return nil
+}
+
+// RemoveUnverified removes a downloaded file which failed verification so that it is not used by accident.
+//
+// @param path The path of the file.
+func RemoveUnverified(path string) {
+	if dryRun {
+		return
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		Warnf("Failed to remove the unverified file %s: %v\n", path, err)
+	}
+}